@@ -1,11 +1,13 @@
 package cliutils
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/paulscherrerinstitute/scicat-cli/v3/datasetUtils"
 	"golang.org/x/term"
@@ -13,65 +15,116 @@ import (
 
 // Authenticator is an abstraction used to support testing and custom auth backends.
 type Authenticator interface {
-	AuthenticateUser(httpClient *http.Client, APIServer string, username string, password string) (map[string]string, []string, error)
-	GetUserInfoFromToken(httpClient *http.Client, APIServer string, token string) (map[string]string, []string, error)
+	AuthenticateUser(ctx context.Context, httpClient *http.Client, APIServer string, username string, password string) (datasetUtils.User, error)
+	GetUserInfoFromToken(ctx context.Context, httpClient *http.Client, APIServer string, token string) (datasetUtils.User, error)
 }
 
 // RealAuthenticator delegates to real datasetUtils auth endpoints.
 type RealAuthenticator struct{}
 
-func (r RealAuthenticator) AuthenticateUser(httpClient *http.Client, APIServer string, username string, password string) (map[string]string, []string, error) {
-	user, groups, err := datasetUtils.AuthenticateUser(httpClient, APIServer, username, password, false)
+func (r RealAuthenticator) AuthenticateUser(ctx context.Context, httpClient *http.Client, APIServer string, username string, password string) (datasetUtils.User, error) {
+	user, err := datasetUtils.AuthenticateUser(ctx, httpClient, APIServer, username, password, false)
 	if err != nil {
-		user, groups, err = datasetUtils.AuthenticateUser(httpClient, APIServer, username, password, true)
+		if ctx.Err() != nil {
+			return datasetUtils.User{}, err
+		}
+		user, err = datasetUtils.AuthenticateUser(ctx, httpClient, APIServer, username, password, true)
 		if err != nil {
-			return map[string]string{}, []string{}, err
+			return datasetUtils.User{}, err
+		}
+		// PSI specific Kerberos user creation
+		if err := datasetUtils.RunKinit(username, password); err != nil {
+			log.Printf("Warning: could not get Kerberos tickets: %v\n", err)
 		}
-		datasetUtils.RunKinit(username, password) // PSI specific Kerberos user creation
 	}
-	return user, groups, err
+	return user, err
 }
 
-func (r RealAuthenticator) GetUserInfoFromToken(httpClient *http.Client, APIServer string, token string) (map[string]string, []string, error) {
-	return datasetUtils.GetUserInfoFromToken(httpClient, APIServer, token)
+func (r RealAuthenticator) GetUserInfoFromToken(ctx context.Context, httpClient *http.Client, APIServer string, token string) (datasetUtils.User, error) {
+	return datasetUtils.GetUserInfoFromToken(ctx, httpClient, APIServer, token)
 }
 
-var oidcTokenProvider func(string) string
+var oidcTokenProvider func(context.Context, string) (string, error)
+var oidcTokenRefresher func(context.Context) (string, time.Time, error)
 
-// SetOIDCTokenProvider configures the function used to fetch OIDC tokens.
-func SetOIDCTokenProvider(provider func(string) string) {
+// SetOIDCTokenProvider configures the function used to fetch OIDC tokens for the given API server.
+func SetOIDCTokenProvider(provider func(context.Context, string) (string, error)) {
 	oidcTokenProvider = provider
 }
 
-// Authenticate handles user authentication by prompting for credentials as needed.
-func Authenticate(authenticator Authenticator, httpClient *http.Client, apiServer string, userpass string, token string, oidc bool, overrideFatalExit ...func(v ...any)) (map[string]string, []string, error) {
+// SetOIDCTokenRefresher configures the function used to renew an OIDC token, e.g. with a refresh
+// token, which returns the new token and its expiry (zero if unknown).
+func SetOIDCTokenRefresher(refresher func(context.Context) (string, time.Time, error)) {
+	oidcTokenRefresher = refresher
+}
+
+// Authenticate handles user authentication by prompting for credentials as needed. If no
+// credentials are given, a session stored by the login command is reused until it expires.
+//
+// If the session can be renewed (logins with username and password, or OIDC logins with a
+// refresh token), httpClient is set up to renew the access token shortly before it expires and
+// to retry requests failing with 401 with a renewed token, so long running commands don't fail
+// halfway. Requests still carry the token of the returned user, it's replaced on the fly.
+// Cancelling ctx aborts the login.
+func Authenticate(ctx context.Context, authenticator Authenticator, httpClient *http.Client, apiServer string, userpass string, token string, oidc bool, overrideFatalExit ...func(v ...any)) (datasetUtils.User, error) {
 	fatalExit := log.Fatal // by default, call log fatal
 	if len(overrideFatalExit) == 1 {
 		fatalExit = overrideFatalExit[0]
 	}
+	user, refresh, err := authenticate(ctx, authenticator, httpClient, apiServer, userpass, token, oidc, true, fatalExit)
+	if err != nil {
+		return user, err
+	}
+	enableTokenRefresh(httpClient, user, refresh)
+	return user, nil
+}
+
+// Login authenticates like Authenticate, but always asks for new credentials instead of reusing
+// a stored session, and stores the resulting session for later commands.
+func Login(ctx context.Context, authenticator Authenticator, httpClient *http.Client, apiServer string, userpass string, token string, oidc bool) (StoredCredentials, error) {
+	user, _, err := authenticate(ctx, authenticator, httpClient, apiServer, userpass, token, oidc, false, log.Fatal)
+	if err != nil {
+		return StoredCredentials{}, err
+	}
+	creds := NewStoredCredentials(apiServer, user)
+	if creds.AccessToken == "" {
+		return StoredCredentials{}, fmt.Errorf("login didn't return an access token")
+	}
+	return creds, SaveCredentials(creds)
+}
 
+// authenticate returns the authenticated user, along with a function to renew its token if that's
+// possible for the used login method.
+func authenticate(ctx context.Context, authenticator Authenticator, httpClient *http.Client, apiServer string, userpass string, token string, oidc bool, useCache bool, fatalExit func(v ...any)) (datasetUtils.User, tokenRefreshFunc, error) {
 	if oidc {
 		if oidcTokenProvider == nil {
-			return map[string]string{}, []string{}, fmt.Errorf("oidc token provider is not configured")
+			return datasetUtils.User{}, nil, fmt.Errorf("oidc token provider is not configured")
 		}
-		token = oidcTokenProvider(apiServer + "/auth/oidc?client=CLI")
-		user, accessGroups, err := authenticator.GetUserInfoFromToken(httpClient, apiServer, token)
+		token, err := oidcTokenProvider(ctx, apiServer)
 		if err != nil {
-			return map[string]string{}, []string{}, err
+			return datasetUtils.User{}, nil, err
 		}
-		return user, accessGroups, nil
+		user, err := authenticator.GetUserInfoFromToken(ctx, httpClient, apiServer, token)
+		if err != nil {
+			return datasetUtils.User{}, nil, err
+		}
+		var refresh tokenRefreshFunc
+		if oidcTokenRefresher != nil {
+			refresh = func(ctx context.Context, _ *http.Client) (string, time.Time, error) { return oidcTokenRefresher(ctx) }
+		}
+		return user, refresh, nil
 	}
 
 	if token != "" {
-		user, accessGroups, err := authenticator.GetUserInfoFromToken(httpClient, apiServer, token)
+		user, err := authenticator.GetUserInfoFromToken(ctx, httpClient, apiServer, token)
 		if err != nil {
-			return map[string]string{}, []string{}, err
+			return datasetUtils.User{}, nil, err
 		}
 		uSplit := strings.Split(userpass, ":")
 		if len(uSplit) > 1 {
-			user["password"] = uSplit[1]
+			user.Password = uSplit[1]
 		}
-		return user, accessGroups, nil
+		return user, nil, nil
 	}
 
 	if userpass != "" {
@@ -89,7 +142,13 @@ func Authenticate(authenticator Authenticator, httpClient *http.Client, apiServe
 			}
 			pass = string(pw)
 		}
-		return authenticator.AuthenticateUser(httpClient, apiServer, user, pass)
+		return authenticateWithPassword(ctx, authenticator, httpClient, apiServer, user, pass)
+	}
+
+	if useCache {
+		if user, ok := authenticateFromCache(ctx, authenticator, httpClient, apiServer); ok {
+			return user, nil, nil
+		}
 	}
 
 	var username string
@@ -103,5 +162,42 @@ func Authenticate(authenticator Authenticator, httpClient *http.Client, apiServe
 	if err != nil {
 		fatalExit(err)
 	}
-	return authenticator.AuthenticateUser(httpClient, apiServer, username, string(pw))
+	return authenticateWithPassword(ctx, authenticator, httpClient, apiServer, username, string(pw))
+}
+
+// authenticateWithPassword logs in with username and password, which are kept to log in again
+// when the token has to be renewed.
+func authenticateWithPassword(ctx context.Context, authenticator Authenticator, httpClient *http.Client, apiServer string, username string, password string) (datasetUtils.User, tokenRefreshFunc, error) {
+	user, err := authenticator.AuthenticateUser(ctx, httpClient, apiServer, username, password)
+	if err != nil {
+		return user, nil, err
+	}
+	refresh := func(ctx context.Context, client *http.Client) (string, time.Time, error) {
+		renewed, err := authenticator.AuthenticateUser(ctx, client, apiServer, username, password)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		return renewed.AccessToken, renewed.Expires(), nil
+	}
+	return user, refresh, nil
+}
+
+// authenticateFromCache returns the user of the session stored for apiServer, if there's a valid one.
+func authenticateFromCache(ctx context.Context, authenticator Authenticator, httpClient *http.Client, apiServer string) (datasetUtils.User, bool) {
+	creds, ok, err := LoadCredentials(apiServer)
+	if err != nil {
+		log.Printf("Ignoring stored login session: %v\n", err)
+		return datasetUtils.User{}, false
+	}
+	if !ok || creds.Expired(time.Now()) {
+		return datasetUtils.User{}, false
+	}
+	user, err := authenticator.GetUserInfoFromToken(ctx, httpClient, apiServer, creds.AccessToken)
+	if err != nil {
+		log.Printf("Stored login session is no longer valid: %v\n", err)
+		return datasetUtils.User{}, false
+	}
+	user.ExpiresIn = creds.ExpiresIn
+	user.Created = creds.Created
+	return user, true
 }
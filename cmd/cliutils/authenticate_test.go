@@ -1,36 +1,41 @@
 package cliutils
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
 	"testing"
+	"time"
+
+	"github.com/paulscherrerinstitute/scicat-cli/v3/datasetUtils"
 )
 
 // Create a mock implementation of the interface
 type MockAuthenticator struct{}
 
-func (m *MockAuthenticator) AuthenticateUser(httpClient *http.Client, APIServer string, username string, password string) (map[string]string, []string, error) {
+func (m *MockAuthenticator) AuthenticateUser(ctx context.Context, httpClient *http.Client, APIServer string, username string, password string) (datasetUtils.User, error) {
 	if username == "" || password == "" {
-		return map[string]string{}, []string{}, fmt.Errorf("no username or password was provided")
+		return datasetUtils.User{}, fmt.Errorf("no username or password was provided")
 	}
-	return map[string]string{"username": username, "password": password}, []string{"group1", "group2"}, nil
+	return datasetUtils.User{Username: username, Password: password, AccessGroups: []string{"group1", "group2"}}, nil
 }
 
-func (m *MockAuthenticator) GetUserInfoFromToken(httpClient *http.Client, APIServer string, token string) (map[string]string, []string, error) {
+func (m *MockAuthenticator) GetUserInfoFromToken(ctx context.Context, httpClient *http.Client, APIServer string, token string) (datasetUtils.User, error) {
 	if token == "" {
-		return map[string]string{}, []string{}, fmt.Errorf("no token was provided")
+		return datasetUtils.User{}, fmt.Errorf("no token was provided")
 	}
-	return map[string]string{"username": "tokenuser", "password": "tokenpass"}, []string{"group3", "group4"}, nil
+	return datasetUtils.User{Username: "tokenuser", AccessToken: token, AccessGroups: []string{"group3", "group4"}}, nil
 }
 
 func TestAuthenticate(t *testing.T) {
 	var auth Authenticator = &MockAuthenticator{}
 	noExit := func(v ...any) {}
+	useTempCredentialsFile(t)
 
 	oldProvider := oidcTokenProvider
-	SetOIDCTokenProvider(func(_ string) string { return "mock-token" })
+	SetOIDCTokenProvider(func(_ context.Context, _ string) (string, error) { return "mock-token", nil })
 	defer SetOIDCTokenProvider(oldProvider)
 
 	// Mock HTTP server
@@ -41,55 +46,53 @@ func TestAuthenticate(t *testing.T) {
 
 	// Test cases
 	tests := []struct {
-		name      string
-		token     string
-		userpass  string
-		wantUser  map[string]string
-		wantGroup []string
+		name     string
+		token    string
+		userpass string
+		wantUser datasetUtils.User
 	}{
 		{
 			name:     "Test with token",
 			token:    "testtoken",
 			userpass: "",
-			wantUser: map[string]string{
-				"username": "tokenuser",
-				"password": "tokenpass",
+			wantUser: datasetUtils.User{
+				Username:     "tokenuser",
+				AccessToken:  "testtoken",
+				AccessGroups: []string{"group3", "group4"},
 			},
-			wantGroup: []string{"group3", "group4"},
 		},
 		{
-			name:      "Test with empty token and userpass",
-			token:     "",
-			userpass:  "",
-			wantUser:  map[string]string{},
-			wantGroup: []string{},
+			name:     "Test with empty token and userpass",
+			token:    "",
+			userpass: "",
+			wantUser: datasetUtils.User{},
 		},
 		{
 			name:     "Test with empty token and non-empty userpass",
 			token:    "",
 			userpass: "testuser:testpass",
-			wantUser: map[string]string{
-				"username": "testuser",
-				"password": "testpass",
+			wantUser: datasetUtils.User{
+				Username:     "testuser",
+				Password:     "testpass",
+				AccessGroups: []string{"group1", "group2"},
 			},
-			wantGroup: []string{"group1", "group2"},
 		},
 		{
 			name:     "Test with non-empty token and empty userpass",
 			token:    "testtoken",
 			userpass: "",
-			wantUser: map[string]string{
-				"username": "tokenuser",
-				"password": "tokenpass",
+			wantUser: datasetUtils.User{
+				Username:     "tokenuser",
+				AccessToken:  "testtoken",
+				AccessGroups: []string{"group3", "group4"},
 			},
-			wantGroup: []string{"group3", "group4"},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			httpClient := server.Client()
-			user, group, err := Authenticate(auth, httpClient, server.URL, tt.userpass, tt.token, false, noExit)
+			user, err := Authenticate(context.Background(), auth, httpClient, server.URL, tt.userpass, tt.token, false, noExit)
 			if err != nil {
 				if err.Error() != "no username or password was provided" {
 					t.Errorf("Authenticate returned an error: %s", err.Error())
@@ -99,10 +102,48 @@ func TestAuthenticate(t *testing.T) {
 			if !reflect.DeepEqual(user, tt.wantUser) {
 				t.Errorf("got %v, want %v", user, tt.wantUser)
 			}
-
-			if !reflect.DeepEqual(group, tt.wantGroup) {
-				t.Errorf("got %v, want %v", group, tt.wantGroup)
-			}
 		})
 	}
 }
+
+func TestAuthenticateWithStoredSession(t *testing.T) {
+	var auth Authenticator = &MockAuthenticator{}
+	noExit := func(v ...any) {}
+	useTempCredentialsFile(t)
+	apiServer := "https://scicat.example.com/api/v3"
+
+	err := SaveCredentials(StoredCredentials{
+		APIServer:   apiServer,
+		AccessToken: "storedtoken",
+		ExpiresIn:   3600,
+		Created:     "2024-01-02T10:00:00.000Z",
+		Expires:     time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user, err := Authenticate(context.Background(), auth, http.DefaultClient, apiServer, "", "", false, noExit)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if user.Username != "tokenuser" || user.ExpiresIn != 3600 || !reflect.DeepEqual(user.AccessGroups, []string{"group3", "group4"}) {
+		t.Errorf("expected the stored session to be used, got %v", user)
+	}
+
+	// explicit credentials take precedence over the stored session
+	user, _ = Authenticate(context.Background(), auth, http.DefaultClient, apiServer, "testuser:testpass", "", false, noExit)
+	if user.Username != "testuser" {
+		t.Errorf("expected explicit credentials to be used, got %v", user)
+	}
+
+	// an expired session isn't used, so the user gets prompted instead
+	err = SaveCredentials(StoredCredentials{APIServer: apiServer, AccessToken: "storedtoken", Expires: time.Now().Add(-time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, _ = Authenticate(context.Background(), auth, http.DefaultClient, apiServer, "", "", false, noExit)
+	if user.Username != "" {
+		t.Errorf("expired session should not be used, got %v", user)
+	}
+}
@@ -4,14 +4,14 @@ import (
 	"net/http"
 
 	"github.com/SwissOpenEM/globus"
+	"github.com/paulscherrerinstitute/scicat-cli/v3/datasetUtils"
 )
 
 type SshParams struct {
-	Client          *http.Client
-	ApiServer       string
-	User            map[string]string
-	RsyncServer     string
-	AbsFilelistPath string
+	Client      *http.Client
+	ApiServer   string
+	User        datasetUtils.User
+	RsyncServer string
 }
 
 type GlobusParams struct {
@@ -20,13 +20,14 @@ type GlobusParams struct {
 	SrcPrefixPath  string
 	DestCollection string
 	DestPrefixPath string
-	Filelist       []string
 	IsSymlinkList  []bool
 }
 
 type S3Params struct {
 	UploadBucket string
 	BrokerServer string
+	Endpoint     string
+	Region       string
 }
 
 type TransferParams struct {
@@ -36,4 +37,6 @@ type TransferParams struct {
 	// other params
 	DatasetId           string
 	DatasetSourceFolder string
+	// Filelist holds the scanned files of the dataset, only these are transferred
+	Filelist []string
 }
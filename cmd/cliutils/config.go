@@ -0,0 +1,166 @@
+package cliutils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/paulscherrerinstitute/scicat-cli/v3/datasetIngestor"
+	"github.com/paulscherrerinstitute/scicat-cli/v3/datasetUtils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// FileConfig is the content of the file passed via the global --config flag. Every field is
+// optional: empty values leave the selected environment profile (or the CLI flags) in charge.
+type FileConfig struct {
+	// the service URLs given at the top level override the ones of the default profile, they're
+	// ignored if a profile or environment flag is given on the command line
+	EnvironmentProfile `yaml:",inline"`
+	GlobusCfg          string `yaml:"globus-cfg,omitempty" json:"globus-cfg,omitempty"`
+
+	// Profile is the profile used when neither --profile nor an environment flag is given.
+	Profile string `yaml:"profile,omitempty" json:"profile,omitempty"`
+	// Profiles defines additional named environments, selectable with --profile.
+	Profiles map[string]EnvironmentProfile `yaml:"profiles,omitempty" json:"profiles,omitempty"`
+
+	// OIDC configures the provider used for logins with --oidc.
+	OIDC OIDCConfig `yaml:"oidc,omitempty" json:"oidc,omitempty"`
+
+	// HTTP configures TLS and proxies of the connections to SciCat and the other services.
+	HTTP HTTPConfig `yaml:"http,omitempty" json:"http,omitempty"`
+
+	// Authorization defines who may run the privileged commands (completeIngest, datasetCleaner
+	// and datasetPublishData), keyed by command name.
+	Authorization map[string]datasetUtils.AccessRule `yaml:"authorization,omitempty" json:"authorization,omitempty"`
+
+	// Facility replaces the PSI rules for instrument accounts and their access groups.
+	Facility *datasetIngestor.FacilityRules `yaml:"facility,omitempty" json:"facility,omitempty"`
+
+	// Defaults holds default values for flags of any command, keyed by flag name.
+	Defaults map[string]interface{} `yaml:"defaults,omitempty" json:"defaults,omitempty"`
+	// Commands holds default flag values for a single command, keyed by command name then
+	// flag name. They take precedence over Defaults.
+	Commands map[string]map[string]interface{} `yaml:"commands,omitempty" json:"commands,omitempty"`
+}
+
+// ReadConfigFile reads a YAML or JSON config file. The format is chosen by the file extension,
+// anything other than ".json" is parsed as YAML. Relative paths in the file (e.g. globus-cfg or
+// http.ca-cert) are resolved against the directory of the config file.
+func ReadConfigFile(path string) (FileConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return FileConfig{}, fmt.Errorf("can't read config file: %v", err)
+	}
+
+	var cfg FileConfig
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(data, &cfg)
+	} else {
+		err = yaml.Unmarshal(data, &cfg)
+	}
+	if err != nil {
+		return FileConfig{}, fmt.Errorf("can't unmarshal config file %q: %v", path, err)
+	}
+
+	if cfg.Facility != nil {
+		if err := cfg.Facility.Validate(); err != nil {
+			return FileConfig{}, fmt.Errorf("invalid facility rules in config file %q: %v", path, err)
+		}
+	}
+
+	if cfg.GlobusCfg != "" && !filepath.IsAbs(cfg.GlobusCfg) {
+		cfg.GlobusCfg = filepath.Join(filepath.Dir(path), cfg.GlobusCfg)
+	}
+	cfg.HTTP.resolvePaths(filepath.Dir(path))
+	return cfg, nil
+}
+
+// ApplyFileConfig fills the fields of c that were not set from the command line with the values
+// of the config file, so explicit flags keep precedence over the file. The profiles of the file
+// are added to the profile registry of c. The top level service URLs of the file only apply to
+// the default profile: an explicit --profile or environment flag selects the environment as is.
+func (c *InputEnvironmentConfig) ApplyFileConfig(cfg FileConfig) {
+	c.Profiles = MergeProfiles(cfg.Profiles)
+	profileSelected := c.Profile != "" || c.envFlagSet()
+	if !profileSelected {
+		c.Profile = cfg.Profile
+	}
+
+	setIfEmpty := func(field *string, value string) {
+		if *field == "" {
+			*field = value
+		}
+	}
+	if !profileSelected {
+		setIfEmpty(&c.ScicatUrl, cfg.ScicatUrl)
+		setIfEmpty(&c.RsyncUrl, cfg.RsyncUrl)
+		setIfEmpty(&c.RsyncRetrieveUrl, cfg.RsyncRetrieveUrl)
+		setIfEmpty(&c.S3UploadBucket, cfg.S3UploadBucket)
+		setIfEmpty(&c.S3BrokerUrl, cfg.S3BrokerUrl)
+		setIfEmpty(&c.S3Endpoint, cfg.S3Endpoint)
+		setIfEmpty(&c.S3Region, cfg.S3Region)
+	}
+	setIfEmpty(&c.GlobusCfg, cfg.GlobusCfg)
+}
+
+// AccessRule returns who may run the privileged command, only the archiveManager account unless
+// the config file says otherwise.
+func (cfg FileConfig) AccessRule(command string) datasetUtils.AccessRule {
+	if rule, ok := cfg.Authorization[command]; ok {
+		return rule
+	}
+	return datasetUtils.DefaultAccessRule
+}
+
+// FacilityRules returns the facility rules of the config file, or the PSI rules if it has none.
+func (cfg FileConfig) FacilityRules() datasetIngestor.FacilityRules {
+	if cfg.Facility != nil {
+		return *cfg.Facility
+	}
+	return datasetIngestor.DefaultFacilityRules()
+}
+
+// ApplyFlagDefaults sets the flags of cmd that weren't given on the command line to the values
+// from the "defaults" and "commands" sections of the config file. Flags unknown to cmd are ignored,
+// since the "defaults" section is shared by all commands.
+func ApplyFlagDefaults(cmd *cobra.Command, cfg FileConfig) error {
+	values := map[string]interface{}{}
+	for name, value := range cfg.Defaults {
+		values[name] = value
+	}
+	for name, value := range cfg.Commands[cmd.Name()] {
+		values[name] = value
+	}
+
+	for name, value := range values {
+		flag := cmd.Flags().Lookup(name)
+		if flag == nil || flag.Changed {
+			continue
+		}
+		if err := setFlagValue(flag, value); err != nil {
+			return fmt.Errorf("invalid default for flag %q in config file: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// setFlagValue sets flag without marking it as changed, so that checks like
+// cmd.Flags().Changed(...) still only report flags given on the command line.
+func setFlagValue(flag *pflag.Flag, value interface{}) error {
+	var s string
+	switch v := value.(type) {
+	case []interface{}:
+		parts := make([]string, len(v))
+		for i, part := range v {
+			parts[i] = fmt.Sprint(part)
+		}
+		s = strings.Join(parts, ",")
+	default:
+		s = fmt.Sprint(v)
+	}
+	return flag.Value.Set(s)
+}
@@ -0,0 +1,190 @@
+package cliutils
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/paulscherrerinstitute/scicat-cli/v3/datasetUtils"
+	"github.com/spf13/cobra"
+)
+
+func TestReadConfigFile(t *testing.T) {
+	dir := t.TempDir()
+
+	yamlPath := filepath.Join(dir, "config.yaml")
+	yamlContent := `# facility settings
+scicat-url: https://scicat.example.com/api/v3
+rsync-url: archive.example.com
+s3-endpoint: https://s3.example.com
+globus-cfg: globus.yaml
+http:
+  ca-cert: certs/ca.pem
+  proxy: http://proxy.example.com:3128
+defaults:
+  noninteractive: true
+  tapecopies: 2
+authorization:
+  datasetCleaner:
+    users: [archiveManager]
+    roles: [admin]
+`
+	if err := os.WriteFile(yamlPath, []byte(yamlContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	jsonPath := filepath.Join(dir, "config.json")
+	jsonContent := `{"scicat-url": "https://scicat.example.com/api/v3", "s3-upload-bucket": "bucket", "globus-cfg": "/etc/globus.yaml"}`
+	if err := os.WriteFile(jsonPath, []byte(jsonContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("yaml", func(t *testing.T) {
+		cfg, err := ReadConfigFile(yamlPath)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cfg.ScicatUrl != "https://scicat.example.com/api/v3" || cfg.RsyncUrl != "archive.example.com" || cfg.S3Endpoint != "https://s3.example.com" {
+			t.Errorf("unexpected config: %+v", cfg)
+		}
+		if cfg.GlobusCfg != filepath.Join(dir, "globus.yaml") {
+			t.Errorf("relative globus-cfg should be resolved against the config dir, got %q", cfg.GlobusCfg)
+		}
+		if cfg.HTTP.CACert != filepath.Join(dir, "certs", "ca.pem") || cfg.HTTP.Proxy != "http://proxy.example.com:3128" {
+			t.Errorf("unexpected http config: %+v", cfg.HTTP)
+		}
+		if len(cfg.Defaults) != 2 {
+			t.Errorf("expected 2 flag defaults, got %v", cfg.Defaults)
+		}
+		if rule := cfg.AccessRule("datasetCleaner"); len(rule.Roles) != 1 || rule.Roles[0] != "admin" {
+			t.Errorf("unexpected access rule for datasetCleaner: %+v", rule)
+		}
+		if rule := cfg.AccessRule("completeIngest"); !reflect.DeepEqual(rule, datasetUtils.DefaultAccessRule) {
+			t.Errorf("commands without a rule should use the default, got %+v", rule)
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		cfg, err := ReadConfigFile(jsonPath)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cfg.S3UploadBucket != "bucket" || cfg.GlobusCfg != "/etc/globus.yaml" {
+			t.Errorf("unexpected config: %+v", cfg)
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		if _, err := ReadConfigFile(filepath.Join(dir, "missing.yaml")); err == nil {
+			t.Error("expected an error for a missing file")
+		}
+	})
+}
+
+func TestApplyFileConfig(t *testing.T) {
+	cfg := FileConfig{
+		EnvironmentProfile: EnvironmentProfile{
+			ScicatUrl:      "https://file.example.com/api/v3",
+			RsyncUrl:       "file-rsync.example.com",
+			S3UploadBucket: "file-bucket",
+			S3Region:       "eu-central-1",
+		},
+	}
+	input := InputEnvironmentConfig{ScicatUrl: "https://flag.example.com/api/v3"}
+	input.ApplyFileConfig(cfg)
+
+	if got := input.ResolveAPIServer(); got != "https://flag.example.com/api/v3" {
+		t.Errorf("flag should take precedence over config file, got %s", got)
+	}
+	if got := input.ResolveRSYNCServer(); got != "file-rsync.example.com" {
+		t.Errorf("ResolveRSYNCServer() = %s, want file-rsync.example.com", got)
+	}
+	if got := input.ResolveS3UploadBucket(); got != "file-bucket" {
+		t.Errorf("ResolveS3UploadBucket() = %s, want file-bucket", got)
+	}
+	if got := input.ResolveS3Region(); got != "eu-central-1" {
+		t.Errorf("ResolveS3Region() = %s, want eu-central-1", got)
+	}
+	if got := input.ResolveS3Endpoint(); got != CSCS_CEPH_ENDPOINT {
+		t.Errorf("ResolveS3Endpoint() = %s, want %s", got, CSCS_CEPH_ENDPOINT)
+	}
+}
+
+func TestApplyFileConfigSelectedProfile(t *testing.T) {
+	cfg := FileConfig{
+		EnvironmentProfile: EnvironmentProfile{
+			ScicatUrl: "https://file.example.com/api/v3",
+			RsyncUrl:  "file-rsync.example.com",
+		},
+		Profiles: map[string]EnvironmentProfile{
+			"qa": {ScicatUrl: "https://qa.example.com/api/v3", RsyncUrl: "qa-rsync.example.com"},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		input     InputEnvironmentConfig
+		wantAPI   string
+		wantRsync string
+	}{
+		{"no profile uses the file URLs", InputEnvironmentConfig{}, "https://file.example.com/api/v3", "file-rsync.example.com"},
+		{"--testenv ignores the file URLs", InputEnvironmentConfig{TestenvFlag: true}, TEST_API_SERVER, TEST_RSYNC_ARCHIVE_SERVER},
+		{"--profile ignores the file URLs", InputEnvironmentConfig{Profile: "qa"}, "https://qa.example.com/api/v3", "qa-rsync.example.com"},
+		{"--scicat-url still overrides the profile", InputEnvironmentConfig{Profile: "qa", ScicatUrl: "https://flag.example.com/api/v3"}, "https://flag.example.com/api/v3", "qa-rsync.example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := tt.input
+			input.ApplyFileConfig(cfg)
+			if got := input.ResolveAPIServer(); got != tt.wantAPI {
+				t.Errorf("ResolveAPIServer() = %s, want %s", got, tt.wantAPI)
+			}
+			if got := input.ResolveRSYNCServer(); got != tt.wantRsync {
+				t.Errorf("ResolveRSYNCServer() = %s, want %s", got, tt.wantRsync)
+			}
+		})
+	}
+}
+
+func TestApplyFlagDefaults(t *testing.T) {
+	cmd := &cobra.Command{Use: "datasetIngestor"}
+	cmd.Flags().Bool("noninteractive", false, "")
+	cmd.Flags().Int("tapecopies", 0, "")
+	cmd.Flags().String("linkfiles", "keepInternalOnly", "")
+	cmd.Flags().Set("linkfiles", "keep")
+
+	cfg := FileConfig{
+		Defaults: map[string]interface{}{
+			"noninteractive": true,
+			"tapecopies":     1,
+			"linkfiles":      "delete",
+			"unknown-flag":   "ignored",
+		},
+		Commands: map[string]map[string]interface{}{
+			"datasetIngestor": {"tapecopies": 2},
+			"datasetArchiver": {"tapecopies": 3},
+		},
+	}
+	if err := ApplyFlagDefaults(cmd, cfg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !GetCobraBoolFlag(cmd, "noninteractive") {
+		t.Error("expected noninteractive to be set from defaults")
+	}
+	if cmd.Flags().Changed("noninteractive") {
+		t.Error("defaults from the config file should not mark flags as changed")
+	}
+	if got := GetCobraIntFlag(cmd, "tapecopies"); got != 2 {
+		t.Errorf("expected command specific default to win, got %d", got)
+	}
+	if got := GetCobraStringFlag(cmd, "linkfiles"); got != "keep" {
+		t.Errorf("explicitly set flag should not be overridden, got %s", got)
+	}
+
+	cfg = FileConfig{Defaults: map[string]interface{}{"tapecopies": "two"}}
+	if err := ApplyFlagDefaults(cmd, cfg); err == nil {
+		t.Error("expected an error for an invalid default value")
+	}
+}
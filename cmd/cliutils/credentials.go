@@ -0,0 +1,137 @@
+package cliutils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/paulscherrerinstitute/scicat-cli/v3/datasetUtils"
+)
+
+// tokens expiring within this margin are treated as expired, so that a command doesn't start
+// with a token that runs out right away
+const tokenExpiryMargin = time.Minute
+
+// StoredCredentials is a login session persisted by the login command.
+type StoredCredentials struct {
+	APIServer    string    `json:"apiServer"`
+	AccessToken  string    `json:"accessToken"`
+	ExpiresIn    int       `json:"expiresIn,omitempty"`
+	Created      string    `json:"created,omitempty"`
+	Expires      time.Time `json:"expires,omitzero"`
+	Username     string    `json:"username,omitempty"`
+	DisplayName  string    `json:"displayName,omitempty"`
+	Mail         string    `json:"mail,omitempty"`
+	AccessGroups []string  `json:"accessGroups,omitempty"`
+}
+
+// credentialsFilePath returns the location of the credentials file, it's a variable so tests can
+// redirect it to a temporary directory
+var credentialsFilePath = func() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("can't determine the user config directory: %v", err)
+	}
+	return filepath.Join(dir, "scicat-cli", "credentials.json"), nil
+}
+
+// NewStoredCredentials creates the credentials of a session from the user returned by
+// Authenticate. If the lifetime of the token is unknown, it's assumed to never expire.
+func NewStoredCredentials(apiServer string, user datasetUtils.User) StoredCredentials {
+	return StoredCredentials{
+		APIServer:    apiServer,
+		AccessToken:  user.AccessToken,
+		ExpiresIn:    user.ExpiresIn,
+		Created:      user.Created,
+		Expires:      user.Expires(),
+		Username:     user.Username,
+		DisplayName:  user.DisplayName,
+		Mail:         user.Mail,
+		AccessGroups: user.AccessGroups,
+	}
+}
+
+// Expired reports whether the token has expired (or is about to) at the given time.
+func (c StoredCredentials) Expired(now time.Time) bool {
+	if c.Expires.IsZero() {
+		return false
+	}
+	return !now.Add(tokenExpiryMargin).Before(c.Expires)
+}
+
+// readCredentialsFile returns the stored sessions keyed by API server. A missing file is no error.
+func readCredentialsFile() (map[string]StoredCredentials, error) {
+	path, err := credentialsFilePath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]StoredCredentials{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't read credentials file: %v", err)
+	}
+	creds := map[string]StoredCredentials{}
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return nil, fmt.Errorf("can't unmarshal credentials file %q: %v", path, err)
+	}
+	return creds, nil
+}
+
+// writeCredentialsFile stores the sessions, the file is only readable by the current user.
+func writeCredentialsFile(creds map[string]StoredCredentials) error {
+	path, err := credentialsFilePath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("can't create credentials directory: %v", err)
+	}
+	data, err := json.MarshalIndent(creds, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("can't write credentials file: %v", err)
+	}
+	// WriteFile keeps the permissions of an already existing file
+	return os.Chmod(path, 0600)
+}
+
+// LoadCredentials returns the stored session for apiServer. The second return value is false if
+// there's none.
+func LoadCredentials(apiServer string) (StoredCredentials, bool, error) {
+	creds, err := readCredentialsFile()
+	if err != nil {
+		return StoredCredentials{}, false, err
+	}
+	c, ok := creds[apiServer]
+	return c, ok, nil
+}
+
+// SaveCredentials stores the session c, replacing any previous session for the same API server.
+func SaveCredentials(c StoredCredentials) error {
+	creds, err := readCredentialsFile()
+	if err != nil {
+		return err
+	}
+	creds[c.APIServer] = c
+	return writeCredentialsFile(creds)
+}
+
+// RemoveCredentials deletes the stored session for apiServer, if any.
+func RemoveCredentials(apiServer string) error {
+	creds, err := readCredentialsFile()
+	if err != nil {
+		return err
+	}
+	if _, ok := creds[apiServer]; !ok {
+		return nil
+	}
+	delete(creds, apiServer)
+	return writeCredentialsFile(creds)
+}
@@ -0,0 +1,89 @@
+package cliutils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/paulscherrerinstitute/scicat-cli/v3/datasetUtils"
+)
+
+// useTempCredentialsFile redirects the credentials file to a temporary directory for one test
+func useTempCredentialsFile(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "scicat-cli", "credentials.json")
+	old := credentialsFilePath
+	credentialsFilePath = func() (string, error) { return path, nil }
+	t.Cleanup(func() { credentialsFilePath = old })
+	return path
+}
+
+func TestNewStoredCredentials(t *testing.T) {
+	user := datasetUtils.User{
+		AccessToken:  "token",
+		Username:     "user",
+		ExpiresIn:    3600,
+		Created:      "2024-01-02T10:00:00.000Z",
+		AccessGroups: []string{"group1"},
+	}
+	creds := NewStoredCredentials("https://scicat.example.com/api/v3", user)
+	want := time.Date(2024, 1, 2, 11, 0, 0, 0, time.UTC)
+	if !creds.Expires.Equal(want) {
+		t.Errorf("Expires = %v, want %v", creds.Expires, want)
+	}
+	if !creds.Expired(want.Add(-30 * time.Second)) {
+		t.Error("a token expiring within the margin should count as expired")
+	}
+	if creds.Expired(want.Add(-time.Hour)) {
+		t.Error("token should not be expired yet")
+	}
+
+	user.ExpiresIn = 0
+	creds = NewStoredCredentials("https://scicat.example.com/api/v3", user)
+	if !creds.Expires.IsZero() || creds.Expired(time.Now()) {
+		t.Error("a token with unknown lifetime should never count as expired")
+	}
+}
+
+func TestSaveLoadRemoveCredentials(t *testing.T) {
+	path := useTempCredentialsFile(t)
+
+	if _, ok, err := LoadCredentials("https://a.example.com"); ok || err != nil {
+		t.Fatalf("expected no credentials without a file, got ok=%v err=%v", ok, err)
+	}
+
+	a := StoredCredentials{APIServer: "https://a.example.com", AccessToken: "token-a", Username: "a", AccessGroups: []string{"g1"}}
+	b := StoredCredentials{APIServer: "https://b.example.com", AccessToken: "token-b", Username: "b"}
+	for _, c := range []StoredCredentials{a, b} {
+		if err := SaveCredentials(c); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("credentials file has permissions %v, want 0600", info.Mode().Perm())
+	}
+
+	got, ok, err := LoadCredentials(a.APIServer)
+	if err != nil || !ok {
+		t.Fatalf("expected stored credentials, got ok=%v err=%v", ok, err)
+	}
+	if got.AccessToken != "token-a" || len(got.AccessGroups) != 1 {
+		t.Errorf("unexpected credentials: %+v", got)
+	}
+
+	if err := RemoveCredentials(a.APIServer); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok, _ := LoadCredentials(a.APIServer); ok {
+		t.Error("credentials should have been removed")
+	}
+	if _, ok, _ := LoadCredentials(b.APIServer); !ok {
+		t.Error("credentials of other servers should be kept")
+	}
+}
@@ -0,0 +1,289 @@
+package cliutils
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"maps"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxCapturedBody is the number of bytes of a body kept for the trace file.
+	maxCapturedBody = 64 * 1024
+	// maxLoggedBody is the number of bytes of a body written to the log.
+	maxLoggedBody = 2048
+	redacted      = "[REDACTED]"
+)
+
+// secretHeaders are headers whose values are never logged.
+var secretHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Amz-Security-Token"}
+
+// secretJSONField matches string fields holding passwords or tokens in JSON, even in truncated
+// documents.
+var secretJSONField = regexp.MustCompile(`(?i)("(?:password|[a-z_]*token|jwt|client_secret|code_verifier)"\s*:\s*)"((?:[^"\\]|\\.)*)"`)
+
+var bearerToken = regexp.MustCompile(`(?i)(bearer\s+)[^\s"',]+`)
+
+// isSecretParam reports whether a query or form parameter holds a password or token.
+func isSecretParam(name string) bool {
+	name = strings.ToLower(name)
+	return name == "password" || name == "jwt" || name == "client_secret" || name == "code_verifier" ||
+		strings.HasSuffix(name, "token")
+}
+
+// debugTransport traces the requests of a client: method, URL, status, latency and the beginning
+// of textual bodies are written to a logger and/or as HAR entries (one JSON object per line) to a
+// trace file. Tokens and passwords are redacted in both.
+type debugTransport struct {
+	base   http.RoundTripper
+	logger *log.Logger
+	trace  io.Writer
+	mu     sync.Mutex
+}
+
+// NewDebugTransport returns a transport tracing the requests sent through base to logger and
+// trace, either can be nil. If trace has a Sync method, like *os.File, it's called after every
+// entry.
+func NewDebugTransport(base http.RoundTripper, logger *log.Logger, trace io.Writer) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &debugTransport{base: base, logger: logger, trace: trace}
+}
+
+// harEntry is an entry of the HAR (HTTP Archive) format, fields starting with an underscore are
+// extensions.
+type harEntry struct {
+	StartedDateTime time.Time    `json:"startedDateTime"`
+	Time            float64      `json:"time"`
+	Request         harRequest   `json:"request"`
+	Response        *harResponse `json:"response,omitempty"`
+	Error           string       `json:"_error,omitempty"`
+}
+
+type harRequest struct {
+	Method   string      `json:"method"`
+	URL      string      `json:"url"`
+	Headers  []harHeader `json:"headers"`
+	PostData *harContent `json:"postData,omitempty"`
+}
+
+type harResponse struct {
+	Status     int         `json:"status"`
+	StatusText string      `json:"statusText"`
+	Headers    []harHeader `json:"headers"`
+	Content    harContent  `json:"content"`
+}
+
+type harHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harContent struct {
+	MimeType  string `json:"mimeType"`
+	Text      string `json:"text,omitempty"`
+	Truncated bool   `json:"_truncated,omitempty"`
+}
+
+func (t *debugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	entry := harEntry{
+		StartedDateTime: time.Now(),
+		Request: harRequest{
+			Method:   req.Method,
+			URL:      redactURL(req.URL),
+			Headers:  redactHeaders(req.Header),
+			PostData: captureRequestBody(req),
+		},
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	latency := time.Since(entry.StartedDateTime)
+	entry.Time = float64(latency.Microseconds()) / 1000
+	if err != nil {
+		entry.Error = err.Error()
+	} else {
+		entry.Response = &harResponse{
+			Status:     resp.StatusCode,
+			StatusText: http.StatusText(resp.StatusCode),
+			Headers:    redactHeaders(resp.Header),
+			Content:    captureResponseBody(resp),
+		}
+	}
+	t.record(entry, latency)
+	return resp, err
+}
+
+// record writes entry to the logger and the trace file.
+func (t *debugTransport) record(entry harEntry, latency time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.logger != nil {
+		latency = latency.Round(time.Millisecond)
+		if entry.Response == nil {
+			t.logger.Printf("HTTP %s %s failed after %v: %s\n", entry.Request.Method, entry.Request.URL, latency, entry.Error)
+		} else {
+			t.logger.Printf("HTTP %s %s -> %d %s (%v)\n", entry.Request.Method, entry.Request.URL, entry.Response.Status, entry.Response.StatusText, latency)
+		}
+		if body := entry.Request.PostData; body != nil && body.Text != "" {
+			t.logger.Printf("HTTP request body: %s\n", logText(*body))
+		}
+		if entry.Response != nil && entry.Response.Content.Text != "" {
+			t.logger.Printf("HTTP response body: %s\n", logText(entry.Response.Content))
+		}
+	}
+	if t.trace != nil {
+		line, err := json.Marshal(entry)
+		if err == nil {
+			_, err = t.trace.Write(append(line, '\n'))
+		}
+		if syncer, ok := t.trace.(interface{ Sync() error }); ok && err == nil {
+			err = syncer.Sync()
+		}
+		if err != nil && t.logger != nil {
+			t.logger.Printf("Can't write the HTTP trace: %v\n", err)
+		}
+	}
+}
+
+// logText returns the body text shortened to maxLoggedBody.
+func logText(content harContent) string {
+	text := content.Text
+	if len(text) > maxLoggedBody {
+		return text[:maxLoggedBody] + "... (truncated)"
+	}
+	if content.Truncated {
+		return text + "... (truncated)"
+	}
+	return text
+}
+
+// captureRequestBody returns the beginning of a textual request body without consuming it, or nil
+// if there's no body.
+func captureRequestBody(req *http.Request) *harContent {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil
+	}
+	content := &harContent{MimeType: req.Header.Get("Content-Type")}
+	if !isText(content.MimeType) || req.GetBody == nil {
+		return content
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return content
+	}
+	defer body.Close()
+	data, truncated := truncateBody(readPrefix(body))
+	content.Text, content.Truncated = redactBody(content.MimeType, data), truncated
+	return content
+}
+
+// captureResponseBody returns the beginning of a textual response body. The captured bytes are
+// put back in front of the rest of the body, so the caller still reads the complete response.
+func captureResponseBody(resp *http.Response) harContent {
+	content := harContent{MimeType: resp.Header.Get("Content-Type")}
+	if !isText(content.MimeType) {
+		return content
+	}
+	prefix := readPrefix(resp.Body)
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(prefix), resp.Body), resp.Body}
+	data, truncated := truncateBody(prefix)
+	content.Text, content.Truncated = redactBody(content.MimeType, data), truncated
+	return content
+}
+
+// readPrefix reads one byte more than maxCapturedBody of r, to tell whether it's truncated.
+func readPrefix(r io.Reader) []byte {
+	data, _ := io.ReadAll(io.LimitReader(r, maxCapturedBody+1))
+	return data
+}
+
+// truncateBody shortens data to maxCapturedBody, and reports whether it was longer.
+func truncateBody(data []byte) ([]byte, bool) {
+	if len(data) > maxCapturedBody {
+		return data[:maxCapturedBody], true
+	}
+	return data, false
+}
+
+// isText reports whether bodies of the media type can be logged.
+func isText(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(mediaType, "text/") || mediaType == "application/x-www-form-urlencoded" ||
+		strings.HasSuffix(mediaType, "json") || strings.HasSuffix(mediaType, "xml")
+}
+
+// redactBody removes passwords and tokens from a form or JSON body.
+func redactBody(contentType string, data []byte) string {
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType == "application/x-www-form-urlencoded" {
+		if values, err := url.ParseQuery(string(data)); err == nil {
+			return redactValues(values).Encode()
+		}
+	}
+	text := string(data)
+	// SciCat repeats the access token of a login in other fields (e.g. "id"), so the values of
+	// secret fields are removed wherever they appear
+	for _, match := range secretJSONField.FindAllStringSubmatch(text, -1) {
+		if len(match[2]) >= 8 {
+			text = strings.ReplaceAll(text, match[2], redacted)
+		}
+	}
+	text = secretJSONField.ReplaceAllString(text, `$1"`+redacted+`"`)
+	return bearerToken.ReplaceAllString(text, "${1}"+redacted)
+}
+
+// redactURL returns u without passwords and tokens in the user info and query.
+func redactURL(u *url.URL) string {
+	redactedURL := *u
+	if u.RawQuery != "" {
+		if values, err := url.ParseQuery(u.RawQuery); err == nil {
+			redactedURL.RawQuery = redactValues(values).Encode()
+		}
+	}
+	return redactedURL.Redacted()
+}
+
+func redactValues(values url.Values) url.Values {
+	for name := range values {
+		if isSecretParam(name) {
+			values[name] = []string{redacted}
+		}
+	}
+	return values
+}
+
+// redactHeaders returns the headers in HAR format, with credentials replaced. The scheme of
+// authorization headers (e.g. "Bearer") is kept.
+func redactHeaders(header http.Header) []harHeader {
+	headers := []harHeader{}
+	for _, name := range slices.Sorted(maps.Keys(header)) {
+		secret := slices.ContainsFunc(secretHeaders, func(h string) bool { return strings.EqualFold(name, h) })
+		for _, value := range header[name] {
+			if secret {
+				scheme, _, ok := strings.Cut(value, " ")
+				if ok && strings.HasSuffix(strings.ToLower(name), "authorization") {
+					value = scheme + " " + redacted
+				} else {
+					value = redacted
+				}
+			}
+			headers = append(headers, harHeader{Name: name, Value: value})
+		}
+	}
+	return headers
+}
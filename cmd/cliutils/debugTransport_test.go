@@ -0,0 +1,132 @@
+package cliutils
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDebugTransport(t *testing.T) {
+	largeBody := `{"pid":"` + strings.Repeat("x", maxCapturedBody) + `"}`
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		switch req.URL.Path {
+		case "/Users/login":
+			rw.Write([]byte(`{"id":"secretToken1234","access_token":"secretToken1234","userId":"u1"}`))
+		case "/Datasets":
+			rw.WriteHeader(http.StatusBadRequest)
+			rw.Write([]byte(`{"statusCode":400,"message":"ownerGroup must be a string"}`))
+		default:
+			rw.Write([]byte(largeBody))
+		}
+	}))
+	defer server.Close()
+
+	logs, trace := &bytes.Buffer{}, &bytes.Buffer{}
+	client := &http.Client{Transport: NewDebugTransport(nil, log.New(logs, "", 0), trace)}
+
+	send := func(method, path, contentType, body string) string {
+		req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("Authorization", "Bearer secretToken1234")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	send("POST", "/Users/login", "application/json", `{"username":"ingestor","password":"secretPassword"}`)
+	send("POST", "/Datasets?access_token=secretToken1234", "application/json", `{"ownerGroup":1}`)
+	send("POST", "/token", "application/x-www-form-urlencoded", "grant_type=refresh_token&refresh_token=secretToken1234")
+	if got := send("GET", "/Datasets/large", "", ""); got != largeBody {
+		t.Errorf("the response body was changed by the trace, got %d bytes, want %d", len(got), len(largeBody))
+	}
+
+	for name, output := range map[string]string{"log": logs.String(), "trace": trace.String()} {
+		for _, secret := range []string{"secretToken1234", "secretPassword"} {
+			if strings.Contains(output, secret) {
+				t.Errorf("%s contains %q:\n%s", name, secret, output)
+			}
+		}
+	}
+	for _, want := range []string{
+		"HTTP POST " + server.URL + "/Datasets?access_token=%5BREDACTED%5D -> 400 Bad Request",
+		`HTTP response body: {"statusCode":400,"message":"ownerGroup must be a string"}`,
+		`HTTP request body: {"username":"ingestor","password":"[REDACTED]"}`,
+		"... (truncated)",
+	} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("log doesn't contain %q:\n%s", want, logs.String())
+		}
+	}
+
+	lines := strings.Split(strings.TrimSpace(trace.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected 4 trace entries, got %d", len(lines))
+	}
+	var entry harEntry
+	if err := json.Unmarshal([]byte(lines[1]), &entry); err != nil {
+		t.Fatalf("invalid trace entry: %v", err)
+	}
+	if entry.Request.Method != "POST" || entry.Response == nil || entry.Response.Status != 400 {
+		t.Errorf("unexpected trace entry: %+v", entry)
+	}
+	var authorization string
+	for _, header := range entry.Request.Headers {
+		if header.Name == "Authorization" {
+			authorization = header.Value
+		}
+	}
+	if authorization != "Bearer [REDACTED]" {
+		t.Errorf("Authorization header in trace = %q, want %q", authorization, "Bearer [REDACTED]")
+	}
+	if err := json.Unmarshal([]byte(lines[3]), &entry); err != nil {
+		t.Fatalf("invalid trace entry: %v", err)
+	}
+	if !entry.Response.Content.Truncated || len(entry.Response.Content.Text) != maxCapturedBody {
+		t.Errorf("expected a truncated body of %d bytes, got %d bytes", maxCapturedBody, len(entry.Response.Content.Text))
+	}
+}
+
+// syncedBuffer counts the Sync calls, like those of an *os.File
+type syncedBuffer struct {
+	bytes.Buffer
+	syncs int
+}
+
+func (b *syncedBuffer) Sync() error {
+	b.syncs++
+	return nil
+}
+
+func TestDebugTransportSyncsTrace(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {}))
+	defer server.Close()
+
+	trace := &syncedBuffer{}
+	client := &http.Client{Transport: NewDebugTransport(nil, nil, trace)}
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp.Body.Close()
+	}
+	if trace.syncs != 2 {
+		t.Errorf("expected the trace to be synced after each of the 2 entries, got %d syncs", trace.syncs)
+	}
+}
@@ -1,17 +1,34 @@
 package cliutils
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 )
 
+// InputEnvironmentConfig selects the SciCat environment to use. The environment is taken from the
+// named Profile (or, for backwards compatibility, from one of the *envFlag fields), and the
+// non-empty service URLs set here override the ones of that profile.
 type InputEnvironmentConfig struct {
-	TestenvFlag   bool
-	DevenvFlag    bool
-	TunnelenvFlag bool
-	LocalenvFlag  bool
-	ScicatUrl     string
-	RsyncUrl      string
+	TestenvFlag      bool
+	DevenvFlag       bool
+	TunnelenvFlag    bool
+	LocalenvFlag     bool
+	Profile          string
+	ScicatUrl        string
+	RsyncUrl         string
+	RsyncRetrieveUrl string
+	S3UploadBucket   string
+	S3BrokerUrl      string
+	S3Endpoint       string
+	S3Region         string
+	GlobusCfg        string
+	// Profiles is the registry Profile is looked up in, the built-in profiles are used when nil
+	Profiles map[string]EnvironmentProfile
 }
 
 type EnvironmentConfig struct {
@@ -19,26 +36,41 @@ type EnvironmentConfig struct {
 	Env       string
 }
 
-func (c *InputEnvironmentConfig) getBaseConfig() EnvironmentConfig {
-	config := EnvironmentConfig{
-		APIServer: PROD_API_SERVER,
-		Env:       "production",
-	}
-	if c.TunnelenvFlag {
-		config.APIServer = TUNNEL_API_SERVER
-		config.Env = "dev"
-	}
-	if c.LocalenvFlag {
-		config.APIServer = LOCAL_API_SERVER
-		config.Env = "local"
+// profileName returns the name of the selected profile. An explicit Profile has precedence over
+// the *envFlag fields, which in turn have precedence over the default production profile.
+func (c *InputEnvironmentConfig) profileName() string {
+	switch {
+	case c.Profile != "":
+		return c.Profile
+	case c.TestenvFlag:
+		return TEST_PROFILE
+	case c.DevenvFlag:
+		return DEV_PROFILE
+	case c.LocalenvFlag:
+		return LOCAL_PROFILE
+	case c.TunnelenvFlag:
+		return TUNNEL_PROFILE
 	}
-	if c.DevenvFlag {
-		config.APIServer = DEV_API_SERVER
-		config.Env = "dev"
+	return PROD_PROFILE
+}
+
+func (c *InputEnvironmentConfig) envFlagSet() bool {
+	return c.TestenvFlag || c.DevenvFlag || c.LocalenvFlag || c.TunnelenvFlag
+}
+
+// profile returns the selected profile, or an empty profile if it isn't registered.
+func (c *InputEnvironmentConfig) profile() EnvironmentProfile {
+	profiles := c.Profiles
+	if profiles == nil {
+		profiles = BuiltinProfiles()
 	}
-	if c.TestenvFlag {
-		config.APIServer = TEST_API_SERVER
-		config.Env = "test"
+	return profiles[c.profileName()]
+}
+
+func (c *InputEnvironmentConfig) getBaseConfig() EnvironmentConfig {
+	config := EnvironmentConfig{
+		APIServer: c.profile().ScicatUrl,
+		Env:       c.profileName(),
 	}
 	if c.ScicatUrl != "" {
 		config.APIServer = c.ScicatUrl
@@ -47,51 +79,56 @@ func (c *InputEnvironmentConfig) getBaseConfig() EnvironmentConfig {
 
 	return config
 }
+
 func (c *InputEnvironmentConfig) ResolveAPIServer() string {
 	cfg := c.getBaseConfig()
 	color.Green("You are about to interact with the === %s === data catalog environment...", cfg.Env)
 	return cfg.APIServer
 }
 
-func (c *InputEnvironmentConfig) ResolveRSYNCServer() string {
-	if c.RsyncUrl != "" && c.ScicatUrl != "" {
-		return c.RsyncUrl
+// resolve returns override if set, else the value of the selected profile
+func resolve(override string, profileValue string) string {
+	if override != "" {
+		return override
 	}
+	return profileValue
+}
 
-	if c.TestenvFlag {
-		return TEST_RSYNC_ARCHIVE_SERVER
-	}
-	if c.DevenvFlag {
-		return DEV_RSYNC_ARCHIVE_SERVER
-	}
-	if c.LocalenvFlag {
-		return LOCAL_RSYNC_ARCHIVE_SERVER
-	}
-	if c.TunnelenvFlag {
-		return TUNNEL_RSYNC_ARCHIVE_SERVER
-	}
+func (c *InputEnvironmentConfig) ResolveRSYNCServer() string {
+	return resolve(c.RsyncUrl, c.profile().RsyncUrl)
+}
 
-	return PROD_RSYNC_ARCHIVE_SERVER
+func (c *InputEnvironmentConfig) ResolveRSYNCRetrieveServer() string {
+	return resolve(c.RsyncRetrieveUrl, c.profile().RsyncRetrieveUrl)
 }
 
 func (c *InputEnvironmentConfig) ResolveS3UploadBucket() string {
-	if c.TestenvFlag {
-		return TEST_S3_UPLOAD_BUCKET
-	}
-	if c.DevenvFlag {
-		return DEV_S3_UPLOAD_BUCKET
-	}
-	return PROD_S3_UPLOAD_BUCKET
+	return resolve(c.S3UploadBucket, c.profile().S3UploadBucket)
 }
 
 func (c *InputEnvironmentConfig) ResolveS3BrokerServer() string {
-	if c.TestenvFlag {
-		return TEST_S3_BROKER_SERVER
+	return resolve(c.S3BrokerUrl, c.profile().S3BrokerUrl)
+}
+
+func (c *InputEnvironmentConfig) ResolveS3Endpoint() string {
+	return resolve(c.S3Endpoint, c.profile().S3Endpoint)
+}
+
+func (c *InputEnvironmentConfig) ResolveS3Region() string {
+	return resolve(c.S3Region, c.profile().S3Region)
+}
+
+// ResolveGlobusConfigPath returns the globus config set via flag or config file, falling back to
+// globus.yaml next to the executable.
+func (c *InputEnvironmentConfig) ResolveGlobusConfigPath() (string, error) {
+	if c.GlobusCfg != "" {
+		return c.GlobusCfg, nil
 	}
-	if c.DevenvFlag {
-		return DEV_S3_BROKER_SERVER
+	execPath, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("can't find executable path: %v", err)
 	}
-	return PROD_S3_BROKER_SERVER
+	return filepath.Join(filepath.Dir(execPath), "globus.yaml"), nil
 }
 
 // --- Cobra Helper Methods ---
@@ -109,3 +146,13 @@ func GetCobraIntFlag(cmd *cobra.Command, name string) int {
 	val, _ := cmd.Flags().GetInt(name)
 	return val
 }
+
+func GetCobraStringArrayFlag(cmd *cobra.Command, name string) []string {
+	val, _ := cmd.Flags().GetStringArray(name)
+	return val
+}
+
+func GetCobraDurationFlag(cmd *cobra.Command, name string) time.Duration {
+	val, _ := cmd.Flags().GetDuration(name)
+	return val
+}
@@ -90,6 +90,97 @@ func TestResolveRSYNCServer(t *testing.T) {
 	}
 }
 
+func TestResolveProfile(t *testing.T) {
+	facility := EnvironmentProfile{
+		ScicatUrl:      "https://scicat.facility.org/api/v3",
+		RsyncUrl:       "archive.facility.org",
+		S3UploadBucket: "facility-upload",
+	}
+	profiles := MergeProfiles(map[string]EnvironmentProfile{"facility": facility})
+
+	tests := []struct {
+		name       string
+		input      InputEnvironmentConfig
+		wantAPI    string
+		wantRsync  string
+		wantBucket string
+	}{
+		{
+			name:       "built-in profile by name",
+			input:      InputEnvironmentConfig{Profile: TEST_PROFILE},
+			wantAPI:    TEST_API_SERVER,
+			wantRsync:  TEST_RSYNC_ARCHIVE_SERVER,
+			wantBucket: TEST_S3_UPLOAD_BUCKET,
+		},
+		{
+			name:       "profile from registry",
+			input:      InputEnvironmentConfig{Profile: "facility", Profiles: profiles},
+			wantAPI:    facility.ScicatUrl,
+			wantRsync:  facility.RsyncUrl,
+			wantBucket: facility.S3UploadBucket,
+		},
+		{
+			name:       "profile takes precedence over env flags",
+			input:      InputEnvironmentConfig{Profile: "facility", DevenvFlag: true, Profiles: profiles},
+			wantAPI:    facility.ScicatUrl,
+			wantRsync:  facility.RsyncUrl,
+			wantBucket: facility.S3UploadBucket,
+		},
+		{
+			name:       "overrides take precedence over profile",
+			input:      InputEnvironmentConfig{Profile: "facility", RsyncUrl: "other.facility.org", Profiles: profiles},
+			wantAPI:    facility.ScicatUrl,
+			wantRsync:  "other.facility.org",
+			wantBucket: facility.S3UploadBucket,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.input.ResolveAPIServer(); got != tt.wantAPI {
+				t.Errorf("ResolveAPIServer() = %s, want %s", got, tt.wantAPI)
+			}
+			if got := tt.input.ResolveRSYNCServer(); got != tt.wantRsync {
+				t.Errorf("ResolveRSYNCServer() = %s, want %s", got, tt.wantRsync)
+			}
+			if got := tt.input.ResolveS3UploadBucket(); got != tt.wantBucket {
+				t.Errorf("ResolveS3UploadBucket() = %s, want %s", got, tt.wantBucket)
+			}
+		})
+	}
+}
+
+func TestApplyFileConfigDefaultProfile(t *testing.T) {
+	cfg := FileConfig{
+		Profile:  "facility",
+		Profiles: map[string]EnvironmentProfile{"facility": {ScicatUrl: "https://scicat.facility.org/api/v3"}},
+	}
+
+	input := InputEnvironmentConfig{}
+	input.ApplyFileConfig(cfg)
+	if got := input.ResolveAPIServer(); got != "https://scicat.facility.org/api/v3" {
+		t.Errorf("expected the default profile of the config file, got %s", got)
+	}
+
+	input = InputEnvironmentConfig{TestenvFlag: true}
+	input.ApplyFileConfig(cfg)
+	if got := input.ResolveAPIServer(); got != TEST_API_SERVER {
+		t.Errorf("env flags should take precedence over the default profile, got %s", got)
+	}
+}
+
+func TestCheckProfile(t *testing.T) {
+	profiles := MergeProfiles(map[string]EnvironmentProfile{"facility": {}})
+	for _, name := range []string{"", PROD_PROFILE, TUNNEL_PROFILE, "facility"} {
+		if err := CheckProfile(name, profiles); err != nil {
+			t.Errorf("CheckProfile(%q) returned an error: %v", name, err)
+		}
+	}
+	if err := CheckProfile("nonexistent", profiles); err == nil {
+		t.Error("expected an error for an unknown profile")
+	}
+}
+
 func TestCobraFlagHelpers(t *testing.T) {
 	cmd := &cobra.Command{Use: "test"}
 	cmd.Flags().Bool("verbose", false, "")
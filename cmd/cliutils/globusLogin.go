@@ -2,8 +2,14 @@ package cliutils
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 
 	"github.com/SwissOpenEM/globus"
 	"golang.org/x/oauth2"
@@ -19,6 +25,13 @@ type GlobusConfig struct {
 	SourcePrefixPath      string   `yaml:"source-prefix-path,omitempty"`
 	DestinationCollection string   `yaml:"destination-collection"`
 	DestinationPrefixPath string   `yaml:"destination-prefix-path,omitempty"`
+	// ClientCredentials logs in as the (confidential) client itself using client-secret, without
+	// any user interaction
+	ClientCredentials bool `yaml:"client-credentials,omitempty"`
+	// TokenCache is the file the tokens of a user login are kept in, so that later logins can use
+	// the refresh token instead of asking for a code. Defaults to "<config name>.token.json" next
+	// to the config file, relative paths are resolved against the directory of the config file.
+	TokenCache string `yaml:"token-cache,omitempty"`
 }
 
 func GlobusLogin(confPath string) (gClient globus.GlobusClient, gConfig GlobusConfig, err error) {
@@ -32,11 +45,36 @@ func GlobusLogin(confPath string) (gClient globus.GlobusClient, gConfig GlobusCo
 		return globus.GlobusClient{}, GlobusConfig{}, fmt.Errorf("can't unmarshal globus config: %v", err)
 	}
 
-	// config setup
 	ctx := context.Background()
+	if gConfig.ClientCredentials {
+		if gConfig.ClientSecret == "" {
+			return globus.GlobusClient{}, GlobusConfig{}, fmt.Errorf("client-credentials mode requires a client-secret in the globus config")
+		}
+		gClient, err = globus.AuthCreateServiceClient(ctx, gConfig.ClientID, gConfig.ClientSecret, gConfig.Scopes)
+		if err != nil {
+			return globus.GlobusClient{}, GlobusConfig{}, fmt.Errorf("globus client credentials login failed: %v", err)
+		}
+		return gClient, gConfig, nil
+	}
+
+	// config setup
 	clientConfig := globus.AuthGenerateOauthClientConfig(ctx, gConfig.ClientID, gConfig.ClientSecret, gConfig.RedirectURL, gConfig.Scopes)
+	cache := globusTokenCachePath(confPath, gConfig)
+
+	// reuse the refresh token of an earlier login if it's still valid
+	tok, err := readGlobusToken(cache)
+	if err != nil {
+		log.Printf("Ignoring globus token cache: %v\n", err)
+	}
+	if tok != nil && tok.RefreshToken != "" {
+		src := &cachingTokenSource{src: clientConfig.TokenSource(ctx, tok), path: cache, last: tok}
+		if _, err := src.Token(); err == nil {
+			return globus.HttpClientToGlobusClient(oauth2.NewClient(ctx, src)), gConfig, nil
+		}
+		log.Printf("Cached globus token can't be refreshed, a new login is needed: %v\n", err)
+	}
+
 	verifier := oauth2.GenerateVerifier()
-	clientConfig.AuthCodeURL("state", oauth2.AccessTypeOffline, oauth2.S256ChallengeOption(verifier))
 
 	// redirect user to consent page to ask for permission and obtain the code
 	url := clientConfig.AuthCodeURL("state", oauth2.AccessTypeOffline, oauth2.S256ChallengeOption(verifier))
@@ -47,11 +85,81 @@ func GlobusLogin(confPath string) (gClient globus.GlobusClient, gConfig GlobusCo
 	if _, err := fmt.Scan(&code); err != nil {
 		return globus.GlobusClient{}, GlobusConfig{}, err
 	}
-	tok, err := clientConfig.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	tok, err = clientConfig.Exchange(ctx, code, oauth2.VerifierOption(verifier))
 	if err != nil {
 		return globus.GlobusClient{}, GlobusConfig{}, fmt.Errorf("oauth2 exchange failed: %v", err)
 	}
+	if err := writeGlobusToken(cache, tok); err != nil {
+		log.Printf("Warning: could not cache the globus token: %v\n", err)
+	}
 
 	// return globus client
-	return globus.HttpClientToGlobusClient(clientConfig.Client(ctx, tok)), gConfig, nil
+	src := &cachingTokenSource{src: clientConfig.TokenSource(ctx, tok), path: cache, last: tok}
+	return globus.HttpClientToGlobusClient(oauth2.NewClient(ctx, src)), gConfig, nil
+}
+
+// globusTokenCachePath returns the token cache of the config at confPath.
+func globusTokenCachePath(confPath string, gConfig GlobusConfig) string {
+	if gConfig.TokenCache == "" {
+		return strings.TrimSuffix(confPath, filepath.Ext(confPath)) + ".token.json"
+	}
+	if filepath.IsAbs(gConfig.TokenCache) {
+		return gConfig.TokenCache
+	}
+	return filepath.Join(filepath.Dir(confPath), gConfig.TokenCache)
+}
+
+// readGlobusToken returns the cached token, or nil if there's none.
+func readGlobusToken(path string) (*oauth2.Token, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var tok oauth2.Token
+	if err := json.Unmarshal(data, &tok); err != nil {
+		return nil, fmt.Errorf("can't unmarshal %q: %v", path, err)
+	}
+	return &tok, nil
+}
+
+// writeGlobusToken caches tok, the file is only readable by the current user.
+func writeGlobusToken(path string, tok *oauth2.Token) error {
+	data, err := json.Marshal(tok)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return err
+	}
+	// WriteFile keeps the permissions of an already existing file
+	return os.Chmod(path, 0600)
+}
+
+// cachingTokenSource writes every renewed token to the token cache, as Globus may rotate the
+// refresh token on renewal.
+type cachingTokenSource struct {
+	src  oauth2.TokenSource
+	path string
+
+	mu   sync.Mutex
+	last *oauth2.Token
+}
+
+func (s *cachingTokenSource) Token() (*oauth2.Token, error) {
+	tok, err := s.src.Token()
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.last == nil || tok.AccessToken != s.last.AccessToken || tok.RefreshToken != s.last.RefreshToken {
+		if err := writeGlobusToken(s.path, tok); err != nil {
+			log.Printf("Warning: could not cache the globus token: %v\n", err)
+		}
+		s.last = tok
+	}
+	return tok, nil
 }
@@ -0,0 +1,86 @@
+package cliutils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/oauth2"
+)
+
+func TestGlobusTokenCachePath(t *testing.T) {
+	confPath := filepath.Join("/etc", "scicat", "globus.yaml")
+	tests := []struct {
+		name       string
+		tokenCache string
+		want       string
+	}{
+		{"default", "", filepath.Join("/etc", "scicat", "globus.token.json")},
+		{"relative", "tokens/globus.json", filepath.Join("/etc", "scicat", "tokens", "globus.json")},
+		{"absolute", "/var/cache/globus.json", "/var/cache/globus.json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := globusTokenCachePath(confPath, GlobusConfig{TokenCache: tt.tokenCache}); got != tt.want {
+				t.Errorf("globusTokenCachePath() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+type sequenceTokenSource struct {
+	tokens []*oauth2.Token
+}
+
+func (s *sequenceTokenSource) Token() (*oauth2.Token, error) {
+	tok := s.tokens[0]
+	if len(s.tokens) > 1 {
+		s.tokens = s.tokens[1:]
+	}
+	return tok, nil
+}
+
+func TestCachingTokenSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "globus.token.json")
+
+	if tok, err := readGlobusToken(path); tok != nil || err != nil {
+		t.Fatalf("expected no token without a cache file, got %v %v", tok, err)
+	}
+
+	first := &oauth2.Token{AccessToken: "access-1", RefreshToken: "refresh-1"}
+	second := &oauth2.Token{AccessToken: "access-2", RefreshToken: "refresh-2"}
+	src := &cachingTokenSource{src: &sequenceTokenSource{tokens: []*oauth2.Token{first, second}}, path: path}
+
+	for _, want := range []*oauth2.Token{first, second} {
+		if _, err := src.Token(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		cached, err := readGlobusToken(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cached.RefreshToken != want.RefreshToken {
+			t.Errorf("cached refresh token %q, want %q", cached.RefreshToken, want.RefreshToken)
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("token cache has permissions %v, want 0600", info.Mode().Perm())
+	}
+}
+
+func TestGlobusLoginClientCredentialsRequiresSecret(t *testing.T) {
+	confPath := filepath.Join(t.TempDir(), "globus.yaml")
+	if err := os.WriteFile(confPath, []byte("client-id: abc\nclient-credentials: true\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, _, err := GlobusLogin(confPath)
+	if err == nil || !strings.Contains(err.Error(), "client-secret") {
+		t.Errorf("expected an error about the missing client-secret, got %v", err)
+	}
+}
@@ -1,11 +1,12 @@
 package cliutils
 
 import (
+	"context"
 	"log"
 	"strings"
 )
 
-func GlobusTransfer(params TransferParams) (archivable bool, err error) {
+func GlobusTransfer(ctx context.Context, params TransferParams) (archivable bool, err error) {
 	// === collecting params. ===
 	globusClient := params.GlobusClient
 
@@ -0,0 +1,87 @@
+package cliutils
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// HTTPConfig configures the connections of the HTTP clients. Empty fields keep the defaults: the
+// CA certificates of the system, no client certificate and the proxy given by the HTTPS_PROXY,
+// HTTP_PROXY and NO_PROXY environment variables.
+type HTTPConfig struct {
+	// CACert is a PEM file with CA certificates trusted in addition to the ones of the system,
+	// e.g. the CA of an internal test instance.
+	CACert string `yaml:"ca-cert,omitempty" json:"ca-cert,omitempty"`
+	// ClientCert and ClientKey are the PEM files of the client certificate presented to servers
+	// requiring mutual TLS. ClientKey can be omitted if ClientCert contains the key as well.
+	ClientCert string `yaml:"client-cert,omitempty" json:"client-cert,omitempty"`
+	ClientKey  string `yaml:"client-key,omitempty" json:"client-key,omitempty"`
+	// Proxy is the URL of the proxy for all requests, replacing the proxy environment variables.
+	Proxy string `yaml:"proxy,omitempty" json:"proxy,omitempty"`
+	// NoProxy lists the hosts contacted without proxy, in the format of NO_PROXY. It only applies
+	// together with Proxy.
+	NoProxy string `yaml:"no-proxy,omitempty" json:"no-proxy,omitempty"`
+}
+
+// resolvePaths makes the relative file paths of c relative to dir.
+func (c *HTTPConfig) resolvePaths(dir string) {
+	for _, path := range []*string{&c.CACert, &c.ClientCert, &c.ClientKey} {
+		if *path != "" && !filepath.IsAbs(*path) {
+			*path = filepath.Join(dir, *path)
+		}
+	}
+}
+
+// NewHTTPTransport returns a transport using the CA certificates, client certificate and proxy of
+// cfg. The files are read immediately, so errors in them surface before any request is sent.
+func NewHTTPTransport(cfg HTTPConfig) (*http.Transport, error) {
+	tlsConfig := &tls.Config{}
+	if cfg.CACert != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		pem, err := os.ReadFile(cfg.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("can't read CA certificates: %v", err)
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM encoded certificates found in %s", cfg.CACert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if cfg.ClientCert != "" {
+		keyFile := cfg.ClientKey
+		if keyFile == "" {
+			keyFile = cfg.ClientCert
+		}
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCert, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("can't load client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	} else if cfg.ClientKey != "" {
+		return nil, fmt.Errorf("a client key requires a client certificate")
+	}
+
+	proxy := http.ProxyFromEnvironment
+	if cfg.Proxy != "" {
+		if _, err := url.Parse(cfg.Proxy); err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %v", err)
+		}
+		proxyFunc := (&httpproxy.Config{HTTPProxy: cfg.Proxy, HTTPSProxy: cfg.Proxy, NoProxy: cfg.NoProxy}).ProxyFunc()
+		proxy = func(req *http.Request) (*url.URL, error) { return proxyFunc(req.URL) }
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	transport.Proxy = proxy
+	return transport, nil
+}
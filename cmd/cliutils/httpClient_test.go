@@ -0,0 +1,154 @@
+package cliutils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeClientCert creates a self-signed client certificate and writes it and its key to dir.
+func writeClientCert(t *testing.T, dir string) (*x509.Certificate, string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "scicat-cli test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile := filepath.Join(dir, "client.pem"), filepath.Join(dir, "client.key")
+	writePEM(t, certFile, "CERTIFICATE", der)
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDer)
+	return cert, certFile, keyFile
+}
+
+func writePEM(t *testing.T, path string, blockType string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewHTTPTransportTLS(t *testing.T) {
+	dir := t.TempDir()
+	clientCert, certFile, keyFile := writeClientCert(t, dir)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {}))
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	server.StartTLS()
+	defer server.Close()
+
+	caFile := filepath.Join(dir, "ca.pem")
+	writePEM(t, caFile, "CERTIFICATE", server.Certificate().Raw)
+
+	tests := []struct {
+		name    string
+		cfg     HTTPConfig
+		wantErr bool
+	}{
+		{name: "unknown CA", cfg: HTTPConfig{ClientCert: certFile, ClientKey: keyFile}, wantErr: true},
+		{name: "missing client certificate", cfg: HTTPConfig{CACert: caFile}, wantErr: true},
+		{name: "CA and client certificate", cfg: HTTPConfig{CACert: caFile, ClientCert: certFile, ClientKey: keyFile}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport, err := NewHTTPTransport(tt.cfg)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			client := &http.Client{Transport: transport, Timeout: 5 * time.Second}
+			resp, err := client.Get(server.URL)
+			if err == nil {
+				resp.Body.Close()
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got error %v, want error: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewHTTPTransportErrors(t *testing.T) {
+	dir := t.TempDir()
+	_, certFile, _ := writeClientCert(t, dir)
+	notPEM := filepath.Join(dir, "ca.txt")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		cfg  HTTPConfig
+	}{
+		{name: "missing CA file", cfg: HTTPConfig{CACert: filepath.Join(dir, "missing.pem")}},
+		{name: "CA file without certificates", cfg: HTTPConfig{CACert: notPEM}},
+		{name: "certificate without key", cfg: HTTPConfig{ClientCert: certFile}},
+		{name: "key without certificate", cfg: HTTPConfig{ClientKey: certFile}},
+		{name: "invalid proxy", cfg: HTTPConfig{Proxy: "http://proxy example.com"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewHTTPTransport(tt.cfg); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestNewHTTPTransportProxy(t *testing.T) {
+	transport, err := NewHTTPTransport(HTTPConfig{Proxy: "http://proxy.example.com:3128", NoProxy: "internal.example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://scicat.example.com/api/v3", "http://proxy.example.com:3128"},
+		{"http://scicat.example.com/api/v3", "http://proxy.example.com:3128"},
+		{"https://internal.example.com/api/v3", ""},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest("GET", tt.url, nil)
+		proxy, err := transport.Proxy(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got := ""
+		if proxy != nil {
+			got = proxy.String()
+		}
+		if got != tt.want {
+			t.Errorf("proxy for %s = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package cliutils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+	"sync"
+)
+
+// IngestStep is a step of ingesting a dataset folder, in the order they're done.
+type IngestStep string
+
+const (
+	StepScanned               IngestStep = "scanned"
+	StepDatasetCreated        IngestStep = "datasetCreated"
+	StepOrigDatablocksCreated IngestStep = "origDatablocksCreated"
+	StepFilesTransferred      IngestStep = "filesTransferred"
+	StepMarkedReady           IngestStep = "markedReady"
+)
+
+var ingestSteps = []IngestStep{StepScanned, StepDatasetCreated, StepOrigDatablocksCreated, StepFilesTransferred, StepMarkedReady}
+
+// Reached tells if step s is step or a later one.
+func (s IngestStep) Reached(step IngestStep) bool {
+	return slices.Index(ingestSteps, s) >= slices.Index(ingestSteps, step)
+}
+
+// JournalEntry is the state of a dataset folder in an IngestJournal.
+type JournalEntry struct {
+	SourceFolder string     `json:"sourceFolder"`
+	Step         IngestStep `json:"step,omitempty"`
+	DatasetId    string     `json:"datasetId,omitempty"`
+	// Done means nothing is left to do for the folder, except archiving it if it's Archivable.
+	Done       bool `json:"done,omitempty"`
+	Archivable bool `json:"archivable,omitempty"`
+}
+
+/*
+IngestJournal records how far each dataset folder of an ingest got, in a JSON file that is
+rewritten after every step. A run that failed or was interrupted can be resumed from the journal:
+the completed steps are skipped, so no dataset is created twice. It's safe for concurrent use, and
+a nil journal records nothing.
+*/
+type IngestJournal struct {
+	path string
+
+	mu           sync.Mutex
+	Folders      []JournalEntry `json:"folders"`
+	ArchiveJobId string         `json:"archiveJobId,omitempty"`
+}
+
+// NewIngestJournal returns an empty journal, which is written to path once a step is recorded.
+func NewIngestJournal(path string) *IngestJournal {
+	return &IngestJournal{path: path}
+}
+
+// LoadIngestJournal reads the journal at path, and keeps writing the following steps to it.
+func LoadIngestJournal(path string) (*IngestJournal, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("can't read the ingest journal: %w", err)
+	}
+	j := &IngestJournal{path: path}
+	if err := json.Unmarshal(data, j); err != nil {
+		return nil, fmt.Errorf("can't unmarshal the ingest journal %q: %w", path, err)
+	}
+	return j, nil
+}
+
+// Entry returns the state of sourceFolder, a zero Step if it wasn't started yet.
+func (j *IngestJournal) Entry(sourceFolder string) JournalEntry {
+	if j == nil {
+		return JournalEntry{SourceFolder: sourceFolder}
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if i := j.index(sourceFolder); i >= 0 {
+		return j.Folders[i]
+	}
+	return JournalEntry{SourceFolder: sourceFolder}
+}
+
+// Record stores that sourceFolder reached step, unless it got further before. datasetId is kept from
+// earlier steps if it's empty.
+func (j *IngestJournal) Record(sourceFolder string, step IngestStep, datasetId string) error {
+	return j.update(sourceFolder, func(entry *JournalEntry) {
+		if !entry.Step.Reached(step) {
+			entry.Step = step
+		}
+		if datasetId != "" {
+			entry.DatasetId = datasetId
+		}
+	})
+}
+
+// Finish stores that nothing is left to do for sourceFolder, and whether it can be archived.
+func (j *IngestJournal) Finish(sourceFolder string, archivable bool) error {
+	return j.update(sourceFolder, func(entry *JournalEntry) {
+		entry.Done = true
+		entry.Archivable = archivable
+	})
+}
+
+// Forget removes sourceFolder, e.g. once its dataset was deleted, so it's started over when resumed.
+func (j *IngestJournal) Forget(sourceFolder string) error {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if i := j.index(sourceFolder); i >= 0 {
+		j.Folders = slices.Delete(j.Folders, i, i+1)
+	}
+	return j.write()
+}
+
+// ArchiveJob returns the id of the archive job created for the archivable datasets, if any.
+func (j *IngestJournal) ArchiveJob() string {
+	if j == nil {
+		return ""
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.ArchiveJobId
+}
+
+// RecordArchiveJob stores the id of the archive job created for the archivable datasets.
+func (j *IngestJournal) RecordArchiveJob(jobId string) error {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.ArchiveJobId = jobId
+	return j.write()
+}
+
+/*
+Complete tells if nothing is left to do for the ingest: every folder of sourceFolders is Done
+(every recorded folder if sourceFolders is nil, empty names are ignored) and, if archiveJob is set,
+the archive job of the archivable datasets was recorded.
+*/
+func (j *IngestJournal) Complete(sourceFolders []string, archiveJob bool) bool {
+	if j == nil {
+		return false
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if sourceFolders == nil {
+		for _, entry := range j.Folders {
+			sourceFolders = append(sourceFolders, entry.SourceFolder)
+		}
+	}
+	archivable := false
+	for _, sourceFolder := range sourceFolders {
+		if sourceFolder == "" {
+			continue
+		}
+		i := j.index(sourceFolder)
+		if i < 0 || !j.Folders[i].Done {
+			return false
+		}
+		archivable = archivable || j.Folders[i].Archivable
+	}
+	return !archiveJob || !archivable || j.ArchiveJobId != ""
+}
+
+// Remove deletes the journal file, once the ingest is complete. Nothing is recorded afterwards.
+func (j *IngestJournal) Remove() error {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	err := os.Remove(j.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (j *IngestJournal) update(sourceFolder string, change func(entry *JournalEntry)) error {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	i := j.index(sourceFolder)
+	if i < 0 {
+		j.Folders = append(j.Folders, JournalEntry{SourceFolder: sourceFolder})
+		i = len(j.Folders) - 1
+	}
+	change(&j.Folders[i])
+	return j.write()
+}
+
+func (j *IngestJournal) index(sourceFolder string) int {
+	return slices.IndexFunc(j.Folders, func(entry JournalEntry) bool { return entry.SourceFolder == sourceFolder })
+}
+
+// write replaces the journal file, so it's never left half written.
+func (j *IngestJournal) write() error {
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmpPath := j.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("can't write the ingest journal: %w", err)
+	}
+	if err := os.Rename(tmpPath, j.path); err != nil {
+		return fmt.Errorf("can't write the ingest journal: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package cliutils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIngestJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "folderlisting.txt.journal.json")
+	j := NewIngestJournal(path)
+	steps := []struct {
+		step      IngestStep
+		datasetId string
+	}{
+		{StepScanned, ""},
+		{StepDatasetCreated, "20.500/a"},
+		{StepOrigDatablocksCreated, ""},
+		// a resumed run scans again, which doesn't undo the later steps
+		{StepScanned, ""},
+	}
+	for _, s := range steps {
+		if err := j.Record("/data/a", s.step, s.datasetId); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := j.Finish("/data/a", true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := j.Record("/data/b", StepScanned, ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := j.RecordArchiveJob("job1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	loaded, err := LoadIngestJournal(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := JournalEntry{SourceFolder: "/data/a", Step: StepOrigDatablocksCreated, DatasetId: "20.500/a", Done: true, Archivable: true}
+	if got := loaded.Entry("/data/a"); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if got := loaded.Entry("/data/b"); got.Step != StepScanned || got.Done || got.DatasetId != "" {
+		t.Errorf("unexpected entry %+v", got)
+	}
+	if got := loaded.Entry("/data/c"); got.Step != "" || got.Step.Reached(StepScanned) {
+		t.Errorf("expected a folder that wasn't started, got %+v", got)
+	}
+	if loaded.ArchiveJob() != "job1" {
+		t.Errorf("got archive job %q", loaded.ArchiveJob())
+	}
+
+	// steps resumed from the loaded journal are added to the same file
+	if err := loaded.Record("/data/b", StepDatasetCreated, "20.500/b"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	reloaded, err := LoadIngestJournal(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := reloaded.Entry("/data/b"); got.DatasetId != "20.500/b" || len(reloaded.Folders) != 2 {
+		t.Errorf("unexpected journal %+v", reloaded.Folders)
+	}
+
+	if err := reloaded.Forget("/data/b"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := reloaded.Entry("/data/b"); got.DatasetId != "" || len(reloaded.Folders) != 1 {
+		t.Errorf("expected /data/b to be forgotten, got %+v", reloaded.Folders)
+	}
+
+	var nilJournal *IngestJournal
+	if err := nilJournal.Record("/data/a", StepScanned, ""); err != nil || nilJournal.Entry("/data/a").Step != "" || nilJournal.ArchiveJob() != "" {
+		t.Error("a nil journal should record nothing")
+	}
+
+	if _, err := LoadIngestJournal(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("expected an error for a missing journal")
+	}
+}
+
+func TestIngestJournalComplete(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.json")
+	j := NewIngestJournal(path)
+	if err := j.Finish("/data/a", true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := j.Record("/data/b", StepDatasetCreated, "20.500/b"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		sourceFolders []string
+		archiveJob    bool
+		want          bool
+	}{
+		{"a folder isn't done", []string{"/data/a", "/data/b"}, false, false},
+		{"a folder wasn't started", []string{"/data/a", "/data/c"}, false, false},
+		{"all folders done", []string{"/data/a", ""}, false, true},
+		{"the archive job is missing", []string{"/data/a"}, true, false},
+		{"all recorded folders", nil, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := j.Complete(tt.sourceFolders, tt.archiveJob); got != tt.want {
+				t.Errorf("Complete() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if err := j.Finish("/data/b", false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := j.RecordArchiveJob("job1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !j.Complete(nil, true) {
+		t.Error("expected the journal to be complete")
+	}
+
+	if err := j.Remove(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected the journal file to be removed, got %v", err)
+	}
+	if err := j.Remove(); err != nil {
+		t.Errorf("removing a removed journal failed: %v", err)
+	}
+}
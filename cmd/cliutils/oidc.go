@@ -0,0 +1,428 @@
+package cliutils
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+/*
+OIDCConfig describes the OIDC provider used for --oidc logins, it's read from the "oidc" section
+of the config file.
+
+Without an Issuer, the login goes through the OIDC endpoint of the SciCat backend
+(<api server>/auth/oidc?client=CLI&state=<state>), which logs in at its provider and redirects
+the browser to http://localhost:<CallbackPort>/success with a SciCat token and the state. The port
+must match the success URL configured in the backend, it's 8080 if CallbackPort is 0. With the
+device flag, the login URL can be opened on any device, and the URL the browser ends up at is
+pasted back, so no browser or callback server is needed on this machine.
+
+With an Issuer, the login is done at the provider directly and its token is sent to SciCat as it
+is. This needs a deployment whose API server accepts the tokens of that provider, e.g. behind a
+gateway validating them; a stock SciCat backend only accepts the tokens it issued itself.
+*/
+type OIDCConfig struct {
+	// Issuer is the URL of the provider, its endpoints are discovered via
+	// <issuer>/.well-known/openid-configuration
+	Issuer   string   `yaml:"issuer,omitempty" json:"issuer,omitempty"`
+	ClientID string   `yaml:"client-id,omitempty" json:"client-id,omitempty"`
+	Scopes   []string `yaml:"scopes,omitempty" json:"scopes,omitempty"`
+	// CallbackPort is the localhost port the browser is redirected to after the login, a random
+	// free port is used if it's 0 and an Issuer is set
+	CallbackPort int `yaml:"callback-port,omitempty" json:"callback-port,omitempty"`
+	// UseIDToken sends the ID token to SciCat instead of the access token
+	UseIDToken bool `yaml:"use-id-token,omitempty" json:"use-id-token,omitempty"`
+}
+
+// how long to wait for the user to finish the login in the browser
+const oidcBrowserLoginTimeout = 5 * time.Minute
+
+// the port of the success URL the SciCat backend redirects to by default
+const scicatLoginDefaultPort = 8080
+
+// loginInput is where the URL of a finished SciCat login is pasted, tests replace it
+var loginInput io.Reader = os.Stdin
+
+// loginState returns the state of a SciCat login, tests replace it
+var loginState = randomState
+
+// openBrowser is a variable so that tests can replace the browser
+var openBrowser = func(url string) error {
+	switch runtime.GOOS {
+	case "linux":
+		return exec.Command("xdg-open", url).Start()
+	case "windows":
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", url).Start()
+	case "darwin":
+		return exec.Command("open", url).Start()
+	default:
+		return fmt.Errorf("unsupported platform")
+	}
+}
+
+// OIDCProvider logs in at the provider of its config or through the SciCat backend, either in the
+// browser or, with the device flow, using a browser on any other machine. The refresh token of a
+// login at the provider is kept to renew the token later on.
+type OIDCProvider struct {
+	cfg        OIDCConfig
+	deviceFlow bool
+	httpClient *http.Client
+
+	mu       sync.Mutex
+	oauthCfg *oauth2.Config
+	token    *oauth2.Token
+}
+
+// NewOIDCProvider creates a provider for the given config, which uses the device code flow
+// instead of the browser flow if deviceFlow is set.
+func NewOIDCProvider(cfg OIDCConfig, deviceFlow bool, httpClient *http.Client) *OIDCProvider {
+	return &OIDCProvider{cfg: cfg, deviceFlow: deviceFlow, httpClient: httpClient}
+}
+
+func (p *OIDCProvider) context(ctx context.Context) context.Context {
+	return context.WithValue(ctx, oauth2.HTTPClient, p.httpClient)
+}
+
+// Token logs in and returns the token to use for the SciCat API server, it can be passed to
+// SetOIDCTokenProvider. Cancelling ctx aborts the login.
+func (p *OIDCProvider) Token(ctx context.Context, apiServer string) (string, error) {
+	if p.cfg.Issuer == "" {
+		port := p.cfg.CallbackPort
+		if port == 0 {
+			port = scicatLoginDefaultPort
+		}
+		if p.deviceFlow {
+			return scicatPastedLogin(ctx, apiServer, port)
+		}
+		return scicatBrowserLogin(ctx, apiServer, port)
+	}
+	if p.cfg.ClientID == "" {
+		return "", fmt.Errorf("an oidc client-id must be set in the config file to log in at %s", p.cfg.Issuer)
+	}
+	ctx = p.context(ctx)
+	oauthCfg, err := discoverOIDCConfig(ctx, p.httpClient, p.cfg)
+	if err != nil {
+		return "", err
+	}
+	var tok *oauth2.Token
+	if p.deviceFlow {
+		tok, err = oidcDeviceLogin(ctx, oauthCfg)
+	} else {
+		tok, err = oidcBrowserLogin(ctx, oauthCfg, p.cfg.CallbackPort)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.oauthCfg, p.token = oauthCfg, tok
+	return tokenForScicat(tok, p.cfg.UseIDToken)
+}
+
+// Refresh renews the token of the last login with its refresh token, it can be passed to
+// SetOIDCTokenRefresher.
+func (p *OIDCProvider) Refresh(ctx context.Context) (string, time.Time, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.token == nil || p.token.RefreshToken == "" {
+		// also the case for logins through the SciCat backend
+		return "", time.Time{}, fmt.Errorf("the oidc login returned no refresh token")
+	}
+	// a token without access token forces the token source to use the refresh token
+	src := p.oauthCfg.TokenSource(p.context(ctx), &oauth2.Token{RefreshToken: p.token.RefreshToken})
+	tok, err := src.Token()
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("oidc token refresh failed: %v", err)
+	}
+	p.token = tok
+	scicatToken, err := tokenForScicat(tok, p.cfg.UseIDToken)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if p.cfg.UseIDToken {
+		// the expiry of the ID token isn't known without decoding it
+		return scicatToken, time.Time{}, nil
+	}
+	return scicatToken, tok.Expiry, nil
+}
+
+// discoverOIDCConfig reads the endpoints of the provider from its discovery document.
+func discoverOIDCConfig(ctx context.Context, httpClient *http.Client, cfg OIDCConfig) (*oauth2.Config, error) {
+	discoveryUrl := strings.TrimSuffix(cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, "GET", discoveryUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("can't fetch the oidc discovery document: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("can't fetch the oidc discovery document: status %d - '%s'", resp.StatusCode, string(body))
+	}
+
+	var discovery struct {
+		AuthorizationEndpoint       string `json:"authorization_endpoint"`
+		TokenEndpoint               string `json:"token_endpoint"`
+		DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&discovery); err != nil {
+		return nil, fmt.Errorf("can't decode the oidc discovery document: %v", err)
+	}
+
+	scopes := cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "profile", "email"}
+	}
+	return &oauth2.Config{
+		ClientID: cfg.ClientID,
+		Scopes:   scopes,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:       discovery.AuthorizationEndpoint,
+			TokenURL:      discovery.TokenEndpoint,
+			DeviceAuthURL: discovery.DeviceAuthorizationEndpoint,
+			AuthStyle:     oauth2.AuthStyleInParams,
+		},
+	}, nil
+}
+
+// scicatLoginUrl returns the URL of the OIDC login of the SciCat backend, carrying state.
+func scicatLoginUrl(apiServer string, state string) string {
+	return apiServer + "/auth/oidc?" + url.Values{"client": {"CLI"}, "state": {state}}.Encode()
+}
+
+// scicatLoginToken returns the SciCat token of the success URL the backend redirected to, if it
+// carries the state of this login.
+func scicatLoginToken(success *url.URL, state string) (string, error) {
+	q := success.Query()
+	if q.Get("state") != state {
+		return "", fmt.Errorf("invalid state, the login can't be completed")
+	}
+	token := q.Get("access-token")
+	if token == "" {
+		return "", fmt.Errorf("the login returned no token")
+	}
+	return token, nil
+}
+
+// scicatBrowserLogin logs in through the OIDC endpoint of the SciCat backend, which redirects the
+// browser to the success URL on localhost with a SciCat token. Only the callback carrying the state
+// of this login is accepted.
+func scicatBrowserLogin(ctx context.Context, apiServer string, port int) (string, error) {
+	listener, err := net.Listen("tcp", fmt.Sprintf("localhost:%d", port))
+	if err != nil {
+		return "", fmt.Errorf("can't listen for the SciCat login on port %d: %v", port, err)
+	}
+	defer listener.Close()
+
+	state, err := loginState()
+	if err != nil {
+		return "", err
+	}
+	tokens := make(chan string, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/success", func(w http.ResponseWriter, r *http.Request) {
+		token, err := scicatLoginToken(r.URL, state)
+		if err != nil {
+			// could be a forged request, keep waiting for the real callback
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintln(w, "Login successful, you can close this window now.")
+		select {
+		case tokens <- token:
+		default:
+		}
+	})
+	srv := &http.Server{Handler: mux}
+	go srv.Serve(listener)
+	defer srv.Close()
+
+	loginUrl := scicatLoginUrl(apiServer, state)
+	fmt.Printf("Log in using your browser. If it doesn't open automatically, visit:\n\n%s\n\n", loginUrl)
+	if err := openBrowser(loginUrl); err != nil {
+		fmt.Printf("Could not open the browser: %v\n", err)
+	}
+
+	select {
+	case token := <-tokens:
+		return token, nil
+	case <-time.After(oidcBrowserLoginTimeout):
+		return "", fmt.Errorf("oidc login timed out after %v", oidcBrowserLoginTimeout)
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// scicatPastedLogin logs in through the OIDC endpoint of the SciCat backend without a browser on
+// this machine: the login URL is opened on any device, and the URL of the success page the browser
+// is redirected to afterwards, which can't be loaded there, is pasted back.
+func scicatPastedLogin(ctx context.Context, apiServer string, port int) (string, error) {
+	state, err := loginState()
+	if err != nil {
+		return "", err
+	}
+	fmt.Printf("To log in, visit:\n\n%s\n\nin a browser on any device. Once logged in, the browser goes to a page "+
+		"starting with http://localhost:%d/success which fails to load. Paste its URL here:\n", scicatLoginUrl(apiServer, state), port)
+
+	type result struct {
+		line string
+		err  error
+	}
+	results := make(chan result, 1)
+	go func() {
+		line, err := bufio.NewReader(loginInput).ReadString('\n')
+		if err == io.EOF && line != "" {
+			err = nil
+		}
+		results <- result{strings.TrimSpace(line), err}
+	}()
+
+	select {
+	case res := <-results:
+		if res.err != nil {
+			return "", fmt.Errorf("can't read the URL of the login: %v", res.err)
+		}
+		success, err := url.Parse(res.line)
+		if err != nil || success.Path != "/success" {
+			return "", fmt.Errorf("%q isn't the URL of the success page", res.line)
+		}
+		return scicatLoginToken(success, state)
+	case <-time.After(oidcBrowserLoginTimeout):
+		return "", fmt.Errorf("oidc login timed out after %v", oidcBrowserLoginTimeout)
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// oidcBrowserLogin runs the authorization code flow with PKCE. The browser is redirected to a
+// server on the loopback address, which only accepts the callback carrying the state of this
+// login. The redirect URL uses the same IP literal the server listens on, as RFC 8252 recommends.
+func oidcBrowserLogin(ctx context.Context, oauthCfg *oauth2.Config, port int) (*oauth2.Token, error) {
+	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		return nil, fmt.Errorf("can't listen for the oidc callback on port %d: %v", port, err)
+	}
+	defer listener.Close()
+	oauthCfg.RedirectURL = "http://" + listener.Addr().String() + "/callback"
+
+	state, err := randomState()
+	if err != nil {
+		return nil, err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	type result struct {
+		code string
+		err  error
+	}
+	results := make(chan result, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("state") != state {
+			// could be a forged request, keep waiting for the real callback
+			http.Error(w, "Invalid state, the login can't be completed.", http.StatusBadRequest)
+			return
+		}
+		res := result{code: q.Get("code")}
+		if errMsg := q.Get("error"); errMsg != "" {
+			res.err = fmt.Errorf("oidc login failed: %s %s", errMsg, q.Get("error_description"))
+		} else if res.code == "" {
+			res.err = fmt.Errorf("oidc login failed: the callback contained no code")
+		}
+		if res.err != nil {
+			http.Error(w, res.err.Error(), http.StatusBadRequest)
+		} else {
+			fmt.Fprintln(w, "Login successful, you can close this window now.")
+		}
+		select {
+		case results <- res:
+		default:
+		}
+	})
+	srv := &http.Server{Handler: mux}
+	go srv.Serve(listener)
+	defer srv.Close()
+
+	authUrl := oauthCfg.AuthCodeURL(state, oauth2.S256ChallengeOption(verifier))
+	fmt.Printf("Log in using your browser. If it doesn't open automatically, visit:\n\n%s\n\n", authUrl)
+	if err := openBrowser(authUrl); err != nil {
+		fmt.Printf("Could not open the browser: %v\n", err)
+	}
+
+	select {
+	case res := <-results:
+		if res.err != nil {
+			return nil, res.err
+		}
+		tok, err := oauthCfg.Exchange(ctx, res.code, oauth2.VerifierOption(verifier))
+		if err != nil {
+			return nil, fmt.Errorf("oidc code exchange failed: %v", err)
+		}
+		return tok, nil
+	case <-time.After(oidcBrowserLoginTimeout):
+		return nil, fmt.Errorf("oidc login timed out after %v", oidcBrowserLoginTimeout)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// oidcDeviceLogin runs the device authorization flow: the user opens the printed URL on any
+// device and enters the code, while this polls the provider until the login is complete.
+func oidcDeviceLogin(ctx context.Context, oauthCfg *oauth2.Config) (*oauth2.Token, error) {
+	if oauthCfg.Endpoint.DeviceAuthURL == "" {
+		return nil, fmt.Errorf("the oidc provider doesn't support the device code flow")
+	}
+	resp, err := oauthCfg.DeviceAuth(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("oidc device authorization failed: %v", err)
+	}
+	if resp.VerificationURIComplete != "" {
+		fmt.Printf("To log in, visit:\n\n%s\n\nand confirm the code %s\n\n", resp.VerificationURIComplete, resp.UserCode)
+	} else {
+		fmt.Printf("To log in, visit:\n\n%s\n\nand enter the code %s\n\n", resp.VerificationURI, resp.UserCode)
+	}
+	tok, err := oauthCfg.DeviceAccessToken(ctx, resp)
+	if err != nil {
+		return nil, fmt.Errorf("oidc device login failed: %v", err)
+	}
+	return tok, nil
+}
+
+func tokenForScicat(tok *oauth2.Token, useIDToken bool) (string, error) {
+	if !useIDToken {
+		return tok.AccessToken, nil
+	}
+	idToken, ok := tok.Extra("id_token").(string)
+	if !ok || idToken == "" {
+		return "", fmt.Errorf("the oidc provider returned no id token")
+	}
+	return idToken, nil
+}
+
+func randomState() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
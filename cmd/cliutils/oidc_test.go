@@ -0,0 +1,269 @@
+package cliutils
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// newMockOIDCProvider serves discovery, authorization, device and token endpoints. The token
+// endpoint only issues a token if the PKCE verifier matches the challenge of the authorization.
+func newMockOIDCProvider(t *testing.T) *httptest.Server {
+	var challenge string
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"authorization_endpoint":        server.URL + "/authorize",
+			"token_endpoint":                server.URL + "/token",
+			"device_authorization_endpoint": server.URL + "/device",
+		})
+	})
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("code_challenge_method") != "S256" || q.Get("client_id") != "cli" {
+			http.Error(w, "invalid request", http.StatusBadRequest)
+			return
+		}
+		challenge = q.Get("code_challenge")
+		redirect, _ := url.Parse(q.Get("redirect_uri"))
+		redirect.RawQuery = url.Values{"code": {"auth-code"}, "state": {q.Get("state")}}.Encode()
+		http.Redirect(w, r, redirect.String(), http.StatusFound)
+	})
+	mux.HandleFunc("/device", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"device_code":      "device-code",
+			"user_code":        "ABCD-EFGH",
+			"verification_uri": server.URL + "/activate",
+			"expires_in":       60,
+			"interval":         1,
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Form.Get("grant_type") {
+		case "authorization_code":
+			sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+			if r.Form.Get("code") != "auth-code" || base64.RawURLEncoding.EncodeToString(sum[:]) != challenge {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error": "invalid_grant"}`))
+				return
+			}
+			w.Write([]byte(`{"access_token": "browser-token", "id_token": "browser-id-token", "token_type": "Bearer"}`))
+		case "urn:ietf:params:oauth:grant-type:device_code":
+			w.Write([]byte(`{"access_token": "device-token", "token_type": "Bearer"}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "unsupported_grant_type"}`))
+		}
+	})
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestOIDCTokenProvider(t *testing.T) {
+	provider := newMockOIDCProvider(t)
+	cfg := OIDCConfig{Issuer: provider.URL, ClientID: "cli"}
+
+	oldBrowser := openBrowser
+	defer func() { openBrowser = oldBrowser }()
+	// the "browser" follows the redirects of the provider back to the callback server
+	openBrowser = func(u string) error {
+		go http.Get(u)
+		return nil
+	}
+
+	t.Run("browser flow", func(t *testing.T) {
+		token, err := NewOIDCProvider(cfg, false, provider.Client()).Token(context.Background(), "https://scicat.example.com/api/v3")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if token != "browser-token" {
+			t.Errorf("got token %q, want browser-token", token)
+		}
+	})
+
+	t.Run("browser flow with id token", func(t *testing.T) {
+		idCfg := cfg
+		idCfg.UseIDToken = true
+		token, err := NewOIDCProvider(idCfg, false, provider.Client()).Token(context.Background(), "")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if token != "browser-id-token" {
+			t.Errorf("got token %q, want browser-id-token", token)
+		}
+	})
+
+	t.Run("device flow", func(t *testing.T) {
+		token, err := NewOIDCProvider(cfg, true, provider.Client()).Token(context.Background(), "")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if token != "device-token" {
+			t.Errorf("got token %q, want device-token", token)
+		}
+	})
+
+	t.Run("missing client id", func(t *testing.T) {
+		_, err := NewOIDCProvider(OIDCConfig{Issuer: provider.URL}, false, provider.Client()).Token(context.Background(), "")
+		if err == nil || !strings.Contains(err.Error(), "client-id") {
+			t.Errorf("expected an error about the missing client-id, got %v", err)
+		}
+	})
+}
+
+func TestOIDCScicatLogin(t *testing.T) {
+	// a free port for the success URL the backend redirects to
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+	successUrl := func(state string) string {
+		return fmt.Sprintf("http://localhost:%d/success?%s", port, url.Values{"access-token": {"scicat-token"}, "state": {state}}.Encode())
+	}
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if r.URL.Path != "/api/v3/auth/oidc" || q.Get("client") != "CLI" || q.Get("state") == "" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		// the backend logs in at its provider, then redirects to its success URL with the state
+		http.Redirect(w, r, successUrl(q.Get("state")), http.StatusFound)
+	}))
+	defer backend.Close()
+	apiServer := backend.URL + "/api/v3"
+	cfg := OIDCConfig{CallbackPort: port}
+
+	oldBrowser, oldInput := openBrowser, loginInput
+	defer func() { openBrowser, loginInput = oldBrowser, oldInput }()
+
+	t.Run("browser flow", func(t *testing.T) {
+		forgedStatus := make(chan int, 1)
+		openBrowser = func(u string) error {
+			go func() {
+				// a token planted without the state of the login is rejected
+				resp, err := http.Get(successUrl("forged"))
+				if err == nil {
+					forgedStatus <- resp.StatusCode
+					resp.Body.Close()
+				}
+				http.Get(u)
+			}()
+			return nil
+		}
+		provider := NewOIDCProvider(cfg, false, backend.Client())
+		token, err := provider.Token(context.Background(), apiServer)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if token != "scicat-token" {
+			t.Errorf("got token %q, want scicat-token", token)
+		}
+		if status := <-forgedStatus; status != http.StatusBadRequest {
+			t.Errorf("callback with a wrong state returned status %d, want %d", status, http.StatusBadRequest)
+		}
+		if _, _, err := provider.Refresh(context.Background()); err == nil {
+			t.Error("expected an error refreshing a token of the SciCat backend")
+		}
+	})
+
+	t.Run("device flow with the pasted URL", func(t *testing.T) {
+		oldState := loginState
+		defer func() { loginState = oldState }()
+		loginState = func() (string, error) { return "login-state", nil }
+
+		tests := []struct {
+			name    string
+			pasted  string
+			wantErr string
+		}{
+			{"the success URL of the login", successUrl("login-state"), ""},
+			{"a foreign state", successUrl("forged"), "invalid state"},
+			{"another URL", "http://localhost/other", "isn't the URL of the success page"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				loginInput = strings.NewReader("  " + tt.pasted + "\n")
+				token, err := NewOIDCProvider(cfg, true, backend.Client()).Token(context.Background(), apiServer)
+				if tt.wantErr != "" {
+					if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+						t.Errorf("error = %v, want one containing %q", err, tt.wantErr)
+					}
+					return
+				}
+				if err != nil || token != "scicat-token" {
+					t.Errorf("got token %q and error %v, want scicat-token", token, err)
+				}
+			})
+		}
+	})
+
+	t.Run("a cancelled context aborts the login", func(t *testing.T) {
+		input, paste := io.Pipe()
+		defer paste.Close()
+		loginInput = input
+		openBrowser = func(u string) error { return nil }
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := NewOIDCProvider(cfg, true, backend.Client()).Token(ctx, apiServer); !errors.Is(err, context.Canceled) {
+			t.Errorf("expected the login to be cancelled, got %v", err)
+		}
+		if _, err := NewOIDCProvider(cfg, false, backend.Client()).Token(ctx, apiServer); !errors.Is(err, context.Canceled) {
+			t.Errorf("expected the login to be cancelled, got %v", err)
+		}
+	})
+}
+
+func TestOIDCBrowserLoginChecksState(t *testing.T) {
+	provider := newMockOIDCProvider(t)
+	cfg := OIDCConfig{Issuer: provider.URL, ClientID: "cli"}
+
+	oldBrowser := openBrowser
+	defer func() { openBrowser = oldBrowser }()
+	forgedStatus := make(chan int, 1)
+	openBrowser = func(u string) error {
+		authUrl, _ := url.Parse(u)
+		callback := authUrl.Query().Get("redirect_uri")
+		if redirect, _ := url.Parse(callback); redirect.Hostname() != "127.0.0.1" {
+			t.Errorf("the redirect URL %s should use the address the callback server listens on", callback)
+		}
+		go func() {
+			// a callback with a foreign state is rejected, the real one still completes the login
+			resp, err := http.Get(callback + "?code=auth-code&state=forged")
+			if err == nil {
+				forgedStatus <- resp.StatusCode
+				resp.Body.Close()
+			}
+			http.Get(u)
+		}()
+		return nil
+	}
+
+	token, err := NewOIDCProvider(cfg, false, provider.Client()).Token(context.Background(), "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if token != "browser-token" {
+		t.Errorf("got token %q, want browser-token", token)
+	}
+	if status := <-forgedStatus; status != http.StatusBadRequest {
+		t.Errorf("callback with a wrong state returned status %d, want %d", status, http.StatusBadRequest)
+	}
+}
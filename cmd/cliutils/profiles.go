@@ -0,0 +1,105 @@
+package cliutils
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Names of the built-in environment profiles, each of them can also be selected with the
+// corresponding --testenv, --devenv, --localenv or --tunnelenv flag.
+const (
+	PROD_PROFILE   = "production"
+	TEST_PROFILE   = "test"
+	DEV_PROFILE    = "dev"
+	LOCAL_PROFILE  = "local"
+	TUNNEL_PROFILE = "tunnel"
+)
+
+// EnvironmentProfile describes the services that make up one SciCat environment.
+type EnvironmentProfile struct {
+	ScicatUrl        string `yaml:"scicat-url,omitempty" json:"scicat-url,omitempty"`
+	RsyncUrl         string `yaml:"rsync-url,omitempty" json:"rsync-url,omitempty"`
+	RsyncRetrieveUrl string `yaml:"rsync-retrieve-url,omitempty" json:"rsync-retrieve-url,omitempty"`
+	S3UploadBucket   string `yaml:"s3-upload-bucket,omitempty" json:"s3-upload-bucket,omitempty"`
+	S3BrokerUrl      string `yaml:"s3-broker-url,omitempty" json:"s3-broker-url,omitempty"`
+	S3Endpoint       string `yaml:"s3-endpoint,omitempty" json:"s3-endpoint,omitempty"`
+	S3Region         string `yaml:"s3-region,omitempty" json:"s3-region,omitempty"`
+}
+
+// BuiltinProfiles returns the environments of the PSI SciCat instance. Profiles defined in a
+// config file are added to (or replace) these.
+func BuiltinProfiles() map[string]EnvironmentProfile {
+	return map[string]EnvironmentProfile{
+		PROD_PROFILE: {
+			ScicatUrl:        PROD_API_SERVER,
+			RsyncUrl:         PROD_RSYNC_ARCHIVE_SERVER,
+			RsyncRetrieveUrl: PROD_RSYNC_RETRIEVE_SERVER,
+			S3UploadBucket:   PROD_S3_UPLOAD_BUCKET,
+			S3BrokerUrl:      PROD_S3_BROKER_SERVER,
+			S3Endpoint:       CSCS_CEPH_ENDPOINT,
+			S3Region:         CSCS_CEPH_AWS_REGION,
+		},
+		TEST_PROFILE: {
+			ScicatUrl:        TEST_API_SERVER,
+			RsyncUrl:         TEST_RSYNC_ARCHIVE_SERVER,
+			RsyncRetrieveUrl: TEST_RSYNC_RETRIEVE_SERVER,
+			S3UploadBucket:   TEST_S3_UPLOAD_BUCKET,
+			S3BrokerUrl:      TEST_S3_BROKER_SERVER,
+			S3Endpoint:       CSCS_CEPH_ENDPOINT,
+			S3Region:         CSCS_CEPH_AWS_REGION,
+		},
+		DEV_PROFILE: {
+			ScicatUrl:        DEV_API_SERVER,
+			RsyncUrl:         DEV_RSYNC_ARCHIVE_SERVER,
+			RsyncRetrieveUrl: DEV_RSYNC_RETRIEVE_SERVER,
+			S3UploadBucket:   DEV_S3_UPLOAD_BUCKET,
+			S3BrokerUrl:      DEV_S3_BROKER_SERVER,
+			S3Endpoint:       CSCS_CEPH_ENDPOINT,
+			S3Region:         CSCS_CEPH_AWS_REGION,
+		},
+		LOCAL_PROFILE: {
+			ScicatUrl:        LOCAL_API_SERVER,
+			RsyncUrl:         LOCAL_RSYNC_ARCHIVE_SERVER,
+			RsyncRetrieveUrl: LOCAL_RSYNC_RETRIEVE_SERVER,
+			S3UploadBucket:   PROD_S3_UPLOAD_BUCKET,
+			S3BrokerUrl:      PROD_S3_BROKER_SERVER,
+			S3Endpoint:       CSCS_CEPH_ENDPOINT,
+			S3Region:         CSCS_CEPH_AWS_REGION,
+		},
+		TUNNEL_PROFILE: {
+			ScicatUrl:        TUNNEL_API_SERVER,
+			RsyncUrl:         TUNNEL_RSYNC_ARCHIVE_SERVER,
+			RsyncRetrieveUrl: DEV_RSYNC_RETRIEVE_SERVER,
+			S3UploadBucket:   PROD_S3_UPLOAD_BUCKET,
+			S3BrokerUrl:      PROD_S3_BROKER_SERVER,
+			S3Endpoint:       CSCS_CEPH_ENDPOINT,
+			S3Region:         CSCS_CEPH_AWS_REGION,
+		},
+	}
+}
+
+// MergeProfiles returns the built-in profiles extended with profiles, a profile with the name of a
+// built-in one replaces it.
+func MergeProfiles(profiles map[string]EnvironmentProfile) map[string]EnvironmentProfile {
+	merged := BuiltinProfiles()
+	for name, profile := range profiles {
+		merged[name] = profile
+	}
+	return merged
+}
+
+// CheckProfile returns an error listing the available profiles if name isn't one of them.
+func CheckProfile(name string, profiles map[string]EnvironmentProfile) error {
+	if name == "" {
+		return nil
+	}
+	if _, ok := profiles[name]; ok {
+		return nil
+	}
+	names := make([]string, 0, len(profiles))
+	for n := range profiles {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return fmt.Errorf("unknown profile %q, available profiles: %v", name, names)
+}
@@ -0,0 +1,65 @@
+package cliutils
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// RunSummary records the state of the items (e.g. datasets) a command works on, so that it can
+// report what was and wasn't completed when it's interrupted. It's safe for concurrent use.
+type RunSummary struct {
+	mu    sync.Mutex
+	items []summaryItem
+}
+
+type summaryItem struct {
+	name   string
+	done   bool
+	status string
+}
+
+// Set records the status of the item name, e.g. "dataset created, files not copied yet". done
+// marks the item as completed. Items are listed in the order they were first set.
+func (s *RunSummary) Set(name string, done bool, status string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.items {
+		if s.items[i].name == name {
+			s.items[i].done = done
+			s.items[i].status = status
+			return
+		}
+	}
+	s.items = append(s.items, summaryItem{name: name, done: done, status: status})
+}
+
+// Print writes the completed and the not completed items to w, nothing if no item was set.
+func (s *RunSummary) Print(w io.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.items) == 0 {
+		return
+	}
+	for _, done := range []bool{true, false} {
+		title := "Completed"
+		if !done {
+			title = "Not completed"
+		}
+		count := 0
+		for _, item := range s.items {
+			if item.done == done {
+				count++
+			}
+		}
+		if count == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "%s (%d):\n", title, count)
+		for _, item := range s.items {
+			if item.done == done {
+				fmt.Fprintf(w, "  - %s: %s\n", item.name, item.status)
+			}
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package cliutils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRunSummary(t *testing.T) {
+	var b strings.Builder
+	s := &RunSummary{}
+	s.Print(&b)
+	if b.Len() != 0 {
+		t.Errorf("empty summary printed %q", b.String())
+	}
+
+	s.Set("/data/a", false, "not started")
+	s.Set("/data/b", false, "not started")
+	s.Set("/data/a", true, "ingested as 20.500/a")
+	s.Set("/data/b", false, "dataset 20.500/b created, files not copied")
+	s.Print(&b)
+	want := `Completed (1):
+  - /data/a: ingested as 20.500/a
+Not completed (1):
+  - /data/b: dataset 20.500/b created, files not copied
+`
+	if b.String() != want {
+		t.Errorf("got summary\n%s\nwant\n%s", b.String(), want)
+	}
+}
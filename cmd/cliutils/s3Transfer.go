@@ -15,27 +15,27 @@ import (
 	"github.com/aws/aws-sdk-go-v2/feature/s3/transfermanager"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/paulscherrerinstitute/scicat-cli/v3/datasetIngestor"
+	"github.com/paulscherrerinstitute/scicat-cli/v3/datasetUtils"
 )
 
 // s3Transfer holds dependencies of transferFiles, so that they can be swapped with mocks in tests
 type s3Transfer struct {
-	upload         func(ctx context.Context, client *http.Client, brokerServer, bucket, datasetId, accessToken string, fileList []string, sourceFolder string) error
-	markFilesReady func(client *http.Client, APIServer string, datasetId string, user map[string]string) error
+	upload         func(ctx context.Context, client *http.Client, s3Params S3Params, datasetId, accessToken string, fileList []string, sourceFolder string) error
+	markFilesReady func(ctx context.Context, client *http.Client, APIServer string, datasetId string, user datasetUtils.User) error
 }
 
 // TransferFilesS3 sets up s3Transfer with real implementations of upload and markFilesReady
-func TransferFilesS3(params TransferParams) (archivable bool, err error) {
+func TransferFilesS3(ctx context.Context, params TransferParams) (archivable bool, err error) {
 	s := s3Transfer{upload: upload, markFilesReady: datasetIngestor.MarkFilesReady}
-	return s.transferFiles(params)
+	return s.transferFiles(ctx, params)
 }
 
 // transferFiles uploads the dataset's files to S3, and on success marks the dataset as archivable.
-func (s *s3Transfer) transferFiles(params TransferParams) (archivable bool, err error) {
-	ctx := context.Background()
-	err = s.upload(ctx, params.Client, params.BrokerServer, params.UploadBucket, params.DatasetId, params.User["accessToken"], params.Filelist, params.DatasetSourceFolder)
+func (s *s3Transfer) transferFiles(ctx context.Context, params TransferParams) (archivable bool, err error) {
+	err = s.upload(ctx, params.Client, params.S3Params, params.DatasetId, params.User.AccessToken, params.Filelist, params.DatasetSourceFolder)
 	if err == nil {
 		log.Println("Marking files ready")
-		err = s.markFilesReady(params.Client, params.ApiServer, params.DatasetId, params.User)
+		err = s.markFilesReady(ctx, params.Client, params.ApiServer, params.DatasetId, params.User)
 		if err != nil {
 			log.Println("Failed to mark files ready i.e. dataset as archivable: ", err)
 			return false, err
@@ -45,15 +45,15 @@ func (s *s3Transfer) transferFiles(params TransferParams) (archivable bool, err
 	return false, err
 }
 
-// upload uploads contents of the sourceFolder, filtered by fileList, to bucket.
+// upload uploads contents of the sourceFolder, filtered by fileList, to the s3Params' bucket.
 // The contents are uploaded under /datasetId prefix
-// It uses brokerServer to get short-term credentials against user's accessToken
-func upload(ctx context.Context, client *http.Client, brokerServer, bucket, datasetId, accessToken string, fileList []string, sourceFolder string) error {
-	transferManagerClient, err := getTransferManagerClient(ctx, client, brokerServer, datasetId, accessToken)
+// It uses the s3Params' broker server to get short-term credentials against user's accessToken
+func upload(ctx context.Context, client *http.Client, s3Params S3Params, datasetId, accessToken string, fileList []string, sourceFolder string) error {
+	transferManagerClient, err := getTransferManagerClient(ctx, client, s3Params, datasetId, accessToken)
 	if err != nil {
 		return err
 	}
-	return transferDirectory(ctx, transferManagerClient, bucket, fileList, sourceFolder, datasetId)
+	return transferDirectory(ctx, transferManagerClient, s3Params.UploadBucket, fileList, sourceFolder, datasetId)
 }
 
 // s3BrokerCredsProvider implements the aws.CredentialsProvider interface
@@ -64,11 +64,11 @@ type s3BrokerCredsProvider struct {
 	operation    string
 	accessToken  string
 
-	getShortTermCreds func(client *http.Client, brokerServer string, datasetId string, operation string, accessToken string) (s3Creds, error)
+	getShortTermCreds func(ctx context.Context, client *http.Client, brokerServer string, datasetId string, operation string, accessToken string) (s3Creds, error)
 }
 
 func (s *s3BrokerCredsProvider) Retrieve(ctx context.Context) (aws.Credentials, error) {
-	s3Creds, err := s.getShortTermCreds(s.client, s.brokerServer, s.datasetId, s.operation, s.accessToken)
+	s3Creds, err := s.getShortTermCreds(ctx, s.client, s.brokerServer, s.datasetId, s.operation, s.accessToken)
 	if err != nil {
 		return aws.Credentials{}, err
 	}
@@ -86,10 +86,10 @@ func (s *s3BrokerCredsProvider) Retrieve(ctx context.Context) (aws.Credentials,
 
 // getTransferManagerClient constructs a transfermanager client using s3BrokerCredsProvider which
 // ensures credentials are auto refreshed on expiry
-func getTransferManagerClient(ctx context.Context, client *http.Client, brokerServer, datasetId, accessToken string) (*transfermanager.Client, error) {
+func getTransferManagerClient(ctx context.Context, client *http.Client, s3Params S3Params, datasetId, accessToken string) (*transfermanager.Client, error) {
 	s3bCredsProvider := &s3BrokerCredsProvider{
 		client:       client,
-		brokerServer: brokerServer,
+		brokerServer: s3Params.BrokerServer,
 		datasetId:    datasetId,
 		operation:    "write",
 		accessToken:  accessToken,
@@ -99,9 +99,19 @@ func getTransferManagerClient(ctx context.Context, client *http.Client, brokerSe
 	if s3bCredsProvider.accessToken == "" {
 		return nil, fmt.Errorf("No access token")
 	}
-	cfg, err := config.LoadDefaultConfig(ctx, config.WithBaseEndpoint(CSCS_CEPH_ENDPOINT),
-		config.WithRegion(CSCS_CEPH_AWS_REGION),
-		config.WithCredentialsProvider(s3bCredsProvider))
+	endpoint, region := s3Params.Endpoint, s3Params.Region
+	if endpoint == "" {
+		endpoint = CSCS_CEPH_ENDPOINT
+	}
+	if region == "" {
+		region = CSCS_CEPH_AWS_REGION
+	}
+	// the uploads use the TLS and proxy settings of client, but not its timeout, which would
+	// abort the transfer of large files
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithBaseEndpoint(endpoint),
+		config.WithRegion(region),
+		config.WithCredentialsProvider(s3bCredsProvider),
+		config.WithHTTPClient(&http.Client{Transport: client.Transport}))
 	if err != nil {
 		return nil, err
 	}
@@ -161,14 +171,14 @@ type s3Creds struct {
 }
 
 // getShortTermCreds makes a GET request to brokerServer's /datasets/s3-creds endpoint with relevant params
-func getShortTermCreds(client *http.Client, brokerServer string, datasetId string, operation string, accessToken string) (s3Creds, error) {
+func getShortTermCreds(ctx context.Context, client *http.Client, brokerServer string, datasetId string, operation string, accessToken string) (s3Creds, error) {
 	u, err := url.Parse(brokerServer + "/datasets/s3-creds")
 	if err != nil {
 		return s3Creds{}, err
 	}
 	u.RawQuery = url.Values{"pid": {datasetId}, "operation": {operation}}.Encode()
 
-	req, err := http.NewRequest("GET", u.String(), nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
 	if err != nil {
 		return s3Creds{}, err
 	}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/s3/transfermanager"
+	"github.com/paulscherrerinstitute/scicat-cli/v3/datasetUtils"
 )
 
 // mockS3Uploader is a receiver struct implementing the `upload` dependency of TransferFilesS3
@@ -17,7 +18,7 @@ type mockS3Uploader struct {
 	uploadErr error
 }
 
-func (f *mockS3Uploader) upload(ctx context.Context, client *http.Client, brokerServer, bucket, datasetId, accessToken string, fileList []string, sourceFolder string) error {
+func (f *mockS3Uploader) upload(ctx context.Context, client *http.Client, s3Params S3Params, datasetId, accessToken string, fileList []string, sourceFolder string) error {
 	return f.uploadErr
 }
 
@@ -27,7 +28,7 @@ type mockDatasetIngestor struct {
 	called bool
 }
 
-func (f *mockDatasetIngestor) MarkFilesReady(client *http.Client, APIServer string, datasetId string, user map[string]string) error {
+func (f *mockDatasetIngestor) MarkFilesReady(ctx context.Context, client *http.Client, APIServer string, datasetId string, user datasetUtils.User) error {
 	f.called = true
 	return f.err
 }
@@ -69,7 +70,7 @@ func TestTransferFilesS3_ControlFlow(t *testing.T) {
 			ingestor := &mockDatasetIngestor{err: tt.markReadyErr}
 			s := s3Transfer{upload: deps.upload, markFilesReady: ingestor.MarkFilesReady}
 
-			archivable, err := s.transferFiles(TransferParams{})
+			archivable, err := s.transferFiles(context.Background(), TransferParams{})
 
 			if tt.wantErr != nil {
 				if !errors.Is(err, tt.wantErr) {
@@ -166,7 +167,7 @@ type shortTermCredsRetrieverMock struct {
 	result   s3Creds
 }
 
-func (s *shortTermCredsRetrieverMock) getShortTermCreds(_ *http.Client, _ string, _ string, _ string, _ string) (s3Creds, error) {
+func (s *shortTermCredsRetrieverMock) getShortTermCreds(_ context.Context, _ *http.Client, _ string, _ string, _ string, _ string) (s3Creds, error) {
 	if s.throwErr {
 		return s3Creds{}, errCredsRetrieval
 	}
@@ -322,7 +323,7 @@ func TestGetShortTermCreds(t *testing.T) {
 				brokerServer = tt.brokerServer
 			}
 
-			got, err := getShortTermCreds(&http.Client{}, brokerServer, datasetId, operation, accessToken)
+			got, err := getShortTermCreds(context.Background(), &http.Client{}, brokerServer, datasetId, operation, accessToken)
 
 			if tt.wantErr {
 				if err == nil {
@@ -1,13 +1,14 @@
 package cliutils
 
 import (
+	"context"
 	"log"
 	"os"
 
 	"github.com/paulscherrerinstitute/scicat-cli/v3/datasetIngestor"
 )
 
-func SshTransfer(params TransferParams) (archivable bool, err error) {
+func SshTransfer(ctx context.Context, params TransferParams) (archivable bool, err error) {
 	// === collecting params. ===
 	client := params.Client
 	apiServer := params.ApiServer
@@ -15,16 +16,16 @@ func SshTransfer(params TransferParams) (archivable bool, err error) {
 	rsyncServer := params.RsyncServer
 	datasetId := params.DatasetId
 	dsSourceFolder := params.DatasetSourceFolder
-	absFilelistPath := params.AbsFilelistPath
+	filelist := params.Filelist
 	archivable = false
 
 	// === copying files ===
 	log.Println("Syncing files to cache server...")
-	err = datasetIngestor.SyncLocalDataToFileserver(datasetId, user, rsyncServer, dsSourceFolder, absFilelistPath, os.Stdout)
+	err = datasetIngestor.SyncLocalDataToFileserver(ctx, datasetId, user, rsyncServer, dsSourceFolder, filelist, os.Stdout)
 	if err == nil {
 		// mark dataset ready for archival
 		archivable = true
-		err = datasetIngestor.MarkFilesReady(client, apiServer, datasetId, user)
+		err = datasetIngestor.MarkFilesReady(ctx, client, apiServer, datasetId, user)
 	}
 	log.Println("Syncing files - DONE")
 
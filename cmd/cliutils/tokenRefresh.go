@@ -0,0 +1,159 @@
+package cliutils
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/paulscherrerinstitute/scicat-cli/v3/datasetUtils"
+)
+
+// tokenRefreshFunc obtains a new access token and its expiry (zero if unknown). The client passed
+// to it sends requests without the token refresh logic, ctx is the one of the request needing the
+// token.
+type tokenRefreshFunc func(ctx context.Context, httpClient *http.Client) (string, time.Time, error)
+
+// tokenRefreshTransport keeps the access token of a session valid. Requests carrying the token
+// (as bearer token or access_token query parameter) are sent with the current token, which is
+// renewed shortly before it expires, and retried once with a renewed token if they fail with 401.
+// Tokens replaced by a refresh are swapped for the current one, so callers can keep using the
+// token of the original user.
+type tokenRefreshTransport struct {
+	base    http.RoundTripper
+	timeout time.Duration
+	refresh tokenRefreshFunc
+
+	mu      sync.Mutex
+	current string
+	expires time.Time
+	stale   map[string]bool
+}
+
+// enableTokenRefresh makes httpClient renew the access token of user with refresh when needed.
+func enableTokenRefresh(httpClient *http.Client, user datasetUtils.User, refresh tokenRefreshFunc) {
+	token := user.AccessToken
+	if httpClient == nil || refresh == nil || token == "" {
+		return
+	}
+	base := httpClient.Transport
+	if t, ok := base.(*tokenRefreshTransport); ok {
+		// the client was used for an earlier session, which is replaced
+		base = t.base
+	}
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	httpClient.Transport = &tokenRefreshTransport{
+		base:    base,
+		timeout: httpClient.Timeout,
+		refresh: refresh,
+		current: token,
+		expires: user.Expires(),
+		stale:   map[string]bool{},
+	}
+}
+
+func (t *tokenRefreshTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	sentToken, ok := t.requestToken(req)
+	if !ok {
+		return t.base.RoundTrip(req)
+	}
+
+	token, err := t.validToken(req.Context(), sentToken, false)
+	if err != nil {
+		log.Printf("Warning: could not renew the access token: %v\n", err)
+		token = sentToken
+	}
+	resp, err := t.base.RoundTrip(withToken(req, sentToken, token))
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	if req.Body != nil && req.GetBody == nil {
+		// the body was consumed and can't be sent again
+		return resp, nil
+	}
+
+	newToken, err := t.validToken(req.Context(), token, true)
+	if err != nil {
+		log.Printf("Warning: could not renew the access token: %v\n", err)
+		return resp, nil
+	}
+	resp.Body.Close()
+	retry := withToken(req, sentToken, newToken)
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		retry.Body = body
+	}
+	return t.base.RoundTrip(retry)
+}
+
+// requestToken returns the token req was built with, if it's the current or a replaced one.
+func (t *tokenRefreshTransport) requestToken(req *http.Request) (string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	candidates := []string{req.URL.Query().Get("access_token")}
+	if auth := req.Header.Get("Authorization"); len(auth) > len("Bearer ") {
+		candidates = append(candidates, auth[len("Bearer "):])
+	}
+	for _, token := range candidates {
+		if token != "" && (token == t.current || t.stale[token]) {
+			return token, true
+		}
+	}
+	return "", false
+}
+
+// validToken returns the current token, after renewing it if it's about to expire or, if force
+// is set, if usedToken (the token a request failed with) is still the current one. Concurrent
+// requests failing with the same token thereby trigger a single refresh.
+func (t *tokenRefreshTransport) validToken(ctx context.Context, usedToken string, force bool) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if force {
+		if usedToken != t.current {
+			return t.current, nil
+		}
+	} else if t.expires.IsZero() || time.Now().Add(tokenExpiryMargin).Before(t.expires) {
+		return t.current, nil
+	}
+
+	token, expires, err := t.refresh(ctx, &http.Client{Transport: t.base, Timeout: t.timeout})
+	if err != nil {
+		if !force {
+			// don't retry the refresh before every request, only when one fails
+			t.expires = time.Time{}
+		}
+		return "", err
+	}
+	if token == "" {
+		return "", fmt.Errorf("the refresh returned no access token")
+	}
+	if token != t.current {
+		t.stale[t.current] = true
+	}
+	t.current, t.expires = token, expires
+	return token, nil
+}
+
+// withToken returns a copy of req using newToken instead of oldToken.
+func withToken(req *http.Request, oldToken string, newToken string) *http.Request {
+	r := req.Clone(req.Context())
+	if oldToken == newToken {
+		return r
+	}
+	if r.Header.Get("Authorization") == "Bearer "+oldToken {
+		r.Header.Set("Authorization", "Bearer "+newToken)
+	}
+	q := r.URL.Query()
+	if q.Get("access_token") == oldToken {
+		q.Set("access_token", newToken)
+		r.URL.RawQuery = q.Encode()
+	}
+	return r
+}
@@ -0,0 +1,153 @@
+package cliutils
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/paulscherrerinstitute/scicat-cli/v3/datasetUtils"
+)
+
+func TestTokenRefreshTransport(t *testing.T) {
+	validToken := "new-token"
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		token := req.URL.Query().Get("access_token")
+		if auth := req.Header.Get("Authorization"); auth != "" {
+			token = auth[len("Bearer "):]
+		}
+		if req.URL.Path != "/public" && token != validToken {
+			rw.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(req.Body)
+		bodies = append(bodies, string(body))
+		rw.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	refreshes := 0
+	refresh := func(_ context.Context, _ *http.Client) (string, time.Time, error) {
+		refreshes++
+		return validToken, time.Time{}, nil
+	}
+
+	t.Run("retry after 401", func(t *testing.T) {
+		client := server.Client()
+		refreshes, bodies = 0, nil
+		enableTokenRefresh(client, datasetUtils.User{AccessToken: "old-token"}, refresh)
+
+		req, _ := http.NewRequest("POST", server.URL+"/datasets", bytes.NewBufferString(`{"a": 1}`))
+		req.Header.Set("Authorization", "Bearer old-token")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || refreshes != 1 {
+			t.Fatalf("expected a successful retry after one refresh, got status %d after %d refreshes", resp.StatusCode, refreshes)
+		}
+		if len(bodies) != 1 || bodies[0] != `{"a": 1}` {
+			t.Errorf("the body should be sent again on retry, got %v", bodies)
+		}
+
+		// the replaced token is swapped for the new one without another refresh
+		resp, err = client.Get(server.URL + "/Jobs?access_token=old-token")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || refreshes != 1 {
+			t.Errorf("expected the renewed token to be used, got status %d after %d refreshes", resp.StatusCode, refreshes)
+		}
+	})
+
+	t.Run("refresh before expiry", func(t *testing.T) {
+		client := server.Client()
+		refreshes = 0
+		user := datasetUtils.User{
+			AccessToken: "old-token",
+			ExpiresIn:   30,
+			Created:     time.Now().UTC().Format(time.RFC3339),
+		}
+		enableTokenRefresh(client, user, refresh)
+
+		req, _ := http.NewRequest("GET", server.URL+"/datasets", nil)
+		req.Header.Set("Authorization", "Bearer old-token")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || refreshes != 1 {
+			t.Errorf("expected the token to be renewed before sending, got status %d after %d refreshes", resp.StatusCode, refreshes)
+		}
+	})
+
+	t.Run("requests without the token are left alone", func(t *testing.T) {
+		client := server.Client()
+		refreshes = 0
+		enableTokenRefresh(client, datasetUtils.User{AccessToken: "old-token"}, refresh)
+
+		resp, err := client.Get(server.URL + "/other")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized || refreshes != 0 {
+			t.Errorf("got status %d after %d refreshes, want 401 without refresh", resp.StatusCode, refreshes)
+		}
+	})
+}
+
+// loginCountingAuthenticator issues a new token on every login
+type loginCountingAuthenticator struct {
+	MockAuthenticator
+	logins int
+}
+
+func (m *loginCountingAuthenticator) AuthenticateUser(ctx context.Context, httpClient *http.Client, APIServer string, username string, password string) (datasetUtils.User, error) {
+	m.logins++
+	return datasetUtils.User{Username: username, AccessToken: "token-" + strconv.Itoa(m.logins)}, nil
+}
+
+func TestAuthenticateEnablesTokenRefresh(t *testing.T) {
+	auth := &loginCountingAuthenticator{}
+	noExit := func(v ...any) {}
+	useTempCredentialsFile(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Authorization") != "Bearer token-2" {
+			rw.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	client := server.Client()
+	if _, err := Authenticate(context.Background(), auth, client, server.URL, "", "testtoken", false, noExit); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := client.Transport.(*tokenRefreshTransport); ok {
+		t.Error("a session from a plain token can't be renewed")
+	}
+
+	user, err := Authenticate(context.Background(), auth, client, server.URL, "testuser:testpass", "", false, noExit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest("GET", server.URL+"/datasets", nil)
+	req.Header.Set("Authorization", "Bearer "+user.AccessToken)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || auth.logins != 2 {
+		t.Errorf("expected a new login after the 401, got status %d after %d logins", resp.StatusCode, auth.logins)
+	}
+}
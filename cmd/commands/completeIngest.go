@@ -1,11 +1,10 @@
 package cmd
 
 import (
-	"crypto/tls"
 	"fmt"
 	"log"
-	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/fatih/color"
@@ -21,7 +20,8 @@ var completeIngestCmd = &cobra.Command{
 	Short: "Complete the ingestion of a dataset by adding files to an existing dataset entry in the SciCat catalog",
 	Long: `Complete the ingestion of a dataset by adding files to an existing dataset entry in the SciCat catalog.
 This command is used to complete the ingestion of a dataset that was previously created without
-any files attached (NumberOfFiles == 0). It checks that the caller is allowed to perform the operation,
+any files attached (NumberOfFiles == 0). It checks that the caller is allowed to perform the operation
+(by default only archiveManager, see the "authorization" section of the config file),
 that the dataset identified by pid exists, is empty and has a sourceFolder defined, then gathers the
 local file list from that sourceFolder and creates the corresponding origdatablocks. Symlinks are kept
 only when they point internally to the sourceFolder; filenames containing "*", "\" or three consecutive
@@ -30,19 +30,20 @@ blanks are excluded from the dataset.
 For further help see "` + cliutils.MANUAL + `"`,
 	Args: rangeArgsWithVersionException(1, 2),
 	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
 
-		var client = &http.Client{
-			Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: false}},
-			Timeout:   120 * time.Second}
+		var client = newHTTPClient(cmd, 120*time.Second)
 
 		const CMD = "completeIngest"
 
 		// pass parameters
 		envConfig := cliutils.InputEnvironmentConfig{
-			TestenvFlag:  cliutils.GetCobraBoolFlag(cmd, "testenv"),
-			DevenvFlag:   cliutils.GetCobraBoolFlag(cmd, "devenv"),
-			ScicatUrl:    cliutils.GetCobraStringFlag(cmd, "scicat-url"),
+			Profile:     cliutils.GetCobraStringFlag(cmd, "profile"),
+			TestenvFlag: cliutils.GetCobraBoolFlag(cmd, "testenv"),
+			DevenvFlag:  cliutils.GetCobraBoolFlag(cmd, "devenv"),
+			ScicatUrl:   cliutils.GetCobraStringFlag(cmd, "scicat-url"),
 		}
+		envConfig.ApplyFileConfig(fileConfig)
 
 		// configure environment
 		APIServer := envConfig.ResolveAPIServer()
@@ -50,6 +51,21 @@ For further help see "` + cliutils.MANUAL + `"`,
 		token := cliutils.GetCobraStringFlag(cmd, "token")
 		showVersion := cliutils.GetCobraBoolFlag(cmd, "version")
 		sourceFolderPrefix := cliutils.GetCobraStringFlag(cmd, "source-folder-prefix")
+		scanOptions := datasetIngestor.ScanOptions{
+			ChecksumAlgorithm: cliutils.GetCobraStringFlag(cmd, "checksum"),
+			ChecksumWorkers:   cliutils.GetCobraIntFlag(cmd, "checksum-workers"),
+			ScanWorkers:       cliutils.GetCobraIntFlag(cmd, "scan-workers"),
+			Include:           cliutils.GetCobraStringArrayFlag(cmd, "include"),
+			Exclude:           cliutils.GetCobraStringArrayFlag(cmd, "exclude"),
+		}
+		filenamePolicy := datasetIngestor.FilenamePolicy{
+			ForbiddenChars: cliutils.GetCobraStringFlag(cmd, "forbidden-chars"),
+			MaxPathLength:  cliutils.GetCobraIntFlag(cmd, "max-path-length"),
+			RejectNonUTF8:  cliutils.GetCobraBoolFlag(cmd, "reject-non-utf8"),
+			ReportPath:     cliutils.GetCobraStringFlag(cmd, "illegal-filenames-report"),
+		}
+
+		streamBlocks := cliutils.GetCobraBoolFlag(cmd, "stream-blocks")
 
 		if datasetUtils.TestFlags != nil {
 			datasetUtils.TestFlags(map[string]interface{}{
@@ -71,16 +87,25 @@ For further help see "` + cliutils.MANUAL + `"`,
 		if err != nil {
 			log.Fatal(err)
 		}
+		if scanOptions.ChecksumAlgorithm != "" {
+			if err := datasetIngestor.CheckChecksumAlgorithm(scanOptions.ChecksumAlgorithm); err != nil {
+				log.Fatal(err)
+			}
+		}
+		filenamePolicy.Mode, err = datasetIngestor.ParseIllegalFilenameMode(cliutils.GetCobraStringFlag(cmd, "illegal-filenames"))
+		if err != nil {
+			log.Fatal(err)
+		}
 
 		// === check for program version ===
 		datasetUtils.CheckForNewVersion(client, CMD, VERSION)
 
-		user, _, err := cliutils.Authenticate(cliutils.RealAuthenticator{}, client, APIServer, "", token, false)
+		user, err := cliutils.Authenticate(ctx, cliutils.RealAuthenticator{}, client, APIServer, "", token, false)
 		if err != nil {
 			log.Fatal(err)
 		}
 
-		err = orchestrator.CompleteIngest(client, APIServer, user, pid, sourceFolderPrefix)
+		err = orchestrator.CompleteIngest(ctx, client, APIServer, user, fileConfig.AccessRule(cmd.Name()), pid, sourceFolderPrefix, scanOptions, filenamePolicy, streamBlocks)
 		if err != nil {
 			switch err.(type) {
 			case *datasetIngestor.SkippedLinksWarning, *datasetIngestor.IllegalFileNamesWarning:
@@ -88,6 +113,8 @@ For further help see "` + cliutils.MANUAL + `"`,
 				log.Print(err)
 				color.Unset()
 			default:
+				summary.Set(pid, false, err.Error())
+				exitIfInterrupted(ctx)
 				color.Set(color.FgRed)
 				log.Print(err)
 				color.Unset()
@@ -104,6 +131,18 @@ func init() {
 	completeIngestCmd.Flags().Bool("testenv", false, "Use test environment (qa) instead of production environment")
 	completeIngestCmd.Flags().Bool("devenv", false, "Use development environment instead of production environment (developers only)")
 	completeIngestCmd.Flags().String("source-folder-prefix", "", "Prefix to prepend to sourceFolder path when scanning for files")
+	completeIngestCmd.Flags().String("checksum", "", fmt.Sprintf("Compute a checksum of every file with this algorithm and store it in the origdatablocks (%s)", strings.Join(datasetIngestor.ChecksumAlgorithms(), ", ")))
+	completeIngestCmd.Flags().Int("checksum-workers", datasetIngestor.DefaultChecksumWorkers, "Number of files whose checksums are computed in parallel")
+	completeIngestCmd.Flags().Int("scan-workers", datasetIngestor.DefaultScanWorkers, "Number of directories read in parallel when scanning the sourceFolder")
+	completeIngestCmd.Flags().String("illegal-filenames", string(datasetIngestor.IllegalFilenamesSkip), "What to do with files whose names are illegal: skip them, fail before the dataset is created, or skip them and write them with the reasons to the --illegal-filenames-report file (skip|fail|report)")
+	completeIngestCmd.Flags().String("illegal-filenames-report", "illegalFilenames.txt", "File the illegal file names are written to with --illegal-filenames=report")
+	completeIngestCmd.Flags().String("forbidden-chars", "", "Characters not allowed in file names, in addition to \"*\" and \"\\\"")
+	completeIngestCmd.Flags().Int("max-path-length", 0, "Maximum length in bytes of a file path relative to the sourceFolder, longer ones are illegal (0: no limit)")
+	completeIngestCmd.Flags().Bool("reject-non-utf8", false, "Treat file names which aren't valid UTF-8 as illegal")
+	completeIngestCmd.Flags().StringArray("include", nil, "Gitignore-style pattern of the files to ingest, all files if none is given (repeatable)")
+	completeIngestCmd.Flags().StringArray("exclude", nil, "Gitignore-style pattern of the files to leave out, in addition to the ones in the sourceFolder's "+datasetIngestor.IgnoreFileName+" (repeatable)")
+
+	completeIngestCmd.Flags().Bool("stream-blocks", false, "Post each origdatablock as soon as it's full while the sourceFolder is scanned, instead of holding the whole file list in memory. The blocks posted already are deleted if the scan fails")
 
 	completeIngestCmd.MarkFlagsMutuallyExclusive("testenv", "devenv")
 }
@@ -2,10 +2,8 @@ package cmd
 
 import (
 	"bufio"
-	"crypto/tls"
 	"fmt"
 	"log"
-	"net/http"
 	"os"
 	"time"
 
@@ -30,10 +28,10 @@ via the --transfer-type flag. Available options: "ssh", "globus", "s3", "rocrate
 
 For further help see "` + cliutils.MANUAL + `"`,
 	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+
 		// consts & vars
-		var client = &http.Client{
-			Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: false}},
-			Timeout:   10 * time.Second}
+		var client = newHTTPClient(cmd, 10*time.Second)
 
 		const CMD = "datasetArchiver"
 		var scanner = bufio.NewScanner(os.Stdin)
@@ -82,11 +80,13 @@ For further help see "` + cliutils.MANUAL + `"`,
 
 		// configure environment
 		config := cliutils.InputEnvironmentConfig{
+			Profile:      cliutils.GetCobraStringFlag(cmd, "profile"),
 			TestenvFlag:  testenvFlag,
 			DevenvFlag:   devenvFlag,
 			LocalenvFlag: localenvFlag,
 			ScicatUrl:    scicatUrl,
 		}
+		config.ApplyFileConfig(fileConfig)
 		APIServer := config.ResolveAPIServer()
 
 		executionTime, err := orchestrator.ParseExecutionTime(executionTimeStr)
@@ -104,17 +104,18 @@ For further help see "` + cliutils.MANUAL + `"`,
 			inputdatasetList = args[0:]
 		}
 
-		user, accessGroups, err := cliutils.Authenticate(cliutils.RealAuthenticator{}, client, APIServer, userpass, token, oidc)
+		user, err := cliutils.Authenticate(ctx, cliutils.RealAuthenticator{}, client, APIServer, userpass, token, oidc)
 		if err != nil {
 			log.Fatal(err)
 		}
 
-		resolvedOwnerGroup, err := orchestrator.ResolveOwnerGroup(ownerGroup, accessGroups)
+		resolvedOwnerGroup, err := orchestrator.ResolveOwnerGroup(ownerGroup, user.AccessGroups)
 		if err != nil {
 			log.Fatal(err)
 		}
-		archivableDatasets, err := orchestrator.ResolveArchivableDatasets(client, APIServer, user["accessToken"], resolvedOwnerGroup, inputdatasetList)
+		archivableDatasets, err := orchestrator.ResolveArchivableDatasets(ctx, client, APIServer, user.AccessToken, resolvedOwnerGroup, inputdatasetList)
 		if err != nil {
+			exitIfInterrupted(ctx)
 			log.Fatal(err)
 		}
 
@@ -138,12 +139,13 @@ For further help see "` + cliutils.MANUAL + `"`,
 
 		log.Printf("You chose to archive the new datasets\n")
 		log.Printf("Submitting Archive Job for the ingested datasets.\n")
-		jobId, err := datasetUtils.CreateArchivalJob(client, APIServer, user, resolvedOwnerGroup, archivableDatasets, datasetUtils.ArchivalJobOptions{
+		jobId, err := datasetUtils.CreateArchivalJob(ctx, client, APIServer, user, resolvedOwnerGroup, archivableDatasets, datasetUtils.ArchivalJobOptions{
 			TapeCopies:    &tapecopies,
 			TransferType:  &convertedTransferType,
 			ExecutionTime: executionTime,
 		})
 		if err != nil {
+			exitIfInterrupted(ctx)
 			log.Fatalf("Couldn't create a job: %s\n", err.Error())
 		}
 		fmt.Println(jobId)
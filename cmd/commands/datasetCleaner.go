@@ -1,10 +1,8 @@
 package cmd
 
 import (
-	"crypto/tls"
 	"fmt"
 	"log"
-	"net/http"
 	"time"
 
 	"github.com/paulscherrerinstitute/scicat-cli/v3/cmd/cliutils"
@@ -30,10 +28,10 @@ Note: these actions can not be un-done! Be careful!
 For further help see "` + cliutils.MANUAL + `"`,
 	Args: exactArgsWithVersionException(1),
 	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+
 		// vars & consts
-		var client = &http.Client{
-			Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: false}},
-			Timeout:   10 * time.Second}
+		var client = newHTTPClient(cmd, 10*time.Second)
 
 		const CMD = "datasetCleaner"
 
@@ -75,10 +73,12 @@ For further help see "` + cliutils.MANUAL + `"`,
 
 		// configure environment
 		config := cliutils.InputEnvironmentConfig{
+			Profile:     cliutils.GetCobraStringFlag(cmd, "profile"),
 			TestenvFlag: testenvFlag,
 			DevenvFlag:  devenvFlag,
 			ScicatUrl:   scicatUrl,
 		}
+		config.ApplyFileConfig(fileConfig)
 		APIServer := config.ResolveAPIServer()
 
 		if len(args) != 1 {
@@ -87,19 +87,21 @@ For further help see "` + cliutils.MANUAL + `"`,
 		}
 		pid := args[0]
 
-		user, _, err := cliutils.Authenticate(cliutils.RealAuthenticator{}, client, APIServer, userpass, token, oidc)
+		user, err := cliutils.Authenticate(ctx, cliutils.RealAuthenticator{}, client, APIServer, userpass, token, oidc)
 		if err != nil {
 			log.Fatal(err)
 		}
 
-		if user["username"] != "archiveManager" {
-			log.Fatalf("You must be archiveManager to be allowed to delete datasets\n")
+		if err := datasetUtils.Authorize(user, fileConfig.AccessRule(cmd.Name()), "delete datasets"); err != nil {
+			log.Fatal(err)
 		}
 
-		jobID, err := datasetUtils.RemoveFromArchive(client, APIServer, pid, user, nonInteractiveFlag)
+		summary.Set(pid, false, "not removed from the archive")
+		jobID, err := datasetUtils.RemoveFromArchive(ctx, client, APIServer, pid, user, nonInteractiveFlag)
 		if err != nil {
+			exitIfInterrupted(ctx)
 			if jobID != "" {
-				patchError := datasetUtils.PatchJobStatus(client, APIServer, user, jobID, string(datasetUtils.JobFailed))
+				patchError := datasetUtils.PatchJobStatus(ctx, client, APIServer, user, jobID, string(datasetUtils.JobFailed))
 				if patchError != nil {
 					log.Fatalf("Failed to patch job status: %v", patchError)
 				}
@@ -108,10 +110,12 @@ For further help see "` + cliutils.MANUAL + `"`,
 		}
 
 		if removeFromCatalogFlag {
-			err = datasetUtils.RemoveFromCatalog(client, APIServer, pid, jobID, user, nonInteractiveFlag)
+			summary.Set(pid, false, "archive deletion job "+jobID+" created, not (completely) removed from the catalog")
+			err = datasetUtils.RemoveFromCatalog(ctx, client, APIServer, pid, jobID, user, nonInteractiveFlag)
 			if err != nil {
+				exitIfInterrupted(ctx)
 				if jobID != "" {
-					patchError := datasetUtils.PatchJobStatus(client, APIServer, user, jobID, string(datasetUtils.JobFailed))
+					patchError := datasetUtils.PatchJobStatus(ctx, client, APIServer, user, jobID, string(datasetUtils.JobFailed))
 					if patchError != nil {
 						log.Fatalf("Failed to patch job status: %v", patchError)
 					}
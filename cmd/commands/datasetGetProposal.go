@@ -1,11 +1,9 @@
 package cmd
 
 import (
-	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"log"
-	"net/http"
 	"time"
 
 	"github.com/paulscherrerinstitute/scicat-cli/v3/cmd/cliutils"
@@ -21,10 +19,10 @@ var datasetGetProposalCmd = &cobra.Command{
 For further help see "` + cliutils.MANUAL + `"`,
 	Args: exactArgsWithVersionException(1),
 	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+
 		// vars and constants
-		var client = &http.Client{
-			Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: false}},
-			Timeout:   10 * time.Second}
+		var client = newHTTPClient(cmd, 10*time.Second)
 
 		const APP = "datasetGetProposal"
 
@@ -63,11 +61,13 @@ For further help see "` + cliutils.MANUAL + `"`,
 
 		// configure environment
 		config := cliutils.InputEnvironmentConfig{
+			Profile:      cliutils.GetCobraStringFlag(cmd, "profile"),
 			TestenvFlag:  testenvFlag,
 			DevenvFlag:   devenvFlag,
 			LocalenvFlag: localenvFlag,
 			ScicatUrl:    scicatUrl,
 		}
+		config.ApplyFileConfig(fileConfig)
 		APIServer := config.ResolveAPIServer()
 
 		//TODO cleanup text formatting:
@@ -76,12 +76,13 @@ For further help see "` + cliutils.MANUAL + `"`,
 		}
 		ownerGroup := args[0]
 
-		user, _, err := cliutils.Authenticate(cliutils.RealAuthenticator{}, client, APIServer, userpass, token, oidc)
+		user, err := cliutils.Authenticate(ctx, cliutils.RealAuthenticator{}, client, APIServer, userpass, token, oidc)
 		if err != nil {
 			log.Fatal(err)
 		}
-		proposal, err := datasetUtils.GetProposal(client, APIServer, ownerGroup, user)
+		proposal, err := datasetUtils.GetProposal(ctx, client, APIServer, ownerGroup, user)
 		if err != nil {
+			exitIfInterrupted(ctx)
 			log.Fatal(err)
 		}
 
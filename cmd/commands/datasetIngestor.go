@@ -2,11 +2,10 @@ package cmd
 
 import (
 	"bufio"
-	"crypto/tls"
+	"context"
 	"errors"
 	"fmt"
 	"log"
-	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
@@ -31,6 +30,13 @@ which comprises the dataset. It takes one or two input
 files and creates the necessary messages which trigger
 the creation of the corresponding datacatalog entries
 
+The metadata file may be JSON, YAML or TOML, the format is taken from the
+extension (.json, .yaml, .yml, .toml) or else from the content.
+
+With --template-metadata the metadata values may contain templates evaluated
+per dataset, e.g. {{ .Folder.Base }}, {{ regex .SourceFolder "run_(\\d+)" 1 }},
+{{ env "PROPOSAL" }} or {{ .Scan.StartTime }}.
+
 For further help see "` + cliutils.MANUAL + `"
 
 Special hints for the decentral use case, where data is copied first to intermediate storage:
@@ -38,14 +44,14 @@ For Linux you need to have a valid Kerberos tickets, which you can get via the k
 For Windows you need instead to specify -user username:password on the command line.`,
 	Args: rangeArgsWithVersionException(1, 2),
 	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+
 		var tooLargeDatasets = 0
 		var emptyDatasets = 0
 
-		var originalMap = make(map[string]string)
+		var originalMap = make(map[string]interface{})
 
-		var client = &http.Client{
-			Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: false}},
-			Timeout:   120 * time.Second}
+		var client = newHTTPClient(cmd, 120*time.Second)
 
 		const CMD = "datasetIngestor"
 
@@ -53,19 +59,24 @@ For Windows you need instead to specify -user username:password on the command l
 
 		// pass parameters
 		envConfig := cliutils.InputEnvironmentConfig{
+			Profile:       cliutils.GetCobraStringFlag(cmd, "profile"),
 			TestenvFlag:   cliutils.GetCobraBoolFlag(cmd, "testenv"),
 			DevenvFlag:    cliutils.GetCobraBoolFlag(cmd, "devenv"),
 			TunnelenvFlag: cliutils.GetCobraBoolFlag(cmd, "tunnelenv"),
 			LocalenvFlag:  cliutils.GetCobraBoolFlag(cmd, "localenv"),
 			ScicatUrl:     cliutils.GetCobraStringFlag(cmd, "scicat-url"),
 			RsyncUrl:      cliutils.GetCobraStringFlag(cmd, "rsync-url"),
+			GlobusCfg:     cliutils.GetCobraStringFlag(cmd, "globus-cfg"),
 		}
+		envConfig.ApplyFileConfig(fileConfig)
 
 		// configure environment
 		APIServer := envConfig.ResolveAPIServer()
 		RSYNCServer := envConfig.ResolveRSYNCServer()
 		S3UploadBucket := envConfig.ResolveS3UploadBucket()
 		S3BrokerServer := envConfig.ResolveS3BrokerServer()
+		S3Endpoint := envConfig.ResolveS3Endpoint()
+		S3Region := envConfig.ResolveS3Region()
 
 		ingestFlag := cliutils.GetCobraBoolFlag(cmd, "ingest")
 		noninteractiveFlag := cliutils.GetCobraBoolFlag(cmd, "noninteractive")
@@ -82,22 +93,54 @@ For Windows you need instead to specify -user username:password on the command l
 		addAttachment := cliutils.GetCobraStringFlag(cmd, "addattachment")
 		addCaption := cliutils.GetCobraStringFlag(cmd, "addcaption")
 		showVersion := cliutils.GetCobraBoolFlag(cmd, "version")
-		globusCfgFlag := cliutils.GetCobraStringFlag(cmd, "globus-cfg")
 		remoteFilesFlag := cliutils.GetCobraBoolFlag(cmd, "remote-files")
+		journalPath := cliutils.GetCobraStringFlag(cmd, "journal")
+		resumePath := cliutils.GetCobraStringFlag(cmd, "resume")
+		manifestPath := cliutils.GetCobraStringFlag(cmd, "manifest")
+		templateMetadataFlag := cliutils.GetCobraBoolFlag(cmd, "template-metadata")
+		streamBlocks := cliutils.GetCobraBoolFlag(cmd, "stream-blocks")
+		onFailureFlag := cliutils.GetCobraStringFlag(cmd, "on-failure")
+		illegalFilenamesFlag := cliutils.GetCobraStringFlag(cmd, "illegal-filenames")
+		scanOptions := datasetIngestor.ScanOptions{
+			ChecksumAlgorithm: cliutils.GetCobraStringFlag(cmd, "checksum"),
+			ChecksumWorkers:   cliutils.GetCobraIntFlag(cmd, "checksum-workers"),
+			ScanWorkers:       cliutils.GetCobraIntFlag(cmd, "scan-workers"),
+			Include:           cliutils.GetCobraStringArrayFlag(cmd, "include"),
+			Exclude:           cliutils.GetCobraStringArrayFlag(cmd, "exclude"),
+		}
+		filenamePolicy := datasetIngestor.FilenamePolicy{
+			ForbiddenChars: cliutils.GetCobraStringFlag(cmd, "forbidden-chars"),
+			MaxPathLength:  cliutils.GetCobraIntFlag(cmd, "max-path-length"),
+			RejectNonUTF8:  cliutils.GetCobraBoolFlag(cmd, "reject-non-utf8"),
+			ReportPath:     cliutils.GetCobraStringFlag(cmd, "illegal-filenames-report"),
+		}
 
 		if remoteFilesFlag {
 			nocopyFlag = true
 		}
 
-		// TODO: read in CFG!
-
 		// transfer type
 		transferType, err := datasetUtils.ConvertToTransferType(transferTypeFlag)
 		if err != nil {
 			log.Fatalln(err)
 		}
 
-		var transferFiles func(params cliutils.TransferParams) (archivable bool, err error)
+		if scanOptions.ChecksumAlgorithm != "" {
+			if err := datasetIngestor.CheckChecksumAlgorithm(scanOptions.ChecksumAlgorithm); err != nil {
+				log.Fatalln(err)
+			}
+		}
+
+		failedIngestAction, err := orchestrator.ParseFailedIngestAction(onFailureFlag)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		filenamePolicy.Mode, err = datasetIngestor.ParseIllegalFilenameMode(illegalFilenamesFlag)
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		var transferFiles func(ctx context.Context, params cliutils.TransferParams) (archivable bool, err error)
 
 		// globus specific vars (if needed)
 		var globusClient globus.GlobusClient
@@ -109,15 +152,9 @@ For Windows you need instead to specify -user username:password on the command l
 			transferFiles = cliutils.SshTransfer
 		case datasetUtils.Globus:
 			transferFiles = cliutils.GlobusTransfer
-			var globusConfigPath string
-			if cmd.Flags().Lookup("globus-cfg").Changed {
-				globusConfigPath = globusCfgFlag
-			} else {
-				execPath, err := os.Executable()
-				if err != nil {
-					log.Fatalln("can't find executable path:", err)
-				}
-				globusConfigPath = filepath.Join(filepath.Dir(execPath), "globus.yaml")
+			globusConfigPath, err := envConfig.ResolveGlobusConfigPath()
+			if err != nil {
+				log.Fatalln(err)
 			}
 
 			globusClient, gConfig, err = cliutils.GlobusLogin(globusConfigPath)
@@ -173,7 +210,6 @@ For Windows you need instead to specify -user username:password on the command l
 		metadatafile := args[0]
 		datasetFileListTxt := ""
 		folderListingTxt := ""
-		absFileListing := ""
 		if len(args) == 2 {
 			argFileName := filepath.Base(args[1])
 			if argFileName == "folderlisting.txt" {
@@ -184,7 +220,6 @@ For Windows you need instead to specify -user username:password on the command l
 				// NOTE datasetFileListTxt is a TEXT FILE that lists the files & folders of a dataset (contained in a folder)
 				//   that should be considered as "part of" the dataset. The paths must be relative to the sourceFolder.
 				datasetFileListTxt = args[1]
-				absFileListing, _ = filepath.Abs(datasetFileListTxt)
 			}
 		}
 
@@ -192,33 +227,104 @@ For Windows you need instead to specify -user username:password on the command l
 			datasetUtils.TestArgs([]interface{}{metadatafile, datasetFileListTxt, folderListingTxt})
 			return
 		}
+		if manifestPath != "" && folderListingTxt != "" {
+			log.Fatalln("--manifest lists the dataset folders, it can't be combined with a folderlisting.txt")
+		}
 
 		if showVersion {
 			fmt.Printf("%s\n", VERSION)
 			return
 		}
 
+		// the journal records the steps done for every folder, so a failed run can be resumed
+		var journal *cliutils.IngestJournal
+		if resumePath != "" {
+			if !ingestFlag {
+				log.Fatalln("--resume needs --ingest")
+			}
+			journal, err = cliutils.LoadIngestJournal(resumePath)
+			if err != nil {
+				log.Fatalln(err)
+			}
+			log.Printf("Resuming the ingest recorded in %s\n", resumePath)
+		} else if ingestFlag && journalPath != "" {
+			// a journal left by a complete ingest is replaced, an incomplete one needs --resume
+			if _, err := os.Stat(journalPath); err == nil {
+				previous, err := cliutils.LoadIngestJournal(journalPath)
+				if err == nil && !previous.Complete(nil, true) {
+					log.Fatalf("The ingest journal %s of an incomplete ingest exists already, use --resume %s to continue that ingest or remove it\n", journalPath, journalPath)
+				}
+				log.Printf("Replacing the ingest journal %s of a previous ingest\n", journalPath)
+			}
+			journal = cliutils.NewIngestJournal(journalPath)
+			log.Printf("Recording the ingest in %s, use --resume %s to continue it if it fails\n", journalPath, journalPath)
+		}
+		recordStep := func(sourceFolder string, step cliutils.IngestStep, datasetId string) {
+			if err := journal.Record(sourceFolder, step, datasetId); err != nil {
+				log.Fatalln(err)
+			}
+		}
+
 		// === check for program version ===
 		datasetUtils.CheckForNewVersion(client, CMD, VERSION)
 		datasetUtils.CheckForServiceAvailability(client, envConfig.TestenvFlag, autoarchiveFlag)
 
-		user, accessGroups, err := cliutils.Authenticate(cliutils.RealAuthenticator{}, client, APIServer, userpass, token, oidc)
+		user, err := cliutils.Authenticate(ctx, cliutils.RealAuthenticator{}, client, APIServer, userpass, token, oidc)
 		if err != nil {
 			log.Fatal(err)
 		}
 
 		/* TODO Add info about policy settings and that autoarchive will take place or not */
-		metaDataMap, metadataSourceFolder, beamlineAccount, err := datasetIngestor.ReadAndCheckMetadata(client, APIServer, metadatafile, user, accessGroups, remoteFilesFlag)
-		if err != nil {
-			log.Fatal("Error in CheckMetadata function: ", err)
+		var metaDataMap map[string]interface{}
+		var metadataSourceFolder string
+		var beamlineAccount bool
+		// the datasets of the manifest, by sourceFolder, each with its own metadata
+		manifestDatasets := make(map[string]orchestrator.ManifestDataset)
+		var datasetPaths []string
+		// templated metadata is checked once more per dataset, when its templates are expanded
+		var metadataTemplates *orchestrator.MetadataTemplates
+		checkMetadata := datasetIngestor.CheckMetadata
+		if templateMetadataFlag {
+			metadataTemplates = &orchestrator.MetadataTemplates{Rules: fileConfig.FacilityRules(), RemoteFiles: remoteFilesFlag}
+			checkMetadata = datasetIngestor.CheckTemplatedMetadata
+		}
+		if manifestPath == "" {
+			metaDataMap, err = datasetIngestor.ReadMetadataFromFile(metadatafile)
+			if err != nil {
+				log.Fatal(err)
+			}
+			metadataSourceFolder, beamlineAccount, err = checkMetadata(ctx, client, APIServer, metaDataMap, user, fileConfig.FacilityRules(), remoteFilesFlag)
+			if err != nil {
+				exitIfInterrupted(ctx)
+				log.Fatal("Error in CheckMetadata function: ", err)
+			}
+		} else {
+			baseMetaDataMap, err := datasetIngestor.ReadMetadataFromFile(metadatafile)
+			if err != nil {
+				log.Fatal(err)
+			}
+			entries, err := datasetIngestor.ReadBatchManifest(manifestPath)
+			if err != nil {
+				log.Fatal(err)
+			}
+			datasets, isBeamlineAccount, err := orchestrator.CheckBatchManifest(ctx, client, APIServer, user, fileConfig.FacilityRules(), remoteFilesFlag, templateMetadataFlag, baseMetaDataMap, entries)
+			if err != nil {
+				exitIfInterrupted(ctx)
+				log.Fatal("Error in CheckMetadata function: ", err)
+			}
+			beamlineAccount = isBeamlineAccount
+			for _, dataset := range datasets {
+				datasetPaths = append(datasetPaths, dataset.SourceFolder)
+				manifestDatasets[dataset.SourceFolder] = dataset
+			}
+			metaDataMap = datasets[0].MetaDataMap
 		}
 		//log.Printf("metadata object: %v\n", metaDataMap)
 
-		// assemble list of datasetPaths (=datasets) to be created
-		var datasetPaths []string
-		if folderListingTxt == "" {
+		// assemble list of datasetPaths (=datasets) to be created, unless the manifest listed them
+		if folderListingTxt == "" && manifestPath == "" {
 			datasetPaths = append(datasetPaths, metadataSourceFolder)
-		} else {
+		} else if folderListingTxt != "" {
 			// get folders from file
 			folderlist, err := os.ReadFile(folderListingTxt)
 			if err != nil {
@@ -251,8 +357,16 @@ For Windows you need instead to specify -user username:password on the command l
 
 		// test if a sourceFolder already used in the past and give warning
 		log.Println("Testing for existing source folders...")
-		foundList, err := datasetIngestor.TestForExistingSourceFolder(datasetPaths, client, APIServer, user["accessToken"])
+		// the datasets created by the resumed run don't count
+		var newDatasetPaths []string
+		for _, datasetPath := range datasetPaths {
+			if journal.Entry(datasetPath).DatasetId == "" {
+				newDatasetPaths = append(newDatasetPaths, datasetPath)
+			}
+		}
+		foundList, err := datasetIngestor.TestForExistingSourceFolder(ctx, newDatasetPaths, client, APIServer, user.AccessToken)
 		if err != nil {
+			exitIfInterrupted(ctx)
 			log.Fatal(err)
 		}
 		color.Set(color.FgYellow)
@@ -302,7 +416,28 @@ For Windows you need instead to specify -user username:password on the command l
 		var skippedLinks uint = 0
 		var illegalFileNames uint = 0
 		localSymlinkCallback := datasetIngestor.CreateLocalSymlinkCallbackForFileLister(&skipSymlinks, &skippedLinks)
-		localFilepathFilterCallback := datasetIngestor.CreateLocalFilenameFilterCallback(&illegalFileNames)
+		filenameFilter, err := datasetIngestor.NewFilenameFilter(filenamePolicy, &illegalFileNames)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// rollback handles a dataset whose origdatablocks or files couldn't be added
+		rollback := func(sourceFolder string, datasetId string) {
+			// roll back even if the ingestion was interrupted, a second Ctrl-C still exits
+			applied, err := orchestrator.RollbackDataset(context.WithoutCancel(ctx), client, APIServer, user, datasetId, failedIngestAction)
+			if err != nil {
+				color.Set(color.FgRed)
+				log.Printf("Couldn't roll back the failed ingestion: %v\n", err)
+				color.Unset()
+				return
+			}
+			if applied == orchestrator.FailedIngestDelete {
+				summary.Set(sourceFolder, false, "dataset "+datasetId+" deleted after the failed ingestion")
+				if err := journal.Forget(sourceFolder); err != nil {
+					log.Println(err)
+				}
+			}
+		}
 
 		// now everything is prepared, prepare to loop over all folders
 		var archivableDatasetList []string
@@ -311,12 +446,53 @@ For Windows you need instead to specify -user username:password on the command l
 			log.Fatal("can't recover ownerGroup. This should normally be impossible as the checkMetadata function should've caught it already.")
 		}
 		for _, datasetSourceFolder := range datasetPaths {
+			if datasetSourceFolder != "" {
+				summary.Set(datasetSourceFolder, false, "not started")
+			}
+		}
+		// with --illegal-filenames=fail no dataset is created if any of the folders has illegal file names
+		if !remoteFilesFlag {
+			var unchecked []string
+			for _, datasetSourceFolder := range datasetPaths {
+				if !journal.Entry(datasetSourceFolder).Done {
+					unchecked = append(unchecked, datasetSourceFolder)
+				}
+			}
+			if err := orchestrator.CheckFilenames(ctx, unchecked, datasetFileListTxt, skipSymlinks, filenamePolicy, scanOptions); err != nil {
+				exitIfInterrupted(ctx)
+				color.Set(color.FgRed)
+				log.Print(err)
+				color.Unset()
+				os.Exit(1)
+			}
+		}
+		for _, datasetSourceFolder := range datasetPaths {
+			exitIfInterrupted(ctx)
 			log.Printf("===== Ingesting: \"%s\" =====\n", datasetSourceFolder)
 			// ignore empty lines
 			if datasetSourceFolder == "" {
 				// NOTE if there are empty source folder(s), shouldn't we raise an error?
 				continue
 			}
+			entry := journal.Entry(datasetSourceFolder)
+			if entry.Done {
+				log.Printf("Skipping \"%s\", it was ingested as %s by the resumed run\n", datasetSourceFolder, entry.DatasetId)
+				summary.Set(datasetSourceFolder, true, "ingested as "+entry.DatasetId+" by the resumed run")
+				if entry.Archivable {
+					archivableDatasetList = append(archivableDatasetList, entry.DatasetId)
+				}
+				continue
+			}
+			attachment, caption := addAttachment, addCaption
+			if dataset, ok := manifestDatasets[datasetSourceFolder]; ok {
+				metaDataMap = dataset.MetaDataMap
+				if dataset.Attachment != "" {
+					attachment = dataset.Attachment
+				}
+				if dataset.Caption != "" {
+					caption = dataset.Caption
+				}
+			}
 			metaDataMap["sourceFolder"] = datasetSourceFolder
 			log.Printf("Scanning files in dataset %s", datasetSourceFolder)
 
@@ -334,52 +510,76 @@ For Windows you need instead to specify -user username:password on the command l
 				color.Unset()
 			}
 			fullFileArray := make([]datasetIngestor.Datafile, 0)
+			// the files of a streamed dataset are scanned once it's created
+			streamed := streamBlocks && ingestFlag && !remoteFilesFlag
 			if remoteFilesFlag {
-				orchestrator.PrepareRemoteDataset(client, APIServer, user, originalMap, metaDataMap, tapecopies)
+				if err := orchestrator.PrepareRemoteDataset(ctx, client, APIServer, user, originalMap, metaDataMap, tapecopies, metadataTemplates); err != nil {
+					color.Set(color.FgRed)
+					log.Print(err)
+					color.Unset()
+					os.Exit(1)
+				}
+			} else if streamed {
+				if err := orchestrator.PrepareStreamedDataset(ctx, client, APIServer, user, originalMap, metaDataMap, tapecopies, datasetSourceFolder); err != nil {
+					color.Set(color.FgRed)
+					log.Print(err)
+					color.Unset()
+					os.Exit(1)
+				}
 			} else {
 				var err error
-				fullFileArray, err = orchestrator.PrepareDatasetAndUpdateCounts(client, APIServer, user, originalMap, metaDataMap, tapecopies,
-					datasetSourceFolder, datasetFileListTxt, localSymlinkCallback, localFilepathFilterCallback,
+				fullFileArray, err = orchestrator.PrepareDatasetAndUpdateCounts(ctx, client, APIServer, user, originalMap, metaDataMap, tapecopies,
+					datasetSourceFolder, datasetFileListTxt, localSymlinkCallback, filenameFilter.Check, scanOptions, metadataTemplates,
 					&emptyDatasets, &tooLargeDatasets)
+				// the illegal file names are reported even if the dataset is skipped
+				if finishErr := filenameFilter.Finish(datasetSourceFolder); err == nil {
+					err = finishErr
+				}
 				if err != nil {
 					var emptyDatasetErr *datasetIngestor.EmptyDatasetError
 					var tooManyFilesErr *datasetIngestor.TooManyFilesError
 					if errors.As(err, &emptyDatasetErr) || errors.As(err, &tooManyFilesErr) {
+						summary.Set(datasetSourceFolder, false, "skipped, it's empty or has too many files")
 						color.Set(color.FgRed)
 						log.Println(err)
 						color.Unset()
 						continue
 					}
+					exitIfInterrupted(ctx)
 					color.Set(color.FgRed)
 					log.Print(err)
 					color.Unset()
 					os.Exit(1)
 				}
+			}
 
-				// check if data is accesible at archive server, unless beamline account (assumed to be centrally available always)
-				// and unless (no)copy flag defined via command line
-				if checkCentralAvailability {
-					newCopyFlag, err := orchestrator.ResolveCentralAvailability(user["username"], RSYNCServer, datasetSourceFolder,
-						copyFlag, accessGroups, noninteractiveFlag, func() bool {
-							log.Printf("Do you want to continue (Y/n)? ")
-							scanner.Scan()
-							return scanner.Text() != "n"
-						})
-					if err != nil {
-						var notCentrallyAvailableWarning *orchestrator.NotCentrallyAvailableWarning
-						if errors.As(err, &notCentrallyAvailableWarning) {
-							color.Set(color.FgYellow)
-							log.Print(err)
-							color.Unset()
-						} else {
-							color.Set(color.FgRed)
-							log.Print(err)
-							color.Unset()
-							os.Exit(1)
-						}
+			// check if data is accesible at archive server, unless beamline account (assumed to be centrally available always)
+			// and unless (no)copy flag defined via command line
+			if !remoteFilesFlag && checkCentralAvailability {
+				newCopyFlag, err := orchestrator.ResolveCentralAvailability(ctx, user.Username, RSYNCServer, datasetSourceFolder,
+					copyFlag, user.AccessGroups, noninteractiveFlag, func() bool {
+						log.Printf("Do you want to continue (Y/n)? ")
+						scanner.Scan()
+						return scanner.Text() != "n"
+					})
+				if err != nil {
+					var notCentrallyAvailableWarning *orchestrator.NotCentrallyAvailableWarning
+					if errors.As(err, &notCentrallyAvailableWarning) {
+						color.Set(color.FgYellow)
+						log.Print(err)
+						color.Unset()
+					} else {
+						exitIfInterrupted(ctx)
+						color.Set(color.FgRed)
+						log.Print(err)
+						color.Unset()
+						os.Exit(1)
 					}
-					copyFlag = newCopyFlag
 				}
+				copyFlag = newCopyFlag
+			}
+			if !streamed {
+				recordStep(datasetSourceFolder, cliutils.StepScanned, "")
 			}
 			// === ingest dataset ===
 			if ingestFlag {
@@ -391,23 +591,106 @@ For Windows you need instead to specify -user username:password on the command l
 				metaDataMap["datasetlifecycle"].(map[string]interface{})["isOnCentralDisk"] = isOnCentralDisk
 				metaDataMap["datasetlifecycle"].(map[string]interface{})["archiveStatusMessage"] = archiveStatusMessage
 				metaDataMap["datasetlifecycle"].(map[string]interface{})["archivable"] = metaArchivable
-				log.Println("Ingesting dataset...")
-				datasetId, err := datasetIngestor.IngestDataset(client, APIServer, metaDataMap, fullFileArray, user)
-				if err != nil {
-					log.Fatal("Couldn't ingest dataset:", err)
+				datasetId := entry.DatasetId
+				// the blocks of a streamed dataset are posted while its files are scanned. They're replaced if
+				// the resumed run didn't copy the files, as they must be scanned again for that anyway
+				streamFiles := streamed && (!entry.Step.Reached(cliutils.StepOrigDatablocksCreated) ||
+					copyFlag && !entry.Step.Reached(cliutils.StepFilesTransferred))
+				if datasetId == "" {
+					log.Println("Ingesting dataset...")
+					var err error
+					// without files if they're streamed
+					datasetId, err = datasetIngestor.IngestDataset(ctx, client, APIServer, metaDataMap, fullFileArray, user)
+					if datasetId != "" {
+						recordStep(datasetSourceFolder, cliutils.StepDatasetCreated, datasetId)
+					}
+					if err != nil {
+						if datasetId != "" {
+							summary.Set(datasetSourceFolder, false, fmt.Sprintf("dataset %s created without all of its files: %v", datasetId, err))
+							rollback(datasetSourceFolder, datasetId)
+						}
+						exitIfInterrupted(ctx)
+						log.Fatal("Couldn't ingest dataset:", err)
+					}
+					log.Println("Dataset created:", datasetId)
+				} else if streamFiles || !streamed && !entry.Step.Reached(cliutils.StepOrigDatablocksCreated) {
+					// some of the blocks may have been created, so all of them are replaced
+					log.Printf("Replacing the origdatablocks of the dataset %s created by the resumed run...\n", datasetId)
+					err := datasetUtils.DeleteOrigDatablocks(ctx, client, APIServer, datasetId, user)
+					if err == nil && !streamed {
+						err = datasetIngestor.CreateOrigDatablocks(ctx, client, APIServer, fullFileArray, datasetId, user)
+					}
+					if err == nil && !streamed {
+						// the catalog added up the replaced blocks too
+						var totalSize int64
+						for _, file := range fullFileArray {
+							totalSize += file.Size
+						}
+						err = orchestrator.UpdateDatasetTotals(ctx, client, APIServer, user, datasetId, int64(len(fullFileArray)), totalSize)
+					}
+					if err != nil {
+						summary.Set(datasetSourceFolder, false, fmt.Sprintf("dataset %s created without all of its files: %v", datasetId, err))
+						rollback(datasetSourceFolder, datasetId)
+						exitIfInterrupted(ctx)
+						log.Fatal("Couldn't ingest dataset:", err)
+					}
+				} else {
+					log.Println("Continuing with the dataset created by the resumed run:", datasetId)
 				}
-				log.Println("Dataset created:", datasetId)
-				// add attachment optionally
-				if addAttachment != "" {
+				if streamFiles {
+					log.Printf("Scanning the files of the dataset %s and posting its origdatablocks...\n", datasetId)
+					var err error
+					fullFileArray, err = orchestrator.StreamDatasetFiles(ctx, client, APIServer, user, metaDataMap, datasetId,
+						datasetSourceFolder, datasetFileListTxt, localSymlinkCallback, filenameFilter.Check, scanOptions, copyFlag,
+						&emptyDatasets, &tooLargeDatasets)
+					// the illegal file names are reported even if the dataset is skipped
+					if finishErr := filenameFilter.Finish(datasetSourceFolder); err == nil {
+						err = finishErr
+					}
+					if err != nil {
+						var emptyDatasetErr *datasetIngestor.EmptyDatasetError
+						var tooManyFilesErr *datasetIngestor.TooManyFilesError
+						skipped := errors.As(err, &emptyDatasetErr) || errors.As(err, &tooManyFilesErr)
+						if skipped {
+							summary.Set(datasetSourceFolder, false, fmt.Sprintf("dataset %s skipped, it's empty or has too many files", datasetId))
+						} else {
+							summary.Set(datasetSourceFolder, false, fmt.Sprintf("dataset %s created without all of its files: %v", datasetId, err))
+						}
+						rollback(datasetSourceFolder, datasetId)
+						if skipped {
+							color.Set(color.FgRed)
+							log.Println(err)
+							color.Unset()
+							continue
+						}
+						exitIfInterrupted(ctx)
+						log.Fatal("Couldn't ingest dataset:", err)
+					}
+				}
+				recordStep(datasetSourceFolder, cliutils.StepOrigDatablocksCreated, datasetId)
+				if copyFlag {
+					summary.Set(datasetSourceFolder, false, "dataset "+datasetId+" created, files not copied yet")
+				} else {
+					summary.Set(datasetSourceFolder, true, "ingested as "+datasetId)
+				}
+				// add attachment optionally, the resumed run added it already
+				if attachment != "" && entry.DatasetId == "" {
 					log.Println("Adding attachment...")
-					err := datasetIngestor.AddAttachment(client, APIServer, datasetId, metaDataMap, user["accessToken"], addAttachment, addCaption)
+					err := datasetIngestor.AddAttachment(ctx, client, APIServer, datasetId, metaDataMap, user.AccessToken, attachment, caption)
 					if err != nil {
+						exitIfInterrupted(ctx)
 						log.Println("Couldn't add attachment:", err)
 					}
-					log.Printf("Attachment file %v added to dataset %v\n", addAttachment, datasetId)
+					log.Printf("Attachment file %v added to dataset %v\n", attachment, datasetId)
 				}
 				// === copying files ===
-				if copyFlag {
+				done := true
+				if copyFlag && entry.Step.Reached(cliutils.StepFilesTransferred) {
+					// the resumed run copied the files, or started an asynchronous transfer that must not be started twice
+					log.Printf("Skipping the copy, the files of the dataset %s were transferred by the resumed run\n", datasetId)
+					archivable = entry.Step.Reached(cliutils.StepMarkedReady)
+					summary.Set(datasetSourceFolder, true, "ingested as "+datasetId+", files transferred by the resumed run")
+				} else if copyFlag {
 					var err error = nil
 					// convert fullFileArray to a list of paths and symlink tests
 					var filePathList []string
@@ -418,11 +701,10 @@ For Windows you need instead to specify -user username:password on the command l
 					}
 					params := cliutils.TransferParams{
 						SshParams: cliutils.SshParams{
-							Client:          client,
-							User:            user,
-							ApiServer:       APIServer,
-							RsyncServer:     RSYNCServer,
-							AbsFilelistPath: absFileListing,
+							Client:      client,
+							User:        user,
+							ApiServer:   APIServer,
+							RsyncServer: RSYNCServer,
 						},
 						GlobusParams: cliutils.GlobusParams{
 							GlobusClient:   globusClient,
@@ -430,24 +712,40 @@ For Windows you need instead to specify -user username:password on the command l
 							SrcPrefixPath:  gConfig.SourcePrefixPath,
 							DestCollection: gConfig.DestinationCollection,
 							DestPrefixPath: gConfig.DestinationPrefixPath,
-							Filelist:       filePathList,
 							IsSymlinkList:  isSymlinkList,
 						},
 						S3Params: cliutils.S3Params{
 							UploadBucket: S3UploadBucket,
 							BrokerServer: S3BrokerServer,
+							Endpoint:     S3Endpoint,
+							Region:       S3Region,
 						},
 						DatasetId:           datasetId,
 						DatasetSourceFolder: datasetSourceFolder,
+						Filelist:            filePathList,
 					}
 
-					archivable, err = transferFiles(params)
-					if err != nil {
+					archivable, err = transferFiles(ctx, params)
+					if err == nil {
+						recordStep(datasetSourceFolder, cliutils.StepFilesTransferred, "")
+						if archivable {
+							recordStep(datasetSourceFolder, cliutils.StepMarkedReady, "")
+						}
+					} else {
+						done = false
+						summary.Set(datasetSourceFolder, false, fmt.Sprintf("dataset %s created, copying the files failed: %v", datasetId, err))
+						rollback(datasetSourceFolder, datasetId)
+						exitIfInterrupted(ctx)
 						color.Set(color.FgRed)
 						log.Printf("The  command to copy files exited with error %v \n", err)
 						log.Printf("The dataset %v is not yet in an archivable state\n", datasetId)
 						color.Unset()
 					}
+					if err == nil && archivable {
+						summary.Set(datasetSourceFolder, true, "ingested as "+datasetId+", files copied")
+					} else if err == nil {
+						summary.Set(datasetSourceFolder, true, "ingested as "+datasetId+", file transfer started")
+					}
 					if err == nil && !archivable {
 						color.Set(color.FgYellow)
 						log.Println("The command finished successfully, however the dataset is not yet archivable.")
@@ -460,6 +758,14 @@ For Windows you need instead to specify -user username:password on the command l
 				if archivable {
 					archivableDatasetList = append(archivableDatasetList, datasetId)
 				}
+				if done {
+					if err := journal.Finish(datasetSourceFolder, archivable); err != nil {
+						log.Fatalln(err)
+					}
+				}
+			}
+			if !ingestFlag {
+				summary.Set(datasetSourceFolder, true, "checked")
 			}
 			// reset dataset metadata for next dataset ingestion
 			datasetIngestor.ResetUpdatedMetaData(originalMap, metaDataMap)
@@ -496,24 +802,36 @@ For Windows you need instead to specify -user username:password on the command l
 		}
 
 		// === create archive jobs ===
-		if autoarchiveFlag && ingestFlag {
+		if jobId := journal.ArchiveJob(); autoarchiveFlag && ingestFlag && jobId != "" {
+			log.Println("The archive job was submitted by the resumed run:", jobId)
+		} else if autoarchiveFlag && ingestFlag {
 			log.Printf("Submitting Archive Job for the ingested datasets.\n")
 			// TODO: change param type from pointer to regular as it is unnecessary
 			//   for it to be passed as pointer
-			jobId, err := datasetUtils.CreateArchivalJob(client, APIServer, user, archivableDatasetListOwnerGroup, archivableDatasetList, datasetUtils.ArchivalJobOptions{
+			jobId, err := datasetUtils.CreateArchivalJob(ctx, client, APIServer, user, archivableDatasetListOwnerGroup, archivableDatasetList, datasetUtils.ArchivalJobOptions{
 				TapeCopies:   &tapecopies,
 				TransferType: &transferType,
 			})
 
 			if err != nil {
+				exitIfInterrupted(ctx)
 				color.Set(color.FgRed)
 				log.Printf("Could not create the archival job for the ingested datasets: %s\n", err.Error())
 				color.Unset()
+			} else if err := journal.RecordArchiveJob(jobId); err != nil {
+				log.Println(err)
 			}
 
 			log.Println("Submitted job:", jobId)
 		}
 
+		// nothing is left to resume once all folders are done and the archive job is recorded
+		if journal.Complete(datasetPaths, autoarchiveFlag && ingestFlag) {
+			if err := journal.Remove(); err != nil {
+				log.Println("Couldn't remove the ingest journal:", err)
+			}
+		}
+
 		// print out results to STDOUT, one line per dataset
 		for i := 0; i < len(archivableDatasetList); i++ {
 			fmt.Println(archivableDatasetList[i])
@@ -542,9 +860,28 @@ func init() {
 	datasetIngestorCmd.Flags().String("addattachment", "", "Filename of image to attach (single dataset case only)")
 	datasetIngestorCmd.Flags().String("addcaption", "", "Optional caption to be stored with attachment (single dataset case only)")
 	datasetIngestorCmd.Flags().String("globus-cfg", "", "Override globus transfer config file location [default: globus.yaml next to executable]")
+	datasetIngestorCmd.Flags().String("checksum", "", fmt.Sprintf("Compute a checksum of every file with this algorithm and store it in the origdatablocks (%s)", strings.Join(datasetIngestor.ChecksumAlgorithms(), ", ")))
+	datasetIngestorCmd.Flags().Int("checksum-workers", datasetIngestor.DefaultChecksumWorkers, "Number of files whose checksums are computed in parallel")
+	datasetIngestorCmd.Flags().Int("scan-workers", datasetIngestor.DefaultScanWorkers, "Number of directories read in parallel when scanning the sourceFolder")
+	datasetIngestorCmd.Flags().String("illegal-filenames", string(datasetIngestor.IllegalFilenamesSkip), "What to do with files whose names are illegal: skip them, fail before any dataset is created, or skip them and write them with the reasons to the --illegal-filenames-report file (skip|fail|report)")
+	datasetIngestorCmd.Flags().String("illegal-filenames-report", "illegalFilenames.txt", "File the illegal file names are written to with --illegal-filenames=report")
+	datasetIngestorCmd.Flags().String("forbidden-chars", "", "Characters not allowed in file names, in addition to \"*\" and \"\\\"")
+	datasetIngestorCmd.Flags().Int("max-path-length", 0, "Maximum length in bytes of a file path relative to the sourceFolder, longer ones are illegal (0: no limit)")
+	datasetIngestorCmd.Flags().Bool("reject-non-utf8", false, "Treat file names which aren't valid UTF-8 as illegal")
+	datasetIngestorCmd.Flags().StringArray("include", nil, "Gitignore-style pattern of the files to ingest, all files if none is given (repeatable)")
+	datasetIngestorCmd.Flags().StringArray("exclude", nil, "Gitignore-style pattern of the files to leave out, in addition to the ones in the sourceFolder's "+datasetIngestor.IgnoreFileName+" (repeatable)")
+	datasetIngestorCmd.Flags().String("manifest", "", "CSV or JSONL file listing the dataset folders to ingest, each with overrides of the metadata file: datasetName, description, keywords, scientificMetadata, attachment and caption")
+	datasetIngestorCmd.Flags().String("journal", "", "File recording the progress of the ingest, so it can be resumed with --resume. It's removed once the ingest is complete")
+	datasetIngestorCmd.Flags().String("resume", "", "Resume the ingest recorded in this journal file, skipping the steps completed already")
+	datasetIngestorCmd.Flags().String("on-failure", string(orchestrator.FailedIngestDelete), "What to do with a dataset whose origdatablocks or files couldn't be added: delete it (it's tagged instead if deleting datasets isn't allowed for the user), tag it with the \"failedIngest\" archive status, or keep it as it is (delete|tag|keep)")
+	datasetIngestorCmd.Flags().Bool("template-metadata", false, "Evaluate the templates in the metadata values per dataset, e.g. {{ .Folder.Base }}, see the command help")
+	datasetIngestorCmd.Flags().Bool("stream-blocks", false, "Post each origdatablock as soon as it's full while the sourceFolder is scanned, instead of holding the whole file list in memory. The dataset is created before the scan, its size, numberOfFiles and the times taken from the files are updated afterwards, and it's rolled back like with --on-failure if it turns out empty or too large. Only the file paths are kept if the files are copied")
 	datasetIngestorCmd.Flags().Bool("remote-files", false, "Defines if files should be accessed remotely instead of locally (i.e. your data is not locally available and therefore needs to be accessed remotely ='remote' case).")
 
 	datasetIngestorCmd.MarkFlagsMutuallyExclusive("testenv", "devenv", "localenv", "tunnelenv")
 	datasetIngestorCmd.MarkFlagsMutuallyExclusive("nocopy", "copy")
 	datasetIngestorCmd.MarkFlagsMutuallyExclusive("remote-files", "copy")
+	datasetIngestorCmd.MarkFlagsMutuallyExclusive("journal", "resume")
+	datasetIngestorCmd.MarkFlagsMutuallyExclusive("stream-blocks", "template-metadata")
+	datasetIngestorCmd.MarkFlagsMutuallyExclusive("stream-blocks", "remote-files")
 }
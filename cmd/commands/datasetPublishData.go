@@ -1,14 +1,9 @@
 package cmd
 
 import (
-	"bytes"
-	"crypto/tls"
-	"encoding/json"
 	"flag"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
 	"net/url"
 	"os"
 	"os/exec"
@@ -41,17 +36,16 @@ on the publication server.
 Usage example:
 ./datasetPublishData --user archiveManager:password --publisheddata 10.16907/05a50450-767f-421d-9832-342b57c201
 
-To update the PublishedData entry with the downloadLink you have to run the script as user archiveManager.`,
+To update the PublishedData entry with the downloadLink you have to run the script as user archiveManager,
+or as a user allowed by the "authorization" section of the config file.`,
 	Args: cobra.NoArgs,
 	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
 
 		// ===== variables =====
-		var APIServer string = cliutils.PROD_API_SERVER
-		var env string = "production"
+		var APIServer string
 
-		var client = &http.Client{
-			Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: false}},
-			Timeout:   10 * time.Second}
+		var client = newHTTPClient(cmd, 10*time.Second)
 
 		type PageData struct {
 			Doi           string
@@ -85,16 +79,17 @@ To update the PublishedData entry with the downloadLink you have to run the scri
 				//log.Printf("Running %v.\n", cmd.Args)
 				log.Printf("\n=== Transfer command: %s.\n", batchCommand)
 
-				err := cmd.Run()
+				err := datasetUtils.RunCommand(ctx, cmd)
 
 				if err != nil {
+					exitIfInterrupted(ctx)
 					log.Fatal(err)
 				}
 			}
 		}
 
 		createWebpage := func(urls []string, title string, doi string, datasetDetails []datasetUtils.Dataset,
-			publishedDataId string, userpass string, token string, oidc bool) {
+			publishedDataId string, user datasetUtils.User) {
 			// A Function that returns the longest common prefix path (runes)
 			// from the array of strings
 			commonPrefix := func(arr []string) string {
@@ -199,18 +194,14 @@ To update the PublishedData entry with the downloadLink you have to run the scri
 			cmd.Stdout = os.Stdout
 			cmd.Stderr = os.Stderr
 			log.Printf("\n=== Transfer download page command: %s .\n", command)
-			err2 := cmd.Run()
+			err2 := datasetUtils.RunCommand(ctx, cmd)
+			exitIfInterrupted(ctx)
 			if err != nil {
 				log.Fatal(err2)
 			}
 
 			// set value in publishedData ==============================
 
-			user, _, err := cliutils.Authenticate(cliutils.RealAuthenticator{}, client, APIServer, userpass, token, oidc)
-			if err != nil {
-				log.Fatal(err)
-			}
-
 			type PublishedDataPart struct {
 				DownloadLink string `json:"downloadLink"`
 			}
@@ -218,29 +209,13 @@ To update the PublishedData entry with the downloadLink you have to run the scri
 				DownloadLink: downloadLink,
 			}
 
-			cmm, _ := json.Marshal(updateData)
-			// metadataString := string(cmm)
-
-			myurl := APIServer + "/PublishedData/" + url.QueryEscape(publishedDataId)
-			req, err := http.NewRequest("PATCH", myurl, bytes.NewBuffer(cmm))
+			api := datasetUtils.NewAPIClient(client, APIServer, user.AccessToken)
+			err = api.Patch(ctx, "/PublishedData/"+url.QueryEscape(publishedDataId), updateData, nil)
 			if err != nil {
-				log.Fatal(err)
-			}
-			req.Header.Set("Authorization", "Bearer "+user["accessToken"])
-			req.Header.Set("Content-Type", "application/json")
-			// fmt.Printf("request to message broker:%v\n", req)
-			resp, err := client.Do(req)
-			if err != nil {
-				log.Fatal(err)
-			}
-
-			defer resp.Body.Close()
-			if resp.StatusCode == 200 {
-				io.ReadAll(resp.Body)
-				log.Printf("Successfully set downloadLink to %v\n", downloadLink)
-			} else {
-				log.Fatalf("Failed to update downloadLink on publishedData %v %v\n", resp.StatusCode, publishedDataId)
+				exitIfInterrupted(ctx)
+				log.Fatalf("Failed to update downloadLink on publishedData %v: %v\n", publishedDataId, err)
 			}
+			log.Printf("Successfully set downloadLink to %v\n", downloadLink)
 		}
 
 		// ===== gather parameters=====
@@ -276,22 +251,15 @@ To update the PublishedData entry with the downloadLink you have to run the scri
 			return
 		}
 
-		if devenvFlag {
-			APIServer = cliutils.DEV_API_SERVER
-			env = "dev"
-		}
-		if testenvFlag {
-			APIServer = cliutils.TEST_API_SERVER
-			env = "test"
-		}
-		if scicatUrl != "" {
-			APIServer = scicatUrl
-			env = "custom"
+		// configure environment
+		config := cliutils.InputEnvironmentConfig{
+			Profile:     cliutils.GetCobraStringFlag(cmd, "profile"),
+			TestenvFlag: testenvFlag,
+			DevenvFlag:  devenvFlag,
+			ScicatUrl:   scicatUrl,
 		}
-
-		color.Set(color.FgGreen)
-		log.Printf("You are about to publish dataset(s) from the === %s === retrieve server...", env)
-		color.Unset()
+		config.ApplyFileConfig(fileConfig)
+		APIServer = config.ResolveAPIServer()
 
 		if !publishFlag {
 			color.Set(color.FgRed)
@@ -314,13 +282,14 @@ To update the PublishedData entry with the downloadLink you have to run the scri
 			return
 		}
 
-		datasetList, title, doi, err := datasetUtils.GetDatasetsOfPublication(client, APIServer, publishedDataId)
+		datasetList, title, doi, err := datasetUtils.GetDatasetsOfPublication(ctx, client, APIServer, publishedDataId)
 		if err != nil {
+			exitIfInterrupted(ctx)
 			log.Fatalf("GetDatasetsOfPublication failed: %s\n", err.Error())
 		}
 
 		// get sourceFolder and other dataset related info for all Datasets
-		datasetDetails, urls := datasetUtils.GetDatasetDetailsPublished(client, APIServer, datasetList)
+		datasetDetails, urls := datasetUtils.GetDatasetDetailsPublished(ctx, client, APIServer, datasetList)
 		if datasetDetails == nil && urls == nil {
 			fmt.Println("No dataset details were retrieved.")
 		}
@@ -336,8 +305,16 @@ To update the PublishedData entry with the downloadLink you have to run the scri
 			log.Printf("%v\n", strings.Join(batchCommands[:], "\n\n"))
 			color.Unset()
 		} else {
+			// check the privileges before anything is copied
+			user, err := cliutils.Authenticate(ctx, cliutils.RealAuthenticator{}, client, APIServer, userpass, token, oidc)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if err := datasetUtils.Authorize(user, fileConfig.AccessRule(cmd.Name()), "publish data"); err != nil {
+				log.Fatal(err)
+			}
 			executeCommands(batchCommands)
-			createWebpage(urls, title, doi, datasetDetails, publishedDataId, userpass, token, oidc)
+			createWebpage(urls, title, doi, datasetDetails, publishedDataId, user)
 		}
 	},
 }
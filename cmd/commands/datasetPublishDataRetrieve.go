@@ -1,11 +1,9 @@
 package cmd
 
 import (
-	"crypto/tls"
 	"flag"
 	"fmt"
 	"log"
-	"net/http"
 	"time"
 
 	"github.com/fatih/color"
@@ -20,9 +18,9 @@ var datasetPublishDataRetrieveCmd = &cobra.Command{
 	Long:  `Create a job to retrieve all datasets of a given PublishedData item.`,
 	Args:  cobra.NoArgs,
 	Run: func(cmd *cobra.Command, args []string) {
-		var client = &http.Client{
-			Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: false}},
-			Timeout:   10 * time.Second}
+		ctx := cmd.Context()
+
+		var client = newHTTPClient(cmd, 10*time.Second)
 
 		// retrieve params
 		retrieveFlag, _ := cmd.Flags().GetBool("retrieve")
@@ -57,10 +55,12 @@ var datasetPublishDataRetrieveCmd = &cobra.Command{
 
 		// configure environment
 		config := cliutils.InputEnvironmentConfig{
+			Profile:     cliutils.GetCobraStringFlag(cmd, "profile"),
 			TestenvFlag: testenvFlag,
 			DevenvFlag:  devenvFlag,
 			ScicatUrl:   scicatUrl,
 		}
+		config.ApplyFileConfig(fileConfig)
 		APIServer := config.ResolveAPIServer()
 
 		if !retrieveFlag {
@@ -82,18 +82,19 @@ var datasetPublishDataRetrieveCmd = &cobra.Command{
 			return
 		}
 
-		user, _, err := cliutils.Authenticate(cliutils.RealAuthenticator{}, client, APIServer, userpass, token, oidc)
+		user, err := cliutils.Authenticate(ctx, cliutils.RealAuthenticator{}, client, APIServer, userpass, token, oidc)
 		if err != nil {
 			log.Fatal(err)
 		}
 
-		datasetList, _, _, err := datasetUtils.GetDatasetsOfPublication(client, APIServer, publishedDataId)
+		datasetList, _, _, err := datasetUtils.GetDatasetsOfPublication(ctx, client, APIServer, publishedDataId)
 		if err != nil {
+			exitIfInterrupted(ctx)
 			log.Fatalf("GetDatasetsOfPublication failed: %s\n", err.Error())
 		}
 
 		// get sourceFolder and other dataset related info for all Datasets and print them
-		datasetDetails, urls := datasetUtils.GetDatasetDetailsPublished(client, APIServer, datasetList)
+		datasetDetails, urls := datasetUtils.GetDatasetDetailsPublished(ctx, client, APIServer, datasetList)
 		if datasetDetails == nil && urls == nil {
 			fmt.Println("No dataset details were retrieved.")
 		}
@@ -105,8 +106,9 @@ var datasetPublishDataRetrieveCmd = &cobra.Command{
 			color.Unset()
 		} else {
 			// create retrieve Job
-			jobId, err := datasetUtils.CreateRetrieveJob(client, APIServer, user, datasetList)
+			jobId, err := datasetUtils.CreateRetrieveJob(ctx, client, APIServer, user, datasetList)
 			if err != nil {
+				exitIfInterrupted(ctx)
 				log.Fatal(err)
 			}
 			fmt.Println(jobId)
@@ -1,10 +1,8 @@
 package cmd
 
 import (
-	"crypto/tls"
 	"fmt"
 	"log"
-	"net/http"
 	"os"
 	"os/exec"
 	"strings"
@@ -34,17 +32,14 @@ Use option --dataset or --ownerGroup to restrict the datasets which should be fe
 For further help see "` + cliutils.MANUAL + `"`,
 	Args: exactArgsWithVersionException(1),
 	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
 
 		// TODO Windows
 		const APP = "datasetRetriever"
 
-		var APIServer string = cliutils.PROD_API_SERVER
-		var RSYNCServer string = cliutils.PROD_RSYNC_RETRIEVE_SERVER
-		var env string = "production"
+		var RSYNCServer string
 
-		var client = &http.Client{
-			Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: false}},
-			Timeout:   10 * time.Second}
+		var client = newHTTPClient(cmd, 10*time.Second)
 
 		// internal functions
 		assembleRsyncCommands := func(username string, datasetDetails []datasetUtils.Dataset, destinationPath string) ([]string, []string) {
@@ -60,20 +55,26 @@ For further help see "` + cliutils.MANUAL + `"`,
 			return batchCommands, destinationFolders
 		}
 
-		executeCommands := func(batchCommands []string) {
+		executeCommands := func(batchCommands []string, destinationFolders []string) {
 			log.Printf("\n\n\n====== Starting transfer of dataset files: \n\n")
-			for _, batchCommand := range batchCommands {
+			for _, destination := range destinationFolders {
+				summary.Set(destination, false, "not retrieved")
+			}
+			for i, batchCommand := range batchCommands {
 				cmd := exec.Command("/bin/sh", "-c", batchCommand)
 				cmd.Stdout = os.Stdout
 				cmd.Stderr = os.Stderr
 				//log.Printf("Running %v.\n", cmd.Args)
 				log.Printf("\n=== Transfer command: %s.\n", batchCommand)
 
-				err := cmd.Run()
+				err := datasetUtils.RunCommand(ctx, cmd)
 
 				if err != nil {
+					summary.Set(destinationFolders[i], false, "retrieval interrupted, the files may be incomplete")
+					exitIfInterrupted(ctx)
 					log.Fatal(err)
 				}
+				summary.Set(destinationFolders[i], true, "retrieved")
 			}
 		}
 
@@ -87,9 +88,10 @@ For further help see "` + cliutils.MANUAL + `"`,
 				cmd.Stderr = os.Stderr
 				// log.Printf("Running %v.\n", cmd.Args)
 				log.Printf("\n=== Checking files within %s.\n", destination)
-				err := cmd.Run()
+				err := datasetUtils.RunCommand(ctx, cmd)
 
 				if err != nil {
+					exitIfInterrupted(ctx)
 					log.Fatal(err)
 				}
 			}
@@ -134,27 +136,20 @@ For further help see "` + cliutils.MANUAL + `"`,
 
 		datasetUtils.CheckForNewVersion(client, APP, VERSION)
 
-		if localenvFlag {
-			APIServer = cliutils.LOCAL_API_SERVER
-			RSYNCServer = cliutils.LOCAL_RSYNC_RETRIEVE_SERVER
-		}
-		if devenvFlag {
-			APIServer = cliutils.DEV_API_SERVER
-			RSYNCServer = cliutils.DEV_RSYNC_RETRIEVE_SERVER
-			env = "dev"
-		}
-		if testenvFlag {
-			APIServer = cliutils.TEST_API_SERVER
-			RSYNCServer = cliutils.TEST_RSYNC_RETRIEVE_SERVER
-			env = "test"
-		}
-		if scicatUrl != "" {
-			APIServer = scicatUrl
-			env = "custom"
+		// configure environment
+		config := cliutils.InputEnvironmentConfig{
+			Profile:      cliutils.GetCobraStringFlag(cmd, "profile"),
+			TestenvFlag:  testenvFlag,
+			DevenvFlag:   devenvFlag,
+			LocalenvFlag: localenvFlag,
+			ScicatUrl:    scicatUrl,
 		}
+		config.ApplyFileConfig(fileConfig)
+		APIServer := config.ResolveAPIServer()
+		RSYNCServer = config.ResolveRSYNCRetrieveServer()
 
 		color.Set(color.FgGreen)
-		log.Printf("You are about to retrieve dataset(s) from the === %s === retrieve server...", env)
+		log.Printf("You are about to retrieve dataset(s) from the === %s === retrieve server...", RSYNCServer)
 		color.Unset()
 
 		if !retrieveFlag {
@@ -171,13 +166,14 @@ For further help see "` + cliutils.MANUAL + `"`,
 		}
 		destinationPath = args[0]
 
-		user, _, err := cliutils.Authenticate(cliutils.RealAuthenticator{}, client, APIServer, userpass, token, oidc)
+		user, err := cliutils.Authenticate(ctx, cliutils.RealAuthenticator{}, client, APIServer, userpass, token, oidc)
 		if err != nil {
 			log.Fatal(err)
 		}
 
-		datasetList, err := datasetUtils.GetAvailableDatasets(user["username"], RSYNCServer, datasetId)
+		datasetList, err := datasetUtils.GetAvailableDatasets(ctx, user.Username, RSYNCServer, datasetId)
 		if err != nil {
+			exitIfInterrupted(ctx)
 			log.Fatal(err)
 		}
 
@@ -187,8 +183,9 @@ For further help see "` + cliutils.MANUAL + `"`,
 		}
 
 		// get sourceFolder and other dataset related info for all Datasets
-		datasetDetails, missingDatasetIds, err := datasetUtils.GetDatasetDetails(client, APIServer, user["accessToken"], datasetList, ownerGroup)
+		datasetDetails, missingDatasetIds, err := datasetUtils.GetDatasetDetails(ctx, client, APIServer, user.AccessToken, datasetList, ownerGroup)
 		if err != nil {
+			exitIfInterrupted(ctx)
 			log.Fatal(err)
 		}
 		fmt.Printf("\nFound datasets:\n")
@@ -205,7 +202,7 @@ For further help see "` + cliutils.MANUAL + `"`,
 		}
 
 		// assemble rsync commands to be submitted
-		batchCommands, destinationFolders := assembleRsyncCommands(user["username"], datasetDetails, destinationPath)
+		batchCommands, destinationFolders := assembleRsyncCommands(user.Username, datasetDetails, destinationPath)
 		// log.Printf("%v\n", batchCommands)
 
 		if !retrieveFlag {
@@ -214,7 +211,7 @@ For further help see "` + cliutils.MANUAL + `"`,
 			log.Printf("Use the --retrieve flag to actually retrieve datasets.")
 			color.Unset()
 		} else {
-			executeCommands(batchCommands)
+			executeCommands(batchCommands, destinationFolders)
 			if !nochksumFlag {
 				checkSumVerification(destinationFolders)
 			}
@@ -96,6 +96,46 @@ func TestMainFlags(t *testing.T) {
 				"argument placeholder",
 			},
 		},
+		// login
+		{
+			name: "login test with all flags set",
+			flags: map[string]interface{}{
+				"testenv":    false,
+				"devenv":     false,
+				"localenv":   false,
+				"tunnelenv":  true,
+				"scicat-url": "",
+				"version":    false,
+				"user":       "usertest:passtest",
+				"token":      "",
+			},
+			args: []string{
+				"login",
+				"--tunnelenv",
+				"--user",
+				"usertest:passtest",
+			},
+		},
+		// whoami
+		{
+			name: "whoami test without flags",
+			flags: map[string]interface{}{
+				"testenv": false,
+				"devenv":  false,
+				"version": false,
+			},
+			args: []string{"whoami"},
+		},
+		// logout
+		{
+			name: "logout test with all flags set",
+			flags: map[string]interface{}{
+				"testenv": false,
+				"devenv":  true,
+				"version": true,
+			},
+			args: []string{"logout", "--devenv", "--version"},
+		},
 		// datasetGetProposal
 		{
 			name: "datasetGetProposal test without flags",
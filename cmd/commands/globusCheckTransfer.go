@@ -1,12 +1,10 @@
 package cmd
 
 import (
-	"crypto/tls"
+	"context"
 	"fmt"
 	"log"
 	"net/http"
-	"os"
-	"path/filepath"
 	"strings"
 	"time"
 
@@ -29,10 +27,10 @@ you can save
 For further help see "` + cliutils.MANUAL + `"`,
 	Args: minArgsWithVersionException(1),
 	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+
 		// consts & vars
-		var client = &http.Client{
-			Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: false}},
-			Timeout:   10 * time.Second}
+		var client = newHTTPClient(cmd, 10*time.Second)
 
 		var APIServer string = cliutils.PROD_API_SERVER
 
@@ -91,28 +89,25 @@ For further help see "` + cliutils.MANUAL + `"`,
 			log.Fatalln("Can't use \"tapecopies\" if \"autoarchive\" is not set.")
 		}
 
-		// find globus config
-		var globusConfigPath string
-		if cmd.Flags().Lookup("globus-cfg").Changed {
-			globusConfigPath = globusCfgFlag
-		} else {
-			execPath, err := os.Executable()
-			if err != nil {
-				log.Fatalln("can't find executable path:", err)
-			}
-			globusConfigPath = filepath.Join(filepath.Dir(execPath), "globus.yaml")
-		}
-
 		// configure environment
 		config := cliutils.InputEnvironmentConfig{
+			Profile:       cliutils.GetCobraStringFlag(cmd, "profile"),
 			TestenvFlag:   testenvFlag,
 			DevenvFlag:    devenvFlag,
 			TunnelenvFlag: tunnelenvFlag,
 			LocalenvFlag:  localenvFlag,
 			ScicatUrl:     scicatUrl,
+			GlobusCfg:     globusCfgFlag,
 		}
+		config.ApplyFileConfig(fileConfig)
 		APIServer = config.ResolveAPIServer()
 
+		// find globus config
+		globusConfigPath, err := config.ResolveGlobusConfigPath()
+		if err != nil {
+			log.Fatalln(err)
+		}
+
 		// start message
 		startMessage := "Checking transfer complpetion"
 		if markArchivable {
@@ -131,10 +126,10 @@ For further help see "` + cliutils.MANUAL + `"`,
 		color.Unset()
 
 		// logging into scicat and globus...
-		var user map[string]string
+		var user datasetUtils.User
 		if markArchivable {
 			var err error
-			user, _, err = cliutils.Authenticate(cliutils.RealAuthenticator{}, client, APIServer, userpass, token, oidc)
+			user, err = cliutils.Authenticate(ctx, cliutils.RealAuthenticator{}, client, APIServer, userpass, token, oidc)
 			if err != nil {
 				log.Fatal(err)
 			}
@@ -148,7 +143,12 @@ For further help see "` + cliutils.MANUAL + `"`,
 		// go through each transfer task, and execute the requested operations
 		archivableDatasetMap := make(map[string][]string)
 		for _, taskId := range args {
-			groupedDatasets := globusCheckTransferHandleTransferTask(globusClient, taskId, markArchivable, gConfig, skipDestPathCheck, dryRun, client, APIServer, user)
+			summary.Set("task "+taskId, false, "not checked")
+		}
+		for _, taskId := range args {
+			exitIfInterrupted(ctx)
+			groupedDatasets := globusCheckTransferHandleTransferTask(ctx, globusClient, taskId, markArchivable, gConfig, skipDestPathCheck, dryRun, client, APIServer, user)
+			summary.Set("task "+taskId, true, "checked")
 			for group := range groupedDatasets {
 				if _, ok := archivableDatasetMap[group]; ok {
 					archivableDatasetMap[group] = append(archivableDatasetMap[group], groupedDatasets[group]...)
@@ -160,7 +160,7 @@ For further help see "` + cliutils.MANUAL + `"`,
 
 		// === create archive job ===
 		if autoarchiveFlag {
-			globusCheckTransferCreateArchiveJobs(client, APIServer, user, archivableDatasetMap, tapecopies)
+			globusCheckTransferCreateArchiveJobs(ctx, client, APIServer, user, archivableDatasetMap, tapecopies)
 		}
 	},
 }
@@ -184,15 +184,16 @@ func init() {
 	globusCheckTransfer.MarkFlagsMutuallyExclusive("dry-run", "tapecopies")
 }
 
-func globusCheckTransferCreateArchiveJobs(client *http.Client, APIServer string, user map[string]string, archivableDatasetMap map[string][]string, tapecopies int) {
+func globusCheckTransferCreateArchiveJobs(ctx context.Context, client *http.Client, APIServer string, user datasetUtils.User, archivableDatasetMap map[string][]string, tapecopies int) {
 	log.Printf("Submitting Archive Job for archivable datasets.\n")
 	// TODO: change param type from pointer to regular as it is unnecessary
 	//   for it to be passed as pointer
-	jobIds, errs := datasetUtils.CreateArchivalJobs(client, APIServer, user, archivableDatasetMap, datasetUtils.ArchivalJobOptions{TapeCopies: &tapecopies})
+	jobIds, errs := datasetUtils.CreateArchivalJobs(ctx, client, APIServer, user, archivableDatasetMap, datasetUtils.ArchivalJobOptions{TapeCopies: &tapecopies})
 
 	color.Set(color.FgRed)
 	for _, err := range errs {
 		if err != nil {
+			exitIfInterrupted(ctx)
 			// normally this only happens if either not the same user checks the completion, the tool somehow adds others' datasets into the list or
 			// the user lost access groups in the mean time
 			log.Printf("Could not create the archival job for a set of the ingested datasets: %s\n", err.Error())
@@ -203,7 +204,7 @@ func globusCheckTransferCreateArchiveJobs(client *http.Client, APIServer string,
 	log.Println("Submitted jobs:", jobIds)
 }
 
-func globusCheckTransferHandleTransferTask(
+func globusCheckTransferHandleTransferTask(ctx context.Context,
 	globusClient globus.GlobusClient,
 	taskId string, markArchivable bool,
 	gConfig cliutils.GlobusConfig,
@@ -211,7 +212,7 @@ func globusCheckTransferHandleTransferTask(
 	dryRun bool,
 	client *http.Client,
 	APIServer string,
-	user map[string]string,
+	user datasetUtils.User,
 ) (archivableDatasetMap map[string][]string) {
 	archivableDatasetMap = make(map[string][]string)
 
@@ -242,10 +243,11 @@ func globusCheckTransferHandleTransferTask(
 			destFolder = *task.DestinationBasePath
 		}
 
-		list, err := datasetIngestor.TestForExistingSourceFolder([]string{sourceFolder}, client, APIServer, user["accessToken"])
+		list, err := datasetIngestor.TestForExistingSourceFolder(ctx, []string{sourceFolder}, client, APIServer, user.AccessToken)
 
 		// error handling and exceptions
 		if err != nil {
+			exitIfInterrupted(ctx)
 			log.Printf("WARNING - an error has occurred when querying the sourcefolder \"%s\" of task id \"%s\": %v\n", sourceFolder, taskId, err)
 			log.Printf("Can't set %s task's dataset to archivable.\n", taskId)
 			return nil
@@ -278,13 +280,15 @@ func globusCheckTransferHandleTransferTask(
 				}
 			}
 			log.Printf("%s dataset is being marked as archivable...\n", result.Pid)
-			err := datasetIngestor.MarkFilesReady(client, APIServer, result.Pid, user)
+			err := datasetIngestor.MarkFilesReady(ctx, client, APIServer, result.Pid, user)
 			if err != nil {
+				exitIfInterrupted(ctx)
 				log.Printf("WARNING - error occurred while trying to mark files ready for dataset with PID \"%s\": %v\n", result.Pid, err)
 				log.Printf("%s dataset was (likely) not marked archivable.\n", result.Pid)
 				continue
 			}
 			log.Printf("%s dataset was successfully marked as archivable.\n", result.Pid)
+			summary.Set(result.Pid, true, "marked as archivable")
 			if _, ok := archivableDatasetMap[result.OwnerGroup]; ok {
 				archivableDatasetMap[result.OwnerGroup] = append(archivableDatasetMap[result.OwnerGroup], result.Pid)
 			} else {
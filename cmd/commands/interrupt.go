@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/paulscherrerinstitute/scicat-cli/v3/cmd/cliutils"
+)
+
+// summary records what the running command has and hasn't done yet, it's printed when the
+// command is interrupted.
+var summary = &cliutils.RunSummary{}
+
+// interruptContext returns a context that is cancelled by the first SIGINT or SIGTERM. Further
+// signals terminate the process right away, as if no handler was installed.
+func interruptContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		signal.Stop(signals)
+		log.Println("Interrupted, cancelling... (press Ctrl-C again to exit immediately)")
+		cancel()
+	}()
+	return ctx
+}
+
+// exitIfInterrupted prints the summary and exits with status 130 (as shells do after Ctrl-C) if
+// ctx was cancelled. Commands call it before treating an error as fatal, so that an interrupted
+// run reports what was completed rather than the error of the aborted operation.
+func exitIfInterrupted(ctx context.Context) {
+	if ctx.Err() == nil {
+		return
+	}
+	log.Println("The command was interrupted.")
+	summary.Print(os.Stderr)
+	os.Exit(130)
+}
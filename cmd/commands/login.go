@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/paulscherrerinstitute/scicat-cli/v3/cmd/cliutils"
+	"github.com/paulscherrerinstitute/scicat-cli/v3/datasetUtils"
+	"github.com/spf13/cobra"
+)
+
+var loginCmd = &cobra.Command{
+	Use:   "login [options]",
+	Short: "Logs in to SciCat and stores the session for later commands",
+	Long: `Tool to log in to SciCat once and reuse the session.
+
+The access token is stored in a per-user credentials file, readable only by
+the current user. Later commands use it instead of asking for credentials,
+until it expires or "logout" is called. Credentials can be passed with
+--user, --token or --oidc, otherwise they are asked for interactively.
+
+For further help see "` + cliutils.MANUAL + `"`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		// vars and constants
+		var client = newHTTPClient(cmd, 10*time.Second)
+
+		// pass parameters
+		userpass, _ := cmd.Flags().GetString("user")
+		token, _ := cmd.Flags().GetString("token")
+		oidc, _ := cmd.Flags().GetBool("oidc")
+		testenvFlag, _ := cmd.Flags().GetBool("testenv")
+		devenvFlag, _ := cmd.Flags().GetBool("devenv")
+		localenvFlag, _ := cmd.Flags().GetBool("localenv")
+		tunnelenvFlag, _ := cmd.Flags().GetBool("tunnelenv")
+		scicatUrl, _ := cmd.Flags().GetString("scicat-url")
+		showVersion, _ := cmd.Flags().GetBool("version")
+
+		if datasetUtils.TestFlags != nil {
+			datasetUtils.TestFlags(map[string]interface{}{
+				"user":       userpass,
+				"token":      token,
+				"testenv":    testenvFlag,
+				"devenv":     devenvFlag,
+				"localenv":   localenvFlag,
+				"tunnelenv":  tunnelenvFlag,
+				"scicat-url": scicatUrl,
+				"version":    showVersion,
+			})
+			return
+		}
+
+		// execute command
+		if showVersion {
+			fmt.Printf("%s\n", VERSION)
+			return
+		}
+
+		// configure environment
+		config := cliutils.InputEnvironmentConfig{
+			Profile:       cliutils.GetCobraStringFlag(cmd, "profile"),
+			TestenvFlag:   testenvFlag,
+			DevenvFlag:    devenvFlag,
+			LocalenvFlag:  localenvFlag,
+			TunnelenvFlag: tunnelenvFlag,
+			ScicatUrl:     scicatUrl,
+		}
+		config.ApplyFileConfig(fileConfig)
+		APIServer := config.ResolveAPIServer()
+
+		creds, err := cliutils.Login(cmd.Context(), cliutils.RealAuthenticator{}, client, APIServer, userpass, token, oidc)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Logged in to %s as %s\n", APIServer, creds.Username)
+		if !creds.Expires.IsZero() {
+			fmt.Printf("The session expires at %s\n", creds.Expires.Local().Format(time.RFC1123))
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(loginCmd)
+
+	loginCmd.Flags().Bool("testenv", false, "Use test environment (qa) instead or production")
+	loginCmd.Flags().Bool("devenv", false, "Use development environment instead or production")
+	loginCmd.Flags().Bool("localenv", false, "Use local environment instead of production environment (developers only)")
+	loginCmd.Flags().Bool("tunnelenv", false, "Use tunneled API server at port 5443 to access development instance (developers only)")
+
+	loginCmd.MarkFlagsMutuallyExclusive("testenv", "devenv", "localenv", "tunnelenv")
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/paulscherrerinstitute/scicat-cli/v3/cmd/cliutils"
+	"github.com/paulscherrerinstitute/scicat-cli/v3/datasetUtils"
+	"github.com/spf13/cobra"
+)
+
+var logoutCmd = &cobra.Command{
+	Use:   "logout [options]",
+	Short: "Revokes and deletes the stored login session",
+	Long: `Tool to end the session stored by "login". The access token is revoked on
+the SciCat server and removed from the credentials file.
+
+For further help see "` + cliutils.MANUAL + `"`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+
+		// vars and constants
+		var client = newHTTPClient(cmd, 10*time.Second)
+
+		// pass parameters
+		testenvFlag, _ := cmd.Flags().GetBool("testenv")
+		devenvFlag, _ := cmd.Flags().GetBool("devenv")
+		localenvFlag, _ := cmd.Flags().GetBool("localenv")
+		tunnelenvFlag, _ := cmd.Flags().GetBool("tunnelenv")
+		scicatUrl, _ := cmd.Flags().GetString("scicat-url")
+		showVersion, _ := cmd.Flags().GetBool("version")
+
+		if datasetUtils.TestFlags != nil {
+			datasetUtils.TestFlags(map[string]interface{}{
+				"testenv":    testenvFlag,
+				"devenv":     devenvFlag,
+				"localenv":   localenvFlag,
+				"tunnelenv":  tunnelenvFlag,
+				"scicat-url": scicatUrl,
+				"version":    showVersion,
+			})
+			return
+		}
+
+		// execute command
+		if showVersion {
+			fmt.Printf("%s\n", VERSION)
+			return
+		}
+
+		// configure environment
+		config := cliutils.InputEnvironmentConfig{
+			Profile:       cliutils.GetCobraStringFlag(cmd, "profile"),
+			TestenvFlag:   testenvFlag,
+			DevenvFlag:    devenvFlag,
+			LocalenvFlag:  localenvFlag,
+			TunnelenvFlag: tunnelenvFlag,
+			ScicatUrl:     scicatUrl,
+		}
+		config.ApplyFileConfig(fileConfig)
+		APIServer := config.ResolveAPIServer()
+
+		creds, ok, err := cliutils.LoadCredentials(APIServer)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if !ok {
+			fmt.Printf("Not logged in to %s\n", APIServer)
+			return
+		}
+
+		// an expired token can't be revoked anymore, but it's removed from the file regardless
+		if !creds.Expired(time.Now()) {
+			if err := datasetUtils.LogoutUser(ctx, client, APIServer, creds.AccessToken); err != nil {
+				log.Printf("Warning: could not revoke the access token: %v\n", err)
+			}
+		}
+		if err := cliutils.RemoveCredentials(APIServer); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Logged out from %s\n", APIServer)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(logoutCmd)
+
+	logoutCmd.Flags().Bool("testenv", false, "Use test environment (qa) instead or production")
+	logoutCmd.Flags().Bool("devenv", false, "Use development environment instead or production")
+	logoutCmd.Flags().Bool("localenv", false, "Use local environment instead of production environment (developers only)")
+	logoutCmd.Flags().Bool("tunnelenv", false, "Use tunneled API server at port 5443 to access development instance (developers only)")
+
+	logoutCmd.MarkFlagsMutuallyExclusive("testenv", "devenv", "localenv", "tunnelenv")
+}
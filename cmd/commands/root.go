@@ -2,11 +2,26 @@ package cmd
 
 import (
 	"fmt"
+	"io"
+	"log"
+	"net/http"
 	"os"
+	"time"
 
+	"github.com/paulscherrerinstitute/scicat-cli/v3/cmd/cliutils"
 	"github.com/spf13/cobra"
 )
 
+// fileConfig holds the content of the file given via --config, it's empty if none was given
+var fileConfig cliutils.FileConfig
+
+// httpTransport carries the TLS and proxy settings of the config file and flags, it's shared by
+// all HTTP clients of a command
+var httpTransport http.RoundTripper
+
+// httpTraceFile is the file given via --debug-http-file, it's closed once the command is done
+var httpTraceFile *os.File
+
 var rootCmd = &cobra.Command{
 	Use:   "cmd",
 	Short: "CLI app for interacting with a SciCat instance",
@@ -22,10 +37,143 @@ to it.`,
 		fmt.Print("No action was specified.\n\n")
 		cmd.Help()
 	},
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := loadFileConfig(cmd); err != nil {
+			return err
+		}
+		if err := configureHTTP(cmd); err != nil {
+			return err
+		}
+		return configureOIDC(cmd)
+	},
+	PersistentPostRunE: func(cmd *cobra.Command, args []string) error {
+		return closeHTTPTrace()
+	},
+}
+
+// closeHTTPTrace closes the file given via --debug-http-file, if any.
+func closeHTTPTrace() error {
+	if httpTraceFile == nil {
+		return nil
+	}
+	err := httpTraceFile.Close()
+	httpTraceFile = nil
+	if err != nil {
+		return fmt.Errorf("can't close the HTTP trace file: %v", err)
+	}
+	return nil
+}
+
+// configureOIDC sets up the token provider used by --oidc logins. --oidc-device implies --oidc.
+func configureOIDC(cmd *cobra.Command) error {
+	deviceFlow := cliutils.GetCobraBoolFlag(cmd, "oidc-device")
+	if deviceFlow {
+		if err := cmd.Flags().Set("oidc", "true"); err != nil {
+			return err
+		}
+	}
+	oidcConfig := fileConfig.OIDC
+	if cmd.Flags().Changed("oidc-port") {
+		oidcConfig.CallbackPort = cliutils.GetCobraIntFlag(cmd, "oidc-port")
+	}
+	client := newHTTPClient(cmd, 30*time.Second)
+	provider := cliutils.NewOIDCProvider(oidcConfig, deviceFlow, client)
+	cliutils.SetOIDCTokenProvider(provider.Token)
+	cliutils.SetOIDCTokenRefresher(provider.Refresh)
+	return nil
+}
+
+// configureHTTP creates the transport of the HTTP clients from the http section of the config
+// file, overridden by the --ca-cert, --client-cert, --client-key, --proxy and --no-proxy flags.
+// With --debug-http or --debug-http-file the requests are traced.
+func configureHTTP(cmd *cobra.Command) error {
+	httpConfig := fileConfig.HTTP
+	for flag, field := range map[string]*string{
+		"ca-cert":     &httpConfig.CACert,
+		"client-cert": &httpConfig.ClientCert,
+		"client-key":  &httpConfig.ClientKey,
+		"proxy":       &httpConfig.Proxy,
+		"no-proxy":    &httpConfig.NoProxy,
+	} {
+		if cmd.Flags().Changed(flag) {
+			*field = cliutils.GetCobraStringFlag(cmd, flag)
+		}
+	}
+	transport, err := cliutils.NewHTTPTransport(httpConfig)
+	if err != nil {
+		return err
+	}
+	httpTransport = transport
+
+	var logger *log.Logger
+	if cliutils.GetCobraBoolFlag(cmd, "debug-http") {
+		logger = log.Default()
+	}
+	var trace io.Writer
+	if err := closeHTTPTrace(); err != nil {
+		return err
+	}
+	if tracePath := cliutils.GetCobraStringFlag(cmd, "debug-http-file"); tracePath != "" {
+		// entries are appended and synced right away, so the trace is complete even if the
+		// command exits with log.Fatal, which skips PersistentPostRunE
+		f, err := os.OpenFile(tracePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return fmt.Errorf("can't open the HTTP trace file: %v", err)
+		}
+		httpTraceFile = f
+		trace = f
+	}
+	if logger != nil || trace != nil {
+		httpTransport = cliutils.NewDebugTransport(transport, logger, trace)
+	}
+	return nil
+}
+
+// newHTTPClient returns a client using the shared transport. Requests time out after
+// defaultTimeout, unless another timeout is given with --timeout (directly or via the defaults
+// and commands sections of the config file).
+func newHTTPClient(cmd *cobra.Command, defaultTimeout time.Duration) *http.Client {
+	timeout := defaultTimeout
+	if t := cliutils.GetCobraDurationFlag(cmd, "timeout"); t > 0 {
+		timeout = t
+	}
+	return &http.Client{Transport: httpTransport, Timeout: timeout}
+}
+
+// loadFileConfig reads the file given via --config, applies its flag defaults to cmd and checks
+// that the selected profile exists.
+func loadFileConfig(cmd *cobra.Command) error {
+	fileConfig = cliutils.FileConfig{}
+	configPath := cliutils.GetCobraStringFlag(cmd, "config")
+	if configPath != "" {
+		cfg, err := cliutils.ReadConfigFile(configPath)
+		if err != nil {
+			return err
+		}
+		if err := cliutils.ApplyFlagDefaults(cmd, cfg); err != nil {
+			return err
+		}
+		fileConfig = cfg
+	}
+
+	profiles := cliutils.MergeProfiles(fileConfig.Profiles)
+	if err := cliutils.CheckProfile(fileConfig.Profile, profiles); err != nil {
+		return fmt.Errorf("invalid default profile in config file: %w", err)
+	}
+	profile := cliutils.GetCobraStringFlag(cmd, "profile")
+	if profile == "" {
+		return nil
+	}
+	for _, envFlag := range []string{"testenv", "devenv", "localenv", "tunnelenv"} {
+		if cliutils.GetCobraBoolFlag(cmd, envFlag) {
+			return fmt.Errorf("--profile can't be combined with --%s", envFlag)
+		}
+	}
+	return cliutils.CheckProfile(profile, profiles)
 }
 
 func Execute() {
-	err := rootCmd.Execute()
+	err := rootCmd.ExecuteContext(interruptContext())
 	if err != nil {
 		os.Exit(1)
 	}
@@ -38,8 +186,20 @@ func init() {
 	rootCmd.PersistentFlags().String("token", "", "Defines optional API token instead of username:password")
 	rootCmd.PersistentFlags().StringP("config", "c", "", "A path to a config file for connecting to SciCat and transfer services")
 	rootCmd.PersistentFlags().StringP("scicat-url", "s", "", "The scicat url to use. Note: it'll overwrite any built-in environments.")
-	rootCmd.PersistentFlags().Bool("oidc", false, "Use OIDC for login instead of internal user")
+	rootCmd.PersistentFlags().String("profile", "", "Name of the environment profile to use, either a built-in one (production, test, dev, local, tunnel) or one defined in the config file")
+	rootCmd.PersistentFlags().Bool("oidc", false, "Use OIDC for login instead of internal user, through the SciCat backend or, if the config file has an oidc issuer, at that provider")
+	rootCmd.PersistentFlags().Bool("oidc-device", false, "Log in with OIDC using a browser on any device, for machines without a browser: the device code flow of the config file's oidc issuer, or the login URL of the SciCat backend whose final URL is pasted back (implies --oidc)")
+	rootCmd.PersistentFlags().Int("oidc-port", 0, "Localhost port for the OIDC login callback, overrides callback-port from the config file (0: any free port)")
+	rootCmd.PersistentFlags().String("ca-cert", "", "PEM file with CA certificates to trust in addition to the system ones")
+	rootCmd.PersistentFlags().String("client-cert", "", "PEM file with the client certificate for servers requiring mutual TLS")
+	rootCmd.PersistentFlags().String("client-key", "", "PEM file with the key of the client certificate (default: read from --client-cert)")
+	rootCmd.PersistentFlags().String("proxy", "", "URL of the proxy for all requests (default: from the HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment variables)")
+	rootCmd.PersistentFlags().String("no-proxy", "", "Comma separated hosts to contact without the proxy given by --proxy")
+	rootCmd.PersistentFlags().Bool("debug-http", false, "Log all HTTP requests and responses, with tokens and passwords redacted")
+	rootCmd.PersistentFlags().String("debug-http-file", "", "Append all HTTP requests and responses as HAR entries (one JSON object per line) to this file, with tokens and passwords redacted")
+	rootCmd.PersistentFlags().Duration("timeout", 0, "Timeout of HTTP requests, e.g. 5m (0: the default of the command)")
 	rootCmd.PersistentFlags().BoolP("version", "v", false, "Show version")
 
 	rootCmd.MarkFlagsMutuallyExclusive("token", "oidc")
+	rootCmd.MarkFlagsMutuallyExclusive("token", "oidc-device")
 }
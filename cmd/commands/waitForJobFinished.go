@@ -1,13 +1,11 @@
 package cmd
 
 import (
-	"crypto/tls"
-	"encoding/json"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
 	"net/url"
 	"time"
 
@@ -23,9 +21,7 @@ var waitForJobFinishedCmd = &cobra.Command{
 	Args:  cobra.NoArgs,
 	Run: func(cmd *cobra.Command, args []string) {
 		// consts & vars
-		var client = &http.Client{
-			Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: false}},
-			Timeout:   10 * time.Second}
+		var client = newHTTPClient(cmd, 10*time.Second)
 
 		// structs
 		type Job struct {
@@ -34,27 +30,15 @@ var waitForJobFinishedCmd = &cobra.Command{
 		}
 
 		// funcs
-		handlePollResponse := func(resp *http.Response) (stopPolling bool, err error) {
-			if resp.StatusCode != 200 {
-				return true, fmt.Errorf("querying dataset details failed with status code %v", resp.StatusCode)
+		handlePollResponse := func(jobDetails Job, err error) (stopPolling bool, _ error) {
+			var apiErr *datasetUtils.APIError
+			if errors.As(err, &apiErr) {
+				return true, fmt.Errorf("querying job details failed: %w", err)
 			}
-			defer resp.Body.Close()
-
-			body, err := io.ReadAll(resp.Body)
 			if err != nil {
-				return true, err
-			}
-
-			var jobDetails Job
-			err = json.Unmarshal(body, &jobDetails)
-			if err != nil {
-				return true, err
-			}
-			if jobDetails.StatusMessage == "finished" {
-				return true, nil
-			} else {
-				return false, nil
+				log.Fatal("Get Job failed:", err)
 			}
+			return jobDetails.StatusMessage == "finished", nil
 		}
 
 		// retrieve flags
@@ -82,11 +66,13 @@ var waitForJobFinishedCmd = &cobra.Command{
 		}
 		// configure environment
 		config := cliutils.InputEnvironmentConfig{
+			Profile:      cliutils.GetCobraStringFlag(cmd, "profile"),
 			TestenvFlag:  testenvFlag,
 			DevenvFlag:   devenvFlag,
 			LocalenvFlag: localenvFlag,
 			ScicatUrl:    scicatUrl,
 		}
+		config.ApplyFileConfig(fileConfig)
 		APIServer := config.ResolveAPIServer()
 
 		// command
@@ -106,51 +92,42 @@ var waitForJobFinishedCmd = &cobra.Command{
 			return
 		}
 
-		user, _, err := cliutils.Authenticate(cliutils.RealAuthenticator{}, client, APIServer, userpass, token, oidc)
+		ctx := cmd.Context()
+		user, err := cliutils.Authenticate(ctx, cliutils.RealAuthenticator{}, client, APIServer, userpass, token, oidc)
 		if err != nil {
 			log.Fatal(err)
 		}
 
-		req, err := http.NewRequest("GET", APIServer+"/Jobs/"+url.QueryEscape(jobId), nil)
-		if err != nil {
-			log.Fatal(err)
-		}
-		req.Header.Set("Authorization", "Bearer "+user["accessToken"])
-		req.Header.Set("accept", "application/json")
+		api := datasetUtils.NewAPIClient(client, APIServer, user.AccessToken)
+		jobPath := "/Jobs/" + url.QueryEscape(jobId)
 
-		timeoutchan := make(chan bool)
+		// poll the job every 5 seconds, for at most 24 hours
+		summary.Set("job "+jobId, false, "not finished")
+		pollCtx, cancel := context.WithTimeout(ctx, 24*time.Hour)
+		defer cancel()
 		ticker := time.NewTicker(5 * time.Second)
-		quit := make(chan struct{})
-		go func() {
-			for {
-				select {
-				case <-ticker.C:
-					resp, err := client.Do(req)
-					if err != nil {
-						log.Fatal("Get Job failed:", err)
-					}
-					stopPolling, err := handlePollResponse(resp)
-					if stopPolling {
-						if err != nil {
-							fmt.Println(err)
-						} else {
-							fmt.Println("finished")
-						}
-						ticker.Stop()
-						timeoutchan <- true
-					}
-				case <-quit:
-					ticker.Stop()
-					timeoutchan <- true
+		defer ticker.Stop()
+		for {
+			select {
+			case <-pollCtx.Done():
+				exitIfInterrupted(ctx)
+				return
+			case <-ticker.C:
+			}
+			var jobDetails Job
+			err := api.Get(pollCtx, jobPath, nil, &jobDetails)
+			if pollCtx.Err() != nil {
+				continue
+			}
+			stopPolling, err := handlePollResponse(jobDetails, err)
+			if stopPolling {
+				if err != nil {
+					fmt.Println(err)
+				} else {
+					fmt.Println("finished")
 				}
+				return
 			}
-		}()
-
-		select {
-		case <-timeoutchan:
-			break
-		case <-time.After(time.Hour * 24):
-			break
 		}
 	},
 }
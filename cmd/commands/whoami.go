@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/paulscherrerinstitute/scicat-cli/v3/cmd/cliutils"
+	"github.com/paulscherrerinstitute/scicat-cli/v3/datasetUtils"
+	"github.com/spf13/cobra"
+)
+
+var whoamiCmd = &cobra.Command{
+	Use:   "whoami [options]",
+	Short: "Shows the user of the stored login session",
+	Long: `Tool to show the identity and access groups of the session stored by "login".
+
+For further help see "` + cliutils.MANUAL + `"`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		// pass parameters
+		testenvFlag, _ := cmd.Flags().GetBool("testenv")
+		devenvFlag, _ := cmd.Flags().GetBool("devenv")
+		localenvFlag, _ := cmd.Flags().GetBool("localenv")
+		tunnelenvFlag, _ := cmd.Flags().GetBool("tunnelenv")
+		scicatUrl, _ := cmd.Flags().GetString("scicat-url")
+		showVersion, _ := cmd.Flags().GetBool("version")
+
+		if datasetUtils.TestFlags != nil {
+			datasetUtils.TestFlags(map[string]interface{}{
+				"testenv":    testenvFlag,
+				"devenv":     devenvFlag,
+				"localenv":   localenvFlag,
+				"tunnelenv":  tunnelenvFlag,
+				"scicat-url": scicatUrl,
+				"version":    showVersion,
+			})
+			return
+		}
+
+		// execute command
+		if showVersion {
+			fmt.Printf("%s\n", VERSION)
+			return
+		}
+
+		// configure environment
+		config := cliutils.InputEnvironmentConfig{
+			Profile:       cliutils.GetCobraStringFlag(cmd, "profile"),
+			TestenvFlag:   testenvFlag,
+			DevenvFlag:    devenvFlag,
+			LocalenvFlag:  localenvFlag,
+			TunnelenvFlag: tunnelenvFlag,
+			ScicatUrl:     scicatUrl,
+		}
+		config.ApplyFileConfig(fileConfig)
+		APIServer := config.ResolveAPIServer()
+
+		creds, ok, err := cliutils.LoadCredentials(APIServer)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if !ok {
+			log.Fatalf("Not logged in to %s, use the login command first\n", APIServer)
+		}
+
+		fmt.Printf("API server:    %s\n", creds.APIServer)
+		fmt.Printf("Username:      %s\n", creds.Username)
+		fmt.Printf("Display name:  %s\n", creds.DisplayName)
+		fmt.Printf("Email:         %s\n", creds.Mail)
+		fmt.Printf("Access groups: %s\n", strings.Join(creds.AccessGroups, ", "))
+		switch {
+		case creds.Expires.IsZero():
+			fmt.Println("Expires:       unknown")
+		case creds.Expired(time.Now()):
+			fmt.Printf("Expires:       %s (expired, use the login command again)\n", creds.Expires.Local().Format(time.RFC1123))
+		default:
+			fmt.Printf("Expires:       %s\n", creds.Expires.Local().Format(time.RFC1123))
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(whoamiCmd)
+
+	whoamiCmd.Flags().Bool("testenv", false, "Use test environment (qa) instead or production")
+	whoamiCmd.Flags().Bool("devenv", false, "Use development environment instead or production")
+	whoamiCmd.Flags().Bool("localenv", false, "Use local environment instead of production environment (developers only)")
+	whoamiCmd.Flags().Bool("tunnelenv", false, "Use tunneled API server at port 5443 to access development instance (developers only)")
+
+	whoamiCmd.MarkFlagsMutuallyExclusive("testenv", "devenv", "localenv", "tunnelenv")
+}
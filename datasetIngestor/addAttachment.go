@@ -2,13 +2,14 @@ package datasetIngestor
 
 import (
 	"bufio"
-	"bytes"
+	"context"
 	"encoding/base64"
-	"encoding/json"
 	"fmt"
 	"net/http"
 	"os"
 	"strings"
+
+	"github.com/paulscherrerinstitute/scicat-cli/v3/datasetUtils"
 )
 
 func ReadAndEncodeImage(attachmentFile string) (string, error) {
@@ -56,7 +57,7 @@ func CreateAttachmentMap(datasetId string, caption string, datasetMetadata map[s
 	return metadata, nil
 }
 
-func AddAttachment(client *http.Client, APIServer string, datasetId string, datasetMetadata map[string]interface{}, accessToken string, attachmentFile string, caption string) error {
+func AddAttachment(ctx context.Context, client *http.Client, APIServer string, datasetId string, datasetMetadata map[string]interface{}, accessToken string, attachmentFile string, caption string) error {
 	imgBase64Str, err := ReadAndEncodeImage(attachmentFile)
 	if err != nil {
 		return err
@@ -67,24 +68,10 @@ func AddAttachment(client *http.Client, APIServer string, datasetId string, data
 		return err
 	}
 
-	attachmentJson, err := json.Marshal(attachmentMap)
-	if err != nil {
-		return err
-	}
-	myurl := APIServer + "/Datasets/" + strings.Replace(datasetId, "/", "%2F", 1) + "/attachments?access_token=" + accessToken
-
-	req, err := http.NewRequest("POST", myurl, bytes.NewBuffer(attachmentJson))
+	path := "/Datasets/" + strings.Replace(datasetId, "/", "%2F", 1) + "/attachments"
+	err = datasetUtils.NewAPIClient(client, APIServer, accessToken).Post(ctx, path, attachmentMap, nil)
 	if err != nil {
-		return err
-	}
-	req.Header.Set("Content-Type", "application/json")
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("attachment file %v could not be added to dataset %v - status code: %d", attachmentFile, datasetId, resp.StatusCode)
+		return fmt.Errorf("attachment file %v could not be added to dataset %v: %w", attachmentFile, datasetId, err)
 	}
 	return nil
 }
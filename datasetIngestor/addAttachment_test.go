@@ -1,6 +1,7 @@
 package datasetIngestor
 
 import (
+	"context"
 	"encoding/base64"
 	"net/http"
 	"net/http/httptest"
@@ -38,7 +39,7 @@ func TestAddAttachment(t *testing.T) {
 		}
 	}()
 
-	err = AddAttachment(client, APIServer, datasetId, metaDataDataset, accessToken, attachmentFile, caption)
+	err = AddAttachment(context.Background(), client, APIServer, datasetId, metaDataDataset, accessToken, attachmentFile, caption)
 	if err != nil {
 		t.Errorf("The function returned an error: \"%v\"", err)
 	}
@@ -0,0 +1,229 @@
+package datasetIngestor
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+/*
+ManifestEntry is a dataset of a batch manifest: its sourceFolder, and the fields overriding the base
+metadata shared by all datasets of the batch. Empty fields don't override anything. The
+ScientificMetadata fragment is merged into the base scientificMetadata, replacing only the keys it
+sets, while the Keywords replace the base keywords. Attachment and Caption override the attachment
+flags for the dataset.
+*/
+type ManifestEntry struct {
+	SourceFolder       string                 `json:"sourceFolder"`
+	DatasetName        string                 `json:"datasetName,omitempty"`
+	Description        string                 `json:"description,omitempty"`
+	Keywords           []string               `json:"keywords,omitempty"`
+	ScientificMetadata map[string]interface{} `json:"scientificMetadata,omitempty"`
+	Attachment         string                 `json:"attachment,omitempty"`
+	Caption            string                 `json:"caption,omitempty"`
+}
+
+// the CSV columns, the keywords are separated by ";" and the scientificMetadata is a JSON object
+var manifestColumns = []string{"sourceFolder", "datasetName", "description", "keywords", "scientificMetadata", "attachment", "caption"}
+
+/*
+ReadBatchManifest reads the datasets of a batch manifest, in order. The format is taken from the
+extension of path:
+  - ".csv": a header row naming some of the columns sourceFolder, datasetName, description,
+    keywords, scientificMetadata, attachment and caption, followed by a row per dataset. The
+    keywords are separated by ";", the scientificMetadata is a JSON object. Lines starting with
+    "#" are skipped.
+  - ".jsonl" or ".ndjson": a JSON object per line with the fields of ManifestEntry.
+
+Every dataset needs a sourceFolder, and no sourceFolder may be listed twice.
+*/
+func ReadBatchManifest(path string) ([]ManifestEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []ManifestEntry
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".csv":
+		entries, err = readManifestCSV(file)
+	case ".jsonl", ".ndjson":
+		entries, err = readManifestJSONL(file)
+	default:
+		return nil, fmt.Errorf("unknown batch manifest format %q, use .csv or .jsonl", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't read the batch manifest %q: %w", path, err)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("the batch manifest %q lists no datasets", path)
+	}
+
+	seen := make(map[string]bool, len(entries))
+	for i, entry := range entries {
+		if entry.SourceFolder == "" {
+			return nil, fmt.Errorf("dataset %d of the batch manifest %q has no sourceFolder", i+1, path)
+		}
+		if seen[entry.SourceFolder] {
+			return nil, fmt.Errorf("the sourceFolder %q is listed twice in the batch manifest %q", entry.SourceFolder, path)
+		}
+		seen[entry.SourceFolder] = true
+	}
+	return entries, nil
+}
+
+func readManifestCSV(r io.Reader) ([]ManifestEntry, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, column := range header {
+		if !slices.Contains(manifestColumns, column) {
+			return nil, fmt.Errorf("unknown column %q, use some of: %s", column, strings.Join(manifestColumns, ", "))
+		}
+	}
+
+	var entries []ManifestEntry
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		var entry ManifestEntry
+		for i, value := range record {
+			value = strings.TrimSpace(value)
+			if value == "" {
+				continue
+			}
+			switch header[i] {
+			case "sourceFolder":
+				entry.SourceFolder = value
+			case "datasetName":
+				entry.DatasetName = value
+			case "description":
+				entry.Description = value
+			case "keywords":
+				for _, keyword := range strings.Split(value, ";") {
+					if keyword = strings.TrimSpace(keyword); keyword != "" {
+						entry.Keywords = append(entry.Keywords, keyword)
+					}
+				}
+			case "scientificMetadata":
+				if err := json.Unmarshal([]byte(value), &entry.ScientificMetadata); err != nil {
+					line, _ := reader.FieldPos(i)
+					return nil, fmt.Errorf("line %d: the scientificMetadata isn't a JSON object: %w", line, err)
+				}
+			case "attachment":
+				entry.Attachment = value
+			case "caption":
+				entry.Caption = value
+			}
+		}
+		entries = append(entries, entry)
+	}
+}
+
+func readManifestJSONL(r io.Reader) ([]ManifestEntry, error) {
+	var entries []ManifestEntry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		decoder := json.NewDecoder(bytes.NewReader(text))
+		decoder.DisallowUnknownFields()
+		var entry ManifestEntry
+		if err := decoder.Decode(&entry); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if decoder.More() {
+			return nil, fmt.Errorf("line %d: more than one JSON object", line)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// Apply returns a copy of the base metadata with the overrides of the entry, and its sourceFolder.
+// base isn't modified.
+func (e ManifestEntry) Apply(base map[string]interface{}) map[string]interface{} {
+	metaDataMap := copyMetadata(base)
+	metaDataMap["sourceFolder"] = e.SourceFolder
+	if e.DatasetName != "" {
+		metaDataMap["datasetName"] = e.DatasetName
+	}
+	if e.Description != "" {
+		metaDataMap["description"] = e.Description
+	}
+	if e.Keywords != nil {
+		keywords := make([]interface{}, len(e.Keywords))
+		for i, keyword := range e.Keywords {
+			keywords[i] = keyword
+		}
+		metaDataMap["keywords"] = keywords
+	}
+	if e.ScientificMetadata != nil {
+		scientificMetadata, ok := metaDataMap["scientificMetadata"].(map[string]interface{})
+		if !ok {
+			scientificMetadata = map[string]interface{}{}
+		}
+		mergeMetadata(scientificMetadata, copyMetadata(e.ScientificMetadata))
+		metaDataMap["scientificMetadata"] = scientificMetadata
+	}
+	return metaDataMap
+}
+
+// mergeMetadata sets the keys of fragment in metadata, merging the objects present in both.
+func mergeMetadata(metadata map[string]interface{}, fragment map[string]interface{}) {
+	for key, value := range fragment {
+		if fragmentObject, ok := value.(map[string]interface{}); ok {
+			if object, ok := metadata[key].(map[string]interface{}); ok {
+				mergeMetadata(object, fragmentObject)
+				continue
+			}
+		}
+		metadata[key] = value
+	}
+}
+
+// copyMetadata returns a deep copy of the objects and arrays of unmarshalled JSON metadata.
+func copyMetadata(metadata map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(metadata))
+	for key, value := range metadata {
+		result[key] = copyMetadataValue(value)
+	}
+	return result
+}
+
+func copyMetadataValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return copyMetadata(v)
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, element := range v {
+			result[i] = copyMetadataValue(element)
+		}
+		return result
+	}
+	return value
+}
@@ -0,0 +1,122 @@
+package datasetIngestor
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeManifest(t *testing.T, name string, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadBatchManifest(t *testing.T) {
+	want := []ManifestEntry{
+		{
+			SourceFolder:       "/data/scan_1",
+			DatasetName:        "Scan 1",
+			Description:        "Sample A, 20K",
+			Keywords:           []string{"sampleA", "cold"},
+			ScientificMetadata: map[string]interface{}{"sample": map[string]interface{}{"name": "A"}},
+			Attachment:         "/data/scan_1/thumb.png",
+			Caption:            "Overview",
+		},
+		{SourceFolder: "/data/scan_2"},
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		path := writeManifest(t, "manifest.csv", `sourceFolder,datasetName,description,keywords,scientificMetadata,attachment,caption
+# the second scan uses the base metadata only
+/data/scan_1,Scan 1,"Sample A, 20K",sampleA; cold,"{""sample"": {""name"": ""A""}}",/data/scan_1/thumb.png,Overview
+/data/scan_2,,,,,,
+`)
+		entries, err := ReadBatchManifest(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(entries, want) {
+			t.Errorf("got %+v, want %+v", entries, want)
+		}
+	})
+
+	t.Run("jsonl", func(t *testing.T) {
+		path := writeManifest(t, "manifest.jsonl", `{"sourceFolder": "/data/scan_1", "datasetName": "Scan 1", "description": "Sample A, 20K", "keywords": ["sampleA", "cold"], "scientificMetadata": {"sample": {"name": "A"}}, "attachment": "/data/scan_1/thumb.png", "caption": "Overview"}
+
+{"sourceFolder": "/data/scan_2"}
+`)
+		entries, err := ReadBatchManifest(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(entries, want) {
+			t.Errorf("got %+v, want %+v", entries, want)
+		}
+	})
+
+	errorCases := []struct {
+		name     string
+		file     string
+		content  string
+		expected string
+	}{
+		{"unknown format", "manifest.txt", "/data/scan_1\n", "unknown batch manifest format"},
+		{"unknown csv column", "manifest.csv", "sourceFolder,sample\n/data/scan_1,A\n", `unknown column "sample"`},
+		{"invalid scientificMetadata", "manifest.csv", "sourceFolder,scientificMetadata\n/data/scan_1,[1]\n", "isn't a JSON object"},
+		{"unknown jsonl field", "manifest.jsonl", `{"sourceFolder": "/data/scan_1", "title": "Scan 1"}`, "line 1"},
+		{"missing sourceFolder", "manifest.jsonl", `{"datasetName": "Scan 1"}`, "has no sourceFolder"},
+		{"duplicate sourceFolder", "manifest.csv", "sourceFolder\n/data/scan_1\n/data/scan_1\n", "listed twice"},
+		{"no datasets", "manifest.csv", "sourceFolder\n", "lists no datasets"},
+	}
+	for _, tc := range errorCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ReadBatchManifest(writeManifest(t, tc.file, tc.content))
+			if err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("expected an error containing %q, got %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestManifestEntryApply(t *testing.T) {
+	base := map[string]interface{}{
+		"sourceFolder": "/data/base",
+		"datasetName":  "Beamtime",
+		"description":  "base description",
+		"keywords":     []interface{}{"beamtime"},
+		"scientificMetadata": map[string]interface{}{
+			"energy": 12.4,
+			"sample": map[string]interface{}{"name": "base", "temperature": 300.0},
+		},
+	}
+	entry := ManifestEntry{
+		SourceFolder:       "/data/scan_1",
+		DatasetName:        "Scan 1",
+		Keywords:           []string{"sampleA"},
+		ScientificMetadata: map[string]interface{}{"sample": map[string]interface{}{"name": "A"}},
+	}
+
+	got := entry.Apply(base)
+	want := map[string]interface{}{
+		"sourceFolder": "/data/scan_1",
+		"datasetName":  "Scan 1",
+		"description":  "base description",
+		"keywords":     []interface{}{"sampleA"},
+		"scientificMetadata": map[string]interface{}{
+			"energy": 12.4,
+			"sample": map[string]interface{}{"name": "A", "temperature": 300.0},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if name := base["scientificMetadata"].(map[string]interface{})["sample"].(map[string]interface{})["name"]; name != "base" || base["sourceFolder"] != "/data/base" {
+		t.Errorf("the base metadata was modified: %v", base)
+	}
+}
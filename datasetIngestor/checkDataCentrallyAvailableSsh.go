@@ -1,12 +1,14 @@
 package datasetIngestor
 
 import (
+	"context"
 	"errors"
 	"io"
 	"net"
 	"os/exec"
 	"runtime"
 
+	"github.com/paulscherrerinstitute/scicat-cli/v3/datasetUtils"
 	"golang.org/x/crypto/ssh"
 )
 
@@ -57,11 +59,13 @@ client, so that whatever authentication the user's environment is already set up
 exactly as it would for an interactive ssh command - no additional setup should be required of the
 user.
 
+Cancelling ctx stops the check, the error of ctx is then returned as otherErr.
+
 Returned values:
   - sshErr - the error returned by the remote directory check
   - otherErr - other error that prevents the check from being executed
 */
-func CheckDataCentrallyAvailableSsh(username string, ARCHIVEServer string, sourceFolder string, sshOutput io.Writer) (sshErr error, otherErr error) {
+func CheckDataCentrallyAvailableSsh(ctx context.Context, username string, ARCHIVEServer string, sourceFolder string, sshOutput io.Writer) (sshErr error, otherErr error) {
 	switch goos {
 	case "windows":
 		client, err := newDumbClient(username, "", ARCHIVEServer)
@@ -70,12 +74,17 @@ func CheckDataCentrallyAvailableSsh(username string, ARCHIVEServer string, sourc
 		}
 		if client.SshClient != nil {
 			defer client.SshClient.Close()
+			stop := context.AfterFunc(ctx, func() { client.SshClient.Close() })
+			defer stop()
 		}
 
 		err = checkRemoteDirectory(client, sourceFolder, sshOutput)
 		if err == nil {
 			return nil, nil
 		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		if isRemoteDirectoryNotFoundSsh(err) {
 			return err, nil
 		}
@@ -102,7 +111,7 @@ func CheckDataCentrallyAvailableSsh(username string, ARCHIVEServer string, sourc
 		cmd.Stdout = sshOutput
 		cmd.Stderr = sshOutput
 
-		err = cmd.Run()
+		err = datasetUtils.RunCommand(ctx, cmd)
 		if err == nil {
 			return nil, nil
 		}
@@ -1,6 +1,7 @@
 package datasetIngestor
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -102,12 +103,12 @@ func TestCheckDataCentrallyAvailable(t *testing.T) {
 			t.Cleanup(func() { execCommand = oldExecCommand })
 			execCommand = (&execCommandMock{t: t, expectedArgs: tt.expectedArgs, exitStatus: tt.exitStatus}).Command
 
-			sshErr, otherErr := CheckDataCentrallyAvailableSsh(tt.username, tt.archiveServer, tt.sourceFolder, nil)
+			sshErr, otherErr := CheckDataCentrallyAvailableSsh(context.Background(), tt.username, tt.archiveServer, tt.sourceFolder, nil)
 			if (sshErr != nil) != tt.wantSshErr {
-				t.Errorf("CheckDataCentrallyAvailableSsh() sshErr = %v, wantSshErr %v", sshErr, tt.wantSshErr)
+				t.Errorf("CheckDataCentrallyAvailableSsh(context.Background(), ) sshErr = %v, wantSshErr %v", sshErr, tt.wantSshErr)
 			}
 			if (otherErr != nil) != tt.wantOtherErr {
-				t.Errorf("CheckDataCentrallyAvailableSsh() otherErr = %v, wantOtherErr %v", otherErr, tt.wantOtherErr)
+				t.Errorf("CheckDataCentrallyAvailableSsh(context.Background(), ) otherErr = %v, wantOtherErr %v", otherErr, tt.wantOtherErr)
 			}
 		})
 	}
@@ -122,7 +123,7 @@ func TestCheckDataCentrallyAvailable_NoSshBinary(t *testing.T) {
 	t.Cleanup(func() { os.Setenv("PATH", oldPath) })
 	os.Setenv("PATH", "")
 
-	sshErr, otherErr := CheckDataCentrallyAvailableSsh("testuser", "testserver", "/test/folder", nil)
+	sshErr, otherErr := CheckDataCentrallyAvailableSsh(context.Background(), "testuser", "testserver", "/test/folder", nil)
 	if sshErr != nil {
 		t.Errorf("expected no sshErr, got %v", sshErr)
 	}
@@ -184,12 +185,12 @@ func TestCheckDataCentrallyAvailable_Windows(t *testing.T) {
 				return tt.isNotFoundErr
 			}
 
-			sshErr, otherErr := CheckDataCentrallyAvailableSsh("testuser", "testserver", "/test/folder", nil)
+			sshErr, otherErr := CheckDataCentrallyAvailableSsh(context.Background(), "testuser", "testserver", "/test/folder", nil)
 			if (sshErr != nil) != tt.wantSshErr {
-				t.Errorf("CheckDataCentrallyAvailableSsh() sshErr = %v, wantSshErr %v", sshErr, tt.wantSshErr)
+				t.Errorf("CheckDataCentrallyAvailableSsh(context.Background(), ) sshErr = %v, wantSshErr %v", sshErr, tt.wantSshErr)
 			}
 			if (otherErr != nil) != tt.wantOtherErr {
-				t.Errorf("CheckDataCentrallyAvailableSsh() otherErr = %v, wantOtherErr %v", otherErr, tt.wantOtherErr)
+				t.Errorf("CheckDataCentrallyAvailableSsh(context.Background(), ) otherErr = %v, wantOtherErr %v", otherErr, tt.wantOtherErr)
 			}
 		})
 	}
@@ -207,7 +208,7 @@ func TestCheckDataCentrallyAvailable_Windows_NewDumbClientError(t *testing.T) {
 		return nil, errors.New("dial failure")
 	}
 
-	sshErr, otherErr := CheckDataCentrallyAvailableSsh("testuser", "testserver", "/test/folder", nil)
+	sshErr, otherErr := CheckDataCentrallyAvailableSsh(context.Background(), "testuser", "testserver", "/test/folder", nil)
 	if sshErr != nil {
 		t.Errorf("expected no sshErr, got %v", sshErr)
 	}
@@ -1,11 +1,9 @@
 package datasetIngestor
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
-	"io"
 	"net"
 	"net/http"
 	"os"
@@ -28,33 +26,47 @@ const unknown = "unknown"
 const raw = "raw"
 
 // a combined function that reads and checks metadata, gathers missing metadata and returns the metadata map, source folder and beamline account check
-func ReadAndCheckMetadata(client *http.Client, APIServer string, metadatafile string, user map[string]string, accessGroups []string, remoteFiles bool) (metaDataMap map[string]interface{}, sourceFolder string, beamlineAccount bool, err error) {
+func ReadAndCheckMetadata(ctx context.Context, client *http.Client, APIServer string, metadatafile string, user datasetUtils.User, rules FacilityRules, remoteFiles bool) (metaDataMap map[string]interface{}, sourceFolder string, beamlineAccount bool, err error) {
 	metaDataMap, err = ReadMetadataFromFile(metadatafile)
 	if err != nil {
 		return nil, "", false, err
 	}
-	sourceFolder, beamlineAccount, err = CheckMetadata(client, APIServer, metaDataMap, user, accessGroups, remoteFiles)
+	sourceFolder, beamlineAccount, err = CheckMetadata(ctx, client, APIServer, metaDataMap, user, rules, remoteFiles)
 	return metaDataMap, sourceFolder, beamlineAccount, err
 }
 
-func CheckMetadata(client *http.Client, APIServer string, metaDataMap map[string]interface{}, user map[string]string, accessGroups []string, remoteFiles bool) (sourceFolder string, beamlineAccount bool, err error) {
+func CheckMetadata(ctx context.Context, client *http.Client, APIServer string, metaDataMap map[string]interface{}, user datasetUtils.User, rules FacilityRules, remoteFiles bool) (sourceFolder string, beamlineAccount bool, err error) {
+	return checkMetadata(ctx, client, APIServer, metaDataMap, user, rules, remoteFiles, true)
+}
+
+// CheckTemplatedMetadata checks metadata whose values may contain templates, see
+// ExpandMetadataTemplates. It's CheckMetadata, except that the server can't validate metadata
+// containing templates: the metadata of every dataset must be checked with CheckMetadata once its
+// templates are expanded.
+func CheckTemplatedMetadata(ctx context.Context, client *http.Client, APIServer string, metaDataMap map[string]interface{}, user datasetUtils.User, rules FacilityRules, remoteFiles bool) (sourceFolder string, beamlineAccount bool, err error) {
+	return checkMetadata(ctx, client, APIServer, metaDataMap, user, rules, remoteFiles, !ContainsMetadataTemplates(metaDataMap))
+}
+
+func checkMetadata(ctx context.Context, client *http.Client, APIServer string, metaDataMap map[string]interface{}, user datasetUtils.User, rules FacilityRules, remoteFiles bool, validate bool) (sourceFolder string, beamlineAccount bool, err error) {
 	if keys := CollectIllegalKeys(metaDataMap); len(keys) > 0 {
 		return "", false, errors.New(ErrIllegalKeys + ": \"" + strings.Join(keys, "\", \"") + "\"")
 	}
 
-	beamlineAccount, err = CheckUserAndOwnerGroup(user, accessGroups, metaDataMap)
+	beamlineAccount, err = CheckUserAndOwnerGroup(user, rules, metaDataMap)
 	if err != nil {
 		return "", false, err
 	}
 
-	err = GatherMissingMetadata(user, metaDataMap, client, APIServer, accessGroups)
+	err = GatherMissingMetadata(ctx, user, rules, metaDataMap, client, APIServer)
 	if err != nil {
 		return "", false, err
 	}
 
-	err = CheckMetadataValidity(client, APIServer, user["accessToken"], metaDataMap)
-	if err != nil {
-		return "", false, err
+	if validate {
+		err = CheckMetadataValidity(ctx, client, APIServer, user.AccessToken, metaDataMap)
+		if err != nil {
+			return "", false, err
+		}
 	}
 
 	sourceFolder, err = GetSourceFolder(metaDataMap, remoteFiles)
@@ -65,22 +77,19 @@ func CheckMetadata(client *http.Client, APIServer string, metaDataMap map[string
 	return sourceFolder, beamlineAccount, nil
 }
 
-// ReadMetadataFromFile reads the metadata from the file and unmarshals it into a map.
+// ReadMetadataFromFile reads the metadata from a JSON, YAML or TOML file, see DetectMetadataFormat,
+// and unmarshals it into a map with the types of DecodeMetadata.
 func ReadMetadataFromFile(metadatafile string) (map[string]interface{}, error) {
 	b, err := os.ReadFile(metadatafile) // just pass the file name
 	if err != nil {
 		return nil, err
 	}
-	// Unmarshal the JSON metadata into an interface{} object.
-	var metadataObj interface{} // Using interface{} allows metadataObj to hold any type of data, since it has no defined methods.
-	err = json.Unmarshal(b, &metadataObj)
+	format := DetectMetadataFormat(metadatafile, b)
+	metaDataMap, err := DecodeMetadata(b, format)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("can't read the %s metadata file %q: %w", strings.ToUpper(string(format)), metadatafile, err)
 	}
-
-	// Use type assertion to convert the interface{} object to a map[string]interface{}.
-	metaDataMap := metadataObj.(map[string]interface{}) // `.(` is type assertion: a way to extract the underlying value of an interface and check whether it's of a specific type.
-	return metaDataMap, err
+	return metaDataMap, nil
 }
 
 // collects keys with illegal characters
@@ -125,9 +134,10 @@ func keyContainsIllegalCharacters(s string) bool {
 	return false
 }
 
-// CheckUserAndOwnerGroup checks the user and owner group and returns whether the user is a beamline account.
-func CheckUserAndOwnerGroup(user map[string]string, accessGroups []string, metaDataMap map[string]interface{}) (bool, error) {
-	if user["displayName"] == "ingestor" {
+// CheckUserAndOwnerGroup checks that user may ingest for the ownerGroup of the metadata and returns
+// whether it does so as instrument (beamline) account, according to the facility rules.
+func CheckUserAndOwnerGroup(user datasetUtils.User, rules FacilityRules, metaDataMap map[string]interface{}) (bool, error) {
+	if rules.IsServiceAccount(user) {
 		return false, nil
 	}
 
@@ -137,42 +147,34 @@ func CheckUserAndOwnerGroup(user map[string]string, accessGroups []string, metaD
 		// NOTE: so if there's no ownergroup, we can pass this check?
 		return false, fmt.Errorf("attribute 'ownerGroup' is missing or invalid (expected string) in metadata")
 	}
-	// Iterate over accessGroups to validate the owner group.
-	if slices.Contains(accessGroups, ownerGroup) {
+	// Iterate over the access groups of the user to validate the owner group.
+	if slices.Contains(user.AccessGroups, ownerGroup) {
 		return false, nil
 	}
 
-	// NOTE: beamline accounts seem to be very PSI specific.
-	// If the owner group is not valid, check for beamline-specific accounts.
-	if creationLocation, ok := metaDataMap["creationLocation"]; ok {
-		// Split the creationLocation string to extract beamline-specific information.
-		parts := strings.Split(creationLocation.(string), "/")
-		expectedAccount := ""
-		if len(parts) == 4 {
-			expectedAccount = strings.ToLower(parts[2]) + strings.ToLower(parts[3])
+	// If the owner group is not valid, check for the account of the instrument the data comes from.
+	if value, ok := metaDataMap["creationLocation"]; ok {
+		creationLocation, ok := value.(string)
+		if !ok {
+			return false, fmt.Errorf("attribute 'creationLocation' is not a string")
 		}
-		// If the user matches the expected beamline account, grant ingest access.
-		if slices.Contains(accessGroups, expectedAccount) {
-			//log.Printf("Beamline specific dataset %s - ingest granted.\n", expectedAccount)
-			return true, nil
-		} else {
-			return false, fmt.Errorf("you are neither member of the ownerGroup %s nor the needed beamline account %s", ownerGroup, expectedAccount)
+		expectedAccount, found, err := rules.LocationGroup(creationLocation)
+		if err != nil {
+			return false, err
 		}
-	} else {
-		// for other data just check user name
-		// this is a quick and dirty test. Should be replaced by test for "globalaccess" role. TODO
-		// facilities: ["SLS", "SINQ", "SWISSFEL", "SmuS"],
-		u := user["displayName"]
-		if strings.HasPrefix(u, "sls") ||
-			strings.HasPrefix(u, "swissfel") ||
-			strings.HasPrefix(u, "sinq") ||
-			strings.HasPrefix(u, "smus") {
+		if !found {
+			return false, fmt.Errorf("you are not member of the ownerGroup %s and no instrument account is known for the creationLocation %s", ownerGroup, creationLocation)
+		}
+		// If the user matches the expected beamline account, grant ingest access.
+		if slices.Contains(user.AccessGroups, expectedAccount) {
 			return true, nil
 		}
+		return false, fmt.Errorf("you are neither member of the ownerGroup %s nor the needed beamline account %s", ownerGroup, expectedAccount)
+	} else if rules.IsInstrumentAccount(user) {
+		// for other data just check the account name
+		return true, nil
 	}
 
-	// NOTE: we can get to this part after the last else,
-	// this lacks an error state for not being a beamline account or part of an expected owner group?
 	return false, fmt.Errorf("user is not part of the ownerGroup and does not have a valid beamline account")
 }
 
@@ -220,21 +222,21 @@ func getHost() string {
 }
 
 // GatherMissingMetadata augments missing metadata fields.
-func GatherMissingMetadata(user map[string]string, metaDataMap map[string]interface{}, client *http.Client, APIServer string, accessGroups []string) error {
+func GatherMissingMetadata(ctx context.Context, user datasetUtils.User, rules FacilityRules, metaDataMap map[string]interface{}, client *http.Client, APIServer string) error {
 	color.Set(color.FgGreen)
 	defer color.Unset()
 
 	// optionally gather missing owner metadata
 	if _, ok := metaDataMap["owner"]; !ok {
-		metaDataMap["owner"] = user["displayName"]
+		metaDataMap["owner"] = user.DisplayName
 		//log.Printf("owner field added: %s", metaDataMap["owner"])
 	}
 	if _, ok := metaDataMap["ownerEmail"]; !ok {
-		metaDataMap["ownerEmail"] = user["mail"]
+		metaDataMap["ownerEmail"] = user.Mail
 		//log.Printf("ownerEmail field added: %s", metaDataMap["ownerEmail"])
 	}
 	if _, ok := metaDataMap["contactEmail"]; !ok {
-		metaDataMap["contactEmail"] = user["mail"]
+		metaDataMap["contactEmail"] = user.Mail
 		//log.Printf("contactEmail field added: %s", metaDataMap["contactEmail"])
 	}
 
@@ -248,15 +250,21 @@ func GatherMissingMetadata(user map[string]string, metaDataMap map[string]interf
 	}
 
 	// for raw data add PI if missing
-	if err := addPrincipalInvestigatorFromProposal(user, metaDataMap, client, APIServer); err != nil {
+	if err := addPrincipalInvestigatorFromProposal(ctx, user, metaDataMap, client, APIServer); err != nil {
 		return err
 	}
 
-	// add/append accessGroups entry for beamline if creationLocation is defined
+	// add/append accessGroups entry for the instrument if creationLocation is defined
 	if value, exists := metaDataMap["creationLocation"]; exists {
-		var parts = strings.Split(value.(string), "/")
-		if len(parts) == 4 {
-			newGroup := strings.ToLower(parts[2]) + strings.ToLower(parts[3])
+		creationLocation, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("'creationLocation' is not a string")
+		}
+		newGroup, found, err := rules.LocationGroup(creationLocation)
+		if err != nil {
+			return err
+		}
+		if found {
 			if accessGroups, ok := metaDataMap["accessGroups"]; ok {
 				switch v := accessGroups.(type) {
 				case []interface{}:
@@ -275,7 +283,7 @@ func GatherMissingMetadata(user map[string]string, metaDataMap map[string]interf
 	return nil
 }
 
-func addPrincipalInvestigatorFromProposal(user map[string]string, metaDataMap map[string]interface{}, client *http.Client, APIServer string) error {
+func addPrincipalInvestigatorFromProposal(ctx context.Context, user datasetUtils.User, metaDataMap map[string]interface{}, client *http.Client, APIServer string) error {
 	typeVal, ok := metaDataMap["type"]
 	if !ok {
 		return fmt.Errorf("type doesn't exist as an attribute")
@@ -302,7 +310,7 @@ func addPrincipalInvestigatorFromProposal(user map[string]string, metaDataMap ma
 		return fmt.Errorf("ownerGroup is not a string")
 	}
 
-	proposal, err := datasetUtils.GetProposal(client, APIServer, ownerGroup, user)
+	proposal, err := datasetUtils.GetProposal(ctx, client, APIServer, ownerGroup, user)
 	if err != nil {
 		return fmt.Errorf("failed to get proposal: %v", err)
 	}
@@ -321,7 +329,7 @@ func addPrincipalInvestigatorFromProposal(user map[string]string, metaDataMap ma
 }
 
 // CheckMetadataValidity checks the validity of the metadata by calling the appropriate API.
-func CheckMetadataValidity(client *http.Client, APIServer string, token string, metaDataMap map[string]interface{}) error {
+func CheckMetadataValidity(ctx context.Context, client *http.Client, APIServer string, token string, metaDataMap map[string]interface{}) error {
 	// add dummy data for fields which can only be filled after file scan to pass the validity test
 	if _, exists := metaDataMap["ownerGroup"]; !exists {
 		metaDataMap["ownerGroup"] = DUMMY_OWNER
@@ -336,39 +344,16 @@ func CheckMetadataValidity(client *http.Client, APIServer string, token string,
 	}
 
 	// request validity check (must be logged-in)
-	bmm, err := json.Marshal(metaDataMap)
-	if err != nil {
-		return err
-	}
-
-	req, err := http.NewRequest("POST", APIServer+"/datasets/isValid", bytes.NewBuffer(bmm))
-	if err != nil {
-		return err
-	}
-	req.Header.Set("Authorization", "Bearer "+token)
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode == http.StatusForbidden {
+	var responseMap map[string]interface{}
+	err := datasetUtils.NewAPIClient(client, APIServer, token).Post(ctx, "/datasets/isValid", metaDataMap, &responseMap)
+	if datasetUtils.IsAPIStatus(err, http.StatusForbidden) {
 		return fmt.Errorf("metadata checking error - SciCat returned 403, user is likely not allowed to ingest datasets")
 	}
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("metadata checking error - unexpected status code: %d", resp.StatusCode)
-	}
-
-	// check response (if {"valid": true} then the metadata is correct)
-	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return err
+		return fmt.Errorf("metadata checking error - %w", err)
 	}
 
-	var responseMap map[string]interface{}
-	json.Unmarshal(body, &responseMap)
+	// check response (if {"valid": true} then the metadata is correct)
 	isValid, ok := responseMap["valid"]
 	if !ok {
 		return fmt.Errorf("no 'valid' attribute was returned in JSON response")
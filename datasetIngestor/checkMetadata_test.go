@@ -1,12 +1,15 @@
 package datasetIngestor
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
 	"strings"
 	"testing"
+
+	"github.com/paulscherrerinstitute/scicat-cli/v3/datasetUtils"
 )
 
 func TestGetHost(t *testing.T) {
@@ -59,17 +62,15 @@ func TestCheckMetadata(t *testing.T) {
 	// Close the server when test finishes
 	defer server.Close()
 
-	// Mock user map
-	user := map[string]string{
-		"displayName": "csaxsswissfel",
-		"mail":        "testuser@example.com",
+	// Mock user
+	user := datasetUtils.User{
+		DisplayName:  "csaxsswissfel",
+		Mail:         "testuser@example.com",
+		AccessGroups: []string{"group1", "group2"},
 	}
 
-	// Mock access groups
-	accessGroups := []string{"group1", "group2"}
-
 	// Call the function with mock parameters
-	metaDataMap, sourceFolder, beamlineAccount, err := ReadAndCheckMetadata(server.Client(), server.URL, metadatafile1, user, accessGroups, false)
+	metaDataMap, sourceFolder, beamlineAccount, err := ReadAndCheckMetadata(context.Background(), server.Client(), server.URL, metadatafile1, user, DefaultFacilityRules(), false)
 	if err != nil {
 		t.Error("Error in CheckMetadata function: ", err)
 	}
@@ -119,7 +120,7 @@ func TestCheckMetadata(t *testing.T) {
 	}
 
 	// test with the second metadata file
-	metaDataMap2, sourceFolder2, beamlineAccount2, err := ReadAndCheckMetadata(server.Client(), server.URL, metadatafile2, user, accessGroups, false)
+	metaDataMap2, sourceFolder2, beamlineAccount2, err := ReadAndCheckMetadata(context.Background(), server.Client(), server.URL, metadatafile2, user, DefaultFacilityRules(), false)
 	if err != nil {
 		t.Error("Error in CheckMetadata function: ", err)
 	}
@@ -173,17 +174,15 @@ func TestCheckMetadata_CrashCase(t *testing.T) {
 	// Create a mock HTTP client
 	client := server.Client()
 
-	// Mock user map
-	user := map[string]string{
-		"displayName": "csaxsswissfel",
-		"mail":        "testuser@example.com",
+	// Mock user
+	user := datasetUtils.User{
+		DisplayName:  "csaxsswissfel",
+		Mail:         "testuser@example.com",
+		AccessGroups: []string{"group1", "group2"},
 	}
 
-	// Mock access groups
-	accessGroups := []string{"group1", "group2"}
-
 	// Call the function that should return an error
-	_, _, _, err := ReadAndCheckMetadata(client, server.URL, metadatafile3, user, accessGroups, false)
+	_, _, _, err := ReadAndCheckMetadata(context.Background(), client, server.URL, metadatafile3, user, DefaultFacilityRules(), false)
 
 	// Check that the function returned the expected error
 	if err == nil {
@@ -216,15 +215,15 @@ func TestCheckMetadata_ValidFalse(t *testing.T) {
 	defer server.Close()
 	client := server.Client()
 
-	// Mock user map
-	user := map[string]string{
-		"displayName": "csaxsswissfel",
-		"mail":        "testuser@example.com",
+	// Mock user
+	user := datasetUtils.User{
+		DisplayName:  "csaxsswissfel",
+		Mail:         "testuser@example.com",
+		AccessGroups: []string{"group1", "group2"},
 	}
-	accessGroups := []string{"group1", "group2"}
 
 	// Call the function with mock parameters
-	_, _, _, err := ReadAndCheckMetadata(client, server.URL, metadatafile2, user, accessGroups, false)
+	_, _, _, err := ReadAndCheckMetadata(context.Background(), client, server.URL, metadatafile2, user, DefaultFacilityRules(), false)
 	if err == nil {
 		t.Fatal("Function did not return an error as expected")
 	} else if !strings.Contains(err.Error(), "metadata is not valid") {
@@ -234,11 +233,38 @@ func TestCheckMetadata_ValidFalse(t *testing.T) {
 	}
 }
 
+func TestCheckTemplatedMetadata(t *testing.T) {
+	validated := 0
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		validated++
+		rw.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(rw, `{"valid":false, "error": "creationTime must be a date"}`)
+	}))
+	defer server.Close()
+	user := datasetUtils.User{AccessGroups: []string{"group1"}}
+
+	metaDataMap, err := ReadMetadataFromFile("testdata/metadata-short.json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	metaDataMap["creationTime"] = "{{ .Scan.StartTime }}"
+	if _, _, err := CheckTemplatedMetadata(context.Background(), server.Client(), server.URL, metaDataMap, user, DefaultFacilityRules(), false); err != nil {
+		t.Errorf("templated metadata shouldn't be validated by the server, got %v", err)
+	}
+	if validated != 0 {
+		t.Errorf("expected no validation request, got %d", validated)
+	}
+
+	metaDataMap["creationTime"] = "not a date"
+	if _, _, err := CheckTemplatedMetadata(context.Background(), server.Client(), server.URL, metaDataMap, user, DefaultFacilityRules(), false); err == nil {
+		t.Error("metadata without templates should be validated by the server")
+	}
+}
+
 func TestCheckUserAndOwnerGroup(t *testing.T) {
 	tests := []struct {
 		name             string
-		user             map[string]string
-		accessGroups     []string
+		user             datasetUtils.User
 		metaDataMap      map[string]interface{}
 		wantBeamline     bool
 		wantErr          bool
@@ -246,24 +272,21 @@ func TestCheckUserAndOwnerGroup(t *testing.T) {
 	}{
 		{
 			name:         "ownerGroup is a valid string present in accessGroups",
-			user:         map[string]string{"displayName": "someuser"},
-			accessGroups: []string{"group1", "group2"},
+			user:         datasetUtils.User{DisplayName: "someuser", AccessGroups: []string{"group1", "group2"}},
 			metaDataMap:  map[string]interface{}{"ownerGroup": "group1"},
 			wantBeamline: false,
 			wantErr:      false,
 		},
 		{
 			name:             "ownerGroup key missing from metadata",
-			user:             map[string]string{"displayName": "someuser"},
-			accessGroups:     []string{"group1"},
+			user:             datasetUtils.User{DisplayName: "someuser", AccessGroups: []string{"group1"}},
 			metaDataMap:      map[string]interface{}{},
 			wantErr:          true,
 			wantErrSubstring: "attribute 'ownerGroup' is missing or invalid",
 		},
 		{
 			name:             "ownerGroup present but not a string does not panic",
-			user:             map[string]string{"displayName": "someuser"},
-			accessGroups:     []string{"group1"},
+			user:             datasetUtils.User{DisplayName: "someuser", AccessGroups: []string{"group1"}},
 			metaDataMap:      map[string]interface{}{"ownerGroup": nil},
 			wantErr:          true,
 			wantErrSubstring: "attribute 'ownerGroup' is missing or invalid",
@@ -272,7 +295,7 @@ func TestCheckUserAndOwnerGroup(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			beamlineAccount, err := CheckUserAndOwnerGroup(tc.user, tc.accessGroups, tc.metaDataMap)
+			beamlineAccount, err := CheckUserAndOwnerGroup(tc.user, DefaultFacilityRules(), tc.metaDataMap)
 
 			if tc.wantErr {
 				if err == nil {
@@ -380,15 +403,15 @@ func TestCheckMetadata_BeamlineAccount(t *testing.T) {
 	defer server.Close()
 	client := server.Client()
 
-	// Mock user map
-	user := map[string]string{
-		"displayName": "slscsaxs1",
-		"mail":        "testuser@example.com",
+	// Mock user
+	user := datasetUtils.User{
+		DisplayName:  "slscsaxs1",
+		Mail:         "testuser@example.com",
+		AccessGroups: []string{"slscsaxs", "slscsaxs1"},
 	}
-	accessGroups := []string{"slscsaxs", "slscsaxs1"}
 
 	// Call the function with mock parameters
-	_, _, beamlineAccount, err := ReadAndCheckMetadata(client, server.URL, metadatafile2, user, accessGroups, false)
+	_, _, beamlineAccount, err := ReadAndCheckMetadata(context.Background(), client, server.URL, metadatafile2, user, DefaultFacilityRules(), false)
 	if err != nil {
 		t.Error("Error in CheckMetadata function: ", err)
 	}
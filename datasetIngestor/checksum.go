@@ -0,0 +1,165 @@
+package datasetIngestor
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// DefaultChecksumWorkers is the number of files hashed in parallel if ScanOptions doesn't say
+// otherwise.
+const DefaultChecksumWorkers = 4
+
+// checksumAlgorithms are the supported checksum algorithms, keyed by their chkAlg name.
+var checksumAlgorithms = map[string]func() hash.Hash{
+	"sha256": sha256.New,
+	"sha1":   sha1.New,
+	"md5":    md5.New,
+	"xxhash": func() hash.Hash { return xxhash.New() },
+}
+
+// ChecksumAlgorithms returns the names of the supported checksum algorithms.
+func ChecksumAlgorithms() []string {
+	return slices.Sorted(maps.Keys(checksumAlgorithms))
+}
+
+// CheckChecksumAlgorithm returns an error if alg isn't a supported checksum algorithm.
+func CheckChecksumAlgorithm(alg string) error {
+	if _, ok := checksumAlgorithms[alg]; !ok {
+		return fmt.Errorf("unknown checksum algorithm %q, use one of: %s", alg, strings.Join(ChecksumAlgorithms(), ", "))
+	}
+	return nil
+}
+
+// fileChecksum returns the hex encoded checksum of the file at path.
+func fileChecksum(ctx context.Context, alg string, path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := checksumAlgorithms[alg]()
+	if _, err := io.Copy(h, contextReader{ctx, f}); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// contextReader stops reading once ctx is cancelled, so hashing large files can be interrupted.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// checksumPool hashes files in a fixed number of workers while the scan goes on. The checksums
+// are collected by the index of the file in the file list.
+type checksumPool struct {
+	alg       string
+	ctx       context.Context
+	cancel    context.CancelCauseFunc
+	jobs      chan checksumJob
+	closeJobs func()
+	wg        sync.WaitGroup
+
+	mu        sync.Mutex
+	checksums map[int]string
+}
+
+type checksumJob struct {
+	index int
+	path  string
+}
+
+// newChecksumPool starts workers hashing with alg, the first error stops all of them.
+func newChecksumPool(ctx context.Context, alg string, workers int) (*checksumPool, error) {
+	if err := CheckChecksumAlgorithm(alg); err != nil {
+		return nil, err
+	}
+	if workers <= 0 {
+		workers = DefaultChecksumWorkers
+	}
+	p := &checksumPool{alg: alg, jobs: make(chan checksumJob, workers), checksums: map[int]string{}}
+	p.ctx, p.cancel = context.WithCancelCause(ctx)
+	p.closeJobs = sync.OnceFunc(func() { close(p.jobs) })
+	for i := 0; i < workers; i++ {
+		p.wg.Add(1)
+		go p.work()
+	}
+	return p, nil
+}
+
+func (p *checksumPool) work() {
+	defer p.wg.Done()
+	for job := range p.jobs {
+		if p.ctx.Err() != nil {
+			continue
+		}
+		chk, err := fileChecksum(p.ctx, p.alg, job.path)
+		if err != nil {
+			p.cancel(fmt.Errorf("can't compute the %s checksum of %s: %w", p.alg, job.path, err))
+			continue
+		}
+		p.mu.Lock()
+		p.checksums[job.index] = chk
+		p.mu.Unlock()
+	}
+}
+
+// add queues the file at path, which is fullFileArray[index] of the scan. It blocks while all
+// workers are busy, and returns the error that stopped the pool, if any.
+func (p *checksumPool) add(index int, path string) error {
+	select {
+	case p.jobs <- checksumJob{index, path}:
+		return nil
+	case <-p.ctx.Done():
+		return context.Cause(p.ctx)
+	}
+}
+
+// take removes and returns the checksum of file index, if it's computed already.
+func (p *checksumPool) take(index int) (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	chk, ok := p.checksums[index]
+	if ok {
+		delete(p.checksums, index)
+	}
+	return chk, ok
+}
+
+// wait returns the checksums of all queued files that weren't taken, once they're computed.
+func (p *checksumPool) wait() (map[int]string, error) {
+	p.closeJobs()
+	p.wg.Wait()
+	if err := context.Cause(p.ctx); err != nil {
+		return nil, err
+	}
+	p.cancel(nil)
+	return p.checksums, nil
+}
+
+// stop aborts the pool and waits for the workers to finish.
+func (p *checksumPool) stop() {
+	p.cancel(context.Canceled)
+	p.closeJobs()
+	p.wg.Wait()
+}
@@ -0,0 +1,93 @@
+package datasetIngestor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGetLocalFileListChecksums(t *testing.T) {
+	tempDir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(tempDir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a", "sub/b", "sub/c"} {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte("test"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		alg  string
+		want string
+	}{
+		{"sha256", "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"},
+		{"sha1", "a94a8fe5ccb19ba61c4c0873d391e987982fbbd3"},
+		{"md5", "098f6bcd4621d373cade4e832627b4f6"},
+		{"xxhash", "4fdcca5ddb678139"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.alg, func(t *testing.T) {
+			files, _, _, _, _, _, err := GetLocalFileList(context.Background(), tempDir, "", nil, nil, ScanOptions{ChecksumAlgorithm: tt.alg, ChecksumWorkers: 2})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(files) != 4 {
+				t.Fatalf("expected 3 files and a directory, got %v", files)
+			}
+			for _, file := range files {
+				if file.Path == "sub" {
+					if file.Chk != "" {
+						t.Errorf("directories shouldn't have a checksum, got %q", file.Chk)
+					}
+					continue
+				}
+				if file.Chk != tt.want || file.ChkAlg != tt.alg {
+					t.Errorf("%s has checksum %s %q, want %s %q", file.Path, file.ChkAlg, file.Chk, tt.alg, tt.want)
+				}
+			}
+			if block := createOrigBlock(0, len(files), files, "pid"); block.ChkAlg != tt.alg {
+				t.Errorf("origdatablock has chkAlg %q, want %q", block.ChkAlg, tt.alg)
+			}
+		})
+	}
+
+	t.Run("without checksums", func(t *testing.T) {
+		files, _, _, _, _, _, err := GetLocalFileList(context.Background(), tempDir, "", nil, nil, ScanOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, file := range files {
+			if file.Chk != "" {
+				t.Errorf("%s has an unexpected checksum %q", file.Path, file.Chk)
+			}
+		}
+		if block := createOrigBlock(0, len(files), files, "pid"); block.ChkAlg != "" {
+			t.Errorf("origdatablock has unexpected chkAlg %q", block.ChkAlg)
+		}
+	})
+
+	t.Run("unknown algorithm", func(t *testing.T) {
+		_, _, _, _, _, _, err := GetLocalFileList(context.Background(), tempDir, "", nil, nil, ScanOptions{ChecksumAlgorithm: "crc32"})
+		if err == nil {
+			t.Error("expected an error for an unknown algorithm")
+		}
+	})
+
+	t.Run("unreadable file", func(t *testing.T) {
+		unreadable := filepath.Join(tempDir, "sub", "c")
+		if err := os.Chmod(unreadable, 0); err != nil {
+			t.Fatal(err)
+		}
+		defer os.Chmod(unreadable, 0644)
+		if f, err := os.Open(unreadable); err == nil {
+			f.Close()
+			t.Skip("file permissions aren't enforced for this user")
+		}
+		_, _, _, _, _, _, err := GetLocalFileList(context.Background(), tempDir, "", nil, nil, ScanOptions{ChecksumAlgorithm: "md5"})
+		if err == nil {
+			t.Error("expected an error for an unreadable file")
+		}
+	})
+}
@@ -1,16 +1,19 @@
 package datasetIngestor
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+
+	"github.com/paulscherrerinstitute/scicat-cli/v3/datasetUtils"
 )
 
 /*
 CreateDatasetEntry is a function that creates a new dataset entry in a specified API server.
 
 Parameters:
+- ctx: Cancels the request.
 - client: An *http.Client object used to send the HTTP request.
 - APIServer: A string representing the URL of the API server.
 - metaDataMap: A map[string]interface{} containing the metadata for the new dataset.
@@ -18,7 +21,7 @@ Parameters:
 
 The function first serializes the metaDataMap to JSON, then sends a POST request to create the new dataset.
 
-If the server responds with a status code of 200, the function decodes the response body to extract the "pid" field, which it returns as a string. If the server responds with any other status code, the function returns an error.
+If the server responds with a 2xx status code, the function decodes the response body to extract the "pid" field, which it returns as a string. If the server responds with any other status code, the function returns an error.
 
 If the "type" field is not present in the metaDataMap, or if it contains an unrecognized value, the function logs an error message and terminates the program.
 
@@ -28,38 +31,21 @@ Returns:
 Note: This function will terminate the program if it encounters an error, such as a failure to serialize the metaDataMap, a failure to send the HTTP request, a non-200 response from the server, or an unrecognized dataset type.
 Note 2: This function is unused in cmd
 */
-func CreateDatasetEntry(client *http.Client, APIServer string, metaDataMap map[string]interface{}, accessToken string) (datasetId string, err error) {
+func CreateDatasetEntry(ctx context.Context, client *http.Client, APIServer string, metaDataMap map[string]interface{}, accessToken string) (datasetId string, err error) {
 	// assemble json structure
 	bm, err := json.Marshal(metaDataMap)
 	if err != nil {
 		return "", fmt.Errorf("couldn't marshal metadata map: %v", metaDataMap)
 	}
 
-	// send request
-	req, err := http.NewRequest("POST", APIServer+"/Datasets"+"?access_token="+accessToken, bytes.NewBuffer(bm))
-	if err != nil {
-		return datasetId, err
-	}
-	req.Header.Set("Content-Type", "application/json")
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		return "", fmt.Errorf("createDatasetEntry:Failed to create new dataset: status code %v", resp.StatusCode)
-	}
-
-	// decode response
+	// send request and decode response
 	type PidType struct {
 		Pid string `json:"pid"`
 	}
-	decoder := json.NewDecoder(resp.Body)
 	var d PidType
-	err = decoder.Decode(&d)
+	err = datasetUtils.NewAPIClient(client, APIServer, accessToken).Post(ctx, "/Datasets", bm, &d)
 	if err != nil {
-		return "", fmt.Errorf("could not decode pid from dataset entry: %v", err)
+		return "", fmt.Errorf("createDatasetEntry:Failed to create new dataset: %w", err)
 	}
 
 	return d.Pid, nil
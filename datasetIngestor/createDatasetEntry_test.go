@@ -1,6 +1,7 @@
 package datasetIngestor
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -26,7 +27,7 @@ func TestCreateDatasetEntry(t *testing.T) {
 	client := server.Client()
 
 	// Call the function
-	datasetId, err := CreateDatasetEntry(client, server.URL, metaDataMap, "testToken")
+	datasetId, err := CreateDatasetEntry(context.Background(), client, server.URL, metaDataMap, "testToken")
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
@@ -1,20 +1,14 @@
 package datasetIngestor
 
 import (
+	"context"
 	"net/http"
 	"strings"
+
+	"github.com/paulscherrerinstitute/scicat-cli/v3/datasetUtils"
 )
 
 // note: this function is unused in cmd after a change in datasetIngestor command
-func DeleteDatasetEntry(client *http.Client, APIServer string, datasetId string, accessToken string) error {
-	req, err := http.NewRequest("DELETE", APIServer+"/Datasets/"+strings.Replace(datasetId, "/", "%2F", 1)+"?access_token="+accessToken, nil)
-	if err != nil {
-		return err
-	}
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	return nil
+func DeleteDatasetEntry(ctx context.Context, client *http.Client, APIServer string, datasetId string, accessToken string) error {
+	return datasetUtils.NewAPIClient(client, APIServer, accessToken).Delete(ctx, "/Datasets/"+strings.Replace(datasetId, "/", "%2F", 1))
 }
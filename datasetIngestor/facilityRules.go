@@ -0,0 +1,110 @@
+package datasetIngestor
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/paulscherrerinstitute/scicat-cli/v3/datasetUtils"
+)
+
+// FacilityRules describe the facility specific part of the ingest authorization: which accounts
+// belong to instruments (beamlines) and which access group an instrument has. They're read from
+// the "facility" section of the config file, DefaultFacilityRules are used if there's none.
+type FacilityRules struct {
+	// ServiceAccounts are display names of accounts that may ingest for any ownerGroup.
+	ServiceAccounts []string `yaml:"service-accounts,omitempty" json:"service-accounts,omitempty"`
+	// LocationGroups map the creationLocation of a dataset to the access group of its
+	// instrument. The first rule matching the creationLocation is used.
+	LocationGroups []LocationGroupRule `yaml:"location-groups,omitempty" json:"location-groups,omitempty"`
+	// InstrumentAccounts are glob patterns (as in path.Match) of the display names of instrument
+	// accounts. They may ingest datasets without creationLocation for any ownerGroup.
+	InstrumentAccounts []string `yaml:"instrument-accounts,omitempty" json:"instrument-accounts,omitempty"`
+}
+
+// LocationGroupRule maps the creationLocations matching Pattern to the access group Group.
+type LocationGroupRule struct {
+	// Pattern is a regular expression, it has to match the whole creationLocation.
+	Pattern string `yaml:"pattern" json:"pattern"`
+	// Group is the access group, it can refer to submatches of Pattern as $1 or ${name}.
+	Group string `yaml:"group" json:"group"`
+	// Lowercase converts the resulting group to lower case.
+	Lowercase bool `yaml:"lowercase,omitempty" json:"lowercase,omitempty"`
+}
+
+// DefaultFacilityRules returns the rules of PSI: a creationLocation like "/PSI/SLS/TOMCAT"
+// belongs to the group "slstomcat", and accounts named after a facility are instrument accounts.
+func DefaultFacilityRules() FacilityRules {
+	return FacilityRules{
+		ServiceAccounts: []string{"ingestor"},
+		LocationGroups: []LocationGroupRule{
+			{Pattern: `[^/]*/[^/]*/([^/]*)/([^/]*)`, Group: "$1$2", Lowercase: true},
+		},
+		InstrumentAccounts: []string{"sls*", "swissfel*", "sinq*", "smus*"},
+	}
+}
+
+// Validate checks that all patterns of the rules can be compiled.
+func (r FacilityRules) Validate() error {
+	for _, rule := range r.LocationGroups {
+		if _, err := rule.compile(); err != nil {
+			return err
+		}
+	}
+	for _, pattern := range r.InstrumentAccounts {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid instrument account pattern %q: %v", pattern, err)
+		}
+	}
+	return nil
+}
+
+func (rule LocationGroupRule) compile() (*regexp.Regexp, error) {
+	re, err := regexp.Compile("^(?:" + rule.Pattern + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid creationLocation pattern %q: %v", rule.Pattern, err)
+	}
+	return re, nil
+}
+
+// LocationGroup returns the access group of the instrument at creationLocation, or false if no
+// rule matches it.
+func (r FacilityRules) LocationGroup(creationLocation string) (string, bool, error) {
+	for _, rule := range r.LocationGroups {
+		re, err := rule.compile()
+		if err != nil {
+			return "", false, err
+		}
+		match := re.FindStringSubmatchIndex(creationLocation)
+		if match == nil {
+			continue
+		}
+		group := string(re.ExpandString(nil, rule.Group, creationLocation, match))
+		if rule.Lowercase {
+			group = strings.ToLower(group)
+		}
+		return group, true, nil
+	}
+	return "", false, nil
+}
+
+// IsServiceAccount reports whether user may ingest for any ownerGroup.
+func (r FacilityRules) IsServiceAccount(user datasetUtils.User) bool {
+	for _, account := range r.ServiceAccounts {
+		if user.DisplayName == account {
+			return true
+		}
+	}
+	return false
+}
+
+// IsInstrumentAccount reports whether user is the account of an instrument.
+func (r FacilityRules) IsInstrumentAccount(user datasetUtils.User) bool {
+	for _, pattern := range r.InstrumentAccounts {
+		if ok, _ := path.Match(pattern, user.DisplayName); ok {
+			return true
+		}
+	}
+	return false
+}
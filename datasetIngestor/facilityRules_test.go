@@ -0,0 +1,80 @@
+package datasetIngestor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/paulscherrerinstitute/scicat-cli/v3/datasetUtils"
+)
+
+func TestDefaultFacilityRules(t *testing.T) {
+	rules := DefaultFacilityRules()
+	if err := rules.Validate(); err != nil {
+		t.Fatalf("the default rules should be valid: %v", err)
+	}
+
+	tests := []struct {
+		creationLocation string
+		wantGroup        string
+		wantFound        bool
+	}{
+		{"/PSI/SLS/TOMCAT", "slstomcat", true},
+		{"/PSI/SWISSFEL/Bernina", "swissfelbernina", true},
+		{"/PSI/SLS", "", false},
+		{"/PSI/SLS/CSAXS/extra", "", false},
+	}
+	for _, tt := range tests {
+		group, found, err := rules.LocationGroup(tt.creationLocation)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if group != tt.wantGroup || found != tt.wantFound {
+			t.Errorf("LocationGroup(%q) = %q, %v, want %q, %v", tt.creationLocation, group, found, tt.wantGroup, tt.wantFound)
+		}
+	}
+
+	if !rules.IsInstrumentAccount(datasetUtils.User{DisplayName: "slscsaxs"}) {
+		t.Error("slscsaxs should be an instrument account")
+	}
+	if rules.IsInstrumentAccount(datasetUtils.User{DisplayName: "someuser"}) {
+		t.Error("someuser should not be an instrument account")
+	}
+}
+
+func TestCustomFacilityRules(t *testing.T) {
+	rules := FacilityRules{
+		LocationGroups: []LocationGroupRule{
+			{Pattern: `ESS/(?P<instrument>[A-Za-z]+)`, Group: "instr-${instrument}"},
+		},
+		InstrumentAccounts: []string{"instr-*"},
+	}
+	if err := rules.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	metaDataMap := map[string]interface{}{"ownerGroup": "p1234", "creationLocation": "ESS/LoKI"}
+	user := datasetUtils.User{DisplayName: "loki", AccessGroups: []string{"instr-LoKI"}}
+	beamlineAccount, err := CheckUserAndOwnerGroup(user, rules, metaDataMap)
+	if err != nil || !beamlineAccount {
+		t.Errorf("expected ingest as instrument account, got %v, %v", beamlineAccount, err)
+	}
+
+	// PSI specific accounts are unknown to these rules
+	user = datasetUtils.User{DisplayName: "slscsaxs"}
+	if _, err := CheckUserAndOwnerGroup(user, rules, map[string]interface{}{"ownerGroup": "p1234"}); err == nil {
+		t.Error("expected an error for an account not matching the instrument accounts")
+	}
+	if _, err := CheckUserAndOwnerGroup(datasetUtils.User{DisplayName: "ingestor"}, rules, metaDataMap); err == nil {
+		t.Error("the ingestor account has no special rights without a service-accounts rule")
+	}
+
+	err = GatherMissingMetadata(context.Background(), user, rules, map[string]interface{}{"creationLocation": "PSI/SLS/TOMCAT", "type": "derived"}, nil, "")
+	if err != nil {
+		t.Errorf("an unmatched creationLocation should not be an error: %v", err)
+	}
+
+	invalid := FacilityRules{LocationGroups: []LocationGroupRule{{Pattern: "(", Group: "x"}}}
+	if err := invalid.Validate(); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+}
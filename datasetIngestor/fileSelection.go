@@ -0,0 +1,170 @@
+package datasetIngestor
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// IgnoreFileName is the name of the file in a sourceFolder listing gitignore-style patterns of
+// the files that aren't part of the dataset.
+const IgnoreFileName = ".scicatignore"
+
+/*
+FileSelection selects the files of a dataset by gitignore-style patterns:
+  - "*", "?" and "[...]" match within a path component, "**" matches any number of components.
+  - A pattern without a "/" (other than a trailing one) matches the name at any depth, others
+    are relative to the sourceFolder, e.g. "/raw/*.h5".
+  - A trailing "/" matches directories only, e.g. ".snapshot/".
+  - A leading "!" re-includes what an earlier exclude pattern excluded; the last matching
+    pattern decides.
+
+An excluded directory isn't read at all, so nothing below it can be re-included. If there are
+include patterns, only the files and directories matching one of them, or lying below a
+matching directory, are kept; the other directories are still searched for matching files.
+*/
+type FileSelection struct {
+	include []selectionPattern
+	exclude []selectionPattern
+}
+
+type selectionPattern struct {
+	negate   bool
+	dirOnly  bool
+	segments []string
+}
+
+// NewFileSelection returns the selection of the include and exclude patterns. It returns nil if
+// there are no patterns at all, a nil selection keeps all files.
+func NewFileSelection(include []string, exclude []string) (*FileSelection, error) {
+	s := &FileSelection{}
+	for _, line := range include {
+		if err := s.add(&s.include, line); err != nil {
+			return nil, err
+		}
+	}
+	for _, line := range exclude {
+		if err := s.add(&s.exclude, line); err != nil {
+			return nil, err
+		}
+	}
+	if len(s.include) == 0 && len(s.exclude) == 0 {
+		return nil, nil
+	}
+	return s, nil
+}
+
+// readIgnoreFile returns the exclude patterns in the IgnoreFileName of sourceFolder, none if
+// there's no such file.
+func readIgnoreFile(sourceFolder string) ([]string, error) {
+	file, err := os.Open(filepath.Join(sourceFolder, IgnoreFileName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
+}
+
+func (s *FileSelection) add(patterns *[]selectionPattern, pattern string) error {
+	line := strings.TrimRight(pattern, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil
+	}
+	var p selectionPattern
+	if strings.HasPrefix(line, "!") {
+		p.negate = true
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return fmt.Errorf("invalid file selection pattern %q", pattern)
+	}
+	if !strings.Contains(line, "/") {
+		// matches at any depth
+		line = "**/" + line
+	}
+	line = strings.TrimPrefix(line, "/")
+	p.segments = strings.Split(line, "/")
+	for _, segment := range p.segments {
+		if _, err := path.Match(segment, ""); err != nil {
+			return fmt.Errorf("invalid file selection pattern %q: %w", pattern, err)
+		}
+	}
+	*patterns = append(*patterns, p)
+	return nil
+}
+
+// Excluded tells if the exclude patterns exclude relPath, a slash separated path relative to the
+// sourceFolder.
+func (s *FileSelection) Excluded(relPath string, isDir bool) bool {
+	if s == nil {
+		return false
+	}
+	excluded, _ := lastMatch(s.exclude, strings.Split(relPath, "/"), isDir)
+	return excluded
+}
+
+// Included tells if relPath, or one of the directories it's in, matches the include patterns.
+func (s *FileSelection) Included(relPath string, isDir bool) bool {
+	if s == nil || len(s.include) == 0 {
+		return true
+	}
+	segments := strings.Split(relPath, "/")
+	for n := len(segments); n > 0; n-- {
+		if included, matched := lastMatch(s.include, segments[:n], isDir || n < len(segments)); matched {
+			return included
+		}
+	}
+	return false
+}
+
+// lastMatch returns the result of the last pattern matching segments, matched is false if none does.
+func lastMatch(patterns []selectionPattern, segments []string, isDir bool) (result bool, matched bool) {
+	for _, p := range patterns {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		if matchSegments(p.segments, segments) {
+			result, matched = !p.negate, true
+		}
+	}
+	return result, matched
+}
+
+func matchSegments(pattern []string, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], segments[1:])
+}
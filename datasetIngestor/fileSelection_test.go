@@ -0,0 +1,103 @@
+package datasetIngestor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileSelection(t *testing.T) {
+	tests := []struct {
+		name     string
+		include  []string
+		exclude  []string
+		path     string
+		isDir    bool
+		excluded bool
+		included bool
+	}{
+		{name: "no patterns", path: "a/b.h5", included: true},
+		{name: "name at any depth", exclude: []string{"*.tmp"}, path: "a/b/c.tmp", excluded: true, included: true},
+		{name: "anchored pattern", exclude: []string{"/raw/*.h5"}, path: "a/raw/x.h5", included: true},
+		{name: "anchored pattern at the root", exclude: []string{"/raw/*.h5"}, path: "raw/x.h5", excluded: true, included: true},
+		{name: "double star", exclude: []string{"logs/**/*.txt"}, path: "logs/1/2/x.txt", excluded: true, included: true},
+		{name: "directory only pattern on a file", exclude: []string{".snapshot/"}, path: ".snapshot", included: true},
+		{name: "directory only pattern on a directory", exclude: []string{".snapshot/"}, path: "a/.snapshot", isDir: true, excluded: true, included: true},
+		{name: "negated pattern", exclude: []string{"*.tmp", "!keep.tmp"}, path: "keep.tmp", included: true},
+		{name: "last pattern wins", exclude: []string{"!keep.tmp", "*.tmp"}, path: "keep.tmp", excluded: true, included: true},
+		{name: "comments and blank lines", exclude: []string{"# *.h5", "", "  "}, path: "x.h5", included: true},
+		{name: "included file", include: []string{"*.h5"}, path: "a/x.h5", included: true},
+		{name: "not included file", include: []string{"*.h5"}, path: "a/x.txt"},
+		{name: "file below an included directory", include: []string{"raw/"}, path: "raw/a/x.txt", included: true},
+		{name: "negated include", include: []string{"*.h5", "!dark_*.h5"}, path: "dark_1.h5"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			selection, err := NewFileSelection(test.include, test.exclude)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if excluded := selection.Excluded(test.path, test.isDir); excluded != test.excluded {
+				t.Errorf("Excluded(%q) = %v, want %v", test.path, excluded, test.excluded)
+			}
+			if included := selection.Included(test.path, test.isDir); included != test.included {
+				t.Errorf("Included(%q) = %v, want %v", test.path, included, test.included)
+			}
+		})
+	}
+
+	t.Run("invalid pattern", func(t *testing.T) {
+		for _, pattern := range []string{"[a-", "!/"} {
+			if _, err := NewFileSelection(nil, []string{pattern}); err == nil {
+				t.Errorf("expected an error for %q", pattern)
+			}
+		}
+	})
+}
+
+func TestGetLocalFileListSelection(t *testing.T) {
+	tempDir := t.TempDir()
+	for _, dir := range []string{"raw/.snapshot", "raw/sub", "logs"} {
+		if err := os.MkdirAll(filepath.Join(tempDir, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"raw/1.h5", "raw/sub/2.h5", "raw/sub/2.tmp", "raw/.snapshot/1.h5", "logs/run.log", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte("test"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, IgnoreFileName), []byte("# scratch files\n*.tmp\n.snapshot/\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// unreadable, so the scan fails if it's read despite being excluded
+	if err := os.Chmod(filepath.Join(tempDir, "raw", ".snapshot"), 0); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(filepath.Join(tempDir, "raw", ".snapshot"), 0755)
+
+	var checked []string
+	filenameCheckCallback := func(path string) bool {
+		checked = append(checked, path)
+		return true
+	}
+	files, _, _, _, _, _, err := GetLocalFileList(context.Background(), tempDir, "", nil, filenameCheckCallback, ScanOptions{
+		Include: []string{"/raw/", "*.txt"},
+		Exclude: []string{"notes.txt"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got []string
+	for _, file := range files {
+		got = append(got, file.Path)
+	}
+	if want := "raw,raw/1.h5,raw/sub,raw/sub/2.h5"; strings.Join(got, ",") != want {
+		t.Errorf("got %v, want %s", got, want)
+	}
+	if strings.Join(checked, ",") != strings.Join(got, ",") {
+		t.Errorf("filename callback got %v, expected only the selected files", checked)
+	}
+}
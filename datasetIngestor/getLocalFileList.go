@@ -3,6 +3,7 @@ package datasetIngestor
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -21,9 +22,29 @@ type Datafile struct {
 	Perm      string `json:"perm"`
 	Size      int64  `json:"size"`
 	Time      string `json:"time"`
+	Chk       string `json:"chk,omitempty"` // checksum of a regular file, computed with ChkAlg
+	ChkAlg    string `json:"-"`             // sent once per origdatablock
 	IsSymlink bool   `json:"-"`
 }
 
+// ScanOptions are the optional parts of a file scan.
+type ScanOptions struct {
+	// ChecksumAlgorithm is the algorithm of the checksums computed for all regular files, one of
+	// ChecksumAlgorithms. No checksums are computed if it's empty.
+	ChecksumAlgorithm string
+	// ChecksumWorkers is the number of files hashed in parallel, DefaultChecksumWorkers if 0.
+	ChecksumWorkers int
+	// ScanWorkers is the number of directories read in parallel, DefaultScanWorkers if 0.
+	ScanWorkers int
+	// Include and Exclude are gitignore-style patterns selecting the files, see FileSelection. The
+	// patterns in the IgnoreFileName of the sourceFolder are excluded before Exclude.
+	Include []string
+	Exclude []string
+	// OnFile, if set, gets the kept files in scan order, each once its checksum is computed,
+	// instead of collecting them in the returned file list. An error stops the scan.
+	OnFile func(file Datafile) error
+}
+
 const windows = "windows"
 
 // readLines reads a whole file into memory
@@ -84,10 +105,26 @@ func (w *IllegalFileNamesWarning) Error() string {
 	return fmt.Sprintf("Total number of illegal file names skipped:%v", w.Count)
 }
 
+// IllegalFileNamesError indicates that a dataset has files whose names were rejected by a
+// FilenamePolicy in the IllegalFilenamesFail mode.
+type IllegalFileNamesError struct {
+	SourceFolder string
+	Files        []RejectedFile
+}
+
+func (e *IllegalFileNamesError) Error() string {
+	msg := fmt.Sprintf("%q dataset cannot be ingested - %d files have illegal names", e.SourceFolder, len(e.Files))
+	for _, file := range e.Files {
+		msg += fmt.Sprintf("\n  %s: %s", file.Path, file.Reason)
+	}
+	return msg
+}
+
 /*
 GetLocalFileList scans a source folder and optionally a file listing, and returns a list of data files, the earliest and latest modification times, the owner, the number of files, and the total size of the files.
 
 Parameters:
+- ctx: Cancelling it stops the scan with the error of ctx.
 - sourceFolder: The path to the source folder to scan.
 - filelistingPath: The path to a file listing to use. If this is an empty string, the function scans the entire source folder.
 - skip: A pointer to a string that controls how the function handles symbolic links. The string can have the following values:
@@ -96,6 +133,8 @@ Parameters:
   - "dA", "da": Keep symbolic links that point to the source folder, skip others.
   - "": The function asks the user how to handle each symbolic link.
 
+- options: Selects the checksums of the files, they're computed by a pool of workers while the scan goes on, the number of directories read in parallel, the include and exclude patterns selecting the files, and whether the files are streamed to a callback.
+
 Returns:
 - fullFileArray: A slice of Datafile structs, each representing a file in the source folder or file listing.
 - startTime: The earliest modification time of the files.
@@ -104,9 +143,12 @@ Returns:
 - numFiles: The number of files.
 - totalSize: The total size of the files.
 
-The function logs an error and returns if it cannot change the working directory to the source folder.
+The entries are returned in the order of filepath.Walk, and the callbacks are called in that order from a
+single goroutine, however many directories are read in parallel. The symlink callback gets the absolute path
+of the link, the filename callback the path relative to the source folder. The working directory is left
+untouched. The function returns an error if the source folder doesn't exist.
 */
-func GetLocalFileList(sourceFolder string, filelistingPath string, symlinkCallback func(symlinkPath string, sourceFolder string) (bool, error), filenameCheckCallback func(filepath string) bool) (fullFileArray []Datafile, startTime time.Time, endTime time.Time, owner string, numFiles int64, totalSize int64, err error) {
+func GetLocalFileList(ctx context.Context, sourceFolder string, filelistingPath string, symlinkCallback func(symlinkPath string, sourceFolder string) (bool, error), filenameCheckCallback func(filepath string) bool, options ScanOptions) (fullFileArray []Datafile, startTime time.Time, endTime time.Time, owner string, numFiles int64, totalSize int64, err error) {
 	// scan all lines
 	//fmt.Println("sourceFolder,listing:", sourceFolder, filelistingPath)
 	fullFileArray = make([]Datafile, 0)
@@ -131,13 +173,6 @@ func GetLocalFileList(sourceFolder string, filelistingPath string, symlinkCallba
 	// TODO verify that filelisting have no overlap, e.g. no lines X/ and X/Y,
 	// because the latter is already contained in X/
 
-	// restore oldWorkDir after function
-	oldWorkDir, err := os.Getwd()
-	if err != nil {
-		return []Datafile{}, time.Time{}, time.Time{}, "", 0, 0, err
-	}
-
-	defer os.Chdir(oldWorkDir)
 	// for windows source path add colon in the leading drive character
 	// windowsSource := strings.Replace(sourceFolder, "/C/", "C:/", 1)
 	if runtime.GOOS == windows {
@@ -145,88 +180,165 @@ func GetLocalFileList(sourceFolder string, filelistingPath string, symlinkCallba
 		sourceFolder = re.ReplaceAllString(sourceFolder, "$1:/")
 	}
 
-	if err := os.Chdir(sourceFolder); err != nil {
+	absSourceFolder, err := filepath.Abs(sourceFolder)
+	if err != nil {
 		return []Datafile{}, time.Time{}, time.Time{}, "", 0, 0, err
 	}
-	/*dir, err := os.Getwd()
+	if _, err := os.Stat(absSourceFolder); err != nil {
+		return []Datafile{}, time.Time{}, time.Time{}, "", 0, 0, err
+	}
+
+	var checksums *checksumPool
+	if options.ChecksumAlgorithm != "" {
+		checksums, err = newChecksumPool(ctx, options.ChecksumAlgorithm, options.ChecksumWorkers)
+		if err != nil {
+			return []Datafile{}, time.Time{}, time.Time{}, "", 0, 0, err
+		}
+		defer checksums.stop()
+	}
+	var emitter *fileEmitter
+	if options.OnFile != nil {
+		emitter = &fileEmitter{onFile: options.OnFile, checksums: checksums, alg: options.ChecksumAlgorithm}
+	}
+
+	ignored, err := readIgnoreFile(absSourceFolder)
 	if err != nil {
-		return fullFileArray, startTime, endTime, owner, numFiles, totalSize, err
+		return []Datafile{}, time.Time{}, time.Time{}, "", 0, 0, fmt.Errorf("can't read the %s of %q: %w", IgnoreFileName, sourceFolder, err)
+	}
+	selection, err := NewFileSelection(options.Include, append(ignored, options.Exclude...))
+	if err != nil {
+		return []Datafile{}, time.Time{}, time.Time{}, "", 0, 0, err
 	}
-	log.Printf("Scanning source folder: %s at %s", sourceFolder, dir)*/
 
+	// queue all listed folders first, so they're read in parallel while the entries are visited
+	scanner := newDirScanner(ctx, options.ScanWorkers, selection)
+	defer scanner.stop()
+	var roots []scanEntry
 	for _, line := range lines {
 		if len(line) == 0 {
 			continue
 		}
+		path := filepath.Clean(line)
+		absPath := path
+		if !filepath.IsAbs(path) {
+			absPath = filepath.Join(absSourceFolder, path)
+		}
+		info, err := os.Lstat(absPath)
+		if err != nil {
+			// like filepath.Walk, listed files that don't exist are ignored
+			continue
+		}
+		if path != "." && selection.Excluded(filepath.ToSlash(path), info.IsDir()) {
+			continue
+		}
+		roots = append(roots, scanEntry{path: path, absPath: absPath, info: info})
+	}
+	for i := len(roots) - 1; i >= 0; i-- {
+		if roots[i].info.IsDir() {
+			roots[i].dir = scanner.queue(roots[i].path, roots[i].absPath)
+		}
+	}
+
+	visit := func(entry scanEntry) error {
+		// stop scanning when cancelled, e.g. by Ctrl-C
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		f := entry.info
+		// ignore ./ (but keep other dot files)
+		if entry.path == "." {
+			return nil
+		}
 
-		// spin.Start() // Start the spinner
-		err := filepath.Walk(line, func(path string, f os.FileInfo, err error) error {
-			// ignore ./ (but keep other dot files)
-			if f == nil || f.Name() == "" {
-				//log.Printf("Skipping file or directory %s", path)
-				return nil
-			}
-			if f.IsDir() && f.Name() == "." {
-				return nil
-			}
+		// replace backslashes for windows path
+		modpath := filepath.ToSlash(entry.path)
+		// directories that aren't included are still searched for included files
+		if !selection.Included(modpath, f.IsDir()) {
+			return nil
+		}
 
-			// extract OS dependent owner IDs and translate to names
+		// extract OS dependent owner IDs and translate to names
+		uidName, gidName := GetFileOwner(f)
+		fileStruct := Datafile{Path: modpath, User: uidName, Group: gidName, Perm: f.Mode().String(), Size: f.Size(), Time: f.ModTime().Format(time.RFC3339), IsSymlink: false}
+		keep := true
+
+		// * handle symlinks *
+		if f.Mode()&os.ModeSymlink != 0 {
+			var err error
+			if symlinkCallback != nil {
+				keep, err = symlinkCallback(entry.absPath, sourceFolder)
+			} else {
+				keep, err = handleSymlink(entry.absPath, sourceFolder)
+			}
 			if err != nil {
-				// stop function if err given by Walk is not nil
 				return err
 			}
-			uidName, gidName := GetFileOwner(f)
-			// replace backslashes for windows path
-			modpath := path
-			if runtime.GOOS == windows {
-				modpath = strings.Replace(path, "\\", "/", -1)
-			}
-			fileStruct := Datafile{Path: modpath, User: uidName, Group: gidName, Perm: f.Mode().String(), Size: f.Size(), Time: f.ModTime().Format(time.RFC3339), IsSymlink: false}
-			keep := true
-
-			// * handle symlinks *
-			if f.Mode()&os.ModeSymlink != 0 {
-				if symlinkCallback != nil {
-					keep, err = symlinkCallback(modpath, sourceFolder)
-				} else {
-					keep, err = handleSymlink(modpath, sourceFolder)
+			fileStruct.IsSymlink = true
+		}
+
+		// filter invalid filenames if callback was set
+		if filenameCheckCallback != nil {
+			keep = keep && filenameCheckCallback(modpath)
+		}
+
+		if keep {
+			index := int(numFiles)
+			numFiles++
+			totalSize += f.Size()
+			hashed := checksums != nil && f.Mode().IsRegular()
+			if hashed {
+				if err := checksums.add(index, entry.absPath); err != nil {
+					return err
 				}
-				if err != nil {
+			}
+			if emitter != nil {
+				if err := emitter.add(fileStruct, hashed); err != nil {
 					return err
 				}
-				fileStruct.IsSymlink = true
+			} else {
+				fullFileArray = append(fullFileArray, fileStruct)
 			}
-
-			// filter invalid filenames if callback was set
-			if filenameCheckCallback != nil {
-				keep = keep && filenameCheckCallback(modpath)
+			// find out earlist creation time
+			modTime := f.ModTime()
+			diff := modTime.Sub(startTime)
+			if diff < (time.Duration(0) * time.Second) {
+				startTime = modTime
 			}
-
-			if keep {
-				numFiles++
-				totalSize += f.Size()
-				fullFileArray = append(fullFileArray, fileStruct)
-				// find out earlist creation time
-				modTime := f.ModTime()
-				diff := modTime.Sub(startTime)
-				if diff < (time.Duration(0) * time.Second) {
-					startTime = modTime
-				}
-				diff = modTime.Sub(endTime)
-				if diff > (time.Duration(0) * time.Second) {
-					endTime = modTime
-				}
-				owner = gidName
+			diff = modTime.Sub(endTime)
+			if diff > (time.Duration(0) * time.Second) {
+				endTime = modTime
 			}
+			owner = gidName
+		}
+		return nil
+	}
 
-			return err
-		})
-
+	for _, root := range roots {
+		err := visit(root)
+		if err == nil && root.dir != nil {
+			err = scanner.walk(root.dir, visit)
+		}
 		if err != nil {
-			return []Datafile{}, time.Time{}, time.Time{}, "", 0, 0, fmt.Errorf("file walk returned error: %v", err)
+			return []Datafile{}, time.Time{}, time.Time{}, "", 0, 0, fmt.Errorf("file walk returned error: %w", err)
+		}
+	}
+	if checksums != nil {
+		chks, err := checksums.wait()
+		if err != nil {
+			return []Datafile{}, time.Time{}, time.Time{}, "", 0, 0, err
+		}
+		if emitter != nil {
+			err = emitter.finish(chks)
+		} else {
+			for i, chk := range chks {
+				fullFileArray[i].Chk = chk
+				fullFileArray[i].ChkAlg = options.ChecksumAlgorithm
+			}
+		}
+		if err != nil {
+			return []Datafile{}, time.Time{}, time.Time{}, "", 0, 0, err
 		}
 	}
-	// spin.Stop()
 	return fullFileArray, startTime, endTime, owner, numFiles, totalSize, err
 }
 
@@ -260,12 +372,12 @@ than datasetUtils.DefaultIngestSizeLimits.TotalMaxFiles. It returns an *EmptyDat
 *TooManyFilesError when one of those conditions is met, so callers share the same validation
 rules and error types.
 */
-func GetValidatedLocalFileList(sourceFolder string, filelistingPath string,
+func GetValidatedLocalFileList(ctx context.Context, sourceFolder string, filelistingPath string,
 	symlinkCallback func(symlinkPath string, sourceFolder string) (bool, error),
-	filenameFilterCallback func(filepath string) bool,
+	filenameFilterCallback func(filepath string) bool, options ScanOptions,
 ) (fullFileArray []Datafile, startTime time.Time, endTime time.Time, owner string, numFiles int64, totalSize int64, err error) {
 	fullFileArray, startTime, endTime, owner, numFiles, totalSize, err =
-		GetLocalFileList(sourceFolder, filelistingPath, symlinkCallback, filenameFilterCallback)
+		GetLocalFileList(ctx, sourceFolder, filelistingPath, symlinkCallback, filenameFilterCallback, options)
 	if err != nil {
 		return fullFileArray, startTime, endTime, owner, numFiles, totalSize,
 			fmt.Errorf("can't gather the filelist of %q: %w", sourceFolder, err)
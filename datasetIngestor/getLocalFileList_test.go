@@ -1,9 +1,11 @@
 package datasetIngestor
 
 import (
+	"context"
 	"errors"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -27,7 +29,7 @@ func TestAssembleFilelisting(t *testing.T) {
 	}
 
 	// Call AssembleFilelisting on the temporary directory
-	fullFileArray, startTime, endTime, _, numFiles, totalSize, err := GetLocalFileList(tempDir, "", nil, nil)
+	fullFileArray, startTime, endTime, _, numFiles, totalSize, err := GetLocalFileList(context.Background(), tempDir, "", nil, nil, ScanOptions{})
 	if err != nil {
 		t.Errorf("got error: %v", err)
 	}
@@ -110,7 +112,7 @@ func TestGetValidatedLocalFileList(t *testing.T) {
 			t.Fatalf("Failed to create test file: %s", err)
 		}
 
-		fullFileArray, _, _, _, numFiles, totalSize, err := GetValidatedLocalFileList(tempDir, "", nil, nil)
+		fullFileArray, _, _, _, numFiles, totalSize, err := GetValidatedLocalFileList(context.Background(), tempDir, "", nil, nil, ScanOptions{})
 		if err != nil {
 			t.Fatalf("expected no error, got: %v", err)
 		}
@@ -129,7 +131,7 @@ func TestGetValidatedLocalFileList(t *testing.T) {
 		}
 		defer os.RemoveAll(tempDir)
 
-		_, _, _, _, _, _, err = GetValidatedLocalFileList(tempDir, "", nil, nil)
+		_, _, _, _, _, _, err = GetValidatedLocalFileList(context.Background(), tempDir, "", nil, nil, ScanOptions{})
 		var emptyDatasetErr *EmptyDatasetError
 		if !errors.As(err, &emptyDatasetErr) {
 			t.Fatalf("expected an *EmptyDatasetError, got: %v (%T)", err, err)
@@ -137,7 +139,7 @@ func TestGetValidatedLocalFileList(t *testing.T) {
 	})
 
 	t.Run("wraps the underlying error when the sourceFolder does not exist", func(t *testing.T) {
-		_, _, _, _, _, _, err := GetValidatedLocalFileList("./does-not-exist", "", nil, nil)
+		_, _, _, _, _, _, err := GetValidatedLocalFileList(context.Background(), "./does-not-exist", "", nil, nil, ScanOptions{})
 		if err == nil {
 			t.Fatal("expected an error, got nil")
 		}
@@ -174,7 +176,7 @@ func TestGetValidatedLocalFileList(t *testing.T) {
 			t.Fatalf("failed to write file listing: %s", err)
 		}
 
-		_, _, _, _, numFiles, _, err := GetValidatedLocalFileList(tempDir, listingPath, nil, nil)
+		_, _, _, _, numFiles, _, err := GetValidatedLocalFileList(context.Background(), tempDir, listingPath, nil, nil, ScanOptions{})
 		var tooManyErr *TooManyFilesError
 		if !errors.As(err, &tooManyErr) {
 			t.Fatalf("expected a *TooManyFilesError, got: %v (%T)", err, err)
@@ -187,3 +189,97 @@ func TestGetValidatedLocalFileList(t *testing.T) {
 		}
 	})
 }
+
+func TestGetLocalFileListOrder(t *testing.T) {
+	tempDir := t.TempDir()
+	for _, dir := range []string{"b/y/deep", "b/x", "a", "c"} {
+		if err := os.MkdirAll(filepath.Join(tempDir, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"z", "b/y/deep/1", "b/y/2", "b/x/3", "b/4", "a/5", ".hidden"} {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte("test"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(tempDir, "z"), filepath.Join(tempDir, "c", "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	// the order of filepath.Walk, which the scanner used before reading directories in parallel
+	var want []string
+	err := filepath.Walk(tempDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || path == tempDir {
+			return err
+		}
+		rel, err := filepath.Rel(tempDir, path)
+		want = append(want, filepath.ToSlash(rel))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	workDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, workers := range []int{1, 3, 0} {
+		var symlinks, checked []string
+		symlinkCallback := func(symlinkPath string, sourceFolder string) (bool, error) {
+			symlinks = append(symlinks, symlinkPath)
+			return true, nil
+		}
+		filenameCheckCallback := func(path string) bool {
+			checked = append(checked, path)
+			return path != "b/x/3"
+		}
+		files, _, _, _, numFiles, _, err := GetLocalFileList(context.Background(), tempDir, "", symlinkCallback, filenameCheckCallback, ScanOptions{ScanWorkers: workers})
+		if err != nil {
+			t.Fatalf("%d workers: unexpected error: %v", workers, err)
+		}
+		var got []string
+		for _, file := range files {
+			got = append(got, file.Path)
+		}
+		if strings.Join(checked, ",") != strings.Join(want, ",") {
+			t.Errorf("%d workers: filename callback order %v, want %v", workers, checked, want)
+		}
+		if int(numFiles) != len(want)-1 || slices.Contains(got, "b/x/3") {
+			t.Errorf("%d workers: expected all but the filtered file, got %v", workers, got)
+		}
+		if len(symlinks) != 1 || symlinks[0] != filepath.Join(tempDir, "c", "link") {
+			t.Errorf("%d workers: symlink callback got %v, want the absolute link path", workers, symlinks)
+		}
+		if dir, _ := os.Getwd(); dir != workDir {
+			t.Errorf("%d workers: working directory changed to %s", workers, dir)
+		}
+	}
+
+	t.Run("file listing", func(t *testing.T) {
+		listingPath := filepath.Join(t.TempDir(), "filelisting.txt")
+		if err := os.WriteFile(listingPath, []byte("b/y/\n\nmissing\nz\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		files, _, _, _, _, _, err := GetLocalFileList(context.Background(), tempDir, listingPath, nil, nil, ScanOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var got []string
+		for _, file := range files {
+			got = append(got, file.Path)
+		}
+		if want := "b/y,b/y/2,b/y/deep,b/y/deep/1,z"; strings.Join(got, ",") != want {
+			t.Errorf("got %v, want %s", got, want)
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, _, _, _, _, _, err := GetLocalFileList(ctx, tempDir, "", nil, nil, ScanOptions{})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected a cancellation error, got %v", err)
+		}
+	})
+}
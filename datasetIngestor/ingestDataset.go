@@ -1,7 +1,7 @@
 package datasetIngestor
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -11,6 +11,7 @@ import (
 
 type FileBlock struct {
 	Size         int64      `json:"size"`
+	ChkAlg       string     `json:"chkAlg,omitempty"`
 	DataFileList []Datafile `json:"dataFileList"`
 	DatasetId    string     `json:"datasetId"`
 }
@@ -19,7 +20,8 @@ type FileBlock struct {
 	createOrigBlock generates a `FileBlock` from a subset of a given `filesArray`.
 
 It takes start and end indices to determine the subset, and a datasetId to associate with the FileBlock.
-The function calculates the total size of all Datafiles in the subset and includes this in the FileBlock.
+The function calculates the total size of all Datafiles in the subset and includes this in the FileBlock,
+along with the checksum algorithm of the files that have a checksum.
 
 Parameters:
 start: The starting index of the subset in the filesArray.
@@ -34,22 +36,27 @@ func createOrigBlock(start int, end int, filesArray []Datafile, datasetId string
 	// accumulate sizes
 	var totalSize int64
 	totalSize = 0
+	chkAlg := ""
 	for i := start; i < end; i++ {
 		totalSize += filesArray[i].Size
+		if filesArray[i].ChkAlg != "" {
+			chkAlg = filesArray[i].ChkAlg
+		}
 	}
 
-	return FileBlock{Size: totalSize, DataFileList: filesArray[start:end], DatasetId: datasetId}
+	return FileBlock{Size: totalSize, ChkAlg: chkAlg, DataFileList: filesArray[start:end], DatasetId: datasetId}
 }
 
 /*
 IngestDataset sends an ingest command to the API server to create a new dataset and associated data blocks.
 
 Parameters:
+ctx: Cancels the requests.
 client: The HTTP client used to send the request.
 APIServer: The URL of the API server.
 metaDataMap: A map containing metadata for the dataset.
 fullFileArray: An array of Datafile objects representing the files in the dataset.
-user: A map containing user information, including the access token.
+user: The user whose access token is used for the requests.
 
 The function first creates a new dataset by sending a POST request to the appropriate endpoint on the API server,
 based on the dataset type specified in metaDataMap. The dataset type can be "raw", "derived", or "base".
@@ -65,53 +72,25 @@ If the total number of files in the dataset exceeds the maximum limit, the funct
 Returns:
 The ID of the created dataset.
 */
-func IngestDataset(client *http.Client, APIServer string, metaDataMap map[string]interface{},
-	fullFileArray []Datafile, user map[string]string) (datasetId string, err error) {
-	datasetId, err = createDataset(client, APIServer, metaDataMap, user)
+func IngestDataset(ctx context.Context, client *http.Client, APIServer string, metaDataMap map[string]interface{},
+	fullFileArray []Datafile, user datasetUtils.User) (datasetId string, err error) {
+	datasetId, err = createDataset(ctx, client, APIServer, metaDataMap, user)
 	if err != nil {
 		return datasetId, err
 	}
-	err = CreateOrigDatablocks(client, APIServer, fullFileArray, datasetId, user)
+	err = CreateOrigDatablocks(ctx, client, APIServer, fullFileArray, datasetId, user)
 
 	return datasetId, err
 }
 
-func createDataset(client *http.Client, APIServer string, metaDataMap map[string]interface{}, user map[string]string) (string, error) {
-	cmm, _ := json.Marshal(metaDataMap)
-	datasetId := ""
-
-	resp, err := sendRequest(client, "POST", APIServer+"/datasets", user["accessToken"], cmm)
+func createDataset(ctx context.Context, client *http.Client, APIServer string, metaDataMap map[string]interface{}, user datasetUtils.User) (string, error) {
+	resp, err := datasetUtils.NewAPIClient(client, APIServer, user.AccessToken).Send(ctx, "POST", "/datasets", nil, metaDataMap)
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("createDataset: Failed to create new dataset: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-		datasetId, err = decodePid(resp)
-		if err != nil {
-			return "", err
-		}
-	} else {
-		return "", fmt.Errorf("createDataset: Failed to create new dataset: status code %v", resp.StatusCode)
-	}
-
-	return datasetId, nil
-}
-
-func sendRequest(client *http.Client, method, url string, accessToken string, body []byte) (*http.Response, error) {
-	req, err := http.NewRequest(method, url, bytes.NewBuffer(body))
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", accessToken))
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-
-	return resp, nil
+	return decodePid(resp)
 }
 
 func decodePid(resp *http.Response) (string, error) {
@@ -131,22 +110,24 @@ func decodePid(resp *http.Response) (string, error) {
 /*
 	createOrigDatablocks sends a series of POST requests to the server to create original data blocks.
 
-It divides the fullFileArray into blocks based on the BlockMaxFiles and BlockMaxBytes limits, and sends a request for each block.
+It divides the fullFileArray into blocks based on the BlockMaxFiles and BlockMaxBytes limits, and sends a request for each block
+through an OrigDatablockBuilder.
 
 Parameters:
 
+ctx: Cancelling it stops adding blocks, the blocks that were added before remain.
 client: The HTTP client used to send the requests.
 APIServer: The base URL of the API server.
 fullFileArray: An array of Datafile objects representing the files in the dataset.
 datasetId: The ID of the dataset.
-user: A map containing user information. The "accessToken" key should contain the user's access token.
+user: The user whose access token is used for the requests.
 
 If the total number of files exceeds TotalMaxFiles, the function logs a fatal error.
-If a request receives a response with a status code other than 200, the function logs a fatal error.
+If a request fails, the function returns an error stating how many files were added before; failing requests are retried by the API client.
 
 The function logs a message for each created data block, including the start and end file, the total size, and the number of files in the block.
 */
-func CreateOrigDatablocks(client *http.Client, APIServer string, fullFileArray []Datafile, datasetId string, user map[string]string) error {
+func CreateOrigDatablocks(ctx context.Context, client *http.Client, APIServer string, fullFileArray []Datafile, datasetId string, user datasetUtils.User) error {
 	limits := datasetUtils.DefaultIngestSizeLimits
 	totalFiles := len(fullFileArray)
 
@@ -156,29 +137,11 @@ func CreateOrigDatablocks(client *http.Client, APIServer string, fullFileArray [
 			totalFiles, limits.TotalMaxFiles)
 	}
 
-	end := 0
-	var blockBytes int64
-	for start := 0; end < totalFiles; {
-		blockBytes = 0
-
-		for end = start; end-start < limits.BlockMaxFiles && blockBytes < limits.BlockMaxBytes && end < totalFiles; {
-			blockBytes += fullFileArray[end].Size
-			end++
+	builder := NewOrigDatablockBuilder(ctx, client, APIServer, datasetId, user)
+	for _, file := range fullFileArray {
+		if err := builder.Add(file); err != nil {
+			break
 		}
-		origBlock := createOrigBlock(start, end, fullFileArray, datasetId)
-
-		payloadString, _ := json.Marshal(origBlock)
-		resp, err := sendRequest(client, "POST", APIServer+"/origdatablocks", user["accessToken"], payloadString)
-		if err != nil {
-			return err
-		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode >= 300 || resp.StatusCode < 200 {
-			return fmt.Errorf("unexpected response code \"%v\" when adding origDatablock for dataset id: \"%v\"", resp.Status, datasetId)
-		}
-
-		start = end
 	}
-	return nil
+	return builder.Close()
 }
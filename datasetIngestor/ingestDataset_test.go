@@ -1,6 +1,7 @@
 package datasetIngestor
 
 import (
+	"context"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -54,11 +55,11 @@ func TestSendIngestCommand(t *testing.T) {
 		},
 	}
 
-	// Mock user map
+	// Mock user
 	const token = "sometoken"
-	user := map[string]string{
-		"displayName": "test user",
-		"accessToken": token,
+	user := datasetUtils.User{
+		DisplayName: "test user",
+		AccessToken: token,
 	}
 
 	// Mock metaDataMap
@@ -94,7 +95,7 @@ func TestSendIngestCommand(t *testing.T) {
 	defer server.Close()
 
 	// Call SendIngestCommand function with the mock server's URL and check the returned dataset ID
-	datasetId, err := IngestDataset(client, server.URL, metaDataMap, datafiles, user)
+	datasetId, err := IngestDataset(context.Background(), client, server.URL, metaDataMap, datafiles, user)
 	if err != nil {
 		t.Errorf("received unexpected error: %v", err)
 	}
@@ -103,29 +104,6 @@ func TestSendIngestCommand(t *testing.T) {
 	}
 }
 
-func TestSendRequest(t *testing.T) {
-	// Create a test server
-	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-	}))
-	defer ts.Close()
-
-	// Create a test client
-	client := &http.Client{}
-
-	// Call the sendRequest function
-	resp, err := sendRequest(client, "GET", ts.URL, "", nil)
-	if err != nil {
-		t.Errorf("received unexpected error: %v", err)
-	}
-	defer resp.Body.Close()
-
-	// Check the response
-	if resp.StatusCode != http.StatusOK {
-		t.Errorf("sendRequest() returned status %d; want %d", resp.StatusCode, http.StatusOK)
-	}
-}
-
 func TestDecodePid(t *testing.T) {
 	// Create a test response
 	resp := &http.Response{
@@ -198,12 +176,12 @@ func TestCreateOrigDatablocks(t *testing.T) {
 			client := server.Client()
 
 			// Define user data
-			user := map[string]string{
-				"accessToken": "testToken",
+			user := datasetUtils.User{
+				AccessToken: "testToken",
 			}
 
 			// Call the function with test data
-			CreateOrigDatablocks(client, server.URL, tc.datafiles, "testDatasetId", user)
+			CreateOrigDatablocks(context.Background(), client, server.URL, tc.datafiles, "testDatasetId", user)
 
 			// Check if the correct number of requests were made
 			if numRequests != tc.expectedRequests {
@@ -2,10 +2,14 @@ package datasetIngestor
 
 import (
 	"bufio"
+	"errors"
+	"fmt"
 	"log"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/fatih/color"
 )
@@ -92,28 +96,154 @@ blanks (used as a column separator in messages). illegalFileNamesCounter, if non
 incremented for every rejected file so callers can report a summary count.
 */
 func CreateLocalFilenameFilterCallback(illegalFileNamesCounter *uint) func(filepath string) bool {
-	return func(filepath string) (keep bool) {
-		keep = true
-		// make sure that filenames do not contain characters like "\" or "*"
-		if strings.ContainsAny(filepath, "*\\") {
-			color.Set(color.FgRed)
-			log.Printf("Warning: the file %s contains illegal characters like *,\\ and will not be archived.", filepath)
-			color.Unset()
-			if illegalFileNamesCounter != nil {
-				*illegalFileNamesCounter++
-			}
-			keep = false
+	filter, _ := NewFilenameFilter(FilenamePolicy{}, illegalFileNamesCounter)
+	return filter.Check
+}
+
+// IllegalFilenameMode selects what happens to the files whose names a FilenamePolicy rejects.
+type IllegalFilenameMode string
+
+const (
+	// IllegalFilenamesSkip leaves the rejected files out of the dataset, with a warning for each.
+	IllegalFilenamesSkip IllegalFilenameMode = "skip"
+	// IllegalFilenamesFail aborts the ingestion of a dataset with rejected files before it's created.
+	IllegalFilenamesFail IllegalFilenameMode = "fail"
+	// IllegalFilenamesReport skips the rejected files like IllegalFilenamesSkip, and writes their
+	// paths and the reasons to the FilenamePolicy's ReportPath.
+	IllegalFilenamesReport IllegalFilenameMode = "report"
+)
+
+// ParseIllegalFilenameMode returns the IllegalFilenameMode called s.
+func ParseIllegalFilenameMode(s string) (IllegalFilenameMode, error) {
+	switch mode := IllegalFilenameMode(s); mode {
+	case IllegalFilenamesSkip, IllegalFilenamesFail, IllegalFilenamesReport:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown mode %q for illegal file names, use one of: skip, fail, report", s)
+}
+
+/*
+FilenamePolicy holds the rules for the file names of a dataset. Names containing "*" or "\", or
+three consecutive blanks, are always rejected. The zero policy skips just these.
+*/
+type FilenamePolicy struct {
+	// ForbiddenChars are rejected in addition to "*" and "\"
+	ForbiddenChars string
+	// MaxPathLength is the maximum length in bytes of a path relative to the sourceFolder, 0 means no limit
+	MaxPathLength int
+	// RejectNonUTF8 rejects the paths which aren't valid UTF-8
+	RejectNonUTF8 bool
+	// Mode is IllegalFilenamesSkip if empty
+	Mode IllegalFilenameMode
+	// ReportPath is the file the rejected paths are written to by IllegalFilenamesReport
+	ReportPath string
+}
+
+// RejectedFile is a file whose name was rejected by a FilenamePolicy.
+type RejectedFile struct {
+	Path   string
+	Reason string
+}
+
+/*
+FilenameFilter applies a FilenamePolicy to the files of a dataset: its Check method is the
+filenameCheckCallback of GetLocalFileList, and Finish must be called once the scan of a sourceFolder
+is done, to fail or to write the report depending on the policy's Mode.
+*/
+type FilenameFilter struct {
+	policy   FilenamePolicy
+	counter  *uint
+	rejected []RejectedFile
+}
+
+// NewFilenameFilter returns the filter for policy. illegalFileNamesCounter, if non-nil, is incremented
+// for every rejected file. With IllegalFilenamesReport the report is truncated, so it lists the
+// rejected files of the datasets checked by this filter only.
+func NewFilenameFilter(policy FilenamePolicy, illegalFileNamesCounter *uint) (*FilenameFilter, error) {
+	if policy.Mode == "" {
+		policy.Mode = IllegalFilenamesSkip
+	}
+	if policy.Mode == IllegalFilenamesReport {
+		if policy.ReportPath == "" {
+			return nil, errors.New("the report of illegal file names needs a path")
 		}
-		// and check for triple blanks, they are used to separate columns in messages
-		if keep && strings.Contains(filepath, "   ") {
-			color.Set(color.FgRed)
-			log.Printf("Warning: the file %s contains 3 consecutive blanks which is not allowed. The file not be archived.", filepath)
-			color.Unset()
-			if illegalFileNamesCounter != nil {
-				*illegalFileNamesCounter++
+		if err := os.WriteFile(policy.ReportPath, nil, 0644); err != nil {
+			return nil, fmt.Errorf("can't create the report of illegal file names: %w", err)
+		}
+	}
+	return &FilenameFilter{policy: policy, counter: illegalFileNamesCounter}, nil
+}
+
+// Check tells if the file at relPath, relative to the sourceFolder, is kept.
+func (f *FilenameFilter) Check(relPath string) (keep bool) {
+	reason := f.reject(relPath)
+	if reason == "" {
+		return true
+	}
+	color.Set(color.FgRed)
+	log.Printf("Warning: the file %s %s. The file will not be archived.", relPath, reason)
+	color.Unset()
+	if f.counter != nil {
+		*f.counter++
+	}
+	f.rejected = append(f.rejected, RejectedFile{Path: relPath, Reason: reason})
+	return false
+}
+
+// reject returns why relPath is rejected, an empty string if it's kept.
+func (f *FilenameFilter) reject(relPath string) string {
+	// make sure that filenames do not contain characters like "\" or "*"
+	if strings.ContainsAny(relPath, "*\\") {
+		return "contains illegal characters like *,\\"
+	}
+	// and check for triple blanks, they are used to separate columns in messages
+	if strings.Contains(relPath, "   ") {
+		return "contains 3 consecutive blanks which is not allowed"
+	}
+	if f.policy.RejectNonUTF8 && !utf8.ValidString(relPath) {
+		return "is not valid UTF-8"
+	}
+	if i := strings.IndexAny(relPath, f.policy.ForbiddenChars); i >= 0 {
+		char, _ := utf8.DecodeRuneInString(relPath[i:])
+		return fmt.Sprintf("contains the forbidden character %q", char)
+	}
+	if f.policy.MaxPathLength > 0 && len(relPath) > f.policy.MaxPathLength {
+		return fmt.Sprintf("is longer than %d bytes", f.policy.MaxPathLength)
+	}
+	return ""
+}
+
+/*
+Finish ends the check of the files in sourceFolder. With IllegalFilenamesFail it returns an
+*IllegalFileNamesError if any file was rejected, with IllegalFilenamesReport it appends the rejected
+files to the report, one "<sourceFolder>/<path>\t<reason>" line each.
+*/
+func (f *FilenameFilter) Finish(sourceFolder string) error {
+	rejected := f.rejected
+	f.rejected = nil
+	if len(rejected) == 0 {
+		return nil
+	}
+	switch f.policy.Mode {
+	case IllegalFilenamesFail:
+		return &IllegalFileNamesError{SourceFolder: sourceFolder, Files: rejected}
+	case IllegalFilenamesReport:
+		report, err := os.OpenFile(f.policy.ReportPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return fmt.Errorf("can't open the report of illegal file names: %w", err)
+		}
+		for _, file := range rejected {
+			if _, err = fmt.Fprintf(report, "%s\t%s\n", path.Join(sourceFolder, file.Path), file.Reason); err != nil {
+				break
 			}
-			keep = false
 		}
-		return keep
+		if closeErr := report.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("can't write the report of illegal file names: %w", err)
+		}
+		log.Printf("The %d illegal file names of %s were written to %s\n", len(rejected), sourceFolder, f.policy.ReportPath)
 	}
+	return nil
 }
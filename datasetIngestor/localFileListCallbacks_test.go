@@ -1,6 +1,7 @@
 package datasetIngestor
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -112,3 +113,92 @@ func TestCreateLocalFilenameFilterCallback(t *testing.T) {
 		})
 	}
 }
+
+func TestFilenameFilter(t *testing.T) {
+	policy := FilenamePolicy{ForbiddenChars: ":?", MaxPathLength: 12, RejectNonUTF8: true}
+	testCases := []struct {
+		name           string
+		filepath       string
+		expectedReason string
+	}{
+		{"plain filename is kept", "some/file.h5", ""},
+		{"forbidden character is rejected", "run:1.h5", `contains the forbidden character ':'`},
+		{"long path is rejected", "some/long/file.h5", "is longer than 12 bytes"},
+		{"non UTF-8 name is rejected", "file\xff.h5", "is not valid UTF-8"},
+		{"builtin rules still apply", "file*.h5", `contains illegal characters like *,\`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			filter, err := NewFilenameFilter(policy, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if keep := filter.Check(tc.filepath); keep != (tc.expectedReason == "") {
+				t.Errorf("expected keep=%v, got %v", tc.expectedReason == "", keep)
+			}
+			if reason := filter.reject(tc.filepath); reason != tc.expectedReason {
+				t.Errorf("expected reason %q, got %q", tc.expectedReason, reason)
+			}
+		})
+	}
+
+	t.Run("fail mode returns the rejected files", func(t *testing.T) {
+		filter, err := NewFilenameFilter(FilenamePolicy{Mode: IllegalFilenamesFail}, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		filter.Check("a*.h5")
+		filter.Check("b.h5")
+		err = filter.Finish("/data/run1")
+		var illegalFileNamesErr *IllegalFileNamesError
+		if !errors.As(err, &illegalFileNamesErr) {
+			t.Fatalf("expected an *IllegalFileNamesError, got %v", err)
+		}
+		if len(illegalFileNamesErr.Files) != 1 || illegalFileNamesErr.Files[0].Path != "a*.h5" {
+			t.Errorf("expected a*.h5 to be rejected, got %v", illegalFileNamesErr.Files)
+		}
+		if err := filter.Finish("/data/run2"); err != nil {
+			t.Errorf("expected the next dataset to start without rejected files, got %v", err)
+		}
+	})
+
+	t.Run("report mode writes the rejected files", func(t *testing.T) {
+		reportPath := filepath.Join(t.TempDir(), "report.txt")
+		if err := os.WriteFile(reportPath, []byte("from an earlier run\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		var illegalFileNames uint
+		filter, err := NewFilenameFilter(FilenamePolicy{Mode: IllegalFilenamesReport, ReportPath: reportPath}, &illegalFileNames)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		filter.Check("a*.h5")
+		if err := filter.Finish("/data/run1"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		filter.Check("b   c.h5")
+		if err := filter.Finish("/data/run2"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		content, err := os.ReadFile(reportPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := "/data/run1/a*.h5\tcontains illegal characters like *,\\\n" +
+			"/data/run2/b   c.h5\tcontains 3 consecutive blanks which is not allowed\n"
+		if string(content) != want {
+			t.Errorf("expected report %q, got %q", want, content)
+		}
+		if illegalFileNames != 2 {
+			t.Errorf("expected 2 illegal file names, got %d", illegalFileNames)
+		}
+	})
+
+	t.Run("unknown mode", func(t *testing.T) {
+		if _, err := ParseIllegalFilenameMode("ignore"); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
@@ -1,51 +1,39 @@
 package datasetIngestor
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
 	"net/http"
 	"net/url"
+
+	"github.com/paulscherrerinstitute/scicat-cli/v3/datasetUtils"
 )
 
 /*
 MarkFilesReady is a function that sends a PATCH request to a specified API server to update the dataset lifecycle status.
 
 Parameters:
+- ctx: Cancels the request.
 - client: An *http.Client object, used to send the HTTP request.
 - APIServer: A string representing the URL of the API server.
 - datasetId: A string representing the ID of the dataset to be updated.
-- user: A map[string]string containing user information, specifically the access token.
+- user: The user whose access token is used for the request.
 
 The function constructs a metadata map with the dataset lifecycle status set to "datasetCreated" and archivable set to true.
 This metadata is then converted to JSON and sent in the body of the PATCH request.
-The URL for the request is constructed using the APIServer and datasetId parameters, and the user's access token is appended as a query parameter.
+The URL for the request is constructed using the APIServer and datasetId parameters, the user's access token is sent in the Authorization header.
 
-If the request is successful (HTTP status code 200), the function logs a success message along with the response body.
-If the request fails, the function logs a failure message along with the status code and metadata map.
+If the request fails, the function returns an error containing the status code and metadata map.
 */
-func MarkFilesReady(client *http.Client, APIServer string, datasetId string, user map[string]string) error {
+func MarkFilesReady(ctx context.Context, client *http.Client, APIServer string, datasetId string, user datasetUtils.User) error {
 	var metaDataMap = map[string]interface{}{}
 	metaDataMap["archiveStatusMessage"] = "datasetCreated"
 	metaDataMap["archivable"] = true
 
-	cmm, _ := json.Marshal(metaDataMap)
-	// metadataString := string(cmm)
-
-	myurl := APIServer + "/Datasets/" + url.QueryEscape(datasetId) + "/datasetlifecycle"
-	req, err := http.NewRequest("PATCH", myurl, bytes.NewBuffer(cmm))
+	path := "/Datasets/" + url.QueryEscape(datasetId) + "/datasetlifecycle"
+	err := datasetUtils.NewAPIClient(client, APIServer, user.AccessToken).Patch(ctx, path, metaDataMap, nil)
 	if err != nil {
-		return err
-	}
-	req.Header.Set("Authorization", "Bearer "+user["accessToken"])
-	req.Header.Set("Content-Type", "application/json")
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode >= 300 || resp.StatusCode < 200 {
-		return fmt.Errorf("failed to update datasetLifecycle %v %v", resp.StatusCode, metaDataMap)
+		return fmt.Errorf("failed to update datasetLifecycle %v: %w", metaDataMap, err)
 	}
 	return nil
 }
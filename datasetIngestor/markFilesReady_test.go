@@ -1,11 +1,14 @@
 package datasetIngestor
 
 import (
+	"context"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+
+	"github.com/paulscherrerinstitute/scicat-cli/v3/datasetUtils"
 )
 
 func TestSendFilesReadyCommand(t *testing.T) {
@@ -40,14 +43,14 @@ func TestSendFilesReadyCommand(t *testing.T) {
 	defer server.Close()
 
 	// Create a map for user info
-	user := make(map[string]string)
-	user["accessToken"] = "testToken"
+	user := datasetUtils.User{}
+	user.AccessToken = "testToken"
 
 	// Create a http client
 	client := &http.Client{}
 
 	// Call the function
-	err := MarkFilesReady(client, server.URL, "testDatasetId", user)
+	err := MarkFilesReady(context.Background(), client, server.URL, "testDatasetId", user)
 	if err != nil {
 		// TODO: write cases that trigger errors maybe
 		t.Errorf("Error encountered: %v", err)
@@ -0,0 +1,228 @@
+package datasetIngestor
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// MetadataFormat is the format of a metadata file.
+type MetadataFormat string
+
+const (
+	MetadataJSON MetadataFormat = "json"
+	MetadataYAML MetadataFormat = "yaml"
+	MetadataTOML MetadataFormat = "toml"
+)
+
+// metadataTimeFormat is how dates are stored in the metadata, the same as DUMMY_TIME.
+const metadataTimeFormat = "2006-01-02T15:04:05.000Z"
+
+// a TOML table header with a bare key, or a "key = value" line
+var tomlLineRegexp = regexp.MustCompile(`^(\[\[?\s*[A-Za-z0-9_-]+\s*[].]|[A-Za-z0-9_"'-][A-Za-z0-9_."' -]*=)`)
+
+/*
+DetectMetadataFormat returns the format of a metadata file. It's taken from the extension of
+path: ".json", ".yaml", ".yml" or ".toml". For other extensions it's taken from the content: a
+document whose first line is a TOML table header or "key = value" is TOML, one starting with "{"
+or "[" is JSON, anything else YAML.
+*/
+func DetectMetadataFormat(path string, content []byte) MetadataFormat {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return MetadataJSON
+	case ".yaml", ".yml":
+		return MetadataYAML
+	case ".toml":
+		return MetadataTOML
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\uFEFF"))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		switch {
+		case tomlLineRegexp.MatchString(line):
+			return MetadataTOML
+		case strings.HasPrefix(line, "{"), strings.HasPrefix(line, "["):
+			return MetadataJSON
+		}
+		return MetadataYAML
+	}
+	return MetadataJSON
+}
+
+/*
+DecodeMetadata decodes a metadata document in the given format into the same types
+encoding/json produces: all numbers are float64, dates with a time are strings in the format of
+DUMMY_TIME (TOML local date-times are in the local time zone), dates without a time are
+"2006-01-02" strings, TOML local times "15:04:05" strings, and all object keys are strings.
+An error is returned if the document isn't a single object.
+*/
+func DecodeMetadata(content []byte, format MetadataFormat) (map[string]interface{}, error) {
+	var metadataObj interface{}
+	switch format {
+	case MetadataJSON:
+		if err := json.Unmarshal(content, &metadataObj); err != nil {
+			return nil, err
+		}
+	case MetadataYAML:
+		decoder := yaml.NewDecoder(bytes.NewReader(content))
+		var document yaml.Node
+		err := decoder.Decode(&document)
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("the YAML document is empty")
+		}
+		if err != nil {
+			return nil, err
+		}
+		var next yaml.Node
+		if err := decoder.Decode(&next); !errors.Is(err, io.EOF) {
+			return nil, errors.New("more than one YAML document")
+		}
+		keepYAMLDates(&document)
+		if err := document.Decode(&metadataObj); err != nil {
+			return nil, err
+		}
+	case MetadataTOML:
+		// a TOML document is always a table
+		var table map[string]interface{}
+		if _, err := toml.Decode(string(content), &table); err != nil {
+			return nil, err
+		}
+		metadataObj = table
+	default:
+		return nil, fmt.Errorf("unknown metadata format %q", format)
+	}
+
+	normalized, err := normalizeMetadataValue(metadataObj)
+	if err != nil {
+		return nil, err
+	}
+	metaDataMap, ok := normalized.(map[string]interface{})
+	if !ok || metaDataMap == nil {
+		return nil, fmt.Errorf("the metadata must be an object, not %s", metadataTypeName(normalized))
+	}
+	return metaDataMap, nil
+}
+
+// keepYAMLDates makes the dates without a time of the YAML document strings, like TOML local
+// dates, instead of timestamps at midnight UTC.
+func keepYAMLDates(node *yaml.Node) {
+	if node.Kind == yaml.ScalarNode && node.ShortTag() == "!!timestamp" {
+		if _, err := time.Parse(time.DateOnly, node.Value); err == nil {
+			node.Tag = "!!str"
+		}
+	}
+	for _, child := range node.Content {
+		keepYAMLDates(child)
+	}
+}
+
+// normalizeMetadataValue converts the values decoded from YAML or TOML into the JSON types.
+func normalizeMetadataValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case nil, string, bool:
+		return v, nil
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, fmt.Errorf("the number %v can't be stored", v)
+		}
+		return v, nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case uint64:
+		return float64(v), nil
+	case float32:
+		return normalizeMetadataValue(float64(v))
+	case time.Time:
+		// the TOML decoder marks local dates and times by the name of their zone
+		switch v.Location().String() {
+		case "date-local":
+			return v.Format(time.DateOnly), nil
+		case "time-local":
+			return v.Format("15:04:05.999999999"), nil
+		}
+		return v.UTC().Format(metadataTimeFormat), nil
+	case []byte:
+		return string(v), nil
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, element := range v {
+			normalized, err := normalizeMetadataValue(element)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			result[key] = normalized
+		}
+		return result, nil
+	case map[interface{}]interface{}:
+		// YAML allows keys which aren't strings, e.g. numbers
+		result := make(map[string]interface{}, len(v))
+		for key, element := range v {
+			stringKey := fmt.Sprint(key)
+			if _, ok := result[stringKey]; ok {
+				return nil, fmt.Errorf("the key %q is there twice", stringKey)
+			}
+			normalized, err := normalizeMetadataValue(element)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", stringKey, err)
+			}
+			result[stringKey] = normalized
+		}
+		return result, nil
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, element := range v {
+			normalized, err := normalizeMetadataValue(element)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %w", i, err)
+			}
+			result[i] = normalized
+		}
+		return result, nil
+	case []map[string]interface{}:
+		// a TOML array of tables
+		result := make([]interface{}, len(v))
+		for i, element := range v {
+			normalized, err := normalizeMetadataValue(element)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %w", i, err)
+			}
+			result[i] = normalized
+		}
+		return result, nil
+	}
+	return nil, fmt.Errorf("unsupported value %v of type %T", value, value)
+}
+
+func metadataTypeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "a string"
+	case bool:
+		return "a boolean"
+	case float64:
+		return "a number"
+	case []interface{}:
+		return "an array"
+	}
+	return fmt.Sprintf("%T", value)
+}
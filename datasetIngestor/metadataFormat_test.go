@@ -0,0 +1,190 @@
+package datasetIngestor
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDetectMetadataFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		content string
+		want    MetadataFormat
+	}{
+		{"json extension", "metadata.json", "a: 1", MetadataJSON},
+		{"yaml extension", "metadata.YAML", "{}", MetadataYAML},
+		{"yml extension", "metadata.yml", "", MetadataYAML},
+		{"toml extension", "metadata.toml", "", MetadataTOML},
+		{"json object", "metadata", "\n  {\"a\": 1}", MetadataJSON},
+		{"json array", "metadata.txt", "[1, 2]", MetadataJSON},
+		{"toml key", "metadata", "# comment\ndatasetName = \"x\"", MetadataTOML},
+		{"toml dotted key", "metadata", "scientificMetadata.energy = 6.2", MetadataTOML},
+		{"toml table", "metadata", "[scientificMetadata]\nenergy = 6.2", MetadataTOML},
+		{"toml array of tables", "metadata", "[[runs]]\nid = 1", MetadataTOML},
+		{"yaml mapping", "metadata", "datasetName: x\nkeywords: [a, b]", MetadataYAML},
+		{"yaml document start", "metadata", "---\ndatasetName: x", MetadataYAML},
+		{"empty", "metadata", "", MetadataJSON},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectMetadataFormat(tt.path, []byte(tt.content)); got != tt.want {
+				t.Errorf("DetectMetadataFormat() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeMetadata(t *testing.T) {
+	want := map[string]interface{}{
+		"datasetName":  "run 42",
+		"creationTime": "2024-03-01T11:30:00.000Z",
+		"keywords":     []interface{}{"a", "b"},
+		"scientificMetadata": map[string]interface{}{
+			"energy":  6.2,
+			"frames":  float64(1000),
+			"day":     "2024-03-01",
+			"enabled": true,
+			"runs": []interface{}{
+				map[string]interface{}{"id": float64(1)},
+				map[string]interface{}{"id": float64(2)},
+			},
+		},
+	}
+	documents := map[MetadataFormat]string{
+		MetadataJSON: `{
+			"datasetName": "run 42",
+			"creationTime": "2024-03-01T11:30:00.000Z",
+			"keywords": ["a", "b"],
+			"scientificMetadata": {
+				"energy": 6.2, "frames": 1000, "day": "2024-03-01", "enabled": true,
+				"runs": [{"id": 1}, {"id": 2}]
+			}
+		}`,
+		MetadataYAML: `
+datasetName: run 42
+creationTime: 2024-03-01T12:30:00+01:00
+keywords: [a, b]
+scientificMetadata:
+  energy: 6.2
+  frames: 1_000
+  day: "2024-03-01"
+  enabled: true
+  runs:
+    - id: 1
+    - id: 0x2
+`,
+		MetadataTOML: `
+datasetName = "run 42"
+creationTime = 2024-03-01T12:30:00+01:00
+keywords = ["a", "b"]
+
+[scientificMetadata]
+energy = 6.2
+frames = 1_000
+day = 2024-03-01
+enabled = true
+
+[[scientificMetadata.runs]]
+id = 1
+
+[[scientificMetadata.runs]]
+id = 0x2
+`,
+	}
+	for format, document := range documents {
+		t.Run(string(format), func(t *testing.T) {
+			got, err := DecodeMetadata([]byte(document), format)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("DecodeMetadata() = %v, want %v", got, want)
+			}
+		})
+	}
+
+	t.Run("yaml and toml dates are the same", func(t *testing.T) {
+		yamlMetadata, err := DecodeMetadata([]byte("day: 2024-01-02\nquoted: \"2024-01-02\"\nstart: 2024-01-02T10:00:00Z\n"), MetadataYAML)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		tomlMetadata, err := DecodeMetadata([]byte("day = 2024-01-02\nquoted = \"2024-01-02\"\nstart = 2024-01-02T10:00:00Z\n"), MetadataTOML)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := map[string]interface{}{"day": "2024-01-02", "quoted": "2024-01-02", "start": "2024-01-02T10:00:00.000Z"}
+		if !reflect.DeepEqual(yamlMetadata, want) || !reflect.DeepEqual(tomlMetadata, want) {
+			t.Errorf("YAML %v and TOML %v, want both %v", yamlMetadata, tomlMetadata, want)
+		}
+	})
+
+	t.Run("yaml keys which aren't strings", func(t *testing.T) {
+		got, err := DecodeMetadata([]byte("scientificMetadata:\n  1: one\n  true: yes\n"), MetadataYAML)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := map[string]interface{}{"scientificMetadata": map[string]interface{}{"1": "one", "true": "yes"}}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("DecodeMetadata() = %v, want %v", got, want)
+		}
+	})
+
+	errorTests := []struct {
+		name     string
+		format   MetadataFormat
+		document string
+		wantErr  string
+	}{
+		{"json array", MetadataJSON, `[{"a": 1}]`, "must be an object, not an array"},
+		{"json null", MetadataJSON, `null`, "must be an object, not null"},
+		{"yaml scalar", MetadataYAML, "just text", "must be an object, not a string"},
+		{"yaml sequence", MetadataYAML, "- a\n- b", "must be an object, not an array"},
+		{"empty yaml", MetadataYAML, "# nothing\n", "empty"},
+		{"two yaml documents", MetadataYAML, "a: 1\n---\nb: 2\n", "more than one YAML document"},
+		{"yaml nan", MetadataYAML, "scientificMetadata:\n  value: .nan\n", "scientificMetadata: value: the number NaN can't be stored"},
+		{"toml syntax error", MetadataTOML, "a = ", "toml"},
+		{"toml inf", MetadataTOML, "value = inf", "can't be stored"},
+	}
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecodeMetadata([]byte(tt.document), tt.format)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReadMetadataFromFile(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("a yaml file without extension", func(t *testing.T) {
+		path := filepath.Join(dir, "metadata")
+		if err := os.WriteFile(path, []byte("datasetName: x\ntype: raw\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		got, err := ReadMetadataFromFile(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := map[string]interface{}{"datasetName": "x", "type": "raw"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("ReadMetadataFromFile() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("a json file which isn't an object", func(t *testing.T) {
+		path := filepath.Join(dir, "metadata.json")
+		if err := os.WriteFile(path, []byte(`"text"`), 0644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := ReadMetadataFromFile(path)
+		if err == nil || !strings.Contains(err.Error(), `can't read the JSON metadata file`) {
+			t.Errorf("error = %v, want the JSON metadata file error", err)
+		}
+	})
+}
@@ -0,0 +1,187 @@
+package datasetIngestor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+)
+
+/*
+MetadataTemplateData is what the template expressions in metadata values are evaluated against,
+per dataset. For example:
+  - {{ .Folder.Base }}: the last element of the sourceFolder.
+  - {{ regex .SourceFolder "run_(\\d+)" 1 }}: the first group of the regular expression matching
+    the sourceFolder. It's an error if the expression doesn't match.
+  - {{ env "PROPOSAL" }}: the value of an environment variable. It's an error if it isn't set.
+  - {{ .Scan.StartTime }}: the modification time of the oldest file, in RFC 3339 format. The
+    methods of time.Time can be used too, e.g. {{ .Scan.StartTime.Format "2006-01-02" }}.
+*/
+type MetadataTemplateData struct {
+	SourceFolder string
+	Folder       TemplateFolder
+	Scan         TemplateScan
+}
+
+// TemplateFolder is the sourceFolder split into its parts.
+type TemplateFolder struct {
+	Path string
+	Base string
+	Dir  string
+}
+
+// TemplateScan holds the results of scanning the files of the dataset. For datasets with remote
+// files, StartTime and EndTime are the time of the ingestion and there are no files.
+type TemplateScan struct {
+	StartTime TemplateTime
+	EndTime   TemplateTime
+	NumFiles  int64
+	TotalSize int64
+	Owner     string
+}
+
+// TemplateTime is printed in RFC 3339 format by templates.
+type TemplateTime struct {
+	time.Time
+}
+
+func (t TemplateTime) String() string {
+	return t.Format(time.RFC3339)
+}
+
+// NewMetadataTemplateData returns the template data of the dataset in sourceFolder.
+func NewMetadataTemplateData(sourceFolder string, startTime time.Time, endTime time.Time, owner string, numFiles int64, totalSize int64) MetadataTemplateData {
+	return MetadataTemplateData{
+		SourceFolder: sourceFolder,
+		Folder: TemplateFolder{
+			Path: sourceFolder,
+			Base: filepath.Base(sourceFolder),
+			Dir:  filepath.Dir(sourceFolder),
+		},
+		Scan: TemplateScan{
+			StartTime: TemplateTime{startTime},
+			EndTime:   TemplateTime{endTime},
+			NumFiles:  numFiles,
+			TotalSize: totalSize,
+			Owner:     owner,
+		},
+	}
+}
+
+var metadataTemplateFuncs = template.FuncMap{
+	"regex": func(s string, pattern string, group int) (string, error) {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return "", err
+		}
+		if group < 0 || group > re.NumSubexp() {
+			return "", fmt.Errorf("the regular expression %q has no group %d", pattern, group)
+		}
+		match := re.FindStringSubmatch(s)
+		if match == nil {
+			return "", fmt.Errorf("the regular expression %q doesn't match %q", pattern, s)
+		}
+		return match[group], nil
+	},
+	"env": func(name string) (string, error) {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("the environment variable %q isn't set", name)
+		}
+		return value, nil
+	},
+}
+
+/*
+ExpandMetadataTemplates evaluates the template expressions in the string values of metaDataMap,
+at any depth, against data. The original value of every top level field containing a template is
+stored in originalMap, so ResetUpdatedMetaData restores the templates for the next dataset.
+
+The templates are replaced by new values, the originals aren't modified. Strings without "{{" are
+left as they are. An error is returned if a template can't be parsed or evaluated, naming the field.
+*/
+func ExpandMetadataTemplates(originalMap map[string]interface{}, metaDataMap map[string]interface{}, data MetadataTemplateData) error {
+	for key, value := range metaDataMap {
+		if !containsTemplate(value) {
+			continue
+		}
+		expanded, err := expandTemplateValue(key, value, data)
+		if err != nil {
+			return err
+		}
+		if _, ok := originalMap[key]; !ok {
+			originalMap[key] = value
+		}
+		metaDataMap[key] = expanded
+	}
+	return nil
+}
+
+// ContainsMetadataTemplates tells if a string value of metaDataMap, at any depth, contains a template.
+func ContainsMetadataTemplates(metaDataMap map[string]interface{}) bool {
+	return containsTemplate(metaDataMap)
+}
+
+func containsTemplate(value interface{}) bool {
+	switch v := value.(type) {
+	case string:
+		return strings.Contains(v, "{{")
+	case map[string]interface{}:
+		for _, element := range v {
+			if containsTemplate(element) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, element := range v {
+			if containsTemplate(element) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// expandTemplateValue returns a copy of value with its templates evaluated, field is the path of
+// value in the metadata for the error messages.
+func expandTemplateValue(field string, value interface{}, data MetadataTemplateData) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		if !strings.Contains(v, "{{") {
+			return v, nil
+		}
+		tmpl, err := template.New(field).Funcs(metadataTemplateFuncs).Option("missingkey=error").Parse(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template in the metadata field %q: %w", field, err)
+		}
+		var result strings.Builder
+		if err := tmpl.Execute(&result, data); err != nil {
+			return nil, fmt.Errorf("can't evaluate the template in the metadata field %q: %w", field, err)
+		}
+		return result.String(), nil
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, element := range v {
+			expanded, err := expandTemplateValue(field+"."+key, element, data)
+			if err != nil {
+				return nil, err
+			}
+			result[key] = expanded
+		}
+		return result, nil
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, element := range v {
+			expanded, err := expandTemplateValue(fmt.Sprintf("%s[%d]", field, i), element, data)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = expanded
+		}
+		return result, nil
+	}
+	return value, nil
+}
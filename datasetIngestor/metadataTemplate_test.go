@@ -0,0 +1,100 @@
+package datasetIngestor
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExpandMetadataTemplates(t *testing.T) {
+	t.Setenv("PROPOSAL", "20231234")
+	startTime := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	data := NewMetadataTemplateData("/data/beamtime/run_0042", startTime, startTime.Add(time.Hour), "owner1", 12, 3456)
+
+	t.Run("templates are expanded at any depth and the originals are kept", func(t *testing.T) {
+		metaDataMap := map[string]interface{}{
+			"datasetName": "{{ .Folder.Base }}",
+			"description": "no template",
+			"keywords":    []interface{}{"{{ env \"PROPOSAL\" }}", "fixed"},
+			"scientificMetadata": map[string]interface{}{
+				"run":     `{{ regex .SourceFolder "run_(\\d+)" 1 }}`,
+				"started": "{{ .Scan.StartTime }}",
+				"day":     `{{ .Scan.StartTime.Format "2006-01-02" }}`,
+				"files":   "{{ .Scan.NumFiles }} files in {{ .Folder.Dir }}",
+				"energy":  6.2,
+			},
+		}
+		original := copyMetadata(metaDataMap)
+		originalMap := map[string]interface{}{}
+
+		if err := ExpandMetadataTemplates(originalMap, metaDataMap, data); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := map[string]interface{}{
+			"datasetName": "run_0042",
+			"description": "no template",
+			"keywords":    []interface{}{"20231234", "fixed"},
+			"scientificMetadata": map[string]interface{}{
+				"run":     "0042",
+				"started": "2024-03-01T12:30:00Z",
+				"day":     "2024-03-01",
+				"files":   "12 files in /data/beamtime",
+				"energy":  6.2,
+			},
+		}
+		if !reflect.DeepEqual(metaDataMap, want) {
+			t.Errorf("metaDataMap = %v, want %v", metaDataMap, want)
+		}
+		if _, ok := originalMap["description"]; ok {
+			t.Errorf("fields without templates shouldn't be stored in originalMap")
+		}
+
+		ResetUpdatedMetaData(originalMap, metaDataMap)
+		if !reflect.DeepEqual(metaDataMap, original) {
+			t.Errorf("after the reset metaDataMap = %v, want %v", metaDataMap, original)
+		}
+	})
+
+	t.Run("the templates are evaluated again for the next dataset", func(t *testing.T) {
+		metaDataMap := map[string]interface{}{"datasetName": "{{ .Folder.Base }}"}
+		originalMap := map[string]interface{}{}
+		for _, folder := range []string{"/data/a", "/data/b"} {
+			data := NewMetadataTemplateData(folder, startTime, startTime, "owner1", 1, 1)
+			if err := ExpandMetadataTemplates(originalMap, metaDataMap, data); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if want := folder[len("/data/"):]; metaDataMap["datasetName"] != want {
+				t.Errorf("datasetName = %v, want %v", metaDataMap["datasetName"], want)
+			}
+			ResetUpdatedMetaData(originalMap, metaDataMap)
+		}
+	})
+
+	errorTests := []struct {
+		name    string
+		value   string
+		wantErr string
+	}{
+		{"unknown field", "{{ .Folder.Name }}", `metadata field "scientificMetadata.x"`},
+		{"syntax error", "{{ .Folder.Base", "invalid template"},
+		{"unset environment variable", `{{ env "SCICAT_UNSET_VARIABLE" }}`, `"SCICAT_UNSET_VARIABLE" isn't set`},
+		{"regex without match", `{{ regex .SourceFolder "scan_(\\d+)" 1 }}`, "doesn't match"},
+		{"regex without group", `{{ regex .SourceFolder "run_\\d+" 1 }}`, "has no group 1"},
+	}
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			metaDataMap := map[string]interface{}{
+				"scientificMetadata": map[string]interface{}{"x": tt.value},
+			}
+			originalMap := map[string]interface{}{}
+			err := ExpandMetadataTemplates(originalMap, metaDataMap, data)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want one containing %q", err, tt.wantErr)
+			}
+			if len(originalMap) != 0 {
+				t.Errorf("originalMap = %v, want it empty", originalMap)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package datasetIngestor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/paulscherrerinstitute/scicat-cli/v3/datasetUtils"
+)
+
+/*
+OrigDatablockBuilder splits a stream of files into origdatablocks and posts each block as soon as
+it's full, so the files of a dataset don't have to be held in memory all at once.
+
+A block is full once it has BlockMaxFiles files or at least BlockMaxBytes bytes, as in
+CreateOrigDatablocks. The blocks are posted in order by a separate goroutine, so the next block
+can be filled, e.g. by a scan, while the previous one is sent. The dataset must exist already.
+
+Blocks that were posted before an error remain in the catalog, see Posted.
+*/
+type OrigDatablockBuilder struct {
+	ctx       context.Context
+	cancel    context.CancelFunc
+	api       *datasetUtils.APIClient
+	limits    datasetUtils.IngestSizeLimits
+	datasetId string
+
+	files    []Datafile
+	size     int64
+	numFiles int64
+
+	blocks chan FileBlock
+	done   chan struct{}
+
+	mu      sync.Mutex
+	added   int // files in the blocks posted so far
+	err     error
+	aborted bool
+}
+
+// NewOrigDatablockBuilder returns a builder posting the blocks of the dataset datasetId to
+// APIServer with the token of user. Cancelling ctx stops posting blocks. Close must be called once
+// all files are added, or Abort if the files can't be completed.
+func NewOrigDatablockBuilder(ctx context.Context, client *http.Client, APIServer string, datasetId string, user datasetUtils.User) *OrigDatablockBuilder {
+	b := &OrigDatablockBuilder{
+		api:       datasetUtils.NewAPIClient(client, APIServer, user.AccessToken),
+		limits:    datasetUtils.DefaultIngestSizeLimits,
+		datasetId: datasetId,
+		blocks:    make(chan FileBlock, 1),
+		done:      make(chan struct{}),
+	}
+	b.ctx, b.cancel = context.WithCancel(ctx)
+	go b.post()
+	return b
+}
+
+// Add appends file to the current block, and queues the block once it's full. It returns the error
+// of a previous block, if any.
+func (b *OrigDatablockBuilder) Add(file Datafile) error {
+	if err := b.Err(); err != nil {
+		return err
+	}
+	b.numFiles++
+	if b.numFiles > b.limits.TotalMaxFiles {
+		return fmt.Errorf(
+			"dataset exceeds the maximum number of files that can be handled by the archiving system per dataset (max: %v)",
+			b.limits.TotalMaxFiles)
+	}
+	b.files = append(b.files, file)
+	b.size += file.Size
+	if len(b.files) >= b.limits.BlockMaxFiles || b.size >= b.limits.BlockMaxBytes {
+		return b.flush()
+	}
+	return nil
+}
+
+// Close queues the last block and waits until all blocks are posted.
+func (b *OrigDatablockBuilder) Close() error {
+	err := b.flush()
+	close(b.blocks)
+	<-b.done
+	b.cancel()
+	if err != nil {
+		return err
+	}
+	return b.Err()
+}
+
+// Abort discards the current block and the queued ones, stops posting and waits until the block
+// being posted is done, if any. It's used instead of Close when the file list is incomplete, e.g.
+// the scan failed.
+func (b *OrigDatablockBuilder) Abort() {
+	b.files = nil
+	b.mu.Lock()
+	b.aborted = true
+	b.mu.Unlock()
+	b.cancel()
+	close(b.blocks)
+	<-b.done
+}
+
+// Posted returns the number of files in the blocks posted so far.
+func (b *OrigDatablockBuilder) Posted() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.added
+}
+
+// Err returns the error that stopped posting blocks, if any.
+func (b *OrigDatablockBuilder) Err() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.err
+}
+
+// flush ends the current block.
+func (b *OrigDatablockBuilder) flush() error {
+	if len(b.files) == 0 {
+		return nil
+	}
+	block := createOrigBlock(0, len(b.files), b.files, b.datasetId)
+	b.files = make([]Datafile, 0, len(b.files))
+	b.size = 0
+	select {
+	case b.blocks <- block:
+		return nil
+	case <-b.done:
+		return b.Err()
+	}
+}
+
+func (b *OrigDatablockBuilder) post() {
+	defer close(b.done)
+	for block := range b.blocks {
+		b.mu.Lock()
+		aborted := b.aborted
+		b.mu.Unlock()
+		if aborted {
+			// the queued blocks are dropped
+			continue
+		}
+		err := b.api.Post(b.ctx, "/origdatablocks", block, nil)
+		b.mu.Lock()
+		if err != nil {
+			b.err = fmt.Errorf("adding origDatablock for dataset id \"%v\" failed, only %d files were added: %w", block.DatasetId, b.added, err)
+			b.mu.Unlock()
+			return
+		}
+		b.added += len(block.DataFileList)
+		b.mu.Unlock()
+	}
+}
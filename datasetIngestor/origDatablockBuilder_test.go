@@ -0,0 +1,160 @@
+package datasetIngestor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/paulscherrerinstitute/scicat-cli/v3/datasetUtils"
+)
+
+// origDatablockServer records the origdatablocks posted to it, failing the request number failAt.
+func origDatablockServer(t *testing.T, failAt int) (*httptest.Server, func() []FileBlock) {
+	var mu sync.Mutex
+	var blocks []FileBlock
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if len(blocks)+1 == failAt {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var block FileBlock
+		if err := json.NewDecoder(r.Body).Decode(&block); err != nil {
+			t.Errorf("can't decode the origdatablock: %v", err)
+		}
+		blocks = append(blocks, block)
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []FileBlock {
+		mu.Lock()
+		defer mu.Unlock()
+		return blocks
+	}
+}
+
+func withBlockLimits(t *testing.T, maxFiles int, maxBytes int64) {
+	oldLimits := datasetUtils.DefaultIngestSizeLimits
+	t.Cleanup(func() { datasetUtils.DefaultIngestSizeLimits = oldLimits })
+	datasetUtils.DefaultIngestSizeLimits.BlockMaxFiles = maxFiles
+	datasetUtils.DefaultIngestSizeLimits.BlockMaxBytes = maxBytes
+}
+
+func TestOrigDatablockBuilder(t *testing.T) {
+	user := datasetUtils.User{AccessToken: "testToken"}
+
+	t.Run("splits the files like CreateOrigDatablocks", func(t *testing.T) {
+		withBlockLimits(t, 3, 10)
+		server, blocks := origDatablockServer(t, 0)
+		builder := NewOrigDatablockBuilder(context.Background(), server.Client(), server.URL, "pid", user)
+		for _, size := range []int64{1, 1, 1, 1, 9, 1} {
+			if err := builder.Add(Datafile{Size: size}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+		if err := builder.Close(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var got []int
+		for _, block := range blocks() {
+			if block.DatasetId != "pid" {
+				t.Errorf("block has datasetId %q", block.DatasetId)
+			}
+			got = append(got, len(block.DataFileList))
+		}
+		if len(got) != 3 || got[0] != 3 || got[1] != 2 || got[2] != 1 {
+			t.Errorf("expected blocks of 3, 2 and 1 files, got %v", got)
+		}
+	})
+
+	t.Run("reports how many files were added when a block fails", func(t *testing.T) {
+		withBlockLimits(t, 2, 100)
+		server, blocks := origDatablockServer(t, 2)
+		builder := NewOrigDatablockBuilder(context.Background(), server.Client(), server.URL, "pid", user)
+		for i := 0; i < 6; i++ {
+			if err := builder.Add(Datafile{Size: 1}); err != nil {
+				break
+			}
+		}
+		err := builder.Close()
+		if err == nil || !strings.Contains(err.Error(), "only 2 files were added") {
+			t.Errorf("expected an error after 2 files, got %v", err)
+		}
+		if len(blocks()) != 1 {
+			t.Errorf("expected the blocks after the failing one not to be posted, got %d", len(blocks()))
+		}
+	})
+
+	t.Run("abort drops the blocks that aren't posted yet", func(t *testing.T) {
+		withBlockLimits(t, 2, 100)
+		server, blocks := origDatablockServer(t, 0)
+		builder := NewOrigDatablockBuilder(context.Background(), server.Client(), server.URL, "pid", user)
+		for i := 0; i < 3; i++ {
+			if err := builder.Add(Datafile{Size: 1}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+		builder.Abort()
+		// the full block may or may not have been posted by then, the partial one never is
+		if posted := builder.Posted(); posted > 2 || len(blocks()) != posted/2 {
+			t.Errorf("expected at most the full block to be posted, got %d files in %d blocks", posted, len(blocks()))
+		}
+	})
+
+	t.Run("a cancelled context is an error", func(t *testing.T) {
+		server, blocks := origDatablockServer(t, 0)
+		ctx, cancel := context.WithCancel(context.Background())
+		builder := NewOrigDatablockBuilder(ctx, server.Client(), server.URL, "pid", user)
+		if err := builder.Add(Datafile{Size: 1}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		cancel()
+		if err := builder.Close(); err == nil || len(blocks()) != 0 {
+			t.Errorf("expected an error and no blocks, got %v and %d blocks", err, len(blocks()))
+		}
+	})
+}
+
+func TestGetLocalFileListStreaming(t *testing.T) {
+	tempDir := t.TempDir()
+	for i, name := range []string{"a", "b", "c", "d", "e"} {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(strings.Repeat("x", i*100000)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	withBlockLimits(t, 2, 1<<30)
+	server, blocks := origDatablockServer(t, 0)
+	builder := NewOrigDatablockBuilder(context.Background(), server.Client(), server.URL, "pid", datasetUtils.User{})
+
+	files, _, _, _, numFiles, _, err := GetLocalFileList(context.Background(), tempDir, "", nil, nil, ScanOptions{ChecksumAlgorithm: "md5", ChecksumWorkers: 3, OnFile: builder.Add})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := builder.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(files) != 0 || numFiles != 5 {
+		t.Errorf("expected the 5 files to be streamed only, got %d and numFiles=%d", len(files), numFiles)
+	}
+	var got []string
+	for _, block := range blocks() {
+		if block.ChkAlg != "md5" {
+			t.Errorf("block has chkAlg %q", block.ChkAlg)
+		}
+		for _, file := range block.DataFileList {
+			if len(file.Chk) != 32 {
+				t.Errorf("%s has checksum %q", file.Path, file.Chk)
+			}
+			got = append(got, file.Path)
+		}
+	}
+	if strings.Join(got, ",") != "a,b,c,d,e" {
+		t.Errorf("expected the files in scan order, got %v", got)
+	}
+}
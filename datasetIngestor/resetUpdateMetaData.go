@@ -1,6 +1,6 @@
 package datasetIngestor
 
-func ResetUpdatedMetaData(originalMap map[string]string, metaDataMap map[string]interface{}) {
+func ResetUpdatedMetaData(originalMap map[string]interface{}, metaDataMap map[string]interface{}) {
 	for k, v := range originalMap {
 		metaDataMap[k] = v
 	}
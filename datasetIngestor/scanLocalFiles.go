@@ -0,0 +1,225 @@
+package datasetIngestor
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// DefaultScanWorkers is the number of directories read in parallel if ScanOptions doesn't say
+// otherwise.
+const DefaultScanWorkers = 8
+
+// scanEntry is a file or directory found by a dirScanner.
+type scanEntry struct {
+	path    string // as stored in the Datafile: relative to the sourceFolder, or absolute for absolute listing lines
+	absPath string
+	info    fs.FileInfo
+	dir     *scanDir // the contents, if the entry is a directory (but not a symlink to one)
+}
+
+// scanDir holds the entries of a directory, once done is closed.
+type scanDir struct {
+	done    chan struct{}
+	entries []scanEntry
+	err     error
+}
+
+type scanDirJob struct {
+	path    string
+	absPath string
+	dir     *scanDir
+}
+
+// dirScanner reads directories in a fixed number of workers, while walk visits their entries in
+// the same order as filepath.Walk: depth first, with the entries of each directory sorted by name.
+// Pending directories are read last in, first out, so the workers stay close to the walk.
+type dirScanner struct {
+	selection *FileSelection // excluded entries are dropped right away, excluded directories aren't read
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	jobs   []scanDirJob
+	closed bool
+}
+
+// newDirScanner starts workers reading directories until stop is called or ctx is cancelled.
+func newDirScanner(ctx context.Context, workers int, selection *FileSelection) *dirScanner {
+	if workers <= 0 {
+		workers = DefaultScanWorkers
+	}
+	s := &dirScanner{selection: selection}
+	s.ctx, s.cancel = context.WithCancel(ctx)
+	s.cond = sync.NewCond(&s.mu)
+	for i := 0; i < workers; i++ {
+		s.wg.Add(1)
+		go s.work()
+	}
+	context.AfterFunc(s.ctx, func() {
+		s.mu.Lock()
+		s.closed = true
+		s.mu.Unlock()
+		s.cond.Broadcast()
+	})
+	return s
+}
+
+// queue schedules reading the directory at absPath, whose entries get paths below path.
+func (s *dirScanner) queue(path string, absPath string) *scanDir {
+	dir := &scanDir{done: make(chan struct{})}
+	s.mu.Lock()
+	s.jobs = append(s.jobs, scanDirJob{path, absPath, dir})
+	s.mu.Unlock()
+	s.cond.Signal()
+	return dir
+}
+
+func (s *dirScanner) work() {
+	defer s.wg.Done()
+	for {
+		s.mu.Lock()
+		for len(s.jobs) == 0 && !s.closed {
+			s.cond.Wait()
+		}
+		if s.closed {
+			s.mu.Unlock()
+			return
+		}
+		job := s.jobs[len(s.jobs)-1]
+		s.jobs = s.jobs[:len(s.jobs)-1]
+		s.mu.Unlock()
+
+		job.dir.entries, job.dir.err = s.readDir(job.path, job.absPath)
+		close(job.dir.done)
+	}
+}
+
+// readDir lists the directory at absPath and queues its subdirectories, first one on top.
+func (s *dirScanner) readDir(path string, absPath string) ([]scanEntry, error) {
+	dirEntries, err := os.ReadDir(absPath)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]scanEntry, 0, len(dirEntries))
+	var subdirs []int
+	for _, dirEntry := range dirEntries {
+		if err := s.ctx.Err(); err != nil {
+			return nil, err
+		}
+		entryPath := filepath.Join(path, dirEntry.Name())
+		if s.selection.Excluded(filepath.ToSlash(entryPath), dirEntry.IsDir()) {
+			continue
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			// removed since the directory was read, filepath.Walk skips these too
+			continue
+		}
+		if dirEntry.IsDir() {
+			subdirs = append(subdirs, len(entries))
+		}
+		entries = append(entries, scanEntry{
+			path:    entryPath,
+			absPath: filepath.Join(absPath, dirEntry.Name()),
+			info:    info,
+		})
+	}
+	for i := len(subdirs) - 1; i >= 0; i-- {
+		entry := &entries[subdirs[i]]
+		entry.dir = s.queue(entry.path, entry.absPath)
+	}
+	return entries, nil
+}
+
+// walk calls fn for every entry below dir, depth first, and stops at the first error.
+func (s *dirScanner) walk(dir *scanDir, fn func(entry scanEntry) error) error {
+	select {
+	case <-dir.done:
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	}
+	if dir.err != nil {
+		return dir.err
+	}
+	entries := dir.entries
+	dir.entries = nil // visited entries aren't needed anymore
+	for _, entry := range entries {
+		if err := fn(entry); err != nil {
+			return err
+		}
+		if entry.dir != nil {
+			if err := s.walk(entry.dir, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// stop aborts reading the remaining directories and waits for the workers to finish.
+func (s *dirScanner) stop() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+// fileEmitter passes the scanned files to ScanOptions.OnFile in scan order, holding back the
+// files whose checksum isn't computed yet.
+type fileEmitter struct {
+	onFile    func(file Datafile) error
+	checksums *checksumPool
+	alg       string
+
+	next    int // index of pending[0] among the kept files
+	pending []emittedFile
+}
+
+type emittedFile struct {
+	file   Datafile
+	hashed bool
+}
+
+// add queues file, hashed tells if it waits for a checksum, and emits the files that are ready.
+func (e *fileEmitter) add(file Datafile, hashed bool) error {
+	e.pending = append(e.pending, emittedFile{file, hashed})
+	for len(e.pending) > 0 {
+		head := &e.pending[0]
+		if head.hashed {
+			chk, ok := e.checksums.take(e.next)
+			if !ok {
+				return nil
+			}
+			head.file.Chk, head.file.ChkAlg = chk, e.alg
+		}
+		if err := e.emit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// finish emits the remaining files with the checksums left once the scan is done.
+func (e *fileEmitter) finish(checksums map[int]string) error {
+	for len(e.pending) > 0 {
+		head := &e.pending[0]
+		if head.hashed {
+			head.file.Chk, head.file.ChkAlg = checksums[e.next], e.alg
+		}
+		if err := e.emit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *fileEmitter) emit() error {
+	file := e.pending[0].file
+	e.pending = e.pending[1:]
+	e.next++
+	return e.onFile(file)
+}
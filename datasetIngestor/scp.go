@@ -19,6 +19,7 @@ import (
 
 	"github.com/bodgit/sshkrb5"
 	"github.com/kballard/go-shellquote"
+	"github.com/paulscherrerinstitute/scicat-cli/v3/datasetUtils"
 	"golang.org/x/crypto/ssh"
 )
 
@@ -80,6 +81,27 @@ func (c *Client) getSendCommand(dst string) string {
 
 // Send the files dst directory on remote side. The paths can be regular files or directories.
 func (c *Client) Send(dst string, paths ...string) error {
+	return c.send(dst, func(w io.Writer) error {
+		for _, p := range paths {
+			if err := c.walkAndSend(w, p); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SendFileList sends the directory sourceFolder to the dst directory on remote side, with only the
+// entries in relPaths: slash separated paths relative to sourceFolder, e.g. the scanned files of a
+// dataset. Listed directories aren't walked, their entries must be listed too.
+func (c *Client) SendFileList(dst string, sourceFolder string, relPaths []string) error {
+	return c.send(dst, func(w io.Writer) error {
+		return c.sendFileList(w, sourceFolder, relPaths)
+	})
+}
+
+// send runs the scp sink for dst, and calls sendFiles to write the scp commands to it.
+func (c *Client) send(dst string, sendFiles func(w io.Writer) error) error {
 	// Create an SSH session
 	session, err := c.SshClient.NewSession()
 	if err != nil {
@@ -111,10 +133,8 @@ func (c *Client) Send(dst string, paths ...string) error {
 		errors <- session.Wait()
 	}()
 
-	for _, p := range paths {
-		if err := c.walkAndSend(w, p); err != nil {
-			return err
-		}
+	if err := sendFiles(w); err != nil {
+		return err
 	}
 	w.Close()
 	io.Copy(os.Stdout, r)
@@ -235,6 +255,85 @@ func (c *Client) walkAndSend(w io.Writer, src string) error {
 	return nil
 }
 
+/*
+sendFileList sends the directory sourceFolder with only the entries in relPaths. The remote side
+is moved into the directory of each entry, creating the directories that aren't listed
+themselves with the attributes of the local ones. The scp protocol can't transfer symlinks, so
+listed symlinks are skipped with a warning, as are the entries that are neither regular files nor
+directories.
+*/
+func (c *Client) sendFileList(w io.Writer, sourceFolder string, relPaths []string) error {
+	root := filepath.Clean(sourceFolder)
+	fi, err := os.Stat(root)
+	if err != nil {
+		return err
+	}
+	if err := c.pushDir(w, filepath.Base(root), fi); err != nil {
+		return err
+	}
+	var dirStack []string // the directories below root the remote side is in
+	for _, relPath := range relPaths {
+		path := filepath.Join(root, filepath.FromSlash(relPath))
+		info, err := os.Lstat(path)
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			log.Printf("WARNING: skipping the symlink %s, scp can't transfer links\n", path)
+			continue
+		}
+		target := strings.Split(relPath, "/")
+		if !info.IsDir() {
+			target = target[:len(target)-1]
+		}
+
+		common := 0
+		for common < len(dirStack) && common < len(target) && dirStack[common] == target[common] {
+			common++
+		}
+		for len(dirStack) > common { // We need to pop
+			if _, err := fmt.Fprintf(w, "E\n"); err != nil {
+				return err
+			}
+			dirStack = dirStack[:len(dirStack)-1]
+		}
+		for _, dir := range target[common:] { // We need to push
+			dirStack = append(dirStack, dir)
+			dirInfo, err := os.Stat(filepath.Join(root, filepath.Join(dirStack...)))
+			if err != nil {
+				return err
+			}
+			if err := c.pushDir(w, dir, dirInfo); err != nil {
+				return err
+			}
+		}
+
+		if info.Mode().IsRegular() {
+			if err := c.sendRegularFile(w, path, info); err != nil {
+				return err
+			}
+		}
+	}
+	for range len(dirStack) + 1 {
+		if _, err := fmt.Fprintf(w, "E\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pushDir moves the remote side into the directory name, creating it if needed.
+func (c *Client) pushDir(w io.Writer, name string, fi os.FileInfo) error {
+	if c.PreseveTimes {
+		_, err := fmt.Fprintf(w, "T%d 0 %d 0\n", fi.ModTime().Unix(), time.Now().Unix())
+		if err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "D%#o 0 %s\n", fi.Mode().Perm(), name)
+	return err
+}
+
 // defaultSshPort appends the standard ssh port (22) to server if it doesn't already specify one.
 func defaultSshPort(server string) string {
 	if _, _, err := net.SplitHostPort(server); err != nil {
@@ -243,6 +342,27 @@ func defaultSshPort(server string) string {
 	return server
 }
 
+// gssOptions returns the options to obtain a Kerberos ticket with the password of username
+// directly, without relying on a ticket cache. There are none if the realm isn't known.
+func gssOptions(username, password string) []sshkrb5.Option[sshkrb5.Client] {
+	if password == "" {
+		return nil
+	}
+	cfg, err := datasetUtils.LoadKrb5Config()
+	if err != nil {
+		return nil
+	}
+	user, realm, err := datasetUtils.KerberosPrincipal(username, cfg)
+	if err != nil {
+		return nil
+	}
+	return []sshkrb5.Option[sshkrb5.Client]{
+		sshkrb5.WithRealm[sshkrb5.Client](realm),
+		sshkrb5.WithUsername[sshkrb5.Client](user),
+		sshkrb5.WithPassword[sshkrb5.Client](password),
+	}
+}
+
 // Creates a new SCP client.  It enables preserve time stamps
 func NewDumbClient(username, password, server string) (*Client, error) {
 	server = defaultSshPort(server)
@@ -255,8 +375,9 @@ func NewDumbClient(username, password, server string) (*Client, error) {
 
 	var authMethods []ssh.AuthMethod
 
-	// Try to initialize Kerberos/GSSAPI using the OS ticket cache
-	gssClient, gssErr := sshkrb5.NewClient()
+	// Try to initialize Kerberos/GSSAPI, logging in with the password if there is one and using
+	// the OS ticket cache otherwise
+	gssClient, gssErr := sshkrb5.NewClient(gssOptions(username, password)...)
 	if gssErr == nil && gssClient != nil {
 		authMethods = append(authMethods, ssh.GSSAPIWithMICAuthMethod(gssClient, host))
 	}
@@ -8,6 +8,7 @@ import (
 	"encoding/pem"
 	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"golang.org/x/crypto/ssh"
@@ -193,6 +194,47 @@ func TestWalkAndSend(t *testing.T) {
 	}
 }
 
+// Checks that only the listed entries are sent, and that the remote side is moved into their directories
+func TestSendFileList(t *testing.T) {
+	client := &Client{Quiet: true}
+
+	tmpDir := t.TempDir()
+	sourceFolder := filepath.Join(tmpDir, "dataset")
+	for _, dir := range []string{"", "a", "a/b", "c"} {
+		if err := os.MkdirAll(filepath.Join(sourceFolder, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(filepath.Join(sourceFolder, dir), 0750); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"a/b/1", "a/2", "a/skipped", "c/3"} {
+		if err := os.WriteFile(filepath.Join(sourceFolder, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(filepath.Join(sourceFolder, name), 0640); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a kept dangling symlink is skipped instead of aborting the transfer
+	if err := os.Symlink(filepath.Join(tmpDir, "missing"), filepath.Join(sourceFolder, "a/dangling")); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	err := client.sendFileList(&buf, sourceFolder, []string{"a", "a/b", "a/b/1", "a/2", "a/dangling", "c/3"})
+	if err != nil {
+		t.Fatalf("sendFileList() error = %v", err)
+	}
+
+	// the unlisted directory c is created nevertheless, but not the unlisted file a/skipped
+	want := "D0750 0 dataset\nD0750 0 a\nD0750 0 b\nC0640 5 1\na/b/1\x00E\nC0640 3 2\na/2\x00E\nD0750 0 c\nC0640 3 3\nc/3\x00E\nE\n"
+	if got := buf.String(); got != want {
+		t.Errorf("sendFileList() sent %q, want %q", got, want)
+	}
+}
+
 // Verifies NewDumbClient returns an error when the server is unreachable.
 func TestNewDumbClientConnectionError(t *testing.T) {
 	client, err := NewDumbClient("user", "pass", "127.0.0.1:0")
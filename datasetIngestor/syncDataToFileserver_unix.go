@@ -5,11 +5,15 @@
 package datasetIngestor
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"os"
 	"os/exec"
 	"regexp"
 	"strings"
+
+	"github.com/paulscherrerinstitute/scicat-cli/v3/datasetUtils"
 )
 
 type RsyncCmd struct {
@@ -19,9 +23,10 @@ type RsyncCmd struct {
 }
 
 // functionality needed for "de-central" data
-// copies data from a local machine to a fileserver, uses RSync underneath
-func SyncLocalDataToFileserver(datasetId string, user map[string]string, RSYNCServer string, sourceFolder string, absFileListing string, cmdOutput io.Writer) (err error) {
-	username := user["username"]
+// copies data from a local machine to a fileserver, uses RSync underneath. Only the files, the
+// paths of the scanned files of the dataset, are copied. Cancelling ctx interrupts rsync.
+func SyncLocalDataToFileserver(ctx context.Context, datasetId string, user datasetUtils.User, RSYNCServer string, sourceFolder string, files []string, cmdOutput io.Writer) (err error) {
+	username := user.Username
 	shortDatasetId := strings.Split(datasetId, "/")[1]
 	destFolder := "archive/" + shortDatasetId + sourceFolder
 	serverConnectString := fmt.Sprintf("%s@%s:%s", username, RSYNCServer, destFolder)
@@ -34,16 +39,50 @@ func SyncLocalDataToFileserver(datasetId string, user map[string]string, RSYNCSe
 		return err
 	}
 
+	absFileListing, err := writeFilesFrom(sourceFolder, files)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(absFileListing)
+
 	cmd := buildRsyncCmd(rsyncCmd, absFileListing, fullSourceFolderPath, serverConnectString)
 
 	// Show rsync's output
 	cmd.Stdout = cmdOutput
 	cmd.Stderr = cmdOutput
 	fmt.Fprintf(cmdOutput, "Running: %v.\n", cmd.Args)
-	err = cmd.Run()
+	err = datasetUtils.RunCommand(ctx, cmd)
 	return err
 }
 
+// writeFilesFrom writes the files to a temporary listing for rsync's --files-from, so exactly the
+// scanned files are copied, without e.g. the skipped symlinks and the files with illegal names.
+// The entries are separated by NUL, to be read with --from0, as file names can contain newlines.
+// The caller removes the listing.
+func writeFilesFrom(sourceFolder string, files []string) (string, error) {
+	paths, err := transferPaths(sourceFolder, files)
+	if err != nil {
+		return "", err
+	}
+	listing, err := os.CreateTemp("", "scicat-files-from-*.txt")
+	if err != nil {
+		return "", fmt.Errorf("can't create the rsync file listing: %w", err)
+	}
+	for _, path := range paths {
+		if _, err = fmt.Fprintf(listing, "%s\x00", path); err != nil {
+			break
+		}
+	}
+	if closeErr := listing.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(listing.Name())
+		return "", fmt.Errorf("can't write the rsync file listing: %w", err)
+	}
+	return listing.Name(), nil
+}
+
 // Inspect the installed rsync binary
 func getRsyncCmd() (*RsyncCmd, error) {
 	path := "/usr/bin/rsync"
@@ -98,11 +137,12 @@ func detectRsyncStderrSupport(rsyncPath string) ([]string, error) {
 	return []string{}, nil
 }
 
-// Check rsync version and adjust command accordingly
+// Check rsync version and adjust command accordingly. The directories in absFileListing aren't
+// recursed into, the listing must hold all files to copy.
 func buildRsyncCmd(rsyncCmd *RsyncCmd, absFileListing, fullSourceFolderPath, serverConnectString string) *exec.Cmd {
 	rsyncFlags := []string{"-e", "ssh", "-avx", "--progress"}
 	if absFileListing != "" {
-		rsyncFlags = append([]string{"-r", "--files-from", absFileListing}, rsyncFlags...)
+		rsyncFlags = append([]string{"--files-from", absFileListing, "--from0"}, rsyncFlags...)
 	}
 	rsyncFlags = append(rsyncFlags, rsyncCmd.StderrFlags...)
 
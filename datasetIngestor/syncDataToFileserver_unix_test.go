@@ -4,6 +4,8 @@
 package datasetIngestor
 
 import (
+	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"testing"
@@ -41,7 +43,7 @@ func TestBuildRsyncCmd(t *testing.T) {
 			absFileListing:   "/path/to/file",
 			fullSourceFolder: "/source/folder",
 			serverConnectStr: "user@server:/dest/folder",
-			expectedCmd:      "/usr/bin/rsync -r --files-from /path/to/file -e ssh -avx --progress --stderr=error /source/folder user@server:/dest/folder",
+			expectedCmd:      "/usr/bin/rsync --files-from /path/to/file --from0 -e ssh -avx --progress --stderr=error /source/folder user@server:/dest/folder",
 		},
 		{
 			name:             "rsync version < 3.2.3, absFileListing not empty",
@@ -50,7 +52,7 @@ func TestBuildRsyncCmd(t *testing.T) {
 			absFileListing:   "/path/to/file",
 			fullSourceFolder: "/source/folder",
 			serverConnectStr: "user@server:/dest/folder",
-			expectedCmd:      "/usr/bin/rsync -r --files-from /path/to/file -e ssh -avx --progress -q --msgs2stderr /source/folder user@server:/dest/folder",
+			expectedCmd:      "/usr/bin/rsync --files-from /path/to/file --from0 -e ssh -avx --progress -q --msgs2stderr /source/folder user@server:/dest/folder",
 		},
 		{
 			name:             "rsync version >= 3.2.3, absFileListing empty",
@@ -92,3 +94,20 @@ func TestBuildRsyncCmd(t *testing.T) {
 		})
 	}
 }
+
+func TestWriteFilesFrom(t *testing.T) {
+	sourceFolder := t.TempDir()
+	listing, err := writeFilesFrom(sourceFolder, []string{"a", "a/b.h5", filepath.Join(sourceFolder, "c.h5"), "line\nbreak.h5"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(listing)
+
+	content, err := os.ReadFile(listing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "a\x00a/b.h5\x00c.h5\x00line\nbreak.h5\x00"; string(content) != want {
+		t.Errorf("got listing %q, want %q", content, want)
+	}
+}
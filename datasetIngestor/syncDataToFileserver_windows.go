@@ -2,17 +2,20 @@
 package datasetIngestor
 
 import (
+	"context"
 	"fmt"
 	"io"
-	"path"
 	"regexp"
 	"strings"
+
+	"github.com/paulscherrerinstitute/scicat-cli/v3/datasetUtils"
 )
 
-// copies data from a local machine to a fileserver, uses scp underneath
-func SyncLocalDataToFileserver(datasetId string, user map[string]string, RSYNCServer string, sourceFolder string, absFileListing string, commandOutput io.Writer) (err error) {
-	username := user["username"]
-	password := user["password"]
+// copies data from a local machine to a fileserver, uses scp underneath. Only the files, the paths
+// of the scanned files of the dataset, are copied. Cancelling ctx closes the connection.
+func SyncLocalDataToFileserver(ctx context.Context, datasetId string, user datasetUtils.User, RSYNCServer string, sourceFolder string, files []string, commandOutput io.Writer) (err error) {
+	username := user.Username
+	password := user.Password
 	shortDatasetId := strings.Split(datasetId, "/")[1]
 	// remove leading "C:"" if existing etc
 	ss := strings.Split(sourceFolder, ":")
@@ -25,38 +28,31 @@ func SyncLocalDataToFileserver(datasetId string, user map[string]string, RSYNCSe
 	destparts := strings.Split(destFull, separator)
 
 	destFolder := "archive/" + shortDatasetId + strings.Join(destparts[0:len(destparts)-1], "/")
-	destFolder2 := "archive/" + shortDatasetId + strings.Join(destparts[0:len(destparts)], "/")
 
 	c, err := NewDumbClient(username, password, RSYNCServer)
 
 	if err != nil {
 		return err
 	}
+	defer c.SshClient.Close()
+	stop := context.AfterFunc(ctx, func() { c.SshClient.Close() })
+	defer stop()
 
 	c.Quiet = false
 	c.PreseveTimes = true
 	re := regexp.MustCompile(`^\/([A-Z])\/`)
 
-	// now copy recursively: either just one sourceFolder or all entries inside absFileListing
+	// now copy the sourceFolder with the listed entries only
 	// Note: destfolder must exist before, needs dedicated scp server support
-
-	if absFileListing != "" {
-		lines, err := readLines(absFileListing)
-		if err != nil {
-			return fmt.Errorf("could not read filelist, readlines: %v", err)
-		}
-		for _, line := range lines {
-			windowsSource := re.ReplaceAllString(path.Join(sourceFolder, line), "$1:/")
-			fmt.Fprintf(commandOutput, "Copying data via scp from %s to %s\n", windowsSource, destFolder)
-			err = c.Send(destFolder2, windowsSource)
-			if err != nil {
-				return err
-			}
-		}
-	} else {
-		windowsSource := re.ReplaceAllString(sourceFolder, "$1:/")
-		fmt.Fprintf(commandOutput, "Copying data via scp from %s to %s\n", windowsSource, destFolder)
-		err = c.Send(destFolder, windowsSource)
+	paths, err := transferPaths(sourceFolder, files)
+	if err != nil {
+		return err
+	}
+	windowsSource := re.ReplaceAllString(sourceFolder, "$1:/")
+	fmt.Fprintf(commandOutput, "Copying %d entries via scp from %s to %s\n", len(paths), windowsSource, destFolder)
+	err = c.SendFileList(destFolder, windowsSource, paths)
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
 	}
 	return err
 }
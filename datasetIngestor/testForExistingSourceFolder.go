@@ -1,12 +1,15 @@
 package datasetIngestor
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"strings"
+
+	"github.com/paulscherrerinstitute/scicat-cli/v3/datasetUtils"
 )
 
 /*
@@ -33,6 +36,7 @@ type DatasetQuery []DatasetInfo
 TestForExistingSourceFolder checks if the provided source folders already exist on the API server.
 
 Parameters:
+- ctx: Cancels the requests.
 - folders: A slice of strings representing the source folders to check.
 - client: An http.Client object used to send the HTTP requests.
 - APIServer: A string representing the URL of the API server.
@@ -41,7 +45,7 @@ Parameters:
 
 The function splits the folders into chunks of 100 and sends a GET request to the API server for each chunk. If a source folder already exists, the function logs a warning and asks the user if they want to continue. If the user chooses not to continue, the function stops the process and logs an error message.
 */
-func TestForExistingSourceFolder(folders []string, client *http.Client, APIServer string, accessToken string) (foundList DatasetQuery, err error) {
+func TestForExistingSourceFolder(ctx context.Context, folders []string, client *http.Client, APIServer string, accessToken string) (foundList DatasetQuery, err error) {
 	// Split into chunks of 100 sourceFolders
 	const chunkSize = 100
 	all := len(folders)
@@ -55,7 +59,7 @@ func TestForExistingSourceFolder(folders []string, client *http.Client, APIServe
 		}
 
 		filter := createFilter(folders[start:end])
-		resp, err := datasetSearchRequest(client, APIServer, accessToken, filter)
+		resp, err := datasetSearchRequest(ctx, client, APIServer, accessToken, filter)
 		if err != nil {
 			return DatasetQuery{}, err
 		}
@@ -75,19 +79,8 @@ func createFilter(sourceFolderList []string) string {
 	return fmt.Sprintf("%s%s%s", header, strings.Join(sourceFolderList, "\",\""), tail)
 }
 
-func datasetSearchRequest(client *http.Client, APIServer string, token string, filter string) (*http.Response, error) {
-	req, err := http.NewRequest("GET", APIServer+"/datasets?filter="+url.QueryEscape(filter), nil)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("Authorization", "Bearer "+token)
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	return resp, nil
+func datasetSearchRequest(ctx context.Context, client *http.Client, APIServer string, token string, filter string) (*http.Response, error) {
+	return datasetUtils.NewAPIClient(client, APIServer, token).Send(ctx, "GET", "/datasets", url.Values{"filter": {filter}}, nil)
 }
 
 func processResponse(resp *http.Response) (DatasetQuery, error) {
@@ -2,6 +2,7 @@ package datasetIngestor
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -31,7 +32,7 @@ func TestTestForExistingSourceFolder(t *testing.T) {
 		accessToken := "testToken"
 
 		// TODO test the results of this function
-		TestForExistingSourceFolder(folders, client, APIServer, accessToken)
+		TestForExistingSourceFolder(context.Background(), folders, client, APIServer, accessToken)
 	})
 
 	t.Run("test with existing folders and allowExistingSourceFolder true", func(t *testing.T) {
@@ -53,7 +54,7 @@ func TestTestForExistingSourceFolder(t *testing.T) {
 		accessToken := "testToken"
 
 		// TODO test the results of this function.
-		TestForExistingSourceFolder(folders, client, APIServer, accessToken)
+		TestForExistingSourceFolder(context.Background(), folders, client, APIServer, accessToken)
 	})
 }
 
@@ -0,0 +1,26 @@
+package datasetIngestor
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// transferPaths returns the scanned files as slash separated paths relative to sourceFolder, the
+// way the transfers list them. Absolute paths, which come from absolute file listing lines, are
+// made relative to sourceFolder.
+func transferPaths(sourceFolder string, files []string) ([]string, error) {
+	paths := make([]string, 0, len(files))
+	for _, file := range files {
+		path := filepath.FromSlash(file)
+		if filepath.IsAbs(path) {
+			rel, err := filepath.Rel(sourceFolder, path)
+			if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				return nil, fmt.Errorf("file %q is outside of the sourceFolder %q", file, sourceFolder)
+			}
+			path = rel
+		}
+		paths = append(paths, filepath.ToSlash(path))
+	}
+	return paths, nil
+}
@@ -0,0 +1,23 @@
+package datasetIngestor
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTransferPaths(t *testing.T) {
+	sourceFolder := filepath.Join(t.TempDir(), "dataset")
+
+	paths, err := transferPaths(sourceFolder, []string{"a", "a/b.h5", filepath.Join(sourceFolder, "c", "d.h5")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "a,a/b.h5,c/d.h5"; strings.Join(paths, ",") != want {
+		t.Errorf("got %v, want %s", paths, want)
+	}
+
+	if _, err := transferPaths(sourceFolder, []string{filepath.Join(sourceFolder+"2", "x")}); err == nil {
+		t.Error("expected an error for a file outside of the sourceFolder")
+	}
+}
@@ -1,13 +1,15 @@
 package datasetIngestor
 
 import (
+	"context"
 	"encoding/json"
-	"io"
 	"log"
 	"net/http"
 	"net/url"
 	"strings"
 	"time"
+
+	"github.com/paulscherrerinstitute/scicat-cli/v3/datasetUtils"
 )
 
 const (
@@ -22,20 +24,21 @@ const (
 getAVFromPolicy retrieves the AV (?) from a policy.
 
 Parameters:
+- ctx: Cancels the policy request.
 - client: An HTTP client used to send requests.
 - APIServer: The URL of the API server.
-- user: A map containing user information. It should contain an "accessToken" key.
+- user: The user whose access token is used for the requests.
 - owner: The owner of the policy.
 
-The function constructs a URL using the APIServer, owner, and user's access token, and sends a GET request to this URL.
-If the response status code is 200, it reads the response body and unmarshals it into a slice of Policy structs.
+The function sends a GET request for the policies of the owner to the APIServer.
+If the request succeeds, it unmarshals the response into a slice of Policy structs.
 If there are no policies available for the owner, it logs a warning and sets the level to "low".
 If there are policies available, it sets the level to the TapeRedundancy of the first policy.
 
 Returns:
 - level: The TapeRedundancy level of the first policy if available, otherwise "low".
 */
-func getAVFromPolicy(client *http.Client, APIServer string, user map[string]string, owner string) (level string) {
+func getAVFromPolicy(ctx context.Context, client *http.Client, APIServer string, user datasetUtils.User, owner string) (level string) {
 	level = "low" // default value
 
 	filterMap := map[string]interface{}{
@@ -47,32 +50,15 @@ func getAVFromPolicy(client *http.Client, APIServer string, user map[string]stri
 	if err != nil {
 		log.Fatal(err)
 	}
-	var myurl = APIServer + "/Policies?filter=" + url.QueryEscape(string(filterBytes))
-	req, err := http.NewRequest("GET", myurl, nil)
-	if err != nil {
-		log.Fatal(err)
-	}
-	req.Header.Set("Authorization", "Bearer "+user["accessToken"])
-	req.Header.Set("Content-Type", "application/json")
-	resp, err := client.Do(req)
-	if err != nil {
-		return level
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != 200 {
-		return level
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return level
-	}
 	type Policy struct {
 		TapeRedundancy string
 		AutoArchive    bool
 	}
 	var policies []Policy
-	_ = json.Unmarshal(body, &policies)
+	query := url.Values{"filter": {string(filterBytes)}}
+	if err := datasetUtils.NewAPIClient(client, APIServer, user.AccessToken).Get(ctx, "/Policies", query, &policies); err != nil {
+		return level
+	}
 	if len(policies) > 0 {
 		level = policies[0].TapeRedundancy
 	}
@@ -83,9 +69,10 @@ func getAVFromPolicy(client *http.Client, APIServer string, user map[string]stri
 UpdateMetaData updates the metadata of a dataset.
 
 Parameters:
+- ctx: Cancels the policy request.
 - client: An HTTP client used to send requests.
 - APIServer: The URL of the API server.
-- user: A map containing user information. It should contain an "accessToken" key.
+- user: The user whose access token is used for the requests.
 - originalMap: A map containing the original metadata.
 - metaDataMap: A map containing the metadata to be updated.
 - startTime: The start time of the dataset.
@@ -105,13 +92,13 @@ The function logs a message each time it updates a field. If the "classification
 
 The function does not return a value.
 */
-func UpdateMetaData(client *http.Client, APIServer string, user map[string]string,
-	originalMap map[string]string, metaDataMap map[string]interface{}, startTime time.Time, endTime time.Time, owner string, tapecopies int) {
+func UpdateMetaData(ctx context.Context, client *http.Client, APIServer string, user datasetUtils.User,
+	originalMap map[string]interface{}, metaDataMap map[string]interface{}, startTime time.Time, endTime time.Time, owner string, tapecopies int) {
 	updateMetadataFromFileFields(originalMap, metaDataMap, startTime, endTime, owner)
-	updateStaticMetadataFields(client, APIServer, user, metaDataMap, tapecopies)
+	updateStaticMetadataFields(ctx, client, APIServer, user, metaDataMap, tapecopies)
 }
 
-func updateMetadataFromFileFields(originalMap map[string]string, metaDataMap map[string]interface{}, startTime time.Time, endTime time.Time, owner string) {
+func updateMetadataFromFileFields(originalMap map[string]interface{}, metaDataMap map[string]interface{}, startTime time.Time, endTime time.Time, owner string) {
 	updateFieldIfDummy(metaDataMap, originalMap, "creationTime", DUMMY_TIME, startTime)
 	updateFieldIfDummy(metaDataMap, originalMap, "ownerGroup", DUMMY_OWNER, owner)
 
@@ -120,15 +107,15 @@ func updateMetadataFromFileFields(originalMap map[string]string, metaDataMap map
 	}
 }
 
-func updateStaticMetadataFields(client *http.Client, APIServer string, user map[string]string, metaDataMap map[string]interface{}, tapecopies int) {
+func updateStaticMetadataFields(ctx context.Context, client *http.Client, APIServer string, user datasetUtils.User, metaDataMap map[string]interface{}, tapecopies int) {
 	addFieldIfNotExists(metaDataMap, "license", "CC BY-SA 4.0")
 	addFieldIfNotExists(metaDataMap, "isPublished", false)
-	updateClassificationField(client, APIServer, user, metaDataMap, tapecopies)
+	updateClassificationField(ctx, client, APIServer, user, metaDataMap, tapecopies)
 }
 
-func updateFieldIfDummy(metaDataMap map[string]interface{}, originalMap map[string]string, fieldName string, dummyValue interface{}, newValue interface{}) {
+func updateFieldIfDummy(metaDataMap map[string]interface{}, originalMap map[string]interface{}, fieldName string, dummyValue interface{}, newValue interface{}) {
 	if metaDataMap[fieldName] == dummyValue {
-		originalMap[fieldName] = metaDataMap[fieldName].(string)
+		originalMap[fieldName] = metaDataMap[fieldName]
 		metaDataMap[fieldName] = newValue
 	}
 }
@@ -139,9 +126,9 @@ func addFieldIfNotExists(metaDataMap map[string]interface{}, fieldName string, v
 	}
 }
 
-func updateClassificationField(client *http.Client, APIServer string, user map[string]string, metaDataMap map[string]interface{}, tapecopies int) {
+func updateClassificationField(ctx context.Context, client *http.Client, APIServer string, user datasetUtils.User, metaDataMap map[string]interface{}, tapecopies int) {
 	if _, ok := metaDataMap[Classification]; !ok {
-		addDefaultClassification(client, APIServer, user, metaDataMap)
+		addDefaultClassification(ctx, client, APIServer, user, metaDataMap)
 	}
 
 	if tapecopies == 1 || tapecopies == 2 {
@@ -149,8 +136,8 @@ func updateClassificationField(client *http.Client, APIServer string, user map[s
 	}
 }
 
-func addDefaultClassification(client *http.Client, APIServer string, user map[string]string, metaDataMap map[string]interface{}) {
-	metaDataMap[Classification] = INMedium + ",AV=" + getAVFromPolicy(client, APIServer, user, metaDataMap["ownerGroup"].(string)) + "," + COLow
+func addDefaultClassification(ctx context.Context, client *http.Client, APIServer string, user datasetUtils.User, metaDataMap map[string]interface{}) {
+	metaDataMap[Classification] = INMedium + ",AV=" + getAVFromPolicy(ctx, client, APIServer, user, metaDataMap["ownerGroup"].(string)) + "," + COLow
 }
 
 func updateAVField(metaDataMap map[string]interface{}, tapecopies int) {
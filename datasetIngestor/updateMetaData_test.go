@@ -1,6 +1,7 @@
 package datasetIngestor
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -8,6 +9,8 @@ import (
 	"reflect"
 	"testing"
 	"time"
+
+	"github.com/paulscherrerinstitute/scicat-cli/v3/datasetUtils"
 )
 
 func TestGetAVFromPolicy(t *testing.T) {
@@ -20,7 +23,7 @@ func TestGetAVFromPolicy(t *testing.T) {
 
 	client := ts1.Client()
 
-	level := getAVFromPolicy(client, ts1.URL, map[string]string{"accessToken": "testToken"}, "testOwner")
+	level := getAVFromPolicy(context.Background(), client, ts1.URL, datasetUtils.User{AccessToken: "testToken"}, "testOwner")
 
 	if level != "low" {
 		t.Errorf("Expected level to be 'low', got '%s'", level)
@@ -40,7 +43,7 @@ func TestGetAVFromPolicy(t *testing.T) {
 
 	client = ts2.Client()
 
-	level = getAVFromPolicy(client, ts2.URL, map[string]string{"accessToken": "testToken"}, "testOwner")
+	level = getAVFromPolicy(context.Background(), client, ts2.URL, datasetUtils.User{AccessToken: "testToken"}, "testOwner")
 
 	if level != "medium" {
 		t.Errorf("Expected level to be 'medium', got '%s'", level)
@@ -74,8 +77,8 @@ func TestUpdateMetaData(t *testing.T) {
 	// Define test parameters
 	APIServer := ts.URL // Use the mock server's URL
 
-	user := map[string]string{"accessToken": "testToken"}
-	originalMap := map[string]string{}
+	user := datasetUtils.User{AccessToken: "testToken"}
+	originalMap := map[string]interface{}{}
 	metaDataMap := map[string]interface{}{
 		"creationTime": DUMMY_TIME,
 		"ownerGroup":   DUMMY_OWNER,
@@ -88,7 +91,7 @@ func TestUpdateMetaData(t *testing.T) {
 	tapecopies := 1
 
 	// Call the function
-	UpdateMetaData(client, APIServer, user, originalMap, metaDataMap, startTime, endTime, owner, tapecopies)
+	UpdateMetaData(context.Background(), client, APIServer, user, originalMap, metaDataMap, startTime, endTime, owner, tapecopies)
 
 	// Check results
 	if metaDataMap["creationTime"] != startTime {
@@ -117,7 +120,7 @@ func TestUpdateFieldIfDummy(t *testing.T) {
 	metaDataMap := map[string]interface{}{
 		"testField": "DUMMY",
 	}
-	originalMap := map[string]string{}
+	originalMap := map[string]interface{}{}
 	fieldName := "testField"
 	dummyValue := "DUMMY"
 	newValue := "newValue"
@@ -164,7 +167,7 @@ func TestUpdateClassificationField(t *testing.T) {
 
 	// Define test parameters
 	APIServer := ts.URL // Use the mock server's URL
-	user := map[string]string{"accessToken": "testToken"}
+	user := datasetUtils.User{AccessToken: "testToken"}
 	metaDataMap := map[string]interface{}{
 		"ownerGroup": "testOwner",
 	}
@@ -174,7 +177,7 @@ func TestUpdateClassificationField(t *testing.T) {
 	expectedValue2 := "IN=medium,AV=medium,CO=low"
 
 	// Call the function
-	updateClassificationField(client, APIServer, user, metaDataMap, tapecopies)
+	updateClassificationField(context.Background(), client, APIServer, user, metaDataMap, tapecopies)
 
 	// Check results
 	if _, ok := metaDataMap["classification"]; !ok {
@@ -185,7 +188,7 @@ func TestUpdateClassificationField(t *testing.T) {
 
 	// Change tapecopies to 2 and call the function again
 	tapecopies = 2
-	updateClassificationField(client, APIServer, user, metaDataMap, tapecopies)
+	updateClassificationField(context.Background(), client, APIServer, user, metaDataMap, tapecopies)
 
 	// Check results
 	if _, ok := metaDataMap["classification"]; !ok {
@@ -0,0 +1,76 @@
+package datasetUtils
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+)
+
+// ArchiveManagerUser is the account that was traditionally the only one allowed to run the
+// privileged commands. It's the default AccessRule of those commands.
+const ArchiveManagerUser = "archiveManager"
+
+// AccessRule lists who may run a privileged operation: users whose username, one of whose roles
+// or one of whose access groups is listed. An empty rule allows nobody.
+type AccessRule struct {
+	Users  []string `yaml:"users,omitempty" json:"users,omitempty"`
+	Roles  []string `yaml:"roles,omitempty" json:"roles,omitempty"`
+	Groups []string `yaml:"groups,omitempty" json:"groups,omitempty"`
+}
+
+// DefaultAccessRule only allows the archiveManager account.
+var DefaultAccessRule = AccessRule{Users: []string{ArchiveManagerUser}}
+
+// IsEmpty reports whether the rule lists no users, roles or groups.
+func (r AccessRule) IsEmpty() bool {
+	return len(r.Users) == 0 && len(r.Roles) == 0 && len(r.Groups) == 0
+}
+
+// Match returns why user is allowed by the rule (e.g. `role "admin"`), or false if it isn't.
+func (r AccessRule) Match(user User) (string, bool) {
+	if user.Username != "" && slices.Contains(r.Users, user.Username) {
+		return fmt.Sprintf("username %q", user.Username), true
+	}
+	for _, role := range user.Roles {
+		if slices.Contains(r.Roles, role) {
+			return fmt.Sprintf("role %q", role), true
+		}
+	}
+	for _, group := range user.AccessGroups {
+		if slices.Contains(r.Groups, group) {
+			return fmt.Sprintf("group %q", group), true
+		}
+	}
+	return "", false
+}
+
+// String describes the rule for error messages.
+func (r AccessRule) String() string {
+	if r.IsEmpty() {
+		return "nobody"
+	}
+	parts := []string{}
+	if len(r.Users) > 0 {
+		parts = append(parts, "users "+strings.Join(r.Users, ", "))
+	}
+	if len(r.Roles) > 0 {
+		parts = append(parts, "roles "+strings.Join(r.Roles, ", "))
+	}
+	if len(r.Groups) > 0 {
+		parts = append(parts, "groups "+strings.Join(r.Groups, ", "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Authorize returns an error if user isn't allowed by rule to perform action (e.g. "delete
+// datasets"). Granted access is logged along with the reason, so it's clear which named account
+// ran the operation.
+func Authorize(user User, rule AccessRule, action string) error {
+	reason, ok := rule.Match(user)
+	if !ok {
+		return fmt.Errorf("user %q is not allowed to %s, this requires one of: %s", user.Username, action, rule)
+	}
+	log.Printf("User %s is allowed to %s by %s\n", user.Username, action, reason)
+	return nil
+}
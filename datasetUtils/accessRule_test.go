@@ -0,0 +1,42 @@
+package datasetUtils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAuthorize(t *testing.T) {
+	rule := AccessRule{
+		Users:  []string{"archiveManager"},
+		Roles:  []string{"admin"},
+		Groups: []string{"archive-admins"},
+	}
+
+	tests := []struct {
+		name    string
+		rule    AccessRule
+		user    User
+		wantErr bool
+	}{
+		{name: "listed user", rule: rule, user: User{Username: "archiveManager"}},
+		{name: "listed role", rule: rule, user: User{Username: "alice", Roles: []string{"user", "admin"}}},
+		{name: "listed group", rule: rule, user: User{Username: "bob", AccessGroups: []string{"p12345", "archive-admins"}}},
+		{name: "not listed", rule: rule, user: User{Username: "eve", Roles: []string{"user"}, AccessGroups: []string{"p12345"}}, wantErr: true},
+		{name: "roles aren't groups", rule: rule, user: User{Username: "eve", AccessGroups: []string{"admin"}}, wantErr: true},
+		{name: "default rule", rule: DefaultAccessRule, user: User{Username: "archiveManager"}},
+		{name: "empty rule", rule: AccessRule{}, user: User{Username: "archiveManager"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Authorize(tt.user, tt.rule, "delete datasets")
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "not allowed to delete datasets") {
+					t.Errorf("expected an authorization error, got %v", err)
+				}
+			} else if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
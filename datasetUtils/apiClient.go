@@ -0,0 +1,333 @@
+package datasetUtils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy defines how an APIClient retries requests that failed for transient reasons.
+type RetryPolicy struct {
+	// MaxRetries is the number of retries after the first attempt, 0 disables retrying.
+	MaxRetries int
+	// InitialBackoff is the delay before the first retry, it doubles with every further retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between two attempts, including delays requested by the
+	// server with Retry-After.
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryPolicy is the retry policy of clients created by NewAPIClient.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries:     4,
+	InitialBackoff: time.Second,
+	MaxBackoff:     time.Minute,
+}
+
+// APIClient sends requests to the SciCat API. The access token is always sent in the
+// Authorization header, requests failing with 429, 5xx or a dropped connection are retried
+// according to Retry, and unsuccessful responses are returned as *APIError.
+type APIClient struct {
+	HTTPClient *http.Client
+	// BaseURL is the URL of the API, e.g. "https://dacat.psi.ch/api/v3"; request paths are
+	// appended to it.
+	BaseURL string
+	// Token is the access token, requests are sent without authorization if it's empty.
+	Token string
+	Retry RetryPolicy
+}
+
+// NewAPIClient returns a client of the API at APIServer authenticating with token.
+func NewAPIClient(client *http.Client, APIServer string, token string) *APIClient {
+	return &APIClient{
+		HTTPClient: client,
+		BaseURL:    APIServer,
+		Token:      token,
+		Retry:      DefaultRetryPolicy,
+	}
+}
+
+// APIError is an unsuccessful response of the API.
+type APIError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	// Message is the error message decoded from the SciCat error body, or the body itself if it
+	// isn't in a known format.
+	Message string
+	Body    []byte
+}
+
+func (e *APIError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = http.StatusText(e.StatusCode)
+	}
+	return fmt.Sprintf("%s %s returned status %d: %s", e.Method, e.Path, e.StatusCode, msg)
+}
+
+// IsAPIStatus reports whether err is an *APIError with the given status code.
+func IsAPIStatus(err error, statusCode int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == statusCode
+}
+
+// Get sends a GET request and decodes the JSON response into out, unless out is nil.
+func (c *APIClient) Get(ctx context.Context, path string, query url.Values, out any) error {
+	return c.Do(ctx, http.MethodGet, path, query, nil, out)
+}
+
+// Post sends body as JSON and decodes the JSON response into out, unless out is nil.
+func (c *APIClient) Post(ctx context.Context, path string, body any, out any) error {
+	return c.Do(ctx, http.MethodPost, path, nil, body, out)
+}
+
+// Patch sends body as JSON and decodes the JSON response into out, unless out is nil.
+func (c *APIClient) Patch(ctx context.Context, path string, body any, out any) error {
+	return c.Do(ctx, http.MethodPatch, path, nil, body, out)
+}
+
+// Delete sends a DELETE request.
+func (c *APIClient) Delete(ctx context.Context, path string) error {
+	return c.Do(ctx, http.MethodDelete, path, nil, nil, nil)
+}
+
+// Do sends a request like Send and decodes the JSON response into out, unless out is nil.
+func (c *APIClient) Do(ctx context.Context, method string, path string, query url.Values, body any, out any) error {
+	resp, err := c.Send(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("can't decode the response of %s %s: %v", method, path, err)
+	}
+	return nil
+}
+
+// Send sends a request to path (which has to be escaped already) with the query parameters
+// query. body is sent as is if it's a []byte and encoded as JSON otherwise, nil sends no body.
+// The successful (2xx) response is returned for the caller to read and close, any other status
+// results in an *APIError. Cancelling ctx aborts the request and any pending retry.
+func (c *APIClient) Send(ctx context.Context, method string, path string, query url.Values, body any) (*http.Response, error) {
+	var data []byte
+	switch b := body.(type) {
+	case nil:
+	case []byte:
+		data = b
+	default:
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return nil, fmt.Errorf("can't encode the body of %s %s: %v", method, path, err)
+		}
+	}
+	myurl := c.BaseURL + path
+	if len(query) > 0 {
+		myurl += "?" + query.Encode()
+	}
+
+	for attempt := 0; ; attempt++ {
+		var reqBody io.Reader
+		if data != nil {
+			reqBody = bytes.NewReader(data)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, myurl, reqBody)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/json")
+		if data != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if c.Token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.Token)
+		}
+
+		retry := attempt < c.Retry.MaxRetries
+		resp, err := c.HTTPClient.Do(req)
+		if err != nil {
+			if !retry || ctx.Err() != nil || !isRetryableError(method, err) {
+				return nil, err
+			}
+			delay := c.Retry.backoff(attempt)
+			log.Printf("%s %s failed (%v), retrying in %v\n", method, path, err, delay)
+			if err := sleepContext(ctx, delay); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return resp, nil
+		}
+
+		apiErr := newAPIError(method, path, resp)
+		if !retry || !isRetryableStatus(method, resp.StatusCode) {
+			return nil, apiErr
+		}
+		delay, ok := retryAfter(resp.Header, c.Retry.MaxBackoff)
+		if !ok {
+			delay = c.Retry.backoff(attempt)
+		}
+		log.Printf("%s %s returned status %d, retrying in %v\n", method, path, resp.StatusCode, delay)
+		if err := sleepContext(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// sleepContext waits for d, or returns the error of ctx if it's cancelled before.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// newAPIError reads and closes the body of the unsuccessful response resp.
+func newAPIError(method string, path string, resp *http.Response) *APIError {
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	return &APIError{
+		Method:     method,
+		Path:       path,
+		StatusCode: resp.StatusCode,
+		Message:    decodeErrorMessage(body),
+		Body:       body,
+	}
+}
+
+// decodeErrorMessage extracts the message of a SciCat error body, which looks like
+// {"statusCode": 400, "message": "..." or ["...", ...], "error": "Bad Request"} in SciCat 4 and
+// like {"error": {"statusCode": 400, "name": "...", "message": "..."}} in SciCat 3.
+func decodeErrorMessage(body []byte) string {
+	var e struct {
+		Message json.RawMessage `json:"message"`
+		Error   json.RawMessage `json:"error"`
+	}
+	if json.Unmarshal(body, &e) != nil {
+		return strings.TrimSpace(string(body))
+	}
+	if msg := rawMessage(e.Message); msg != "" {
+		return msg
+	}
+	var nested struct {
+		Message json.RawMessage `json:"message"`
+	}
+	if json.Unmarshal(e.Error, &nested) == nil {
+		if msg := rawMessage(nested.Message); msg != "" {
+			return msg
+		}
+	}
+	if msg := rawMessage(e.Error); msg != "" {
+		return msg
+	}
+	return strings.TrimSpace(string(body))
+}
+
+// rawMessage returns a JSON string, or the strings of a JSON array joined by "; ".
+func rawMessage(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var list []string
+	if json.Unmarshal(raw, &list) == nil {
+		return strings.Join(list, "; ")
+	}
+	return ""
+}
+
+// isIdempotent reports whether a request can be repeated without changing its effect. PATCH
+// requests of the API only set fields and are therefore treated as idempotent.
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodPatch, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// isRetryableStatus reports whether a request that failed with statusCode is retried. 429 and 503
+// mean the request was rejected without being processed, other server errors are only retried
+// for idempotent requests as e.g. a POST may have created a document despite the error.
+func isRetryableStatus(method string, statusCode int) bool {
+	switch {
+	case statusCode == http.StatusTooManyRequests || statusCode == http.StatusServiceUnavailable:
+		return true
+	case statusCode >= 500:
+		return isIdempotent(method)
+	}
+	return false
+}
+
+// isRetryableError reports whether a request failing with the network error err is retried. A
+// refused connection never reached the server, a connection reset or closed while waiting for
+// the response is only retried for idempotent requests.
+func isRetryableError(method string, err error) bool {
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return isIdempotent(method)
+	}
+	return false
+}
+
+// backoff returns the delay before retry number attempt+1: exponential with jitter, so parallel
+// requests don't retry in lockstep.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.InitialBackoff
+	for i := 0; i < attempt && (p.MaxBackoff <= 0 || delay < p.MaxBackoff); i++ {
+		delay *= 2
+	}
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// retryAfter returns the delay requested by the Retry-After header, in seconds or as HTTP date,
+// limited to max (unless max is 0).
+func retryAfter(header http.Header, max time.Duration) (time.Duration, bool) {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	var delay time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		delay = time.Duration(seconds) * time.Second
+	} else if t, err := http.ParseTime(value); err == nil {
+		delay = time.Until(t)
+	} else {
+		return 0, false
+	}
+	if delay < 0 {
+		delay = 0
+	}
+	if max > 0 && delay > max {
+		delay = max
+	}
+	return delay, true
+}
@@ -0,0 +1,162 @@
+package datasetUtils
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+var fastRetries = RetryPolicy{MaxRetries: 3, InitialBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond}
+
+func TestAPIClientRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if got := req.Header.Get("Authorization"); got != "Bearer testToken" {
+			t.Errorf("Authorization header = %q, want %q", got, "Bearer testToken")
+		}
+		if req.URL.Query().Has("access_token") {
+			t.Errorf("the token was sent in the query: %s", req.URL.RawQuery)
+		}
+		if got := req.URL.Query().Get("filter"); got != `{"a":1}` {
+			t.Errorf("filter = %q, want %q", got, `{"a":1}`)
+		}
+		rw.Write([]byte(`{"pid": "1234"}`))
+	}))
+	defer server.Close()
+
+	api := NewAPIClient(server.Client(), server.URL, "testToken")
+	var out struct {
+		Pid string `json:"pid"`
+	}
+	if err := api.Get(context.Background(), "/Datasets", url.Values{"filter": {`{"a":1}`}}, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.Pid != "1234" {
+		t.Errorf("pid = %q, want %q", out.Pid, "1234")
+	}
+}
+
+func TestAPIClientRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		statuses     []int
+		retryAfter   string
+		wantAttempts int
+		wantStatus   int
+	}{
+		{name: "GET is retried on 500", method: "GET", statuses: []int{500, 502, 200}, wantAttempts: 3},
+		{name: "POST is retried on 429", method: "POST", statuses: []int{429, 201}, wantAttempts: 2},
+		{name: "POST is retried on 503", method: "POST", statuses: []int{503, 200}, wantAttempts: 2},
+		{name: "POST isn't retried on 500", method: "POST", statuses: []int{500, 200}, wantAttempts: 1, wantStatus: 500},
+		{name: "client errors aren't retried", method: "GET", statuses: []int{404, 200}, wantAttempts: 1, wantStatus: 404},
+		{name: "retries are limited", method: "GET", statuses: []int{503, 503, 503, 503, 503}, wantAttempts: 4, wantStatus: 503},
+		{name: "Retry-After is honoured", method: "PATCH", statuses: []int{429, 200}, retryAfter: "0", wantAttempts: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				status := tt.statuses[attempts]
+				attempts++
+				if tt.retryAfter != "" {
+					rw.Header().Set("Retry-After", tt.retryAfter)
+				}
+				rw.WriteHeader(status)
+				rw.Write([]byte(`{}`))
+			}))
+			defer server.Close()
+
+			api := NewAPIClient(server.Client(), server.URL, "testToken")
+			api.Retry = fastRetries
+			err := api.Do(context.Background(), tt.method, "/Jobs", nil, map[string]string{"a": "b"}, nil)
+			if tt.wantStatus == 0 && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if tt.wantStatus != 0 && !IsAPIStatus(err, tt.wantStatus) {
+				t.Errorf("got error %v, want status %d", err, tt.wantStatus)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("got %d attempts, want %d", attempts, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestAPIClientRetryAfterDelay(t *testing.T) {
+	attempts := 0
+	var first time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		attempts++
+		if attempts == 1 {
+			first = time.Now()
+			rw.Header().Set("Retry-After", "1")
+			rw.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		if waited := time.Since(first); waited < 900*time.Millisecond {
+			t.Errorf("retried after %v, want 1s as requested by Retry-After", waited)
+		}
+	}))
+	defer server.Close()
+
+	api := NewAPIClient(server.Client(), server.URL, "")
+	api.Retry = RetryPolicy{MaxRetries: 1, InitialBackoff: time.Millisecond, MaxBackoff: 5 * time.Second}
+	if err := api.Get(context.Background(), "/", nil, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestAPIClientRetriesRefusedConnections(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {}))
+	serverURL := server.URL
+	server.Close()
+
+	api := NewAPIClient(&http.Client{}, serverURL, "")
+	api.Retry = fastRetries
+	err := api.Post(context.Background(), "/Datasets", map[string]string{}, nil)
+	var apiErr *APIError
+	if err == nil || errors.As(err, &apiErr) {
+		t.Errorf("expected a connection error, got %v", err)
+	}
+}
+
+func TestDecodeErrorMessage(t *testing.T) {
+	tests := []struct {
+		body string
+		want string
+	}{
+		{`{"statusCode":400,"message":"ownerGroup must be a string","error":"Bad Request"}`, "ownerGroup must be a string"},
+		{`{"statusCode":400,"message":["a is missing","b is missing"],"error":"Bad Request"}`, "a is missing; b is missing"},
+		{`{"error":{"statusCode":401,"name":"Error","message":"Authorization Required"}}`, "Authorization Required"},
+		{`{"statusCode":403,"error":"Forbidden"}`, "Forbidden"},
+		{"invalid username or password\n", "invalid username or password"},
+		{`[1, 2]`, "[1, 2]"},
+	}
+	for _, tt := range tests {
+		if got := decodeErrorMessage([]byte(tt.body)); got != tt.want {
+			t.Errorf("decodeErrorMessage(%q) = %q, want %q", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestAPIErrorMessage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusBadRequest)
+		rw.Write([]byte(`{"statusCode":400,"message":"invalid pid","error":"Bad Request"}`))
+	}))
+	defer server.Close()
+
+	err := NewAPIClient(server.Client(), server.URL, "testToken").Delete(context.Background(), "/Datasets/x")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected an APIError, got %v", err)
+	}
+	want := "DELETE /Datasets/x returned status 400: invalid pid"
+	if err.Error() != want {
+		t.Errorf("got error %q, want %q", err.Error(), want)
+	}
+}
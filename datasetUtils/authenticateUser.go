@@ -1,10 +1,10 @@
 package datasetUtils
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
 )
 
@@ -25,6 +25,7 @@ type identityResponse struct {
 		DisplayName  string   `json:"displayName"`
 		Email        string   `json:"email"`
 		AccessGroups []string `json:"accessGroups"`
+		Roles        []string `json:"roles"`
 	} `json:"profile"`
 }
 
@@ -36,77 +37,40 @@ func newLoginRequestJson(username string, password string) ([]byte, error) {
 	return json.Marshal(l)
 }
 
-func AuthenticateUser(client *http.Client, APIServer string, username string, password string, ldapLogin bool) (map[string]string, []string, error) {
+func AuthenticateUser(ctx context.Context, client *http.Client, APIServer string, username string, password string, ldapLogin bool) (User, error) {
 	loginReqJson, err := newLoginRequestJson(username, password)
 	if err != nil {
-		return map[string]string{}, []string{}, err
+		return User{}, err
 	}
 
-	reqUrl := APIServer + "/auth/login" // "local" user login
+	path := "/auth/login" // "local" user login
 	if ldapLogin {
-		reqUrl = APIServer + "/auth/ldap" // "normal" user login
+		path = "/auth/ldap" // "normal" user login
 	}
-	req, err := http.NewRequest("POST", reqUrl, bytes.NewBuffer(loginReqJson))
-	if err != nil {
-		return map[string]string{}, []string{}, err
-	}
-	req.Header.Set("Content-Type", "application/json")
-	resp, err := client.Do(req)
-	if err != nil {
-		return map[string]string{}, []string{}, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 201 {
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return map[string]string{}, []string{}, fmt.Errorf("error when logging in: unknown error (can't parse body)")
-		}
-		return map[string]string{}, []string{}, fmt.Errorf("error when logging in: '%s'", string(body))
-	}
-
-	respJson, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return map[string]string{}, []string{}, err
-	}
-
 	var lr loginResponse
-	err = json.Unmarshal(respJson, &lr)
-	if err != nil {
-		return map[string]string{}, []string{}, err
+	err = NewAPIClient(client, APIServer, "").Post(ctx, path, loginReqJson, &lr)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return User{}, fmt.Errorf("error when logging in: '%s'", apiErr.Message)
 	}
-
-	req, err = http.NewRequest("GET", APIServer+"/users/my/identity", nil)
-	if err != nil {
-		return map[string]string{}, []string{}, err
-	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+lr.AccessToken)
-
-	resp, err = client.Do(req)
 	if err != nil {
-		return map[string]string{}, []string{}, err
-	}
-	defer resp.Body.Close()
-
-	respJson, err = io.ReadAll(resp.Body)
-	if err != nil {
-		return map[string]string{}, []string{}, err
+		return User{}, err
 	}
 
 	var ir identityResponse
-	err = json.Unmarshal(respJson, &ir)
-	if err != nil {
-		return map[string]string{}, []string{}, err
+	if err := NewAPIClient(client, APIServer, lr.AccessToken).Get(ctx, "/users/my/identity", nil, &ir); err != nil {
+		return User{}, fmt.Errorf("can't get the identity of user %s: %w", username, err)
 	}
 
-	u := make(map[string]string)
-	u["username"] = ir.Profile.Username
-	u["mail"] = ir.Profile.Email
-	u["displayName"] = ir.Profile.DisplayName
-	u["accessToken"] = lr.AccessToken
-	u["expiresIn"] = fmt.Sprintf("%d", lr.ExpiresIn)
-	u["created"] = lr.Created
-	u["password"] = password
-	return u, ir.Profile.AccessGroups, nil
+	return User{
+		Username:     ir.Profile.Username,
+		Mail:         ir.Profile.Email,
+		DisplayName:  ir.Profile.DisplayName,
+		AccessGroups: ir.Profile.AccessGroups,
+		Roles:        ir.Profile.Roles,
+		AccessToken:  lr.AccessToken,
+		ExpiresIn:    lr.ExpiresIn,
+		Created:      lr.Created,
+		Password:     password,
+	}, nil
 }
@@ -1,7 +1,9 @@
 package datasetUtils
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -150,10 +152,10 @@ func TestAuthenticateUser(t *testing.T) {
 		currentToken       string
 		groupsReturned     []string
 
-		useToken    bool
-		ldapLogin   bool
-		wantError   string
-		wantUserMap map[string]string
+		useToken  bool
+		ldapLogin bool
+		wantError string
+		wantUser  User
 	}{
 		{
 			testName: "Basic user and pass",
@@ -170,14 +172,15 @@ func TestAuthenticateUser(t *testing.T) {
 			useToken:  false,
 			ldapLogin: false,
 			wantError: "",
-			wantUserMap: map[string]string{
-				"username":    "user",
-				"mail":        "user@your.site",
-				"displayName": "Some Name",
-				"accessToken": "sometoken",
-				"expiresIn":   "3600",
-				"created":     timeStamp,
-				"password":    "password",
+			wantUser: User{
+				Username:     "user",
+				Mail:         "user@your.site",
+				DisplayName:  "Some Name",
+				AccessToken:  "sometoken",
+				ExpiresIn:    3600,
+				Created:      timeStamp,
+				AccessGroups: []string{"group1", "group2"},
+				Password:     "password",
 			},
 		},
 		{
@@ -221,7 +224,7 @@ func TestAuthenticateUser(t *testing.T) {
 		groupsToReturn = test.groupsReturned
 
 		t.Run(test.testName, func(t *testing.T) {
-			user, group, err := AuthenticateUser(server.Client(), server.URL, test.usedUsername, test.usedPass, test.ldapLogin)
+			user, err := AuthenticateUser(context.Background(), server.Client(), server.URL, test.usedUsername, test.usedPass, test.ldapLogin)
 
 			if test.wantError != "" {
 				if err == nil {
@@ -236,14 +239,19 @@ func TestAuthenticateUser(t *testing.T) {
 				t.Errorf("authenticate returned an error: %s", err.Error())
 			}
 
-			if !reflect.DeepEqual(user, test.wantUserMap) {
-				t.Errorf("got %v, want %v", user, test.wantUserMap)
-			}
-
-			if !reflect.DeepEqual(group, test.groupsReturned) {
-				t.Errorf("got %v, want %v", group, test.groupsReturned)
+			if !reflect.DeepEqual(user, test.wantUser) {
+				t.Errorf("got %v, want %v", user, test.wantUser)
 			}
 		})
 	}
 	_ = tests
+
+	t.Run("a cancelled context aborts the login", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := AuthenticateUser(ctx, server.Client(), server.URL, "user", "password", false)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected the login to be cancelled, got %v", err)
+		}
+	})
 }
@@ -1,10 +1,10 @@
 package datasetUtils
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"time"
 )
@@ -25,25 +25,26 @@ type ArchivalJobOptions struct {
 }
 
 /*
-`CreateArchivalJob` creates a new job on the server. It takes in an HTTP client, the API server URL, a user map, a list of datasets, and a set of job options.
+`CreateArchivalJob` creates a new job on the server. It takes in an HTTP client, the API server URL, the user, a list of datasets, and a set of job options.
 
 The function constructs a job map with various parameters, including the email of the job initiator, the type of job, the creation time, the job parameters, and the job status message. It also includes a list of datasets.
 
-The job map is then marshalled into JSON and sent as a POST request to the server. If the server responds with a status code of 200, the function decodes the job ID from the response and returns it. If the server responds with any other status code, the function returns an empty string.
+The job map is then marshalled into JSON and sent as a POST request to the server. If the server responds with a 2xx status code, the function decodes the job ID from the response and returns it. If the server responds with any other status code, the function returns an error.
 
 Note that the job will belong to one specific ownerGroup. Use CreateArchivalJobs to create a job per ownergroup.
 
 Parameters:
+- ctx: Cancels the request.
 - client: A pointer to an http.Client instance
 - APIServer: A string representing the API server URL
-- user: A map with string keys and values representing user information
+- user: The user submitting the job
 - datasetMap: A list of datasets grouped by ownerGroups
 - opts: The archival job's optional parameters (tape copies, transfer type, execution time)
 
 Returns:
 - jobId: A string representing the job ID if the job was successfully created, or an empty string otherwise
 */
-func CreateArchivalJob(client *http.Client, APIServer string, user map[string]string, ownerGroup string, datasetList []string, opts ArchivalJobOptions) (jobId string, err error) {
+func CreateArchivalJob(ctx context.Context, client *http.Client, APIServer string, user User, ownerGroup string, datasetList []string, opts ArchivalJobOptions) (jobId string, err error) {
 	// important: define field with capital names and rename fields via 'json' constructs
 	// otherwise the marshaling will omit the fields !
 
@@ -61,7 +62,7 @@ func CreateArchivalJob(client *http.Client, APIServer string, user map[string]st
 
 	jobParams := jobParamsStruct{
 		TapeCopies:  "one",
-		Username:    user["username"],
+		Username:    user.Username,
 		OwnerGroup:  ownerGroup,
 		LandingZone: make(map[string]LandingZoneEntry),
 	}
@@ -100,7 +101,7 @@ func CreateArchivalJob(client *http.Client, APIServer string, user map[string]st
 		JobParams:        jobParams,
 		JobStatusMessage: "jobSubmitted",
 		DatasetList:      dsMap,
-		ContactEmail:     user["mail"],
+		ContactEmail:     user.Mail,
 		ExecutionTime:    opts.ExecutionTime,
 	}
 
@@ -112,26 +113,18 @@ func CreateArchivalJob(client *http.Client, APIServer string, user map[string]st
 	// fmt.Printf("Marshalled job description : %s\n", string(bmm))
 
 	// now send  archive job request
-	myurl := APIServer + "/jobs"
-	req, err := http.NewRequest("POST", myurl, bytes.NewBuffer(bmm))
-	req.Header.Set("Authorization", "Bearer "+user["accessToken"])
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := client.Do(req)
+	resp, err := NewAPIClient(client, APIServer, user.AccessToken).Send(ctx, "POST", "/jobs", nil, bmm)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return "", fmt.Errorf("CreateJob - request returned error status code: %d, body: %s", apiErr.StatusCode, string(apiErr.Body))
+	}
 	if err != nil {
 		return "", err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return "", fmt.Errorf("CreateJob - request returned error status code: %d", resp.StatusCode)
-		}
-		return "", fmt.Errorf("CreateJob - request returned error status code: %d, body: %s", resp.StatusCode, string(body))
-	}
 	// the request succeeded based on status code
-	// an email should be sent by SciCat to user["email"]
+	// an email should be sent by SciCat to user.Mail
 	decoder := json.NewDecoder(resp.Body)
 	var j Job
 	err = decoder.Decode(&j)
@@ -142,12 +135,12 @@ func CreateArchivalJob(client *http.Client, APIServer string, user map[string]st
 }
 
 // Auxiliary function to CreateArchivalJob when you need to use a list of datasets grouped by ownerGroups
-func CreateArchivalJobs(client *http.Client, APIServer string, user map[string]string, groupedDatasetLists map[string][]string, opts ArchivalJobOptions) (jobIds []string, errs []error) {
+func CreateArchivalJobs(ctx context.Context, client *http.Client, APIServer string, user User, groupedDatasetLists map[string][]string, opts ArchivalJobOptions) (jobIds []string, errs []error) {
 	jobIds = make([]string, len(groupedDatasetLists))
 	errs = make([]error, len(groupedDatasetLists))
 	i := 0
 	for group := range groupedDatasetLists {
-		jobIds[i], errs[i] = CreateArchivalJob(client, APIServer, user, group, groupedDatasetLists[group], opts)
+		jobIds[i], errs[i] = CreateArchivalJob(ctx, client, APIServer, user, group, groupedDatasetLists[group], opts)
 		i++
 	}
 	return jobIds, errs
@@ -2,6 +2,7 @@ package datasetUtils
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
@@ -32,17 +33,17 @@ func TestCreateJob(t *testing.T) {
 
 		// Define the parameters
 		APIServer := server.URL
-		user := map[string]string{
-			"mail":        "test@example.com",
-			"username":    "testuser",
-			"accessToken": "testtoken",
+		user := User{
+			Mail:        "test@example.com",
+			Username:    "testuser",
+			AccessToken: "testtoken",
 		}
 		datasetList := []string{"dataset1", "dataset2"}
 		tapecopies := new(int)
 		*tapecopies = 1
 
 		// Call the function
-		jobId, err := CreateArchivalJob(client, APIServer, user, "group1", datasetList, ArchivalJobOptions{TapeCopies: tapecopies})
+		jobId, err := CreateArchivalJob(context.Background(), client, APIServer, user, "group1", datasetList, ArchivalJobOptions{TapeCopies: tapecopies})
 		if err != nil {
 			t.Errorf("Unexpected error received: %v", err)
 		}
@@ -65,17 +66,17 @@ func TestCreateJob(t *testing.T) {
 
 		// Define the parameters
 		APIServer := server.URL
-		user := map[string]string{
-			"mail":        "test@example.com",
-			"username":    "testuser",
-			"accessToken": "testtoken",
+		user := User{
+			Mail:        "test@example.com",
+			Username:    "testuser",
+			AccessToken: "testtoken",
 		}
 		datasetList := []string{"dataset1", "dataset2"}
 		tapecopies := new(int)
 		*tapecopies = 1
 
 		// Call the function
-		jobId, err := CreateArchivalJob(client, APIServer, user, "group1", datasetList, ArchivalJobOptions{TapeCopies: tapecopies})
+		jobId, err := CreateArchivalJob(context.Background(), client, APIServer, user, "group1", datasetList, ArchivalJobOptions{TapeCopies: tapecopies})
 		if err == nil {
 			t.Errorf("Expected an error to be returned from CreateJob")
 		}
@@ -103,17 +104,17 @@ func TestCreateJob(t *testing.T) {
 
 		// Define the parameters
 		APIServer := server.URL
-		user := map[string]string{
-			"mail":        "test@example.com",
-			"username":    "testuser",
-			"accessToken": "testtoken",
+		user := User{
+			Mail:        "test@example.com",
+			Username:    "testuser",
+			AccessToken: "testtoken",
 		}
 		datasetList := []string{"dataset1", "dataset2"}
 		tapecopies := new(int)
 		*tapecopies = 1
 
 		// Call the function
-		jobId, err := CreateArchivalJob(client, APIServer, user, "group1", datasetList, ArchivalJobOptions{TapeCopies: tapecopies})
+		jobId, err := CreateArchivalJob(context.Background(), client, APIServer, user, "group1", datasetList, ArchivalJobOptions{TapeCopies: tapecopies})
 
 		if err == nil {
 			t.Error("Expected an error to be returned from CreateJob")
@@ -137,10 +138,10 @@ func TestCreateJob(t *testing.T) {
 		}))
 		defer server.Close()
 
-		user := map[string]string{
-			"mail":        "test@example.com",
-			"username":    "testuser",
-			"accessToken": "testtoken",
+		user := User{
+			Mail:        "test@example.com",
+			Username:    "testuser",
+			AccessToken: "testtoken",
 		}
 		datasetList := []string{"dataset1", "dataset2"}
 		tapecopies := new(int)
@@ -196,7 +197,7 @@ func TestCreateJob(t *testing.T) {
 		}
 
 		// Call the function with the mock client
-		jobId, err := CreateArchivalJob(client, server.URL, user, "group1", datasetList, ArchivalJobOptions{TapeCopies: tapecopies})
+		jobId, err := CreateArchivalJob(context.Background(), client, server.URL, user, "group1", datasetList, ArchivalJobOptions{TapeCopies: tapecopies})
 		if err != nil {
 			t.Errorf("Got an error when creating a job: %s", err.Error())
 		}
@@ -287,10 +288,10 @@ func TestCreateArchivalJobs(t *testing.T) {
 		defer server.Close()
 
 		client := server.Client()
-		user := map[string]string{
-			"mail":        "test@example.com",
-			"username":    "testuser",
-			"accessToken": "testtoken",
+		user := User{
+			Mail:        "test@example.com",
+			Username:    "testuser",
+			AccessToken: "testtoken",
 		}
 		groupedDatasets := map[string][]string{
 			"group1": {"ds1", "ds2"},
@@ -299,7 +300,7 @@ func TestCreateArchivalJobs(t *testing.T) {
 		tapecopies := new(int)
 		*tapecopies = 1
 
-		jobIds, errs := CreateArchivalJobs(client, server.URL, user, groupedDatasets, ArchivalJobOptions{TapeCopies: tapecopies})
+		jobIds, errs := CreateArchivalJobs(context.Background(), client, server.URL, user, groupedDatasets, ArchivalJobOptions{TapeCopies: tapecopies})
 
 		if len(jobIds) != 2 {
 			t.Errorf("Expected 2 job IDs, got %d", len(jobIds))
@@ -334,10 +335,10 @@ func TestCreateArchivalJobs(t *testing.T) {
 		defer server.Close()
 
 		client := server.Client()
-		user := map[string]string{
-			"mail":        "test@example.com",
-			"username":    "testuser",
-			"accessToken": "testtoken",
+		user := User{
+			Mail:        "test@example.com",
+			Username:    "testuser",
+			AccessToken: "testtoken",
 		}
 		groupedDatasets := map[string][]string{
 			"group1": {"ds1"},
@@ -345,7 +346,7 @@ func TestCreateArchivalJobs(t *testing.T) {
 		tapecopies := new(int)
 		*tapecopies = 1
 
-		_, errs := CreateArchivalJobs(client, server.URL, user, groupedDatasets, ArchivalJobOptions{TapeCopies: tapecopies})
+		_, errs := CreateArchivalJobs(context.Background(), client, server.URL, user, groupedDatasets, ArchivalJobOptions{TapeCopies: tapecopies})
 
 		if len(errs) == 0 {
 			t.Error("Expected at least one error")
@@ -1,7 +1,7 @@
 package datasetUtils
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -9,7 +9,7 @@ import (
 	"time"
 )
 
-func constructJobRequest(user map[string]string, datasetList []string) ([]byte, error) {
+func constructJobRequest(user User, datasetList []string) ([]byte, error) {
 	type datasetStruct struct {
 		Pid   string   `json:"pid"`
 		Files []string `json:"files"`
@@ -21,10 +21,10 @@ func constructJobRequest(user map[string]string, datasetList []string) ([]byte,
 	}
 
 	jobMap := make(map[string]interface{})
-	jobMap["emailJobInitiator"] = user["mail"]
+	jobMap["emailJobInitiator"] = user.Mail
 	jobMap["type"] = "retrieve"
 	jobMap["creationTime"] = time.Now().Format(time.RFC3339)
-	jobMap["jobParams"] = jobparamsStruct{"/archive/retrieve", user["username"]}
+	jobMap["jobParams"] = jobparamsStruct{"/archive/retrieve", user.Username}
 	jobMap["jobStatusMessage"] = "jobSubmitted"
 
 	emptyfiles := make([]string, 0)
@@ -39,25 +39,14 @@ func constructJobRequest(user map[string]string, datasetList []string) ([]byte,
 	return json.Marshal(jobMap)
 }
 
-func sendJobRequest(client *http.Client, APIServer string, user map[string]string, bmm []byte) (*http.Response, error) {
-	myurl := APIServer + "/Jobs?access_token=" + user["accessToken"]
-	req, err := http.NewRequest("POST", myurl, bytes.NewBuffer(bmm))
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	return resp, nil
+func sendJobRequest(ctx context.Context, client *http.Client, APIServer string, user User, bmm []byte) (*http.Response, error) {
+	return NewAPIClient(client, APIServer, user.AccessToken).Send(ctx, "POST", "/Jobs", nil, bmm)
 }
 
-func handleJobResponse(resp *http.Response, user map[string]string) (string, error) {
-	if resp.StatusCode == 200 {
+func handleJobResponse(resp *http.Response, user User) (string, error) {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
 		log.Println("Job response Status: okay")
-		log.Println("A confirmation email will be sent to", user["mail"])
+		log.Println("A confirmation email will be sent to", user.Mail)
 		decoder := json.NewDecoder(resp.Body)
 		var j Job
 		err := decoder.Decode(&j)
@@ -75,9 +64,10 @@ func handleJobResponse(resp *http.Response, user map[string]string) (string, err
 CreateRetrieveJob creates a job to retrieve a dataset from an API server.
 
 Parameters:
+- ctx: Cancels the request.
 - client: An *http.Client object that is used to send the HTTP request.
 - APIServer: A string representing the URL of the API server.
-- user: A map[string]string containing user information. It should have keys "mail", "username", and "accessToken".
+- user: The user submitting the job. Its Mail, Username and AccessToken are used.
 - datasetList: A slice of strings representing the list of datasets to be retrieved.
 
 The function constructs a job request with the provided parameters and sends it to the API server. If the job is successfully created, it returns the job ID as a string. If the job creation fails, it returns an empty string.
@@ -86,13 +76,13 @@ The function logs the status of the job creation and sends a confirmation email
 
 Note: The function will terminate the program if it encounters an error while sending the HTTP request or decoding the job ID from the response.
 */
-func CreateRetrieveJob(client *http.Client, APIServer string, user map[string]string, datasetList []string) (jobId string, err error) {
+func CreateRetrieveJob(ctx context.Context, client *http.Client, APIServer string, user User, datasetList []string) (jobId string, err error) {
 	bmm, err := constructJobRequest(user, datasetList)
 	if err != nil {
 		return "", err
 	}
 
-	resp, err := sendJobRequest(client, APIServer, user, bmm)
+	resp, err := sendJobRequest(ctx, client, APIServer, user, bmm)
 	if err != nil {
 		return "", err
 	}
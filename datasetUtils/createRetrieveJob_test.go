@@ -1,6 +1,7 @@
 package datasetUtils
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -22,28 +23,28 @@ func TestCreateRetrieveJob(t *testing.T) {
 
 	// Define the parameters for the CreateRetrieveJob function
 	APIServer := server.URL
-	user := map[string]string{
-		"mail":        "test@example.com",
-		"username":    "testuser",
-		"accessToken": "testtoken",
+	user := User{
+		Mail:        "test@example.com",
+		Username:    "testuser",
+		AccessToken: "testtoken",
 	}
 	datasetList := []string{"dataset1", "dataset2"}
 
 	// Call the CreateRetrieveJob function
-	jobId, _ := CreateRetrieveJob(client, APIServer, user, datasetList)
+	jobId, _ := CreateRetrieveJob(context.Background(), client, APIServer, user, datasetList)
 
 	// Check if the function returned a job ID
 	if jobId == "" {
-		t.Errorf("CreateRetrieveJob() returned an empty job ID, want non-empty")
+		t.Errorf("CreateRetrieveJob(context.Background(), ) returned an empty job ID, want non-empty")
 	}
 }
 
 // checks if the function returns a valid JSON byte array and no error when it's called with valid parameters.
 func TestConstructJobRequest(t *testing.T) {
 	// Define the parameters for the constructJobRequest function
-	user := map[string]string{
-		"mail":     "test@example.com",
-		"username": "testuser",
+	user := User{
+		Mail:     "test@example.com",
+		Username: "testuser",
 	}
 	datasetList := []string{"dataset1", "dataset2"}
 
@@ -66,9 +67,9 @@ func TestConstructJobRequest(t *testing.T) {
 
 	// Define the expected data
 	expectedData := map[string]interface{}{
-		"emailJobInitiator": user["mail"],
+		"emailJobInitiator": user.Mail,
 		"jobParams": map[string]interface{}{
-			"username":        user["username"],
+			"username":        user.Username,
 			"destinationPath": "/archive/retrieve",
 		},
 		"datasetList": []interface{}{
@@ -114,35 +115,38 @@ func TestConstructJobRequest(t *testing.T) {
 // Checks if the function returns a valid HTTP response and no error when it's called with valid parameters.
 func TestSendJobRequest(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Authorization") != "Bearer testtoken" || req.URL.RawQuery != "" {
+			t.Errorf("expected the token in the Authorization header only, got header %q and query %q", req.Header.Get("Authorization"), req.URL.RawQuery)
+		}
 		rw.Write([]byte(`{"id": "12345"}`))
 	}))
 	defer server.Close()
 
 	client := server.Client()
 	APIServer := server.URL
-	user := map[string]string{
-		"mail":        "test@example.com",
-		"username":    "testuser",
-		"accessToken": "testtoken",
+	user := User{
+		Mail:        "test@example.com",
+		Username:    "testuser",
+		AccessToken: "testtoken",
 	}
 	bmm := []byte(`{"key": "value"}`)
 
-	resp, err := sendJobRequest(client, APIServer, user, bmm)
+	resp, err := sendJobRequest(context.Background(), client, APIServer, user, bmm)
 	if err != nil {
-		t.Errorf("sendJobRequest() returned an error: %v", err)
+		t.Errorf("sendJobRequest(context.Background(), ) returned an error: %v", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		t.Errorf("sendJobRequest() returned status code %v, want 200", resp.StatusCode)
+		t.Errorf("sendJobRequest(context.Background(), ) returned status code %v, want 200", resp.StatusCode)
 	}
 }
 
 // Checks for a successful response, a response with a non-200 status code, and a response with invalid JSON.
 func TestHandleJobResponse(t *testing.T) {
-	user := map[string]string{
-		"mail":     "test@example.com",
-		"username": "testuser",
+	user := User{
+		Mail:     "test@example.com",
+		Username: "testuser",
 	}
 
 	// Test successful response
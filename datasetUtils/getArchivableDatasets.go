@@ -1,10 +1,8 @@
 package datasetUtils
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"net/url"
@@ -27,54 +25,31 @@ type QueryResult []DatasetInfo
 addResult is a helper function that sends a GET request to the API server to fetch dataset details and appends the IDs of the datasets that are archivable to the datasetList.
 
 Parameters:
+- ctx: Cancels the requests.
 - client: An instance of http.Client used to send the request.
 - APIServer: The URL of the API server.
 - filter: The filter query to be used in the GET request.
 - accessToken: The access token used for authentication.
 - datasetList: The list of dataset IDs to which the IDs of the archivable datasets will be appended.
 
-The function first constructs the URL for the GET request by appending the filter and access token to the APIServer URL. It then sends the GET request and reads the response.
+The function sends the GET request with the filter and reads the response.
 
-If the status code of the response is 200, the function reads the body of the response and unmarshals it into a QueryResult object. It then iterates over the datasets in the QueryResult. If a dataset's size is greater than 0, the function logs the dataset's details and appends its ID to the datasetList. If a dataset's size is 0, the function logs the dataset's details in red and does not append its ID to the datasetList.
+If the request succeeds, the function unmarshals the response into a QueryResult object. It then iterates over the datasets in the QueryResult. If a dataset's size is greater than 0, the function logs the dataset's details and appends its ID to the datasetList. If a dataset's size is 0, the function logs the dataset's details in red and does not append its ID to the datasetList.
 
-If the status code of the response is not 200, the function logs the status code.
+If the request fails, the function returns an error.
 
 The function returns the updated datasetList.
 
 Note: The function logs a fatal error and terminates the program if it fails to send the GET request or unmarshal the response body.
 */
-func addResult(client *http.Client, APIServer string, filter string, accessToken string, datasetList []string) ([]string, error) {
+func addResult(ctx context.Context, client *http.Client, APIServer string, filter string, accessToken string, datasetList []string) ([]string, error) {
 	v := url.Values{}
 	v.Set("filter", filter)
 
-	myurl := fmt.Sprintf("%s/datasets?%s", APIServer, v.Encode())
-
-	var body []byte
-	req, err := http.NewRequest("GET", myurl, bytes.NewBuffer(body))
-	if err != nil {
-		return nil, fmt.Errorf("get dataset details request creation failed: %s", err.Error())
-	}
-	req.Header.Set("Authorization", "Bearer "+accessToken)
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("get dataset details request failed: %s", err.Error())
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-	}
-
-	body, err = io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("read response body failed: %w", err)
-	}
-
 	var respObj QueryResult
-	err = json.Unmarshal(body, &respObj)
+	err := NewAPIClient(client, APIServer, accessToken).Get(ctx, "/datasets", v, &respObj)
 	if err != nil {
-		return nil, fmt.Errorf("unmarshal response body failed: %w", err)
+		return nil, fmt.Errorf("get dataset details request failed: %w", err)
 	}
 
 	if len(respObj) > 0 {
@@ -98,6 +73,7 @@ func addResult(client *http.Client, APIServer string, filter string, accessToken
 GetArchivableDatasets retrieves a list of datasets that are eligible for archiving.
 
 Parameters:
+- ctx: Cancels the requests.
 - client: An instance of http.Client used to send the request.
 - APIServer: The URL of the API server.
 - ownerGroup: The owner group of the datasets. If this is not empty, the function will fetch datasets belonging to this owner group. If it is empty, the function will fetch datasets based on the inputdatasetList.
@@ -112,14 +88,14 @@ The function returns a list of dataset IDs that are archivable.
 
 Note: A dataset is considered archivable if its size is greater than 0.
 */
-func GetArchivableDatasets(client *http.Client, APIServer string, ownerGroup string, inputdatasetList []string, accessToken string) (datasetList []string, err error) {
+func GetArchivableDatasets(ctx context.Context, client *http.Client, APIServer string, ownerGroup string, inputdatasetList []string, accessToken string) (datasetList []string, err error) {
 	datasetList = make([]string, 0)
 
 	filter := ""
 	if len(inputdatasetList) == 0 {
 		filter = `{"where":{"ownerGroup":"` + ownerGroup + `","datasetlifecycle.archivable":true},"fields": {"pid":1,"size":1,"sourceFolder":1}}`
 		var err error
-		datasetList, err = addResult(client, APIServer, filter, accessToken, datasetList)
+		datasetList, err = addResult(ctx, client, APIServer, filter, accessToken, datasetList)
 		if err != nil {
 			return datasetList, err
 		}
@@ -134,7 +110,7 @@ func GetArchivableDatasets(client *http.Client, APIServer string, ownerGroup str
 			quotedList := strings.Join(inputdatasetList[i:end], "\",\"")
 			filter = `{"where":{"pid":{"inq":["` + quotedList + `"]},"datasetlifecycle.archivable":true},"fields": {"pid":1,"size":1,"sourceFolder":1}}`
 			var err error
-			datasetList, err = addResult(client, APIServer, filter, accessToken, datasetList)
+			datasetList, err = addResult(ctx, client, APIServer, filter, accessToken, datasetList)
 			if err != nil {
 				return datasetList, err
 			}
@@ -1,6 +1,7 @@
 package datasetUtils
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -47,7 +48,7 @@ func TestGetArchivableDatasets(t *testing.T) {
 			accessToken := "testToken"
 
 			// Call our function
-			datasetList, err := GetArchivableDatasets(client, APIServer, tt.ownerGroup, tt.inputdatasetList, accessToken)
+			datasetList, err := GetArchivableDatasets(context.Background(), client, APIServer, tt.ownerGroup, tt.inputdatasetList, accessToken)
 			if err != nil {
 				t.Errorf("Unexpected error: %s", err.Error())
 			}
@@ -107,7 +108,7 @@ func TestAddResult(t *testing.T) {
 			accessToken := "testToken"
 
 			// Call our function
-			datasetList, err := addResult(client, APIServer, tt.filter, accessToken, tt.datasetList)
+			datasetList, err := addResult(context.Background(), client, APIServer, tt.filter, accessToken, tt.datasetList)
 			if err != nil {
 				t.Errorf("Error: %v", err)
 			}
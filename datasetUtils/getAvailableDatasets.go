@@ -1,6 +1,8 @@
 package datasetUtils
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"os/exec"
 	"regexp"
@@ -24,13 +26,13 @@ The function first checks if a singleDatasetId is provided. If so, it adds it to
 Returns:
 - A slice of strings, where each string is a dataset ID.
 */
-func GetAvailableDatasets(username string, RSYNCServer string, singleDatasetId string) ([]string, error) {
+func GetAvailableDatasets(ctx context.Context, username string, RSYNCServer string, singleDatasetId string) ([]string, error) {
 	datasetList := make([]string, 0)
 	if singleDatasetId != "" {
 		datasetList = append(datasetList, formatDatasetId(singleDatasetId))
 	} else {
 		printMessage(RSYNCServer)
-		datasets, err := fetchDatasetsFromServer(username, RSYNCServer)
+		datasets, err := fetchDatasetsFromServer(ctx, username, RSYNCServer)
 		if err != nil {
 			return nil, err
 		}
@@ -46,19 +48,20 @@ func formatDatasetId(datasetId string) string {
 	return DatasetIdPrefix + "/" + datasetId
 }
 
-func fetchDatasetsFromServer(username string, RSYNCServer string) ([]string, error) {
+func fetchDatasetsFromServer(ctx context.Context, username string, RSYNCServer string) ([]string, error) {
 	versionNumber, err := getRsyncVersion()
 	if err != nil {
 		return nil, fmt.Errorf("error getting rsync version: %w", err)
 	}
 
 	cmd := buildRsyncCommand(username, RSYNCServer, versionNumber)
-	out, err := cmd.Output()
-	if err != nil {
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := RunCommand(ctx, cmd); err != nil {
 		return nil, err
 	}
 
-	return parseRsyncOutput(out), nil
+	return parseRsyncOutput(out.Bytes()), nil
 }
 
 func buildRsyncCommand(username string, RSYNCServer string, versionNumber string) *exec.Cmd {
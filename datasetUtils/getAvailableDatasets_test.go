@@ -1,6 +1,7 @@
 package datasetUtils
 
 import (
+	"context"
 	"testing"
 	"reflect"
 	"os/exec"
@@ -20,7 +21,7 @@ func TestGetAvailableDatasets(t *testing.T) {
 	// Test single dataset ID
 	datasetID := "12345"
 	expected := []string{DatasetIdPrefix + "/" + datasetID}
-	result, _ := GetAvailableDatasets("username", "rsyncserver", datasetID)
+	result, _ := GetAvailableDatasets(context.Background(), "username", "rsyncserver", datasetID)
 	assert.Equal(t, expected, result, "The two slices should be the same.")
 }
 
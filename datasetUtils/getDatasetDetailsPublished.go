@@ -1,8 +1,8 @@
 package datasetUtils
 
 import (
-	"encoding/json"
-	"io"
+	"context"
+	"errors"
 	"log"
 	"net/http"
 	"net/url"
@@ -15,6 +15,7 @@ import (
 GetDatasetDetailsPublished retrieves details of published datasets from a given API server.
 
 Parameters:
+- ctx: Cancels the requests.
 - client: An HTTP client used to send requests.
 - APIServer: The URL of the API server from which to retrieve dataset details.
 - datasetList: A list of dataset IDs for which to retrieve details.
@@ -27,7 +28,7 @@ The function returns two lists:
 - A list of Dataset objects for which details were found.
 - A list of URLs for the datasets.
 */
-func GetDatasetDetailsPublished(client *http.Client, APIServer string, datasetList []string) ([]Dataset, []string) {
+func GetDatasetDetailsPublished(ctx context.Context, client *http.Client, APIServer string, datasetList []string) ([]Dataset, []string) {
 	outputDatasetDetails := make([]Dataset, 0)
 	urls := make([]string, 0)
 	sizeArray := make([]int, 0)
@@ -52,48 +53,40 @@ func GetDatasetDetailsPublished(client *http.Client, APIServer string, datasetLi
 		v.Set("filter", filter)
 		v.Add("isPublished", "true")
 
-		var myurl = APIServer + "/Datasets?" + v.Encode()
-		// log.Println("Url:", myurl)
-
-		resp, err := client.Get(myurl)
+		datasetDetails := make([]Dataset, 0)
+		err := NewAPIClient(client, APIServer, "").Get(ctx, "/Datasets", v, &datasetDetails)
+		var apiErr *APIError
+		if errors.As(err, &apiErr) {
+			log.Printf("Querying dataset details failed with status code %v\n", apiErr.StatusCode)
+			continue
+		}
 		if err != nil {
 			log.Fatal("Get dataset details failed:", err)
 		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode == 200 {
-			body, _ := io.ReadAll(resp.Body)
-
-			datasetDetails := make([]Dataset, 0)
 
-			_ = json.Unmarshal(body, &datasetDetails)
-
-			// verify if details were actually found for all available Datasets
-			for _, datasetId := range datasetList[i:end] {
-				detailsFound := false
-				for _, datasetDetail := range datasetDetails {
-					if datasetDetail.Pid == datasetId {
-						detailsFound = true
-						outputDatasetDetails = append(outputDatasetDetails, datasetDetail)
-						color.Set(color.FgGreen)
-						log.Printf("%s %9d %v %v\n", datasetId, datasetDetail.Size/1024./1024., datasetDetail.OwnerGroup, datasetDetail.SourceFolder)
-						color.Unset()
-						//https: //doi.psi.ch/datasets/das/work/p16/p16628/20181012_lungs/large_volume_360/R2-6/stitching/data_final_volume_fullresolution/
-						url := "https://" + PUBLISHServer + "/datasets" + datasetDetail.SourceFolder
-						urls = append(urls, url)
-						sizeArray = append(sizeArray, datasetDetail.Size)
-						numFilesArray = append(numFilesArray, datasetDetail.NumberOfFiles)
-						break
-					}
-				}
-				if !detailsFound {
-					color.Set(color.FgRed)
-					log.Printf("Dataset %s no infos found in catalog - will not be copied !\n", datasetId)
+		// verify if details were actually found for all available Datasets
+		for _, datasetId := range datasetList[i:end] {
+			detailsFound := false
+			for _, datasetDetail := range datasetDetails {
+				if datasetDetail.Pid == datasetId {
+					detailsFound = true
+					outputDatasetDetails = append(outputDatasetDetails, datasetDetail)
+					color.Set(color.FgGreen)
+					log.Printf("%s %9d %v %v\n", datasetId, datasetDetail.Size/1024./1024., datasetDetail.OwnerGroup, datasetDetail.SourceFolder)
 					color.Unset()
+					//https: //doi.psi.ch/datasets/das/work/p16/p16628/20181012_lungs/large_volume_360/R2-6/stitching/data_final_volume_fullresolution/
+					url := "https://" + PUBLISHServer + "/datasets" + datasetDetail.SourceFolder
+					urls = append(urls, url)
+					sizeArray = append(sizeArray, datasetDetail.Size)
+					numFilesArray = append(numFilesArray, datasetDetail.NumberOfFiles)
+					break
 				}
 			}
-		} else {
-			log.Printf("Querying dataset details failed with status code %v\n", resp.StatusCode)
+			if !detailsFound {
+				color.Set(color.FgRed)
+				log.Printf("Dataset %s no infos found in catalog - will not be copied !\n", datasetId)
+				color.Unset()
+			}
 		}
 	}
 	return outputDatasetDetails, urls
@@ -1,6 +1,7 @@
 package datasetUtils
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -19,7 +20,7 @@ func TestGetDatasetDetailsPublished(t *testing.T) {
 	client := &http.Client{}
 
 	// Call the function with the mock server's URL and a list of dataset IDs
-	datasets, urls := GetDatasetDetailsPublished(client, server.URL, []string{"1"})
+	datasets, urls := GetDatasetDetailsPublished(context.Background(), client, server.URL, []string{"1"})
 
 	// Test that the function returns the expected results
 	if len(datasets) != 1 || datasets[0].Pid != "1" {
@@ -41,7 +42,7 @@ func TestGetDatasetDetailsPublished_MissingDatasets(t *testing.T) {
 	client := &http.Client{}
 
 	// Call the function with the mock server's URL and a list of dataset IDs
-	datasets, urls := GetDatasetDetailsPublished(client, server.URL, []string{"1", "2"})
+	datasets, urls := GetDatasetDetailsPublished(context.Background(), client, server.URL, []string{"1", "2"})
 
 	// Since the server does not return details for all the requested datasets, the function should log a message for the missing datasets.
 	// We can't directly test this with the `testing` package
@@ -64,7 +65,7 @@ func TestGetDatasetDetailsPublished_EmptyList(t *testing.T) {
 	client := &http.Client{}
 
 	// Call the function with the mock server's URL and a list of dataset IDs
-	datasets, urls := GetDatasetDetailsPublished(client, server.URL, []string{"1"})
+	datasets, urls := GetDatasetDetailsPublished(context.Background(), client, server.URL, []string{"1"})
 
 	// Since the server returns an empty list, the function should return empty lists as well
 	if len(datasets) != 0 || len(urls) != 0 {
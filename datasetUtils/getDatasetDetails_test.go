@@ -1,6 +1,7 @@
 package datasetUtils
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -26,7 +27,7 @@ func TestGetDatasetDetails_EmptyList(t *testing.T) {
 	client := &http.Client{}
 
 	// Call the function to be tested
-	datasets, notFoundIds, err := GetDatasetDetails(client, APIServer, accessToken, datasetList, ownerGroup)
+	datasets, notFoundIds, err := GetDatasetDetails(context.Background(), client, APIServer, accessToken, datasetList, ownerGroup)
 	if err != nil {
 		t.Errorf("Unexpected error: %s", err.Error())
 	}
@@ -59,7 +60,7 @@ func TestGetDatasetDetails_Non200StatusCode(t *testing.T) {
 	client := &http.Client{}
 
 	// Call the function to be tested
-	datasets, notFoundIds, err := GetDatasetDetails(client, APIServer, accessToken, datasetList, ownerGroup)
+	datasets, notFoundIds, err := GetDatasetDetails(context.Background(), client, APIServer, accessToken, datasetList, ownerGroup)
 	if err == nil {
 		t.Errorf("Expected an error to be returned, got nil")
 	}
@@ -92,7 +93,7 @@ func TestGetDatasetDetails_DatasetNotFound(t *testing.T) {
 	client := &http.Client{}
 
 	// Call the function to be tested
-	datasets, notFoundIds, err := GetDatasetDetails(client, APIServer, accessToken, datasetList, ownerGroup)
+	datasets, notFoundIds, err := GetDatasetDetails(context.Background(), client, APIServer, accessToken, datasetList, ownerGroup)
 	if err != nil {
 		t.Errorf("Unexpected error: %s", err.Error())
 	}
@@ -125,7 +126,7 @@ func TestGetDatasetDetails_DatasetFound(t *testing.T) {
 	client := &http.Client{}
 
 	// Call the function to be tested
-	datasets, notFoundIds, err := GetDatasetDetails(client, APIServer, accessToken, datasetList, ownerGroup)
+	datasets, notFoundIds, err := GetDatasetDetails(context.Background(), client, APIServer, accessToken, datasetList, ownerGroup)
 	if err != nil {
 		t.Errorf("Unexpected error: %s", err.Error())
 	}
@@ -167,5 +168,5 @@ func TestGetDatasetDetails_FilterString(t *testing.T) {
 	client := &http.Client{}
 
 	// Call the function to be tested
-	GetDatasetDetails(client, APIServer, accessToken, datasetList, ownerGroup)
+	GetDatasetDetails(context.Background(), client, APIServer, accessToken, datasetList, ownerGroup)
 }
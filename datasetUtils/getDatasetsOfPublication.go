@@ -1,8 +1,8 @@
 package datasetUtils
 
 import (
-	"encoding/json"
-	"io"
+	"context"
+	"errors"
 	"log"
 	"net/http"
 	"net/url"
@@ -14,33 +14,20 @@ type PublishedDataInfo struct {
 	PidArray []string `json:"pidArray"`
 }
 
-func GetDatasetsOfPublication(client *http.Client, APIServer string, publishedDataId string) (datasetList []string, title string, doi string, err error) {
+func GetDatasetsOfPublication(ctx context.Context, client *http.Client, APIServer string, publishedDataId string) (datasetList []string, title string, doi string, err error) {
 	datasetList = make([]string, 0)
 
-	var myurl = APIServer + "/PublishedData/" + url.QueryEscape(publishedDataId)
-	// log.Println("Url:", myurl)
-
-	resp, err := client.Get(myurl)
+	var respObj PublishedDataInfo
+	err = NewAPIClient(client, APIServer, "").Get(ctx, "/PublishedData/"+url.QueryEscape(publishedDataId), nil, &respObj)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		log.Printf("Statuscode:%v", apiErr.StatusCode)
+		return datasetList, title, doi, nil
+	}
 	if err != nil {
 		return []string{}, "", "", err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode == 200 {
-		body, _ := io.ReadAll(resp.Body)
 
-		var respObj PublishedDataInfo
-		err = json.Unmarshal(body, &respObj)
-		if err != nil {
-			return []string{}, "", "", err
-		}
-
-		log.Printf("Found the following datasets in published data\n")
-		datasetList = respObj.PidArray
-		title = respObj.Title
-		doi = respObj.Doi
-	} else {
-		log.Printf("Statuscode:%v", resp.StatusCode)
-	}
-	return datasetList, title, doi, nil
+	log.Printf("Found the following datasets in published data\n")
+	return respObj.PidArray, respObj.Title, respObj.Doi, nil
 }
@@ -1,9 +1,8 @@
 package datasetUtils
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 )
@@ -12,44 +11,26 @@ import (
 GetProposal retrieves a proposal from a given API server.
 
 Parameters:
+- ctx: Cancels the request.
 - client: An *http.Client object used to send the request.
 - APIServer: A string representing the base URL of the API server.
 - ownerGroup: A string representing the owner group of the proposal.
-- user: A map containing user information, including an access token.
+- user: The user whose access token is used for the request.
 
 The function constructs a filter based on the ownerGroup, then sends a GET request to the API server with the filter and user's access token. The response is then parsed into a map and returned.
 
-If the request or JSON unmarshalling fails, the function will log the error and terminate the program.
+If the request or JSON unmarshalling fails, the function returns an error.
 
 Returns:
 - A map representing the proposal. If no proposal is found, an empty map is returned.
 */
-func GetProposal(client *http.Client, APIServer string, ownerGroup string, user map[string]string) (map[string]interface{}, error) {
+func GetProposal(ctx context.Context, client *http.Client, APIServer string, ownerGroup string, user User) (map[string]interface{}, error) {
 	filter := fmt.Sprintf(`{"where":{"ownerGroup":"%s"}}`, ownerGroup)
-	url := fmt.Sprintf("%s/proposals?filters=%s", APIServer, url.QueryEscape(filter))
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("Authorization", "Bearer "+user["accessToken"])
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("failing status code: %d", resp.StatusCode)
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
+	v := url.Values{}
+	v.Set("filters", filter)
 
 	var respObj []map[string]interface{}
-	err = json.Unmarshal(body, &respObj)
+	err := NewAPIClient(client, APIServer, user.AccessToken).Get(ctx, "/proposals", v, &respObj)
 	if err != nil {
 		return nil, err
 	}
@@ -1,6 +1,7 @@
 package datasetUtils
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -19,11 +20,11 @@ func TestGetProposal(t *testing.T) {
 	client := &http.Client{}
 
 	// Create a user
-	user := make(map[string]string)
-	user["accessToken"] = "testToken"
+	user := User{}
+	user.AccessToken = "testToken"
 
 	// Call GetProposal
-	proposal, _ := GetProposal(client, server.URL, "testOwnerGroup", user)
+	proposal, _ := GetProposal(context.Background(), client, server.URL, "testOwnerGroup", user)
 
 	// Check the proposal
 	if proposal["proposal"] != "test proposal" {
@@ -1,9 +1,8 @@
 package datasetUtils
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 )
@@ -20,6 +19,7 @@ type Profile struct {
 	Username     string   `json:"username"`
 	DisplayName  string   `json:"displayName"`
 	AccessGroups []string `json:"accessGroups"`
+	Roles        []string `json:"roles"`
 	Emails       []Email  `json:"emails"`
 }
 
@@ -27,90 +27,34 @@ type Email struct {
 	Value string `json:"value"`
 }
 
-type ErrorMsg struct {
-	Message    string `json:"message,omitempty"`
-	Error      string `json:"error,omitempty"`
-	StatusCode int    `json:"statusCode,omitempty"`
-}
-
-func GetUserInfoFromToken(client *http.Client, APIServer string, token string) (map[string]string, []string, error) {
-	var newUserInfo ReturnedUser
-	var accessGroups []string
-	u := map[string]string{}
-	bearerToken := fmt.Sprintf("Bearer %s", token)
+func GetUserInfoFromToken(ctx context.Context, client *http.Client, APIServer string, token string) (User, error) {
+	api := NewAPIClient(client, APIServer, token)
 
 	// get user info (does not contain access groups) [1st request]
-	req1, err := http.NewRequest("GET", APIServer+"/users/my/self", nil)
-	if err != nil {
-		return map[string]string{}, []string{}, err
-	}
-	req1.Header.Set("Authorization", bearerToken)
-	resp1, err := client.Do(req1)
-	if err != nil {
-		return map[string]string{}, []string{}, err
-	}
-	defer resp1.Body.Close()
-	body1, err := io.ReadAll(resp1.Body)
-	if err != nil {
-		return map[string]string{}, []string{}, err
-	}
-
-	if resp1.StatusCode != 200 {
-		var e ErrorMsg
-		err := json.Unmarshal(body1, &e)
-		var msg string
-		if err != nil {
-			msg = string(body1)
-		} else {
-			msg = e.Message
-		}
-		return map[string]string{}, []string{}, fmt.Errorf("unable to login with token. %s/users/my/self returned %d - '%s'", APIServer, resp1.StatusCode, msg)
-	}
-	if err := json.Unmarshal(body1, &newUserInfo); err != nil {
-		return map[string]string{}, []string{}, err
+	var newUserInfo ReturnedUser
+	if err := api.Get(ctx, "/users/my/self", nil, &newUserInfo); err != nil {
+		return User{}, fmt.Errorf("unable to login with token: %w", err)
 	}
 
 	// get extra details about user [2nd request]
 	var respObj UserIdentity
-	req2, err := http.NewRequest("GET", APIServer+"/users/"+url.QueryEscape(newUserInfo.Id)+"/userIdentity", nil)
-	if err != nil {
-		return map[string]string{}, []string{}, err
-	}
-	req2.Header.Set("Authorization", bearerToken)
-
-	resp2, err := client.Do(req2)
-	if err != nil {
-		return map[string]string{}, []string{}, err
-	}
-	defer resp2.Body.Close()
-	body2, err := io.ReadAll(resp2.Body)
-	if err != nil {
-		return map[string]string{}, []string{}, err
-	}
-	if resp2.StatusCode != 200 {
-		var e ErrorMsg
-		err := json.Unmarshal(body2, &e)
-		if err != nil {
-			return map[string]string{}, []string{}, fmt.Errorf("status %d - unknown response body: '%s'", resp1.StatusCode, string(body2))
-		}
-		return map[string]string{}, []string{}, fmt.Errorf("could not login with token: status %d - '%s'", resp1.StatusCode, e.Message)
-	}
-	err = json.Unmarshal(body2, &respObj)
-	if err != nil {
-		return map[string]string{}, []string{}, err
+	if err := api.Get(ctx, "/users/"+url.QueryEscape(newUserInfo.Id)+"/userIdentity", nil, &respObj); err != nil {
+		return User{}, fmt.Errorf("could not login with token: %w", err)
 	}
 
 	// return important user informations
 	if respObj.Profile.Username == "" {
-		return map[string]string{}, []string{}, fmt.Errorf("could not map a user to the token '%v'", token)
+		return User{}, fmt.Errorf("could not map a user to the token '%v'", token)
+	}
+	u := User{
+		Username:     respObj.Profile.Username,
+		DisplayName:  respObj.Profile.DisplayName,
+		AccessGroups: respObj.Profile.AccessGroups,
+		Roles:        respObj.Profile.Roles,
+		AccessToken:  token,
 	}
-	u["username"] = respObj.Profile.Username
 	if len(respObj.Profile.Emails) > 0 {
-		u["mail"] = respObj.Profile.Emails[0].Value
+		u.Mail = respObj.Profile.Emails[0].Value
 	}
-	u["displayName"] = respObj.Profile.DisplayName
-	u["accessToken"] = token
-	accessGroups = respObj.Profile.AccessGroups
-
-	return u, accessGroups, nil
+	return u, nil
 }
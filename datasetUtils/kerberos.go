@@ -0,0 +1,125 @@
+package datasetUtils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/client"
+	"github.com/jcmturner/gokrb5/v8/config"
+	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/jcmturner/gokrb5/v8/types"
+)
+
+// LoadKrb5Config reads the Kerberos configuration of the system, KRB5_CONFIG overrides the
+// default location /etc/krb5.conf.
+func LoadKrb5Config() (*config.Config, error) {
+	path := "/etc/krb5.conf"
+	if env, ok := os.LookupEnv("KRB5_CONFIG"); ok {
+		path = strings.TrimPrefix(env, "FILE:")
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		return nil, fmt.Errorf("can't load the Kerberos config %s: %v", path, err)
+	}
+	return cfg, nil
+}
+
+// KerberosPrincipal splits username into user and realm. Usernames without "@REALM" belong to
+// the default realm of cfg.
+func KerberosPrincipal(username string, cfg *config.Config) (string, string, error) {
+	realm := cfg.LibDefaults.DefaultRealm
+	if i := strings.LastIndex(username, "@"); i >= 0 {
+		username, realm = username[:i], username[i+1:]
+	}
+	if realm == "" {
+		return "", "", fmt.Errorf("no Kerberos realm given for user %s and no default_realm configured", username)
+	}
+	return username, realm, nil
+}
+
+// requestTGT logs in at the KDC with username and password and returns the reply containing the
+// ticket-granting ticket and its session key.
+func requestTGT(username string, password string, cfg *config.Config) (messages.ASRep, error) {
+	username, realm, err := KerberosPrincipal(username, cfg)
+	if err != nil {
+		return messages.ASRep{}, err
+	}
+	// FAST isn't supported by every KDC, kinit doesn't use it by default either
+	cl := client.NewWithPassword(username, realm, password, cfg, client.DisablePAFXFAST(true))
+	defer cl.Destroy()
+
+	req, err := messages.NewASReqForTGT(realm, cfg, cl.Credentials.CName())
+	if err != nil {
+		return messages.ASRep{}, fmt.Errorf("can't create the Kerberos login request: %v", err)
+	}
+	rep, err := cl.ASExchange(realm, req, 0)
+	if err != nil {
+		return messages.ASRep{}, fmt.Errorf("Kerberos login of %s@%s failed: %v", username, realm, err)
+	}
+	return rep, nil
+}
+
+// marshalCCache encodes the ticket of rep in the MIT credential cache format (version 4), as
+// read by kinit, klist, ssh and the Kerberos libraries.
+// See https://web.mit.edu/kerberos/krb5-latest/doc/formats/ccache_file_format.html
+func marshalCCache(rep messages.ASRep) ([]byte, error) {
+	ticket, err := rep.Ticket.Marshal()
+	if err != nil {
+		return nil, fmt.Errorf("can't encode the Kerberos ticket: %v", err)
+	}
+	part := rep.DecryptedEncPart
+	b := &bytes.Buffer{}
+	write := func(v any) { binary.Write(b, binary.BigEndian, v) }
+	writeData := func(data []byte) {
+		write(uint32(len(data)))
+		b.Write(data)
+	}
+	writePrincipal := func(name types.PrincipalName, realm string) {
+		write(name.NameType)
+		write(uint32(len(name.NameString)))
+		writeData([]byte(realm))
+		for _, component := range name.NameString {
+			writeData([]byte(component))
+		}
+	}
+	writeTime := func(t time.Time) {
+		if t.IsZero() {
+			write(uint32(0))
+		} else {
+			write(uint32(t.Unix()))
+		}
+	}
+
+	// version 4 with a header holding the (zero) KDC time offset
+	write(uint16(0x0504))
+	write(uint16(12))
+	write(uint16(1))
+	write(uint16(8))
+	write(uint32(0))
+	write(uint32(0))
+
+	writePrincipal(rep.CName, rep.CRealm)
+
+	// the single credential is the ticket-granting ticket
+	writePrincipal(rep.CName, rep.CRealm)
+	writePrincipal(part.SName, part.SRealm)
+	write(uint16(part.Key.KeyType))
+	writeData(part.Key.KeyValue)
+	writeTime(part.AuthTime)
+	writeTime(part.StartTime)
+	writeTime(part.EndTime)
+	writeTime(part.RenewTill)
+	write(uint8(0)) // not a session key ticket
+	flags := make([]byte, 4)
+	copy(flags, part.Flags.Bytes)
+	b.Write(flags)
+	write(uint32(0)) // addresses
+	write(uint32(0)) // authorization data
+	writeData(ticket)
+	writeData(nil) // second ticket
+	return b.Bytes(), nil
+}
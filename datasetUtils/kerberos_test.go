@@ -0,0 +1,100 @@
+package datasetUtils
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/config"
+	"github.com/jcmturner/gokrb5/v8/credentials"
+	"github.com/jcmturner/gokrb5/v8/iana/nametype"
+	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/jcmturner/gokrb5/v8/types"
+)
+
+func TestKerberosPrincipal(t *testing.T) {
+	cfg := config.New()
+	cfg.LibDefaults.DefaultRealm = "EXAMPLE.COM"
+
+	tests := []struct {
+		username  string
+		wantUser  string
+		wantRealm string
+	}{
+		{"user", "user", "EXAMPLE.COM"},
+		{"user@OTHER.ORG", "user", "OTHER.ORG"},
+	}
+	for _, tt := range tests {
+		user, realm, err := KerberosPrincipal(tt.username, cfg)
+		if err != nil || user != tt.wantUser || realm != tt.wantRealm {
+			t.Errorf("KerberosPrincipal(%q) = %q, %q, %v, want %q, %q", tt.username, user, realm, err, tt.wantUser, tt.wantRealm)
+		}
+	}
+
+	if _, _, err := KerberosPrincipal("user", config.New()); err == nil {
+		t.Error("expected an error without any realm")
+	}
+}
+
+func TestMarshalCCache(t *testing.T) {
+	cname := types.NewPrincipalName(nametype.KRB_NT_PRINCIPAL, "user")
+	sname := types.NewPrincipalName(nametype.KRB_NT_SRV_INST, "krbtgt/EXAMPLE.COM")
+	authTime := time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)
+	flags := types.NewKrbFlags()
+	types.SetFlag(&flags, 1) // forwardable
+
+	rep := messages.ASRep{KDCRepFields: messages.KDCRepFields{
+		CRealm: "EXAMPLE.COM",
+		CName:  cname,
+		Ticket: messages.Ticket{
+			TktVNO:  5,
+			Realm:   "EXAMPLE.COM",
+			SName:   sname,
+			EncPart: types.EncryptedData{EType: 18, KVNO: 1, Cipher: []byte("encrypted ticket")},
+		},
+		DecryptedEncPart: messages.EncKDCRepPart{
+			Key:       types.EncryptionKey{KeyType: 18, KeyValue: bytes.Repeat([]byte{7}, 32)},
+			Flags:     flags,
+			AuthTime:  authTime,
+			StartTime: authTime,
+			EndTime:   authTime.Add(10 * time.Hour),
+			RenewTill: authTime.Add(24 * time.Hour),
+			SRealm:    "EXAMPLE.COM",
+			SName:     sname,
+		},
+	}}
+
+	data, err := marshalCCache(rep)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var ccache credentials.CCache
+	if err := ccache.Unmarshal(data); err != nil {
+		t.Fatalf("the cache can't be read back: %v", err)
+	}
+
+	if ccache.Version != 4 || ccache.GetClientRealm() != "EXAMPLE.COM" || !ccache.GetClientPrincipalName().Equal(cname) {
+		t.Errorf("unexpected default principal %v@%s (version %d)", ccache.GetClientPrincipalName(), ccache.GetClientRealm(), ccache.Version)
+	}
+	entries := ccache.GetEntries()
+	if len(entries) != 1 {
+		t.Fatalf("expected one credential, got %d", len(entries))
+	}
+	cred := entries[0]
+	if !cred.Server.PrincipalName.Equal(sname) || cred.Server.Realm != "EXAMPLE.COM" {
+		t.Errorf("unexpected server principal %v@%s", cred.Server.PrincipalName, cred.Server.Realm)
+	}
+	if cred.Key.KeyType != 18 || !bytes.Equal(cred.Key.KeyValue, rep.DecryptedEncPart.Key.KeyValue) {
+		t.Errorf("unexpected session key %v", cred.Key)
+	}
+	if !cred.AuthTime.Equal(authTime) || !cred.EndTime.Equal(authTime.Add(10*time.Hour)) || !cred.RenewTill.Equal(authTime.Add(24*time.Hour)) {
+		t.Errorf("unexpected times: auth %v, end %v, renew %v", cred.AuthTime, cred.EndTime, cred.RenewTill)
+	}
+	if !types.IsFlagSet(&cred.TicketFlags, 1) {
+		t.Error("the ticket flags were not kept")
+	}
+	var ticket messages.Ticket
+	if err := ticket.Unmarshal(cred.Ticket); err != nil || !bytes.Equal(ticket.EncPart.Cipher, []byte("encrypted ticket")) {
+		t.Errorf("the ticket can't be decoded: %v", err)
+	}
+}
@@ -0,0 +1,15 @@
+package datasetUtils
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// LogoutUser revokes the access token on the API server, after which it can't be used anymore.
+func LogoutUser(ctx context.Context, client *http.Client, APIServer string, token string) error {
+	if err := NewAPIClient(client, APIServer, token).Post(ctx, "/auth/logout", nil, nil); err != nil {
+		return fmt.Errorf("error when logging out: %w", err)
+	}
+	return nil
+}
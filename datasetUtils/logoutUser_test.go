@@ -0,0 +1,31 @@
+package datasetUtils
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLogoutUser(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/auth/logout" || req.Method != "POST" {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if req.Header.Get("Authorization") != "Bearer valid-token" {
+			rw.WriteHeader(http.StatusUnauthorized)
+			rw.Write([]byte(`{"message":"Unauthorized"}`))
+			return
+		}
+		rw.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	if err := LogoutUser(context.Background(), server.Client(), server.URL, "valid-token"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := LogoutUser(context.Background(), server.Client(), server.URL, "invalid-token"); err == nil {
+		t.Error("expected an error for an invalid token")
+	}
+}
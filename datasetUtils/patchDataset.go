@@ -1,30 +1,31 @@
 package datasetUtils
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
 	"net/http"
 	"net/url"
 )
 
-func PatchDataset(client *http.Client, APIServer string, token string, datasetId string, meta map[string]interface{}) error {
-	cmm, _ := json.Marshal(meta)
-
-	myurl := APIServer + "/Datasets/" + url.QueryEscape(datasetId)
-	req, err := http.NewRequest("PATCH", myurl, bytes.NewBuffer(cmm))
+func PatchDataset(ctx context.Context, client *http.Client, APIServer string, token string, datasetId string, meta map[string]interface{}) error {
+	err := NewAPIClient(client, APIServer, token).Patch(ctx, "/Datasets/"+url.QueryEscape(datasetId), meta, nil)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to update dataset %v: %w", meta, err)
 	}
-	req.Header.Set("Authorization", "Bearer "+token)
-	req.Header.Set("Content-Type", "application/json")
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
+	return nil
+}
+
+// GetDatasetLifecycle returns the datasetlifecycle object of the dataset datasetId, so some of its
+// fields can be patched without replacing the others. It's empty if the dataset has none.
+func GetDatasetLifecycle(ctx context.Context, client *http.Client, APIServer string, token string, datasetId string) (map[string]interface{}, error) {
+	var dataset struct {
+		DatasetLifecycle map[string]interface{} `json:"datasetlifecycle"`
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode >= 300 || resp.StatusCode < 200 {
-		return fmt.Errorf("failed to update dataset %v %v", resp.StatusCode, meta)
+	if err := NewAPIClient(client, APIServer, token).Get(ctx, "/Datasets/"+url.QueryEscape(datasetId), nil, &dataset); err != nil {
+		return nil, fmt.Errorf("failed to get the lifecycle of dataset %s: %w", datasetId, err)
 	}
-	return nil
+	if dataset.DatasetLifecycle == nil {
+		return map[string]interface{}{}, nil
+	}
+	return dataset.DatasetLifecycle, nil
 }
@@ -1,6 +1,7 @@
 package datasetUtils
 
 import (
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
@@ -35,7 +36,7 @@ func TestPatchDataset(t *testing.T) {
 		defer server.Close()
 
 		meta := map[string]interface{}{"creationTime": "2024-01-01T00:00:00Z"}
-		err := PatchDataset(server.Client(), server.URL, "testToken", "testPid", meta)
+		err := PatchDataset(context.Background(), server.Client(), server.URL, "testToken", "testPid", meta)
 		if err != nil {
 			t.Fatalf("expected no error, got: %v", err)
 		}
@@ -52,7 +53,7 @@ func TestPatchDataset(t *testing.T) {
 		}))
 		defer server.Close()
 
-		err := PatchDataset(server.Client(), server.URL, "testToken", "some/pid", map[string]interface{}{})
+		err := PatchDataset(context.Background(), server.Client(), server.URL, "testToken", "some/pid", map[string]interface{}{})
 		if err != nil {
 			t.Fatalf("expected no error, got: %v", err)
 		}
@@ -67,9 +68,38 @@ func TestPatchDataset(t *testing.T) {
 		}))
 		defer server.Close()
 
-		err := PatchDataset(server.Client(), server.URL, "testToken", "testPid", map[string]interface{}{})
+		err := PatchDataset(context.Background(), server.Client(), server.URL, "testToken", "testPid", map[string]interface{}{})
 		if err == nil {
 			t.Fatal("expected an error, got nil")
 		}
 	})
 }
+
+func TestGetDatasetLifecycle(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch req.URL.EscapedPath() {
+		case "/Datasets/testPid":
+			rw.Write([]byte(`{"pid": "testPid", "datasetlifecycle": {"archivable": true, "retrievable": false}}`))
+		case "/Datasets/noLifecycle":
+			rw.Write([]byte(`{"pid": "noLifecycle"}`))
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	lifecycle, err := GetDatasetLifecycle(context.Background(), server.Client(), server.URL, "testToken", "testPid")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if lifecycle["archivable"] != true || lifecycle["retrievable"] != false {
+		t.Errorf("unexpected lifecycle: %v", lifecycle)
+	}
+	lifecycle, err = GetDatasetLifecycle(context.Background(), server.Client(), server.URL, "testToken", "noLifecycle")
+	if err != nil || lifecycle == nil || len(lifecycle) != 0 {
+		t.Errorf("expected an empty lifecycle, got %v, %v", lifecycle, err)
+	}
+	if _, err := GetDatasetLifecycle(context.Background(), server.Client(), server.URL, "testToken", "missing"); err == nil {
+		t.Error("expected an error for a missing dataset")
+	}
+}
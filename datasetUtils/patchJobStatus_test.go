@@ -2,6 +2,7 @@ package datasetUtils
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
@@ -22,10 +23,10 @@ func TestPatchJobStatus(t *testing.T) {
 			mockResponseStatusCode: 404,
 		},
 	}
-	user := map[string]string{
-		"mail":        "test@example.com",
-		"username":    "testuser",
-		"accessToken": "testtoken",
+	user := User{
+		Mail:        "test@example.com",
+		Username:    "testuser",
+		AccessToken: "testtoken",
 	}
 
 	for _, tt := range tests {
@@ -55,7 +56,7 @@ func TestPatchJobStatus(t *testing.T) {
 				},
 			}
 
-			err := PatchJobStatus(client, "http://mockserver", user, "123", "Completed")
+			err := PatchJobStatus(context.Background(), client, "http://mockserver", user, "123", "Completed")
 			if err != nil && tt.mockResponseStatusCode < 400 {
 				t.Fatalf("PatchJobStatus returned unexpected error: %v", err)
 			}
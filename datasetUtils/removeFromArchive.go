@@ -1,10 +1,8 @@
 package datasetUtils
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"net/url"
@@ -28,12 +26,12 @@ type jobParamsStruct struct {
 }
 
 type JobSubmissionResponse struct {
-	ID string `json:"id"`
+	ID               string `json:"id"`
 	JobStatusMessage string `json:"jobStatusMessage"`
 }
 
-func RemoveFromArchive(client *http.Client, APIServer string, pid string, user map[string]string, nonInteractive bool) (string, error) {
-	respObj, err := getDatablocks(client, APIServer, pid, user)
+func RemoveFromArchive(ctx context.Context, client *http.Client, APIServer string, pid string, user User, nonInteractive bool) (string, error) {
+	respObj, err := getDatablocks(ctx, client, APIServer, pid, user)
 	if err != nil {
 		return "", fmt.Errorf("failed to fetch datablocks: %w", err)
 	}
@@ -64,7 +62,7 @@ func RemoveFromArchive(client *http.Client, APIServer string, pid string, user m
 		log.Println("Non-interactive mode: proceeding automatically.")
 	}
 	jobMap := buildResetJobMap(pid, user)
-	jobID, err := submitJob(client, APIServer, user, jobMap)
+	jobID, err := submitJob(ctx, client, APIServer, user, jobMap)
 	if err != nil {
 		return "", fmt.Errorf("archive reset job submission failed: %w", err)
 	}
@@ -72,41 +70,23 @@ func RemoveFromArchive(client *http.Client, APIServer string, pid string, user m
 	return jobID, nil
 }
 
-func getDatablocks(client *http.Client, APIServer string, pid string, user map[string]string) ([]datablockInfo, error) {
+func getDatablocks(ctx context.Context, client *http.Client, APIServer string, pid string, user User) ([]datablockInfo, error) {
 	filter := fmt.Sprintf(`{"where":{"datasetId":"%s"},"fields": {"id":1,"size":1}}`, pid)
-	url := fmt.Sprintf("%s/Datablocks?filter=%s", APIServer, url.QueryEscape(filter))
 
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("Authorization", "Bearer "+user["accessToken"])
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := client.Do(req)
+	var respObj []datablockInfo
+	err := NewAPIClient(client, APIServer, user.AccessToken).Get(ctx, "/Datablocks", url.Values{"filter": {filter}}, &respObj)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode >= 400 {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(body))
-	}
-
-	var respObj []datablockInfo
-	if err := json.NewDecoder(resp.Body).Decode(&respObj); err != nil {
-		return nil, fmt.Errorf("failed to decode datablocks: %w", err)
-	}
 	return respObj, nil
 }
 
-func buildResetJobMap(pid string, user map[string]string) map[string]interface{} {
+func buildResetJobMap(pid string, user User) map[string]interface{} {
 	return map[string]interface{}{
-		"emailJobInitiator": user["mail"],
+		"emailJobInitiator": user.Mail,
 		"type":              "reset",
 		"creationTime":      time.Now().Format(time.RFC3339),
-		"jobParams":         jobParamsStruct{Username: user["username"]},
+		"jobParams":         jobParamsStruct{Username: user.Username},
 		"jobStatusMessage":  "jobSubmitted",
 		"datasetList": []datasetStruct{
 			{Pid: pid, Files: []string{}},
@@ -114,33 +94,10 @@ func buildResetJobMap(pid string, user map[string]string) map[string]interface{}
 	}
 }
 
-func submitJob(client *http.Client, APIServer string, user map[string]string, jobMap map[string]interface{}) (string, error) {
-	jsonData, err := json.Marshal(jobMap)
-	if err != nil {
-		return "", fmt.Errorf("json marshal failed: %w", err)
-	}
-
-	myurl := APIServer + "/Jobs"
-	req, err := http.NewRequest("POST", myurl, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return "", fmt.Errorf("failed to create job request: %w", err)
-	}
-	req.Header.Set("Authorization", "Bearer "+user["accessToken"])
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("network error on job submission: %w", err)
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode >= 400 {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("job submission failed (%d): %s", resp.StatusCode, string(body))
-	}
-
+func submitJob(ctx context.Context, client *http.Client, APIServer string, user User, jobMap map[string]interface{}) (string, error) {
 	var respObj JobSubmissionResponse
-	if err := json.NewDecoder(resp.Body).Decode(&respObj); err != nil {
-		return "", fmt.Errorf("failed to decode job submission response: %w", err)
+	if err := NewAPIClient(client, APIServer, user.AccessToken).Post(ctx, "/Jobs", jobMap, &respObj); err != nil {
+		return "", fmt.Errorf("job submission failed: %w", err)
 	}
 
 	if respObj.ID == "" {
@@ -148,6 +105,6 @@ func submitJob(client *http.Client, APIServer string, user map[string]string, jo
 	}
 
 	log.Println("Job response Status: okay")
-	log.Println("A confirmation email will be sent to", user["mail"])
+	log.Println("A confirmation email will be sent to", user.Mail)
 	return respObj.ID, nil
 }
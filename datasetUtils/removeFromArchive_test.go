@@ -2,6 +2,7 @@ package datasetUtils
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
@@ -46,10 +47,10 @@ func TestRemoveFromArchive(t *testing.T) {
 			expectPost:    true,
 		},
 	}
-	user := map[string]string{
-		"mail":        "test@example.com",
-		"username":    "testuser",
-		"accessToken": "testtoken",
+	user := User{
+		Mail:        "test@example.com",
+		Username:    "testuser",
+		AccessToken: "testtoken",
 	}
 
 	for _, tt := range tests {
@@ -103,7 +104,7 @@ func TestRemoveFromArchive(t *testing.T) {
 				},
 			}
 
-			jobID, err := RemoveFromArchive(client, "http://mockserver", "dataset1", user, true)
+			jobID, err := RemoveFromArchive(context.Background(), client, "http://mockserver", "dataset1", user, true)
 			if err != nil {
 				t.Fatalf("RemoveFromArchive returned unexpected error: %v", err)
 			}
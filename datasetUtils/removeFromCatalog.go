@@ -1,9 +1,8 @@
 package datasetUtils
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"net/url"
@@ -26,82 +25,45 @@ const (
 var removeFromCatalogTimeout = 5 * time.Minute
 var waitTime = 10 * time.Second
 
-func returnJobStatus(client *http.Client, APIServer string, user map[string]string, jobID string) (string, error) {
-	myurl := fmt.Sprintf("%s/Jobs/%s", APIServer, url.PathEscape(jobID))
-
-	req, err := http.NewRequest("GET", myurl, nil)
-	if err != nil {
-		return "", fmt.Errorf("failed to create job status request: %w", err)
-	}
-
-	req.Header.Set("Authorization", "Bearer "+user["accessToken"])
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("network error on job status: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode >= 400 {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("job status request failed (%d): %s", resp.StatusCode, string(body))
-	}
-
+func returnJobStatus(ctx context.Context, client *http.Client, APIServer string, user User, jobID string) (string, error) {
 	var j JobSubmissionResponse
-	if err := json.NewDecoder(resp.Body).Decode(&j); err != nil {
-		return "", fmt.Errorf("failed to decode job response: %w", err)
+	err := NewAPIClient(client, APIServer, user.AccessToken).Get(ctx, "/Jobs/"+url.PathEscape(jobID), nil, &j)
+	if err != nil {
+		return "", fmt.Errorf("job status request failed: %w", err)
 	}
-
 	return j.JobStatusMessage, nil
 }
 
-func returnCount(client *http.Client, APIServer string, pid string, user map[string]string, collection string) (int, error) {
-	myurl := APIServer + "/Datasets"
+func returnCount(ctx context.Context, client *http.Client, APIServer string, pid string, user User, collection string) (int, error) {
+	path := "/Datasets"
 	if collection != "datasets" {
-		myurl += "/" + url.PathEscape(pid) + "/" + collection
+		path += "/" + url.PathEscape(pid) + "/" + collection
 	}
-	myurl += "/count"
+	path += "/count"
+	var query url.Values
 	if collection == "datasets" {
-		filter := `{"where":{"pid":"` + pid + `"}}`
-		myurl += "?filter=" + url.QueryEscape(filter)
-	}
-	req, err := http.NewRequest("GET", myurl, nil)
-	if err != nil {
-		return 0, fmt.Errorf("failed to create count request: %w", err)
-	}
-	req.Header.Set("Authorization", "Bearer "+user["accessToken"])
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return 0, fmt.Errorf("network error on count: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode >= 400 {
-		body, _ := io.ReadAll(resp.Body)
-		return 0, fmt.Errorf("count failed with status %d: %s", resp.StatusCode, string(body))
+		query = url.Values{"filter": {`{"where":{"pid":"` + pid + `"}}`}}
 	}
 
 	var respObj countResult
-	if err := json.NewDecoder(resp.Body).Decode(&respObj); err != nil {
-		return 0, fmt.Errorf("failed to decode count response: %w", err)
+	if err := NewAPIClient(client, APIServer, user.AccessToken).Get(ctx, path, query, &respObj); err != nil {
+		return 0, fmt.Errorf("count failed: %w", err)
 	}
 	return respObj.Count, nil
 }
 
-func RemoveFromCatalog(client *http.Client, APIServer string, pid string, jobID string, user map[string]string, nonInteractive bool) error {
-	countOrig, err := returnCount(client, APIServer, pid, user, "origdatablocks")
+func RemoveFromCatalog(ctx context.Context, client *http.Client, APIServer string, pid string, jobID string, user User, nonInteractive bool) error {
+	countOrig, err := returnCount(ctx, client, APIServer, pid, user, "origdatablocks")
 	if err != nil {
 		return fmt.Errorf("pre-check failed: could not count origdatablocks: %w", err)
 	}
 
-	countAttachments, err := returnCount(client, APIServer, pid, user, "attachments")
+	countAttachments, err := returnCount(ctx, client, APIServer, pid, user, "attachments")
 	if err != nil {
 		return fmt.Errorf("pre-check failed: could not count attachments: %w", err)
 	}
 
-	countDataset, err := returnCount(client, APIServer, pid, user, "datasets")
+	countDataset, err := returnCount(ctx, client, APIServer, pid, user, "datasets")
 	if err != nil {
 		return fmt.Errorf("pre-check failed: could not count datasets: %w", err)
 	}
@@ -127,13 +89,13 @@ func RemoveFromCatalog(client *http.Client, APIServer string, pid string, jobID
 	startTime := time.Now()
 	var jobStatus string
 	for {
-		countDatablocks, countErr := returnCount(client, APIServer, pid, user, "datablocks")
+		countDatablocks, countErr := returnCount(ctx, client, APIServer, pid, user, "datablocks")
 		if countErr != nil {
 			log.Printf("Error checking datablocks: %v\n", countErr)
 		}
 
 		if jobID != "" && jobStatus != string(JobSuccess) {
-			jobStatus, err = returnJobStatus(client, APIServer, user, jobID)
+			jobStatus, err = returnJobStatus(ctx, client, APIServer, user, jobID)
 			if err != nil {
 				log.Printf("Error checking job status: %v\n", err)
 			}
@@ -143,10 +105,10 @@ func RemoveFromCatalog(client *http.Client, APIServer string, pid string, jobID
 		}
 
 		// technically when jobID is empty we should not even check for countDatablocks,
-		// but this is to prevent false positive nil returns from RemoveFromArchive which can 
+		// but this is to prevent false positive nil returns from RemoveFromArchive which can
 		// cause the function to clean up the catalog without actually waiting for datablocks to be removed from the archive
 		if countDatablocks == 0 && countErr == nil && (jobID == "" || jobStatus == string(JobSuccess)) {
-			err = deleteLinkedDocuments(client, APIServer, pid, user, countOrig, countAttachments, countDataset)
+			err = deleteLinkedDocuments(ctx, client, APIServer, pid, user, countOrig, countAttachments, countDataset)
 			if err != nil {
 				return fmt.Errorf("final cleanup failed: %w", err)
 			}
@@ -158,51 +120,60 @@ func RemoveFromCatalog(client *http.Client, APIServer string, pid string, jobID
 		}
 
 		log.Printf("Waiting for archive deletion... (Blocks: %d)\n", countDatablocks)
-		time.Sleep(waitTime)
+		if err := sleepContext(ctx, waitTime); err != nil {
+			return fmt.Errorf("waiting for archive deletion: %w", err)
+		}
 	}
 }
 
-func deleteDocumentsFrom(collection string, client *http.Client, APIServer string, pid string, user map[string]string) error {
-	pidEncoded := url.PathEscape(pid)
-	myurl := APIServer + "/Datasets/" + pidEncoded
-	if collection != "datasets" {
-		myurl += "/" + collection
-		log.Printf("Deleting linked %s...\n", collection)
-	} else {
-		log.Println("Deleting the primary dataset entry...")
-	}
-	req, err := http.NewRequest("DELETE", myurl, nil)
+// DeleteOrigDatablocks deletes all origdatablocks of the dataset pid, e.g. to replace partially
+// created ones.
+func DeleteOrigDatablocks(ctx context.Context, client *http.Client, APIServer string, pid string, user User) error {
+	return deleteDocumentsFrom(ctx, "origdatablocks", client, APIServer, pid, user)
+}
+
+// DeleteDatasetDocuments deletes the dataset pid along with its origdatablocks and attachments,
+// without waiting for the archive like RemoveFromCatalog. It's meant for datasets that were never
+// archived, e.g. to roll back a failed ingestion.
+func DeleteDatasetDocuments(ctx context.Context, client *http.Client, APIServer string, pid string, user User) error {
+	countOrig, err := returnCount(ctx, client, APIServer, pid, user, "origdatablocks")
 	if err != nil {
-		return err
+		return fmt.Errorf("could not count origdatablocks: %w", err)
 	}
-	req.Header.Set("Authorization", "Bearer "+user["accessToken"])
-	resp, err := client.Do(req)
+	countAttachments, err := returnCount(ctx, client, APIServer, pid, user, "attachments")
 	if err != nil {
-		return err
+		return fmt.Errorf("could not count attachments: %w", err)
 	}
-	defer resp.Body.Close()
+	return deleteLinkedDocuments(ctx, client, APIServer, pid, user, countOrig, countAttachments, 1)
+}
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("delete failed (%d): %s", resp.StatusCode, string(body))
+func deleteDocumentsFrom(ctx context.Context, collection string, client *http.Client, APIServer string, pid string, user User) error {
+	path := "/Datasets/" + url.PathEscape(pid)
+	if collection != "datasets" {
+		path += "/" + collection
+		log.Printf("Deleting linked %s...\n", collection)
+	} else {
+		log.Println("Deleting the primary dataset entry...")
+	}
+	if err := NewAPIClient(client, APIServer, user.AccessToken).Delete(ctx, path); err != nil {
+		return fmt.Errorf("delete failed: %w", err)
 	}
-
 	return nil
 }
 
-func deleteLinkedDocuments(client *http.Client, APIServer string, pid string, user map[string]string, countOrig int, countAttachments int, countDataset int) error {
+func deleteLinkedDocuments(ctx context.Context, client *http.Client, APIServer string, pid string, user User, countOrig int, countAttachments int, countDataset int) error {
 	if countOrig > 0 {
-		if err := deleteDocumentsFrom("origdatablocks", client, APIServer, pid, user); err != nil {
+		if err := deleteDocumentsFrom(ctx, "origdatablocks", client, APIServer, pid, user); err != nil {
 			return fmt.Errorf("cleanup failed at origdatablocks: %w", err)
 		}
 	}
 	if countAttachments > 0 {
-		if err := deleteDocumentsFrom("attachments", client, APIServer, pid, user); err != nil {
+		if err := deleteDocumentsFrom(ctx, "attachments", client, APIServer, pid, user); err != nil {
 			return fmt.Errorf("cleanup failed at attachments: %w", err)
 		}
 	}
 	if countDataset > 0 {
-		if err := deleteDocumentsFrom("datasets", client, APIServer, pid, user); err != nil {
+		if err := deleteDocumentsFrom(ctx, "datasets", client, APIServer, pid, user); err != nil {
 			color.Set(color.FgRed)
 			return fmt.Errorf("cleanup failed at primary dataset: %w", err)
 		}
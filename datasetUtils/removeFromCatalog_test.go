@@ -2,6 +2,7 @@ package datasetUtils
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"net/http"
 	"strconv"
@@ -192,10 +193,10 @@ func TestRemoveFromCatalog_AllCases(t *testing.T) {
 		},
 	}
 
-	user := map[string]string{
-		"mail":        "test@example.com",
-		"username":    "testuser",
-		"accessToken": "testtoken",
+	user := User{
+		Mail:        "test@example.com",
+		Username:    "testuser",
+		AccessToken: "testtoken",
 	}
 
 	for _, tt := range tests {
@@ -205,12 +206,16 @@ func TestRemoveFromCatalog_AllCases(t *testing.T) {
 				effectiveStatus = string(JobSuccess)
 			}
 			oldTimeout := removeFromCatalogTimeout
+			oldRetryPolicy := DefaultRetryPolicy
 			waitTime = tt.timeout
 			if tt.timeout != 0 {
 				removeFromCatalogTimeout = tt.timeout
 			}
+			// every request is expected exactly once
+			DefaultRetryPolicy = RetryPolicy{}
 			defer func() {
 				removeFromCatalogTimeout = oldTimeout
+				DefaultRetryPolicy = oldRetryPolicy
 			}()
 			calledDeletes := []string{}
 
@@ -311,7 +316,7 @@ func TestRemoveFromCatalog_AllCases(t *testing.T) {
 			if tt.emptyJobId {
 				effectiveJobID = ""
 			}
-			err := RemoveFromCatalog(client, "http://mockserver", "dataset/1", effectiveJobID, user, true)
+			err := RemoveFromCatalog(context.Background(), client, "http://mockserver", "dataset/1", effectiveJobID, user, true)
 			if tt.expectError {
 				if err == nil {
 					t.Fatalf("expected error containing %q, got nil", tt.expectedErrSubstr)
@@ -338,3 +343,43 @@ func TestRemoveFromCatalog_AllCases(t *testing.T) {
 		})
 	}
 }
+
+func TestDeleteDatasetDocuments(t *testing.T) {
+	var calledDeletes []string
+	client := &http.Client{
+		Transport: &MockTransport{
+			RoundTripFunc: func(req *http.Request) (*http.Response, error) {
+				if req.Method == http.MethodGet && strings.HasSuffix(req.URL.Path, "/count") {
+					count := 0
+					if strings.Contains(req.URL.Path, "origdatablocks") {
+						count = 2
+					}
+					return &http.Response{
+						StatusCode: 200,
+						Body:       io.NopCloser(bytes.NewBufferString(`{"count":` + strconv.Itoa(count) + `}`)),
+					}, nil
+				}
+				if req.Method == http.MethodDelete {
+					calledDeletes = append(calledDeletes, req.URL.RawPath)
+					return &http.Response{
+						StatusCode: 200,
+						Body:       io.NopCloser(bytes.NewBufferString(`{}`)),
+					}, nil
+				}
+				t.Errorf("unexpected request %s %s", req.Method, req.URL)
+				return &http.Response{
+					StatusCode: 400,
+					Body:       io.NopCloser(bytes.NewBufferString(`{}`)),
+				}, nil
+			},
+		},
+	}
+
+	if err := DeleteDatasetDocuments(context.Background(), client, "http://mockserver", "dataset/1", User{AccessToken: "token"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"/Datasets/dataset%2F1/origdatablocks", "/Datasets/dataset%2F1"}
+	if strings.Join(calledDeletes, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected DELETE calls %v, got %v", expected, calledDeletes)
+	}
+}
@@ -0,0 +1,47 @@
+package datasetUtils
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// CommandGracePeriod is the time a cancelled command gets to exit after being interrupted, before
+// it's killed.
+var CommandGracePeriod = 10 * time.Second
+
+// RunCommand runs cmd like cmd.Run, but stops it when ctx is cancelled: the command is interrupted
+// first (as by Ctrl-C) so that e.g. rsync can clean up, and killed if it's still running after
+// CommandGracePeriod. The error of ctx is returned in that case.
+func RunCommand(ctx context.Context, cmd *exec.Cmd) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if cmd.WaitDelay == 0 {
+		// don't wait forever for output of e.g. ssh processes started by the command
+		cmd.WaitDelay = CommandGracePeriod
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+	}
+	// interrupts aren't supported on Windows, the command is killed right away there
+	if err := cmd.Process.Signal(os.Interrupt); err != nil {
+		cmd.Process.Kill()
+	}
+	select {
+	case <-done:
+	case <-time.After(CommandGracePeriod):
+		cmd.Process.Kill()
+		<-done
+	}
+	return ctx.Err()
+}
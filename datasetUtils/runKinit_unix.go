@@ -4,37 +4,77 @@
 package datasetUtils
 
 import (
-	"log"
+	"fmt"
 	"os"
-	"os/exec"
-	"time"
-
-	expect "github.com/Netflix/go-expect"
+	"path/filepath"
+	"strings"
 )
 
-func RunKinit(username string, password string) (err error) {
-	c, err := expect.NewConsole(expect.WithStdout(os.Stdout))
+// RunKinit obtains a Kerberos ticket-granting ticket for username and stores it in the
+// credential cache of the user, like kinit does. The cache is the file named by KRB5CCNAME or
+// /tmp/krb5cc_<uid>. If KRB5CCNAME names a cache type other than a file (e.g. KEYRING or KCM),
+// a private cache file is used instead and KRB5CCNAME is set to it for this process, so that
+// ssh, rsync and the GSSAPI authentication of NewDumbClient use the new ticket.
+func RunKinit(username string, password string) error {
+	cfg, err := LoadKrb5Config()
+	if err != nil {
+		return err
+	}
+	rep, err := requestTGT(username, password, cfg)
+	if err != nil {
+		return err
+	}
+	data, err := marshalCCache(rep)
 	if err != nil {
-		log.Printf("Warning: Could not start kinit to get Kerberos tickets: %v\n", err)
+		return err
 	}
-	defer c.Close()
 
-	cmd := exec.Command("/usr/bin/kinit", username)
-	cmd.Stdin = c.Tty()
-	cmd.Stdout = c.Tty()
-	cmd.Stderr = c.Tty()
+	path, private, err := credentialCachePath()
+	if err != nil {
+		return err
+	}
+	if err := writeCredentialCache(path, data); err != nil {
+		return fmt.Errorf("can't write the Kerberos credential cache %s: %v", path, err)
+	}
+	if private {
+		os.Setenv("KRB5CCNAME", "FILE:"+path)
+	}
+	return nil
+}
 
-	err = cmd.Start()
+// credentialCachePath returns the credential cache file to use and whether it's a private one.
+func credentialCachePath() (string, bool, error) {
+	name, ok := os.LookupEnv("KRB5CCNAME")
+	if !ok || name == "" {
+		return fmt.Sprintf("/tmp/krb5cc_%d", os.Getuid()), false, nil
+	}
+	if path, isFile := strings.CutPrefix(name, "FILE:"); isFile || !strings.Contains(name, ":") {
+		return path, false, nil
+	}
+	dir, err := os.UserCacheDir()
 	if err != nil {
-		log.Printf("Warning: Could not start kinit to get Kerberos tickets: %v\n", err)
-	} else {
-		time.Sleep(time.Second)
-		c.SendLine(password)
+		return "", false, err
+	}
+	return filepath.Join(dir, "scicat-cli", "krb5cc"), true, nil
+}
 
-		err = cmd.Wait()
-		if err != nil {
-			log.Printf("Warning: kinit error: %v\n", err)
-		}
+// writeCredentialCache replaces the cache at path with data, readable only by the current user.
+func writeCredentialCache(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	// write to a temporary file first so that concurrent readers never see a partial cache
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
 	}
-	return err
+	return os.Rename(tmp.Name(), path)
 }
@@ -0,0 +1,65 @@
+// +build aix darwin dragonfly freebsd js,wasm linux nacl netbsd openbsd solaris
+
+package datasetUtils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCredentialCachePath(t *testing.T) {
+	tests := []struct {
+		ccname      string
+		wantPath    string
+		wantPrivate bool
+	}{
+		{"", fmt.Sprintf("/tmp/krb5cc_%d", os.Getuid()), false},
+		{"FILE:/tmp/mycache", "/tmp/mycache", false},
+		{"/tmp/othercache", "/tmp/othercache", false},
+		{"KEYRING:persistent:1000", "", true},
+	}
+	for _, tt := range tests {
+		t.Setenv("KRB5CCNAME", tt.ccname)
+		path, private, err := credentialCachePath()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if private != tt.wantPrivate || (tt.wantPath != "" && path != tt.wantPath) {
+			t.Errorf("KRB5CCNAME=%q: got %q (private %v), want %q (private %v)", tt.ccname, path, private, tt.wantPath, tt.wantPrivate)
+		}
+	}
+}
+
+func TestRunKinitReportsErrors(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("KRB5CCNAME", "FILE:"+filepath.Join(dir, "krb5cc"))
+
+	t.Setenv("KRB5_CONFIG", filepath.Join(dir, "missing.conf"))
+	if err := RunKinit("user", "password"); err == nil || !strings.Contains(err.Error(), "Kerberos config") {
+		t.Errorf("expected an error about the missing config, got %v", err)
+	}
+
+	// no KDC is listening on the configured port
+	conf := filepath.Join(dir, "krb5.conf")
+	content := `[libdefaults]
+ default_realm = EXAMPLE.COM
+ udp_preference_limit = 1
+[realms]
+ EXAMPLE.COM = {
+  kdc = 127.0.0.1:1
+ }
+`
+	if err := os.WriteFile(conf, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("KRB5_CONFIG", conf)
+	if err := RunKinit("user", "password"); err == nil || !strings.Contains(err.Error(), "user@EXAMPLE.COM") {
+		t.Errorf("expected the failed login to be reported, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "krb5cc")); !os.IsNotExist(err) {
+		t.Error("no credential cache should be written after a failed login")
+	}
+}
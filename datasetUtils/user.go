@@ -0,0 +1,38 @@
+package datasetUtils
+
+import (
+	"time"
+)
+
+// User is an authenticated SciCat user along with the access token of its session.
+type User struct {
+	Username     string   `json:"username"`
+	DisplayName  string   `json:"displayName,omitempty"`
+	Mail         string   `json:"mail,omitempty"`
+	AccessGroups []string `json:"accessGroups,omitempty"`
+	Roles        []string `json:"roles,omitempty"`
+
+	AccessToken string `json:"accessToken"`
+	// ExpiresIn is the lifetime of the access token in seconds, 0 if unknown
+	ExpiresIn int `json:"expiresIn,omitempty"`
+	// Created is the creation time of the access token as returned by SciCat, empty if unknown
+	Created string `json:"created,omitempty"`
+
+	// Password is only known for logins with username and password. It's used for the PSI
+	// specific Kerberos login and for transfers authenticated with the SciCat credentials.
+	Password string `json:"-"`
+}
+
+// Expires returns the expiry time of the access token, computed from Created and ExpiresIn. It's
+// zero if the lifetime is unknown. If the creation time can't be parsed, the token is assumed to
+// have been created now.
+func (u User) Expires() time.Time {
+	if u.ExpiresIn <= 0 {
+		return time.Time{}
+	}
+	created, err := time.Parse(time.RFC3339, u.Created)
+	if err != nil {
+		created = time.Now()
+	}
+	return created.Add(time.Duration(u.ExpiresIn) * time.Second)
+}
@@ -0,0 +1,25 @@
+package datasetUtils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUserExpires(t *testing.T) {
+	user := User{ExpiresIn: 3600, Created: "2024-01-02T10:00:00.000Z"}
+	want := time.Date(2024, 1, 2, 11, 0, 0, 0, time.UTC)
+	if got := user.Expires(); !got.Equal(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	user.ExpiresIn = 0
+	if got := user.Expires(); !got.IsZero() {
+		t.Errorf("a token with unknown lifetime should have no expiry, got %v", got)
+	}
+
+	// without a valid creation time the token is assumed to be fresh
+	user = User{ExpiresIn: 60, Created: "invalid"}
+	if got := user.Expires(); got.Before(time.Now().Add(50*time.Second)) || got.After(time.Now().Add(time.Minute)) {
+		t.Errorf("got %v, want about a minute from now", got)
+	}
+}
@@ -0,0 +1,54 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/paulscherrerinstitute/scicat-cli/v3/datasetIngestor"
+	"github.com/paulscherrerinstitute/scicat-cli/v3/datasetUtils"
+)
+
+var checkMetadataFunc = datasetIngestor.CheckMetadata
+var checkTemplatedMetadataFunc = datasetIngestor.CheckTemplatedMetadata
+
+// ManifestDataset is a dataset of a batch manifest, with its checked metadata.
+type ManifestDataset struct {
+	SourceFolder string
+	MetaDataMap  map[string]interface{}
+	Attachment   string
+	Caption      string
+}
+
+/*
+CheckBatchManifest merges the overrides of every entry of a batch manifest over the base metadata,
+and checks the result with datasetIngestor.CheckMetadata. All datasets are checked before any of
+them is ingested, so a typo in the last row doesn't leave a half ingested batch behind.
+
+The SourceFolder of the returned datasets is the one returned by CheckMetadata, in canonical form.
+beamlineAccount tells if the user is the beamline account of the datasets' ownerGroup, which the
+entries can't override. With templates the metadata is checked with
+datasetIngestor.CheckTemplatedMetadata instead, the templates are expanded per dataset later.
+*/
+func CheckBatchManifest(ctx context.Context, client *http.Client, APIServer string, user datasetUtils.User, rules datasetIngestor.FacilityRules,
+	remoteFiles bool, templates bool, base map[string]interface{}, entries []datasetIngestor.ManifestEntry) (datasets []ManifestDataset, beamlineAccount bool, err error) {
+	checkMetadata := checkMetadataFunc
+	if templates {
+		checkMetadata = checkTemplatedMetadataFunc
+	}
+	for _, entry := range entries {
+		metaDataMap := entry.Apply(base)
+		sourceFolder, isBeamlineAccount, err := checkMetadata(ctx, client, APIServer, metaDataMap, user, rules, remoteFiles)
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid metadata for the sourceFolder %q of the batch manifest: %w", entry.SourceFolder, err)
+		}
+		beamlineAccount = isBeamlineAccount
+		datasets = append(datasets, ManifestDataset{
+			SourceFolder: sourceFolder,
+			MetaDataMap:  metaDataMap,
+			Attachment:   entry.Attachment,
+			Caption:      entry.Caption,
+		})
+	}
+	return datasets, beamlineAccount, nil
+}
@@ -0,0 +1,67 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/paulscherrerinstitute/scicat-cli/v3/datasetIngestor"
+	"github.com/paulscherrerinstitute/scicat-cli/v3/datasetUtils"
+)
+
+func TestCheckBatchManifest(t *testing.T) {
+	oldCheckMetadata := checkMetadataFunc
+	defer func() { checkMetadataFunc = oldCheckMetadata }()
+
+	var checked []map[string]interface{}
+	checkMetadataFunc = func(ctx context.Context, client *http.Client, APIServer string, metaDataMap map[string]interface{}, user datasetUtils.User, rules datasetIngestor.FacilityRules, remoteFiles bool) (string, bool, error) {
+		checked = append(checked, metaDataMap)
+		if metaDataMap["datasetName"] == "" {
+			return "", false, errors.New("datasetName is empty")
+		}
+		return "/canonical" + metaDataMap["sourceFolder"].(string), true, nil
+	}
+
+	base := map[string]interface{}{"datasetName": "Beamtime", "ownerGroup": "p12345"}
+	entries := []datasetIngestor.ManifestEntry{
+		{SourceFolder: "/data/scan_1", DatasetName: "Scan 1", Attachment: "thumb.png", Caption: "Overview"},
+		{SourceFolder: "/data/scan_2"},
+	}
+
+	t.Run("checks the merged metadata of every dataset", func(t *testing.T) {
+		checked = nil
+		datasets, beamlineAccount, err := CheckBatchManifest(context.Background(), nil, "", datasetUtils.User{}, datasetIngestor.FacilityRules{}, false, false, base, entries)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !beamlineAccount {
+			t.Error("expected the beamline account of CheckMetadata")
+		}
+		if len(datasets) != 2 || len(checked) != 2 {
+			t.Fatalf("expected 2 checked datasets, got %d (%d checked)", len(datasets), len(checked))
+		}
+		if datasets[0].SourceFolder != "/canonical/data/scan_1" || datasets[0].MetaDataMap["datasetName"] != "Scan 1" {
+			t.Errorf("unexpected first dataset %+v", datasets[0])
+		}
+		if datasets[0].Attachment != "thumb.png" || datasets[0].Caption != "Overview" {
+			t.Errorf("expected the attachment of the manifest, got %+v", datasets[0])
+		}
+		if datasets[1].MetaDataMap["datasetName"] != "Beamtime" || datasets[1].MetaDataMap["sourceFolder"] != "/data/scan_2" {
+			t.Errorf("expected the base metadata with the sourceFolder of the manifest, got %v", datasets[1].MetaDataMap)
+		}
+	})
+
+	t.Run("fails before returning any dataset", func(t *testing.T) {
+		invalid := append(entries, datasetIngestor.ManifestEntry{SourceFolder: "/data/scan_3"})
+		base := map[string]interface{}{"datasetName": ""}
+		datasets, _, err := CheckBatchManifest(context.Background(), nil, "", datasetUtils.User{}, datasetIngestor.FacilityRules{}, false, false, base, invalid)
+		if err == nil || !strings.Contains(err.Error(), "/data/scan_2") {
+			t.Errorf("expected an error naming the invalid sourceFolder, got %v", err)
+		}
+		if datasets != nil {
+			t.Errorf("expected no datasets, got %v", datasets)
+		}
+	})
+}
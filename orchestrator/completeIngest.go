@@ -1,6 +1,7 @@
 package orchestrator
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
@@ -18,23 +19,33 @@ var createOrigDatablocksFunc = datasetIngestor.CreateOrigDatablocks
 var patchDatasetFunc = datasetUtils.PatchDataset
 var markFilesReadyFunc = datasetIngestor.MarkFilesReady
 var gatherCompletionFileListFunc = gatherCompletionFileList
+var deleteOrigDatablocksFunc = datasetUtils.DeleteOrigDatablocks
 
 /*
 CompleteIngest defines and adds a dataset to the SciCat catalog for a dataset entry that was
 previously created without any files attached (NumberOfFiles == 0).
 
-It checks that the caller is allowed to perform the operation, that the dataset identified by
+It checks that the caller is allowed by rule to perform the operation, that the dataset identified by
 pid exists, is empty and has a sourceFolder defined, then gathers the local file list from that
 sourceFolder and creates the corresponding origdatablocks. Symlinks are kept only when they point
-internally to the sourceFolder; filenames containing "*", "\" or three consecutive blanks are
-excluded from the dataset.
+internally to the sourceFolder; the files whose names are rejected by filenamePolicy are excluded
+from the dataset, or fail the ingestion before any origdatablock is created, depending on its Mode.
+scanOptions selects the checksums sent with the files.
+
+With streamBlocks, each origdatablock is posted as soon as it's full while the sourceFolder is
+still scanned, so the file list is never held in memory as a whole. If the scan fails, e.g. the
+dataset has too many files or only empty ones, the blocks posted already are deleted again, so the
+dataset is back to its empty state and the completion can be run again.
 */
-func CompleteIngest(client *http.Client, APIServer string, user map[string]string, pid string, sourceFolderPrefix string) error {
-	if err := requireArchiveManager(user); err != nil {
+func CompleteIngest(ctx context.Context, client *http.Client, APIServer string, user datasetUtils.User, rule datasetUtils.AccessRule, pid string, sourceFolderPrefix string, scanOptions datasetIngestor.ScanOptions, filenamePolicy datasetIngestor.FilenamePolicy, streamBlocks bool) error {
+	if streamBlocks && filenamePolicy.Mode == datasetIngestor.IllegalFilenamesFail {
+		return fmt.Errorf("failing on illegal file names can't be combined with streaming the origdatablocks")
+	}
+	if err := datasetUtils.Authorize(user, rule, "complete the ingestion"); err != nil {
 		return err
 	}
 
-	dataset, err := resolveEmptyDatasetSourceFolder(client, APIServer, user, pid)
+	dataset, err := resolveEmptyDatasetSourceFolder(ctx, client, APIServer, user, pid)
 	if err != nil {
 		return err
 	}
@@ -46,20 +57,43 @@ func CompleteIngest(client *http.Client, APIServer string, user map[string]strin
 		log.Printf("Using sourceFolder %s (prefix %s applied)\n", sourceFolder, sourceFolderPrefix)
 	}
 
-	fullFileArray, startTime, endTime, skippedLinks, illegalFileNames, err := gatherCompletionFileListFunc(sourceFolder)
+	var builder *datasetIngestor.OrigDatablockBuilder
+	if streamBlocks {
+		// the dataset exists already, so its blocks can be posted during the scan
+		builder = datasetIngestor.NewOrigDatablockBuilder(ctx, client, APIServer, pid, user)
+		scanOptions.OnFile = builder.Add
+	}
+
+	fullFileArray, startTime, endTime, skippedLinks, illegalFileNames, err := gatherCompletionFileListFunc(ctx, sourceFolder, scanOptions, filenamePolicy)
+	if builder != nil {
+		if err != nil {
+			builder.Abort()
+		} else if err = builder.Close(); err != nil {
+			err = fmt.Errorf("failed to create origdatablocks for dataset %s: %w", pid, err)
+		}
+		if err != nil {
+			// a failing post may have been stored anyway, so the blocks are deleted in any case
+			if deleteErr := deleteOrigDatablocksFunc(context.WithoutCancel(ctx), client, APIServer, pid, user); deleteErr != nil {
+				return fmt.Errorf("%w, and the origdatablocks posted already couldn't be deleted: %v", err, deleteErr)
+			}
+			log.Printf("Deleted the origdatablocks of dataset %s posted before the error (%d files)\n", pid, builder.Posted())
+		}
+	}
 	if err != nil {
 		return err
 	}
 
-	if err := createOrigDatablocksFunc(client, APIServer, fullFileArray, pid, user); err != nil {
-		return fmt.Errorf("failed to create origdatablocks for dataset %s: %w", pid, err)
+	if builder == nil {
+		if err := createOrigDatablocksFunc(ctx, client, APIServer, fullFileArray, pid, user); err != nil {
+			return fmt.Errorf("failed to create origdatablocks for dataset %s: %w", pid, err)
+		}
 	}
 
-	if err := updateDatasetTimes(client, APIServer, user, pid, startTime, endTime); err != nil {
+	if err := updateDatasetTimes(ctx, client, APIServer, user, pid, startTime, endTime); err != nil {
 		return err
 	}
 
-	if err := markFilesReadyFunc(client, APIServer, pid, user); err != nil {
+	if err := markFilesReadyFunc(ctx, client, APIServer, pid, user); err != nil {
 		return err
 	}
 
@@ -72,20 +106,11 @@ func CompleteIngest(client *http.Client, APIServer string, user map[string]strin
 	return nil
 }
 
-// requireArchiveManager enforces that only the archiveManager account may complete an ingest.
-// Kept as a pure function so the authorization rule can be unit-tested without any client/network setup.
-func requireArchiveManager(user map[string]string) error {
-	if user["username"] != "archiveManager" {
-		return fmt.Errorf("you must be archiveManager to be allowed to complete the ingestion")
-	}
-	return nil
-}
-
 // resolveEmptyDatasetSourceFolder fetches the dataset identified by pid and validates that it is
 // in the expected pre-completion state: it exists, has no files yet, and has a sourceFolder to
 // scan. Returns that sourceFolder on success.
-func resolveEmptyDatasetSourceFolder(client *http.Client, APIServer string, user map[string]string, pid string) (datasetUtils.Dataset, error) {
-	dataset, missing, err := getDatasetDetailsFunc(client, APIServer, user["accessToken"], []string{pid}, "")
+func resolveEmptyDatasetSourceFolder(ctx context.Context, client *http.Client, APIServer string, user datasetUtils.User, pid string) (datasetUtils.Dataset, error) {
+	dataset, missing, err := getDatasetDetailsFunc(ctx, client, APIServer, user.AccessToken, []string{pid}, "")
 	if err != nil {
 		return datasetUtils.Dataset{}, err
 	}
@@ -105,26 +130,33 @@ func resolveEmptyDatasetSourceFolder(client *http.Client, APIServer string, user
 // counts of symlinks skipped and files excluded for illegal filenames. Symlinks are kept only
 // when they resolve to a path internal to sourceFolder ("dA" policy); this path never prompts,
 // since dataset completion is meant to run unattended.
-func gatherCompletionFileList(sourceFolder string) ([]datasetIngestor.Datafile, time.Time, time.Time, uint, uint, error) {
+func gatherCompletionFileList(ctx context.Context, sourceFolder string, scanOptions datasetIngestor.ScanOptions, filenamePolicy datasetIngestor.FilenamePolicy) ([]datasetIngestor.Datafile, time.Time, time.Time, uint, uint, error) {
 	skipSymlinks := "dA"
 	var skippedLinks, illegalFileNames uint
 	symlinkCallback := datasetIngestor.CreateLocalSymlinkCallbackForFileLister(&skipSymlinks, &skippedLinks)
-	filenameFilterCallback := datasetIngestor.CreateLocalFilenameFilterCallback(&illegalFileNames)
+	filenameFilter, err := datasetIngestor.NewFilenameFilter(filenamePolicy, &illegalFileNames)
+	if err != nil {
+		return nil, time.Time{}, time.Time{}, 0, 0, err
+	}
 
 	fullFileArray, startTime, endTime, _, _, _, err :=
-		datasetIngestor.GetValidatedLocalFileList(sourceFolder, "", symlinkCallback, filenameFilterCallback)
+		datasetIngestor.GetValidatedLocalFileList(ctx, sourceFolder, "", symlinkCallback, filenameFilter.Check, scanOptions)
+	// the report is written even if all files were rejected
+	if finishErr := filenameFilter.Finish(sourceFolder); err == nil {
+		err = finishErr
+	}
 	if err != nil {
 		return nil, time.Time{}, time.Time{}, 0, 0, err
 	}
 	return fullFileArray, startTime, endTime, skippedLinks, illegalFileNames, nil
 }
 
-func updateDatasetTimes(client *http.Client, APIServer string, user map[string]string, pid string, startTime time.Time, endTime time.Time) error {
+func updateDatasetTimes(ctx context.Context, client *http.Client, APIServer string, user datasetUtils.User, pid string, startTime time.Time, endTime time.Time) error {
 	meta := map[string]interface{}{
 		"creationTime": startTime.Format(time.RFC3339),
 		"endTime":      endTime.Format(time.RFC3339),
 	}
-	return patchDatasetFunc(client, APIServer, user["accessToken"], pid, meta)
+	return patchDatasetFunc(ctx, client, APIServer, user.AccessToken, pid, meta)
 }
 
 func ExtractPidFromArgs(args []string) (string, error) {
@@ -1,8 +1,11 @@
 package orchestrator
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"testing"
@@ -22,68 +25,86 @@ func withCompleteIngestMocks(t *testing.T) {
 	oldCreateOrigDatablocks := createOrigDatablocksFunc
 	oldPatchDataset := patchDatasetFunc
 	oldMarkFilesReady := markFilesReadyFunc
+	oldDeleteOrigDatablocks := deleteOrigDatablocksFunc
 	t.Cleanup(func() {
 		getDatasetDetailsFunc = oldGetDatasetDetails
 		gatherCompletionFileListFunc = oldGather
 		createOrigDatablocksFunc = oldCreateOrigDatablocks
 		patchDatasetFunc = oldPatchDataset
 		markFilesReadyFunc = oldMarkFilesReady
+		deleteOrigDatablocksFunc = oldDeleteOrigDatablocks
 	})
 
-	getDatasetDetailsFunc = func(client *http.Client, APIServer string, accessToken string, datasetList []string, ownerGroup string) ([]datasetUtils.Dataset, []string, error) {
+	getDatasetDetailsFunc = func(ctx context.Context, client *http.Client, APIServer string, accessToken string, datasetList []string, ownerGroup string) ([]datasetUtils.Dataset, []string, error) {
 		return []datasetUtils.Dataset{{Pid: "testPid", SourceFolder: "/some/folder", NumberOfFiles: 0}}, nil, nil
 	}
-	gatherCompletionFileListFunc = func(sourceFolder string) ([]datasetIngestor.Datafile, time.Time, time.Time, uint, uint, error) {
+	gatherCompletionFileListFunc = func(ctx context.Context, sourceFolder string, scanOptions datasetIngestor.ScanOptions, filenamePolicy datasetIngestor.FilenamePolicy) ([]datasetIngestor.Datafile, time.Time, time.Time, uint, uint, error) {
 		return []datasetIngestor.Datafile{{Path: "a"}}, time.Now(), time.Now(), 0, 0, nil
 	}
-	createOrigDatablocksFunc = func(client *http.Client, APIServer string, fullFileArray []datasetIngestor.Datafile, datasetId string, user map[string]string) error {
+	createOrigDatablocksFunc = func(ctx context.Context, client *http.Client, APIServer string, fullFileArray []datasetIngestor.Datafile, datasetId string, user datasetUtils.User) error {
 		return nil
 	}
-	patchDatasetFunc = func(client *http.Client, APIServer string, token string, datasetId string, meta map[string]interface{}) error {
+	patchDatasetFunc = func(ctx context.Context, client *http.Client, APIServer string, token string, datasetId string, meta map[string]interface{}) error {
 		return nil
 	}
-	markFilesReadyFunc = func(client *http.Client, APIServer string, datasetId string, user map[string]string) error {
+	markFilesReadyFunc = func(ctx context.Context, client *http.Client, APIServer string, datasetId string, user datasetUtils.User) error {
+		return nil
+	}
+	deleteOrigDatablocksFunc = func(ctx context.Context, client *http.Client, APIServer string, pid string, user datasetUtils.User) error {
+		t.Error("no origdatablocks should be deleted")
 		return nil
 	}
 }
 
 func TestCompleteIngest(t *testing.T) {
-	archiveManager := map[string]string{"username": "archiveManager", "accessToken": "testToken"}
+	archiveManager := datasetUtils.User{Username: "archiveManager", AccessToken: "testToken"}
 
 	t.Run("rejects non archiveManager users", func(t *testing.T) {
-		err := CompleteIngest(nil, "", map[string]string{"username": "someoneElse"}, "testPid", "")
+		err := CompleteIngest(context.Background(), nil, "", datasetUtils.User{Username: "someoneElse"}, datasetUtils.DefaultAccessRule, "testPid", "", datasetIngestor.ScanOptions{}, datasetIngestor.FilenamePolicy{}, false)
 		if err == nil {
 			t.Fatal("expected an error, got nil")
 		}
 	})
 
+	t.Run("accepts users with a configured role", func(t *testing.T) {
+		withCompleteIngestMocks(t)
+		admin := datasetUtils.User{Username: "someAdmin", Roles: []string{"ingestor"}, AccessToken: "testToken"}
+		rule := datasetUtils.AccessRule{Roles: []string{"ingestor"}}
+		if err := CompleteIngest(context.Background(), nil, "", admin, rule, "testPid", "", datasetIngestor.ScanOptions{}, datasetIngestor.FilenamePolicy{}, false); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if err := CompleteIngest(context.Background(), nil, "", archiveManager, rule, "testPid", "", datasetIngestor.ScanOptions{}, datasetIngestor.FilenamePolicy{}, false); err == nil {
+			t.Error("archiveManager should need the role as well once a rule is configured")
+		}
+	})
+
 	resolutionFailures := []struct {
 		name                  string
-		mockGetDatasetDetails func(client *http.Client, APIServer string, accessToken string, datasetList []string, ownerGroup string) ([]datasetUtils.Dataset, []string, error)
-		mockGather            func(sourceFolder string) ([]datasetIngestor.Datafile, time.Time, time.Time, uint, uint, error)
+		mockGetDatasetDetails func(ctx context.Context, client *http.Client, APIServer string, accessToken string, datasetList []string, ownerGroup string) ([]datasetUtils.Dataset, []string, error)
+		mockGather            func(ctx context.Context, sourceFolder string, scanOptions datasetIngestor.ScanOptions, filenamePolicy datasetIngestor.FilenamePolicy) ([]datasetIngestor.Datafile, time.Time, time.Time, uint, uint, error)
 		checkErr              func(t *testing.T, err error)
 	}{
 		{
 			name: "the dataset already contains files",
-			mockGetDatasetDetails: func(client *http.Client, APIServer string, accessToken string, datasetList []string, ownerGroup string) ([]datasetUtils.Dataset, []string, error) {
+			mockGetDatasetDetails: func(ctx context.Context, client *http.Client, APIServer string, accessToken string, datasetList []string, ownerGroup string) ([]datasetUtils.Dataset, []string, error) {
 				return []datasetUtils.Dataset{{Pid: "testPid", SourceFolder: "/some/folder", NumberOfFiles: 3}}, nil, nil
 			},
 		},
 		{
 			name: "the dataset has no sourceFolder",
-			mockGetDatasetDetails: func(client *http.Client, APIServer string, accessToken string, datasetList []string, ownerGroup string) ([]datasetUtils.Dataset, []string, error) {
+			mockGetDatasetDetails: func(ctx context.Context, client *http.Client, APIServer string, accessToken string, datasetList []string, ownerGroup string) ([]datasetUtils.Dataset, []string, error) {
 				return []datasetUtils.Dataset{{Pid: "testPid", SourceFolder: "", NumberOfFiles: 0}}, nil, nil
 			},
 		},
 		{
 			name: "the dataset is not found",
-			mockGetDatasetDetails: func(client *http.Client, APIServer string, accessToken string, datasetList []string, ownerGroup string) ([]datasetUtils.Dataset, []string, error) {
+			mockGetDatasetDetails: func(ctx context.Context, client *http.Client, APIServer string, accessToken string, datasetList []string, ownerGroup string) ([]datasetUtils.Dataset, []string, error) {
 				return nil, []string{"testPid"}, nil
 			},
 		},
 		{
 			name: "the sourceFolder contains no files",
-			mockGather: func(sourceFolder string) ([]datasetIngestor.Datafile, time.Time, time.Time, uint, uint, error) {
+			mockGather: func(ctx context.Context, sourceFolder string, scanOptions datasetIngestor.ScanOptions, filenamePolicy datasetIngestor.FilenamePolicy) ([]datasetIngestor.Datafile, time.Time, time.Time, uint, uint, error) {
 				return nil, time.Time{}, time.Time{}, 0, 0, &datasetIngestor.EmptyDatasetError{SourceFolder: sourceFolder}
 			},
 			checkErr: func(t *testing.T, err error) {
@@ -104,7 +125,7 @@ func TestCompleteIngest(t *testing.T) {
 				gatherCompletionFileListFunc = tt.mockGather
 			}
 
-			err := CompleteIngest(nil, "", archiveManager, "testPid", "")
+			err := CompleteIngest(context.Background(), nil, "", archiveManager, datasetUtils.DefaultAccessRule, "testPid", "", datasetIngestor.ScanOptions{}, datasetIngestor.FilenamePolicy{}, false)
 			if tt.checkErr != nil {
 				tt.checkErr(t, err)
 			} else if err == nil {
@@ -149,16 +170,16 @@ func TestCompleteIngest(t *testing.T) {
 	for _, tt := range warnings {
 		t.Run("creates the origdatablock and returns a "+tt.name, func(t *testing.T) {
 			withCompleteIngestMocks(t)
-			gatherCompletionFileListFunc = func(sourceFolder string) ([]datasetIngestor.Datafile, time.Time, time.Time, uint, uint, error) {
+			gatherCompletionFileListFunc = func(ctx context.Context, sourceFolder string, scanOptions datasetIngestor.ScanOptions, filenamePolicy datasetIngestor.FilenamePolicy) ([]datasetIngestor.Datafile, time.Time, time.Time, uint, uint, error) {
 				return []datasetIngestor.Datafile{{Path: "a"}}, time.Now(), time.Now(), tt.skippedLinks, tt.illegalFileNames, nil
 			}
 			var createdOrigDatablock bool
-			createOrigDatablocksFunc = func(client *http.Client, APIServer string, fullFileArray []datasetIngestor.Datafile, datasetId string, user map[string]string) error {
+			createOrigDatablocksFunc = func(ctx context.Context, client *http.Client, APIServer string, fullFileArray []datasetIngestor.Datafile, datasetId string, user datasetUtils.User) error {
 				createdOrigDatablock = true
 				return nil
 			}
 
-			err := CompleteIngest(nil, "", archiveManager, "testPid", "")
+			err := CompleteIngest(context.Background(), nil, "", archiveManager, datasetUtils.DefaultAccessRule, "testPid", "", datasetIngestor.ScanOptions{}, datasetIngestor.FilenamePolicy{}, false)
 			tt.checkWarning(t, err)
 			if !createdOrigDatablock {
 				t.Error("expected an origdatablock to be created even when a warning is returned")
@@ -169,12 +190,12 @@ func TestCompleteIngest(t *testing.T) {
 	t.Run("gathers the filelist and creates the origdatablocks", func(t *testing.T) {
 		withCompleteIngestMocks(t)
 		var createdOrigDatablock bool
-		createOrigDatablocksFunc = func(client *http.Client, APIServer string, fullFileArray []datasetIngestor.Datafile, datasetId string, user map[string]string) error {
+		createOrigDatablocksFunc = func(ctx context.Context, client *http.Client, APIServer string, fullFileArray []datasetIngestor.Datafile, datasetId string, user datasetUtils.User) error {
 			createdOrigDatablock = true
 			return nil
 		}
 
-		if err := CompleteIngest(nil, "", archiveManager, "testPid", ""); err != nil {
+		if err := CompleteIngest(context.Background(), nil, "", archiveManager, datasetUtils.DefaultAccessRule, "testPid", "", datasetIngestor.ScanOptions{}, datasetIngestor.FilenamePolicy{}, false); err != nil {
 			t.Fatalf("expected no error, got: %v", err)
 		}
 		if !createdOrigDatablock {
@@ -182,15 +203,90 @@ func TestCompleteIngest(t *testing.T) {
 		}
 	})
 
+	t.Run("posts the origdatablocks during the scan with streamBlocks", func(t *testing.T) {
+		withCompleteIngestMocks(t)
+		var posted []datasetIngestor.FileBlock
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var block datasetIngestor.FileBlock
+			if err := json.NewDecoder(r.Body).Decode(&block); err != nil {
+				t.Errorf("can't decode the origdatablock: %v", err)
+			}
+			posted = append(posted, block)
+		}))
+		defer server.Close()
+		gatherCompletionFileListFunc = func(ctx context.Context, sourceFolder string, scanOptions datasetIngestor.ScanOptions, filenamePolicy datasetIngestor.FilenamePolicy) ([]datasetIngestor.Datafile, time.Time, time.Time, uint, uint, error) {
+			if scanOptions.OnFile == nil {
+				t.Fatal("expected the files to be streamed")
+			}
+			for _, path := range []string{"a", "b"} {
+				if err := scanOptions.OnFile(datasetIngestor.Datafile{Path: path, Size: 1}); err != nil {
+					return nil, time.Time{}, time.Time{}, 0, 0, err
+				}
+			}
+			return nil, time.Now(), time.Now(), 0, 0, nil
+		}
+		createOrigDatablocksFunc = func(ctx context.Context, client *http.Client, APIServer string, fullFileArray []datasetIngestor.Datafile, datasetId string, user datasetUtils.User) error {
+			t.Error("the streamed blocks shouldn't be created again")
+			return nil
+		}
+
+		if err := CompleteIngest(context.Background(), server.Client(), server.URL, archiveManager, datasetUtils.DefaultAccessRule, "testPid", "", datasetIngestor.ScanOptions{}, datasetIngestor.FilenamePolicy{}, true); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if len(posted) != 1 || len(posted[0].DataFileList) != 2 || posted[0].DatasetId != "testPid" || posted[0].Size != 2 {
+			t.Errorf("expected one block with both files, got %+v", posted)
+		}
+	})
+
+	t.Run("deletes the streamed origdatablocks when the scan fails", func(t *testing.T) {
+		withCompleteIngestMocks(t)
+		oldLimits := datasetUtils.DefaultIngestSizeLimits
+		t.Cleanup(func() { datasetUtils.DefaultIngestSizeLimits = oldLimits })
+		datasetUtils.DefaultIngestSizeLimits.BlockMaxFiles = 1
+		var posted int
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			posted++
+		}))
+		defer server.Close()
+		gatherCompletionFileListFunc = func(ctx context.Context, sourceFolder string, scanOptions datasetIngestor.ScanOptions, filenamePolicy datasetIngestor.FilenamePolicy) ([]datasetIngestor.Datafile, time.Time, time.Time, uint, uint, error) {
+			for _, path := range []string{"a", "b"} {
+				if err := scanOptions.OnFile(datasetIngestor.Datafile{Path: path, Size: 1}); err != nil {
+					return nil, time.Time{}, time.Time{}, 0, 0, err
+				}
+			}
+			return nil, time.Time{}, time.Time{}, 0, 0, errors.New("scan failed")
+		}
+		var deletedPid string
+		deleteOrigDatablocksFunc = func(ctx context.Context, client *http.Client, APIServer string, pid string, user datasetUtils.User) error {
+			deletedPid = pid
+			return nil
+		}
+		markFilesReadyFunc = func(ctx context.Context, client *http.Client, APIServer string, datasetId string, user datasetUtils.User) error {
+			t.Error("the files shouldn't be marked as ready")
+			return nil
+		}
+
+		err := CompleteIngest(context.Background(), server.Client(), server.URL, archiveManager, datasetUtils.DefaultAccessRule, "testPid", "", datasetIngestor.ScanOptions{}, datasetIngestor.FilenamePolicy{}, true)
+		if err == nil || err.Error() != "scan failed" {
+			t.Fatalf("expected the scan error, got: %v", err)
+		}
+		if deletedPid != "testPid" {
+			t.Errorf("expected the origdatablocks of testPid to be deleted, got %q", deletedPid)
+		}
+		if posted > 2 {
+			t.Errorf("expected at most the full blocks to be posted, got %d", posted)
+		}
+	})
+
 	t.Run("applies the sourceFolderPrefix to the dataset's sourceFolder before gathering files", func(t *testing.T) {
 		withCompleteIngestMocks(t)
 		var gotSourceFolder string
-		gatherCompletionFileListFunc = func(sourceFolder string) ([]datasetIngestor.Datafile, time.Time, time.Time, uint, uint, error) {
+		gatherCompletionFileListFunc = func(ctx context.Context, sourceFolder string, scanOptions datasetIngestor.ScanOptions, filenamePolicy datasetIngestor.FilenamePolicy) ([]datasetIngestor.Datafile, time.Time, time.Time, uint, uint, error) {
 			gotSourceFolder = sourceFolder
 			return []datasetIngestor.Datafile{{Path: "a"}}, time.Now(), time.Now(), 0, 0, nil
 		}
 
-		if err := CompleteIngest(nil, "", archiveManager, "testPid", "/mnt/remote/"); err != nil {
+		if err := CompleteIngest(context.Background(), nil, "", archiveManager, datasetUtils.DefaultAccessRule, "testPid", "/mnt/remote/", datasetIngestor.ScanOptions{}, datasetIngestor.FilenamePolicy{}, false); err != nil {
 			t.Fatalf("expected no error, got: %v", err)
 		}
 		if want := "/mnt/remote/some/folder"; gotSourceFolder != want {
@@ -200,20 +296,20 @@ func TestCompleteIngest(t *testing.T) {
 
 	t.Run("aborts before updating dataset times or marking files ready when creating origdatablocks fails", func(t *testing.T) {
 		withCompleteIngestMocks(t)
-		createOrigDatablocksFunc = func(client *http.Client, APIServer string, fullFileArray []datasetIngestor.Datafile, datasetId string, user map[string]string) error {
+		createOrigDatablocksFunc = func(ctx context.Context, client *http.Client, APIServer string, fullFileArray []datasetIngestor.Datafile, datasetId string, user datasetUtils.User) error {
 			return errors.New("boom")
 		}
 		var updatedTimes, markedFilesReady bool
-		patchDatasetFunc = func(client *http.Client, APIServer string, token string, datasetId string, meta map[string]interface{}) error {
+		patchDatasetFunc = func(ctx context.Context, client *http.Client, APIServer string, token string, datasetId string, meta map[string]interface{}) error {
 			updatedTimes = true
 			return nil
 		}
-		markFilesReadyFunc = func(client *http.Client, APIServer string, datasetId string, user map[string]string) error {
+		markFilesReadyFunc = func(ctx context.Context, client *http.Client, APIServer string, datasetId string, user datasetUtils.User) error {
 			markedFilesReady = true
 			return nil
 		}
 
-		err := CompleteIngest(nil, "", archiveManager, "testPid", "")
+		err := CompleteIngest(context.Background(), nil, "", archiveManager, datasetUtils.DefaultAccessRule, "testPid", "", datasetIngestor.ScanOptions{}, datasetIngestor.FilenamePolicy{}, false)
 		if err == nil {
 			t.Fatal("expected an error, got nil")
 		}
@@ -227,16 +323,16 @@ func TestCompleteIngest(t *testing.T) {
 
 	t.Run("aborts before marking files ready when updating the dataset times fails", func(t *testing.T) {
 		withCompleteIngestMocks(t)
-		patchDatasetFunc = func(client *http.Client, APIServer string, token string, datasetId string, meta map[string]interface{}) error {
+		patchDatasetFunc = func(ctx context.Context, client *http.Client, APIServer string, token string, datasetId string, meta map[string]interface{}) error {
 			return errors.New("boom")
 		}
 		var markedFilesReady bool
-		markFilesReadyFunc = func(client *http.Client, APIServer string, datasetId string, user map[string]string) error {
+		markFilesReadyFunc = func(ctx context.Context, client *http.Client, APIServer string, datasetId string, user datasetUtils.User) error {
 			markedFilesReady = true
 			return nil
 		}
 
-		err := CompleteIngest(nil, "", archiveManager, "testPid", "")
+		err := CompleteIngest(context.Background(), nil, "", archiveManager, datasetUtils.DefaultAccessRule, "testPid", "", datasetIngestor.ScanOptions{}, datasetIngestor.FilenamePolicy{}, false)
 		if err == nil {
 			t.Fatal("expected an error, got nil")
 		}
@@ -249,16 +345,16 @@ func TestCompleteIngest(t *testing.T) {
 		withCompleteIngestMocks(t)
 		wantStartTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
 		wantEndTime := time.Date(2020, 6, 7, 8, 9, 10, 0, time.UTC)
-		gatherCompletionFileListFunc = func(sourceFolder string) ([]datasetIngestor.Datafile, time.Time, time.Time, uint, uint, error) {
+		gatherCompletionFileListFunc = func(ctx context.Context, sourceFolder string, scanOptions datasetIngestor.ScanOptions, filenamePolicy datasetIngestor.FilenamePolicy) ([]datasetIngestor.Datafile, time.Time, time.Time, uint, uint, error) {
 			return []datasetIngestor.Datafile{{Path: "a"}}, wantStartTime, wantEndTime, 0, 0, nil
 		}
 		var patchedMeta map[string]interface{}
-		patchDatasetFunc = func(client *http.Client, APIServer string, token string, datasetId string, meta map[string]interface{}) error {
+		patchDatasetFunc = func(ctx context.Context, client *http.Client, APIServer string, token string, datasetId string, meta map[string]interface{}) error {
 			patchedMeta = meta
 			return nil
 		}
 
-		if err := CompleteIngest(nil, "", archiveManager, "testPid", ""); err != nil {
+		if err := CompleteIngest(context.Background(), nil, "", archiveManager, datasetUtils.DefaultAccessRule, "testPid", "", datasetIngestor.ScanOptions{}, datasetIngestor.FilenamePolicy{}, false); err != nil {
 			t.Fatalf("expected no error, got: %v", err)
 		}
 
@@ -312,7 +408,7 @@ func TestGatherCompletionFileList(t *testing.T) {
 			t.Fatalf("failed to create regular file: %s", err)
 		}
 
-		_, _, _, skippedLinks, illegalFileNames, err := gatherCompletionFileList(tempDirAbs)
+		_, _, _, skippedLinks, illegalFileNames, err := gatherCompletionFileList(context.Background(), tempDirAbs, datasetIngestor.ScanOptions{}, datasetIngestor.FilenamePolicy{})
 		if err != nil {
 			t.Fatalf("expected no error, got: %v", err)
 		}
@@ -342,7 +438,7 @@ func TestGatherCompletionFileList(t *testing.T) {
 			t.Fatalf("failed to create regular file: %s", err)
 		}
 
-		_, _, _, skippedLinks, illegalFileNames, err := gatherCompletionFileList(tempDir)
+		_, _, _, skippedLinks, illegalFileNames, err := gatherCompletionFileList(context.Background(), tempDir, datasetIngestor.ScanOptions{}, datasetIngestor.FilenamePolicy{})
 		if err != nil {
 			t.Fatalf("expected no error, got: %v", err)
 		}
@@ -354,6 +450,25 @@ func TestGatherCompletionFileList(t *testing.T) {
 		}
 	})
 
+	t.Run("fails on illegal filenames in the fail mode", func(t *testing.T) {
+		tempDir := t.TempDir()
+		for _, name := range []string{"regular.txt", "run:1.txt"} {
+			if err := os.WriteFile(filepath.Join(tempDir, name), []byte("test"), 0644); err != nil {
+				t.Fatalf("failed to create file: %s", err)
+			}
+		}
+
+		policy := datasetIngestor.FilenamePolicy{ForbiddenChars: ":", Mode: datasetIngestor.IllegalFilenamesFail}
+		_, _, _, _, _, err := gatherCompletionFileList(context.Background(), tempDir, datasetIngestor.ScanOptions{}, policy)
+		var illegalFileNamesErr *datasetIngestor.IllegalFileNamesError
+		if !errors.As(err, &illegalFileNamesErr) {
+			t.Fatalf("expected an *IllegalFileNamesError, got: %v (%T)", err, err)
+		}
+		if len(illegalFileNamesErr.Files) != 1 || illegalFileNamesErr.Files[0].Path != "run:1.txt" {
+			t.Errorf("expected run:1.txt to be rejected, got %v", illegalFileNamesErr.Files)
+		}
+	})
+
 	t.Run("propagates the underlying error for an empty sourceFolder", func(t *testing.T) {
 		tempDir, err := os.MkdirTemp("./", "test")
 		if err != nil {
@@ -361,7 +476,7 @@ func TestGatherCompletionFileList(t *testing.T) {
 		}
 		defer os.RemoveAll(tempDir)
 
-		_, _, _, _, _, err = gatherCompletionFileList(tempDir)
+		_, _, _, _, _, err = gatherCompletionFileList(context.Background(), tempDir, datasetIngestor.ScanOptions{}, datasetIngestor.FilenamePolicy{})
 		var emptyDatasetErr *datasetIngestor.EmptyDatasetError
 		if !errors.As(err, &emptyDatasetErr) {
 			t.Fatalf("expected an *EmptyDatasetError, got: %v (%T)", err, err)
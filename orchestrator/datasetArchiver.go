@@ -1,6 +1,7 @@
 package orchestrator
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"time"
@@ -18,12 +19,12 @@ missing, not archivable, or not accessible, it returns an error.
 Otherwise, it returns the archivable datasets belonging to ownerGroup. If neither
 ownerGroup nor inputDatasetList is set, it returns an error.
 */
-func ResolveArchivableDatasets(client *http.Client, APIServer string, accessToken string, ownerGroup string, inputDatasetList []string) ([]string, error) {
+func ResolveArchivableDatasets(ctx context.Context, client *http.Client, APIServer string, accessToken string, ownerGroup string, inputDatasetList []string) ([]string, error) {
 	if ownerGroup == "" && len(inputDatasetList) == 0 {
 		return nil, fmt.Errorf("either ownergroup or datasetId(s) must be specified")
 	}
 
-	archivableDatasets, err := datasetUtils.GetArchivableDatasets(client, APIServer, ownerGroup, inputDatasetList, accessToken)
+	archivableDatasets, err := datasetUtils.GetArchivableDatasets(ctx, client, APIServer, ownerGroup, inputDatasetList, accessToken)
 	if err != nil {
 		return nil, fmt.Errorf("GetArchivableDatasets: %w", err)
 	}
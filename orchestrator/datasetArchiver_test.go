@@ -1,6 +1,7 @@
 package orchestrator
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -15,7 +16,7 @@ func TestResolveArchivableDatasets(t *testing.T) {
 		}))
 		defer server.Close()
 
-		datasets, err := ResolveArchivableDatasets(server.Client(), server.URL, "testToken", "testGroup", nil)
+		datasets, err := ResolveArchivableDatasets(context.Background(), server.Client(), server.URL, "testToken", "testGroup", nil)
 		if err != nil {
 			t.Fatalf("expected no error, got: %v", err)
 		}
@@ -30,7 +31,7 @@ func TestResolveArchivableDatasets(t *testing.T) {
 		}))
 		defer server.Close()
 
-		datasets, err := ResolveArchivableDatasets(server.Client(), server.URL, "testToken", "", []string{"1", "2"})
+		datasets, err := ResolveArchivableDatasets(context.Background(), server.Client(), server.URL, "testToken", "", []string{"1", "2"})
 		if err != nil {
 			t.Fatalf("expected no error, got: %v", err)
 		}
@@ -45,14 +46,14 @@ func TestResolveArchivableDatasets(t *testing.T) {
 		}))
 		defer server.Close()
 
-		_, err := ResolveArchivableDatasets(server.Client(), server.URL, "testToken", "", []string{"1", "2"})
+		_, err := ResolveArchivableDatasets(context.Background(), server.Client(), server.URL, "testToken", "", []string{"1", "2"})
 		if err == nil {
 			t.Fatal("expected an error, got nil")
 		}
 	})
 
 	t.Run("fails when neither ownerGroup nor datasetIds are set", func(t *testing.T) {
-		_, err := ResolveArchivableDatasets(http.DefaultClient, "", "testToken", "", nil)
+		_, err := ResolveArchivableDatasets(context.Background(), http.DefaultClient, "", "testToken", "", nil)
 		if err == nil {
 			t.Fatal("expected an error, got nil")
 		}
@@ -64,7 +65,7 @@ func TestResolveArchivableDatasets(t *testing.T) {
 		}))
 		defer server.Close()
 
-		_, err := ResolveArchivableDatasets(server.Client(), server.URL, "testToken", "testGroup", nil)
+		_, err := ResolveArchivableDatasets(context.Background(), server.Client(), server.URL, "testToken", "testGroup", nil)
 		if err == nil {
 			t.Fatal("expected an error, got nil")
 		}
@@ -1,6 +1,7 @@
 package orchestrator
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,6 +11,7 @@ import (
 	"time"
 
 	"github.com/paulscherrerinstitute/scicat-cli/v3/datasetIngestor"
+	"github.com/paulscherrerinstitute/scicat-cli/v3/datasetUtils"
 )
 
 // The dependencies are assigned to module level vars so they can be swapped by mocks in tests
@@ -17,22 +19,31 @@ var getValidatedLocalFileListFunc = datasetIngestor.GetValidatedLocalFileList
 var updateMetadataFunc = datasetIngestor.UpdateMetaData
 var checkDataCentrallyAvailableSsh = datasetIngestor.CheckDataCentrallyAvailableSsh
 
+// MetadataTemplates enables the templates in the metadata values, see
+// datasetIngestor.ExpandMetadataTemplates. Once expanded, the metadata of every dataset is checked
+// with datasetIngestor.CheckMetadata using these rules.
+type MetadataTemplates struct {
+	Rules       datasetIngestor.FacilityRules
+	RemoteFiles bool
+}
+
 // PrepareDataset scans a dataset's local files via datasetIngestor.GetValidatedLocalFileList and,
-// if the dataset survives the empty/too-many-files checks, updates and logs its metadata.
+// if the dataset survives the empty/too-many-files checks, updates and logs its metadata. The
+// templates in the metadata are expanded and the result is checked, unless templates is nil.
 //
 // The returned error follows the same errors.As pattern as ResolveCentralAvailability:
 // *datasetIngestor.EmptyDatasetError or *datasetIngestor.TooManyFilesError just mean this dataset
 // must be skipped (not fatal, no os.Exit); anything else is a hard failure gathering the local
-// file list. emptyDatasets/tooLargeDatasets are incremented to match whichever of those two
-// errors is returned.
-func PrepareDatasetAndUpdateCounts(client *http.Client, APIServer string, user map[string]string,
-	originalMap map[string]string, metaDataMap map[string]interface{}, tapecopies int,
+// file list or with the templates in the metadata. emptyDatasets/tooLargeDatasets are incremented
+// to match whichever of those two errors is returned.
+func PrepareDatasetAndUpdateCounts(ctx context.Context, client *http.Client, APIServer string, user datasetUtils.User,
+	originalMap map[string]interface{}, metaDataMap map[string]interface{}, tapecopies int,
 	datasetSourceFolder string, datasetFileListTxt string,
 	symlinkCallback func(symlinkPath string, sourceFolder string) (bool, error),
-	filenameCheckCallback func(filepath string) bool,
+	filenameCheckCallback func(filepath string) bool, scanOptions datasetIngestor.ScanOptions, templates *MetadataTemplates,
 	emptyDatasets *int, tooLargeDatasets *int) (fullFileArray []datasetIngestor.Datafile, err error) {
-	fullFileArray, err = prepareDataset(client, APIServer, user, originalMap, metaDataMap, tapecopies,
-		datasetSourceFolder, datasetFileListTxt, symlinkCallback, filenameCheckCallback)
+	fullFileArray, err = prepareDataset(ctx, client, APIServer, user, originalMap, metaDataMap, tapecopies,
+		datasetSourceFolder, datasetFileListTxt, symlinkCallback, filenameCheckCallback, scanOptions, templates)
 	if err != nil {
 		var emptyDatasetErr *datasetIngestor.EmptyDatasetError
 		var tooManyFilesErr *datasetIngestor.TooManyFilesError
@@ -48,47 +59,203 @@ func PrepareDatasetAndUpdateCounts(client *http.Client, APIServer string, user m
 }
 
 // prepareDataset scans a dataset's local files via datasetIngestor.GetValidatedLocalFileList and,
-// if the dataset survives the empty/too-many-files checks, updates and logs its metadata.
+// if the dataset survives the empty/too-many-files checks, updates and logs its metadata. The
+// templates in the metadata are expanded and the result is checked, unless templates is nil.
 //
 // The returned error follows the same errors.As pattern as ResolveCentralAvailability:
 // *datasetIngestor.EmptyDatasetError or *datasetIngestor.TooManyFilesError just mean this dataset
 // must be skipped (not fatal, no os.Exit); anything else is a hard failure gathering the local
-// file list. emptyDatasets/tooLargeDatasets are incremented to match whichever of those two
-// errors is returned.
-func prepareDataset(client *http.Client, APIServer string, user map[string]string,
-	originalMap map[string]string, metaDataMap map[string]interface{}, tapecopies int,
+// file list or with the templates in the metadata. emptyDatasets/tooLargeDatasets are incremented
+// to match whichever of those two errors is returned.
+func prepareDataset(ctx context.Context, client *http.Client, APIServer string, user datasetUtils.User,
+	originalMap map[string]interface{}, metaDataMap map[string]interface{}, tapecopies int,
 	datasetSourceFolder string, datasetFileListTxt string,
 	symlinkCallback func(symlinkPath string, sourceFolder string) (bool, error),
-	filenameCheckCallback func(filepath string) bool) (fullFileArray []datasetIngestor.Datafile, err error) {
+	filenameCheckCallback func(filepath string) bool, scanOptions datasetIngestor.ScanOptions, templates *MetadataTemplates) (fullFileArray []datasetIngestor.Datafile, err error) {
 	fullFileArray, startTime, endTime, owner, numFiles, totalSize, err :=
-		getValidatedLocalFileListFunc(datasetSourceFolder, datasetFileListTxt, symlinkCallback, filenameCheckCallback)
+		getValidatedLocalFileListFunc(ctx, datasetSourceFolder, datasetFileListTxt, symlinkCallback, filenameCheckCallback, scanOptions)
 	if err != nil {
 		return fullFileArray, err
 	}
 	log.Println("File list collected.")
 	log.Printf("The dataset contains %v files and directories with a total size of %v bytes.\n", numFiles, totalSize)
 
-	updateAndLogMetaData(client, APIServer, user, originalMap, metaDataMap, startTime, endTime, owner, tapecopies)
+	templateData := datasetIngestor.NewMetadataTemplateData(datasetSourceFolder, startTime, endTime, owner, numFiles, totalSize)
+	if err := updateAndLogMetaData(ctx, client, APIServer, user, originalMap, metaDataMap, templates, templateData, tapecopies); err != nil {
+		return fullFileArray, err
+	}
 	return fullFileArray, nil
 }
 
-// updateAndLogMetaData updates the dataset's metadata fields from the
-// scanned file list and logs the resulting metadata object.
-func updateAndLogMetaData(client *http.Client, APIServer string, user map[string]string,
-	originalMap map[string]string, metaDataMap map[string]interface{}, startTime time.Time, endTime time.Time, owner string, tapecopies int) {
-	updateMetadataFunc(client, APIServer, user, originalMap, metaDataMap, startTime, endTime, owner, tapecopies)
+// updateAndLogMetaData expands the templates in the dataset's metadata and checks the result, if
+// templates isn't nil, updates its fields from the scanned file list and logs the resulting metadata
+// object.
+func updateAndLogMetaData(ctx context.Context, client *http.Client, APIServer string, user datasetUtils.User,
+	originalMap map[string]interface{}, metaDataMap map[string]interface{}, templates *MetadataTemplates,
+	templateData datasetIngestor.MetadataTemplateData, tapecopies int) error {
+	if templates != nil && datasetIngestor.ContainsMetadataTemplates(metaDataMap) {
+		if err := datasetIngestor.ExpandMetadataTemplates(originalMap, metaDataMap, templateData); err != nil {
+			return err
+		}
+		if _, _, err := checkMetadataFunc(ctx, client, APIServer, metaDataMap, user, templates.Rules, templates.RemoteFiles); err != nil {
+			return fmt.Errorf("invalid metadata once the templates are expanded: %w", err)
+		}
+	}
+	scan := templateData.Scan
+	updateMetadataFunc(ctx, client, APIServer, user, originalMap, metaDataMap, scan.StartTime.Time, scan.EndTime.Time, scan.Owner, tapecopies)
 	pretty, _ := json.MarshalIndent(metaDataMap, "", "    ")
 	log.Printf("Updated metadata object:\n%s\n", pretty)
+	return nil
 }
 
 // PrepareRemoteDataset updates and logs metadata for a dataset whose files are accessed remotely and
 // therefore can't be scanned locally: startTime/endTime default to now (there is no file list to derive
-// them from) and owner is read directly from metaDataMap's "owner" field.
-func PrepareRemoteDataset(client *http.Client, APIServer string, user map[string]string,
-	originalMap map[string]string, metaDataMap map[string]interface{}, tapecopies int) {
+// them from) and owner is read directly from metaDataMap's "owner" field. The returned error means the
+// templates in the metadata can't be evaluated, or the expanded metadata is invalid.
+func PrepareRemoteDataset(ctx context.Context, client *http.Client, APIServer string, user datasetUtils.User,
+	originalMap map[string]interface{}, metaDataMap map[string]interface{}, tapecopies int, templates *MetadataTemplates) error {
 	now := time.Now().UTC()
 	owner := metaDataMap["owner"].(string)
-	updateAndLogMetaData(client, APIServer, user, originalMap, metaDataMap, now, now, owner, tapecopies)
+	sourceFolder, _ := metaDataMap["sourceFolder"].(string)
+	templateData := datasetIngestor.NewMetadataTemplateData(sourceFolder, now, now, owner, 0, 0)
+	return updateAndLogMetaData(ctx, client, APIServer, user, originalMap, metaDataMap, templates, templateData, tapecopies)
+}
+
+// streamedTimePlaceholder stands in for the times derived from the files of a streamed dataset,
+// which is created before they're scanned.
+var streamedTimePlaceholder, _ = time.Parse(time.RFC3339, datasetIngestor.DUMMY_TIME)
+
+// PrepareStreamedDataset updates and logs the metadata of a dataset whose origdatablocks are posted
+// while its files are scanned, see StreamDatasetFiles. The dataset is created before the scan, so
+// the owner is taken from the group of datasetSourceFolder, and the times derived from the files
+// keep a placeholder until the scan. The templates in the metadata can't be expanded without the
+// scan, so they aren't supported.
+func PrepareStreamedDataset(ctx context.Context, client *http.Client, APIServer string, user datasetUtils.User,
+	originalMap map[string]interface{}, metaDataMap map[string]interface{}, tapecopies int, datasetSourceFolder string) error {
+	fi, err := os.Stat(datasetSourceFolder)
+	if err != nil {
+		return fmt.Errorf("can't read the sourceFolder %s: %w", datasetSourceFolder, err)
+	}
+	_, owner := datasetIngestor.GetFileOwner(fi)
+	updateMetadataFunc(ctx, client, APIServer, user, originalMap, metaDataMap, streamedTimePlaceholder, streamedTimePlaceholder, owner, tapecopies)
+	pretty, _ := json.MarshalIndent(metaDataMap, "", "    ")
+	log.Printf("Updated metadata object:\n%s\n", pretty)
+	return nil
+}
+
+// StreamDatasetFiles scans the files of the dataset datasetId in datasetSourceFolder like
+// PrepareDatasetAndUpdateCounts, but posts each origdatablock as soon as it's full, so the file
+// list isn't held in memory. Only the paths of the files and whether they're symlinks are returned,
+// if keepPaths is set, e.g. to copy them. Once all blocks are posted, the size and numberOfFiles of
+// the dataset and the times left by PrepareStreamedDataset are patched from the scan.
+//
+// The errors are the ones of PrepareDatasetAndUpdateCounts, the blocks posted before an error
+// remain.
+func StreamDatasetFiles(ctx context.Context, client *http.Client, APIServer string, user datasetUtils.User,
+	metaDataMap map[string]interface{}, datasetId string, datasetSourceFolder string, datasetFileListTxt string,
+	symlinkCallback func(symlinkPath string, sourceFolder string) (bool, error),
+	filenameCheckCallback func(filepath string) bool, scanOptions datasetIngestor.ScanOptions, keepPaths bool,
+	emptyDatasets *int, tooLargeDatasets *int) (files []datasetIngestor.Datafile, err error) {
+	builder := datasetIngestor.NewOrigDatablockBuilder(ctx, client, APIServer, datasetId, user)
+	var scanned int64
+	scanOptions.OnFile = func(file datasetIngestor.Datafile) error {
+		scanned++
+		if scanned > datasetUtils.DefaultIngestSizeLimits.TotalMaxFiles {
+			// the scan goes on to report a TooManyFilesError
+			return nil
+		}
+		if keepPaths {
+			files = append(files, datasetIngestor.Datafile{Path: file.Path, IsSymlink: file.IsSymlink})
+		}
+		return builder.Add(file)
+	}
+
+	_, startTime, endTime, _, numFiles, totalSize, err :=
+		getValidatedLocalFileListFunc(ctx, datasetSourceFolder, datasetFileListTxt, symlinkCallback, filenameCheckCallback, scanOptions)
+	if err != nil {
+		builder.Abort()
+		var emptyDatasetErr *datasetIngestor.EmptyDatasetError
+		var tooManyFilesErr *datasetIngestor.TooManyFilesError
+		switch {
+		case errors.As(err, &emptyDatasetErr):
+			(*emptyDatasets)++
+		case errors.As(err, &tooManyFilesErr):
+			(*tooLargeDatasets)++
+		}
+		return nil, err
+	}
+	if err := builder.Close(); err != nil {
+		return nil, err
+	}
+	log.Println("File list collected and origdatablocks created.")
+	log.Printf("The dataset contains %v files and directories with a total size of %v bytes.\n", numFiles, totalSize)
+
+	meta := map[string]interface{}{
+		"size":          totalSize,
+		"numberOfFiles": numFiles,
+	}
+	scanTimes := map[string]time.Time{"creationTime": startTime, "endTime": endTime}
+	for field, scanTime := range scanTimes {
+		if placeholder, ok := metaDataMap[field].(time.Time); ok && placeholder.Equal(streamedTimePlaceholder) {
+			meta[field] = scanTime.Format(time.RFC3339)
+			metaDataMap[field] = scanTime
+		}
+	}
+	return files, patchDatasetFunc(ctx, client, APIServer, user.AccessToken, datasetId, meta)
+}
+
+// UpdateDatasetTotals sets the size and numberOfFiles of the dataset datasetId to the totals of its
+// files. The catalog only adds up the origdatablocks as they're posted, so the totals must be set
+// whenever they're not posted all at once with the dataset, e.g. once they were replaced.
+func UpdateDatasetTotals(ctx context.Context, client *http.Client, APIServer string, user datasetUtils.User, datasetId string, numFiles int64, totalSize int64) error {
+	meta := map[string]interface{}{
+		"size":          totalSize,
+		"numberOfFiles": numFiles,
+	}
+	return patchDatasetFunc(ctx, client, APIServer, user.AccessToken, datasetId, meta)
+}
+
+// CheckFilenames scans the file names of all sourceFolders with filenamePolicy, so that with
+// IllegalFilenamesFail no dataset is created if any of them has illegal file names. The files are
+// selected like in the ingestion, by filelistingPath, the patterns of scanOptions and skipSymlinks,
+// but no checksums are computed, and the symlinks the user would be asked about are checked too.
+// It returns the *datasetIngestor.IllegalFileNamesError of every folder joined, other scan errors
+// are left to the ingestion. Nothing is checked in the other modes.
+func CheckFilenames(ctx context.Context, sourceFolders []string, filelistingPath string, skipSymlinks string,
+	filenamePolicy datasetIngestor.FilenamePolicy, scanOptions datasetIngestor.ScanOptions) error {
+	if filenamePolicy.Mode != datasetIngestor.IllegalFilenamesFail {
+		return nil
+	}
+	switch skipSymlinks {
+	case "sA", "kA", "dA":
+	default:
+		skipSymlinks = "kA"
+	}
+	var skippedLinks uint
+	symlinkCallback := datasetIngestor.CreateLocalSymlinkCallbackForFileLister(&skipSymlinks, &skippedLinks)
+	filenameFilter, err := datasetIngestor.NewFilenameFilter(filenamePolicy, nil)
+	if err != nil {
+		return err
+	}
+	scanOptions.ChecksumAlgorithm = ""
+	scanOptions.OnFile = nil
+
+	var errs []error
+	for _, sourceFolder := range sourceFolders {
+		if sourceFolder == "" {
+			continue
+		}
+		_, _, _, _, _, _, err := datasetIngestor.GetLocalFileList(ctx, sourceFolder, filelistingPath, symlinkCallback, filenameFilter.Check, scanOptions)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if finishErr := filenameFilter.Finish(sourceFolder); finishErr != nil {
+			errs = append(errs, finishErr)
+		} else if err != nil {
+			log.Printf("Couldn't check the file names of %s: %v\n", sourceFolder, err)
+		}
+	}
+	return errors.Join(errs...)
 }
 
 // DetermineDatasetLifecycle computes the datasetlifecycle fields for a dataset about to be ingested.
@@ -144,13 +311,13 @@ func (w *NotCentrallyAvailableWarning) Error() string {
 // *NotCentrallyAvailableWarning - not a failure, just something the caller should report. It
 // returns ErrCopyRequiresPersonalAccount if no personal account (access group) is available, and
 // ErrIngestAborted if the user declines to continue.
-func ResolveCentralAvailability(username string, rsyncServer string, datasetSourceFolder string,
+func ResolveCentralAvailability(ctx context.Context, username string, rsyncServer string, datasetSourceFolder string,
 	currentCopyFlag bool, accessGroups []string, noninteractive bool, confirmContinue func() bool) (copyFlag bool, err error) {
 	if len(accessGroups) == 0 {
 		return false, ErrCopyRequiresPersonalAccount
 	}
 	log.Println("Checking if data is centrally available...")
-	sshErr, otherErr := checkDataCentrallyAvailableSsh(username, rsyncServer, datasetSourceFolder, os.Stdout)
+	sshErr, otherErr := checkDataCentrallyAvailableSsh(ctx, username, rsyncServer, datasetSourceFolder, os.Stdout)
 	if otherErr != nil {
 		return currentCopyFlag, fmt.Errorf("cannot check if data is centrally available: %w", otherErr)
 	}
@@ -1,14 +1,22 @@
 package orchestrator
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"io"
+	"maps"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/paulscherrerinstitute/scicat-cli/v3/datasetIngestor"
+	"github.com/paulscherrerinstitute/scicat-cli/v3/datasetUtils"
 )
 
 // --- PrepareDataset ---
@@ -77,9 +85,9 @@ func TestPrepareDataset(t *testing.T) {
 			})
 
 			wantFiles := []datasetIngestor.Datafile{{Path: "a"}}
-			getValidatedLocalFileListFunc = func(sourceFolder string, filelistingPath string,
+			getValidatedLocalFileListFunc = func(ctx context.Context, sourceFolder string, filelistingPath string,
 				symlinkCallback func(symlinkPath string, sourceFolder string) (bool, error),
-				filenameFilterCallback func(filepath string) bool,
+				filenameFilterCallback func(filepath string) bool, options datasetIngestor.ScanOptions,
 			) ([]datasetIngestor.Datafile, time.Time, time.Time, string, int64, int64, error) {
 				if tt.fileListErr != nil {
 					return nil, time.Time{}, time.Time{}, "", 0, 0, tt.fileListErr
@@ -88,15 +96,15 @@ func TestPrepareDataset(t *testing.T) {
 			}
 
 			updateMetadataCalled := false
-			updateMetadataFunc = func(client *http.Client, APIServer string, user map[string]string,
-				originalMap map[string]string, metaDataMap map[string]interface{}, startTime time.Time, endTime time.Time, owner string, tapecopies int) {
+			updateMetadataFunc = func(ctx context.Context, client *http.Client, APIServer string, user datasetUtils.User,
+				originalMap map[string]interface{}, metaDataMap map[string]interface{}, startTime time.Time, endTime time.Time, owner string, tapecopies int) {
 				updateMetadataCalled = true
 			}
 
 			var emptyDatasets, tooLargeDatasets int
-			fullFileArray, err := PrepareDatasetAndUpdateCounts(nil, "", map[string]string{"accessToken": "testToken"},
-				map[string]string{}, map[string]interface{}{"ownerGroup": datasetIngestor.DUMMY_OWNER}, 1,
-				"/some/folder", "", nil, nil, &emptyDatasets, &tooLargeDatasets)
+			fullFileArray, err := PrepareDatasetAndUpdateCounts(context.Background(), nil, "", datasetUtils.User{AccessToken: "testToken"},
+				map[string]interface{}{}, map[string]interface{}{"ownerGroup": datasetIngestor.DUMMY_OWNER}, 1,
+				"/some/folder", "", nil, nil, datasetIngestor.ScanOptions{}, nil, &emptyDatasets, &tooLargeDatasets)
 
 			tt.checkErr(t, err)
 
@@ -117,6 +125,73 @@ func TestPrepareDataset(t *testing.T) {
 	}
 }
 
+func TestPrepareDatasetTemplates(t *testing.T) {
+	oldList, oldUpdate, oldCheck := getValidatedLocalFileListFunc, updateMetadataFunc, checkMetadataFunc
+	t.Cleanup(func() {
+		getValidatedLocalFileListFunc, updateMetadataFunc, checkMetadataFunc = oldList, oldUpdate, oldCheck
+	})
+
+	startTime := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	getValidatedLocalFileListFunc = func(ctx context.Context, sourceFolder string, filelistingPath string,
+		symlinkCallback func(symlinkPath string, sourceFolder string) (bool, error),
+		filenameFilterCallback func(filepath string) bool, options datasetIngestor.ScanOptions,
+	) ([]datasetIngestor.Datafile, time.Time, time.Time, string, int64, int64, error) {
+		return []datasetIngestor.Datafile{{Path: "a"}}, startTime, startTime, "abc", 1, 10, nil
+	}
+	updateMetadataFunc = func(ctx context.Context, client *http.Client, APIServer string, user datasetUtils.User,
+		originalMap map[string]interface{}, metaDataMap map[string]interface{}, startTime time.Time, endTime time.Time, owner string, tapecopies int) {
+	}
+	var checked []map[string]interface{}
+	var checkErr error
+	checkMetadataFunc = func(ctx context.Context, client *http.Client, APIServer string, metaDataMap map[string]interface{},
+		user datasetUtils.User, rules datasetIngestor.FacilityRules, remoteFiles bool) (string, bool, error) {
+		checked = append(checked, maps.Clone(metaDataMap))
+		return "", false, checkErr
+	}
+
+	prepare := func(templates *MetadataTemplates) (map[string]interface{}, error) {
+		metaDataMap := map[string]interface{}{
+			"creationTime": "{{ .Scan.StartTime }}",
+			"datasetName":  "{{ .Folder.Base }}",
+		}
+		var emptyDatasets, tooLargeDatasets int
+		_, err := PrepareDatasetAndUpdateCounts(context.Background(), nil, "", datasetUtils.User{}, map[string]interface{}{}, metaDataMap, 1,
+			"/data/run_1", "", nil, nil, datasetIngestor.ScanOptions{}, templates, &emptyDatasets, &tooLargeDatasets)
+		return metaDataMap, err
+	}
+
+	t.Run("the templates are kept as they are unless enabled", func(t *testing.T) {
+		checked = nil
+		metaDataMap, err := prepare(nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if metaDataMap["datasetName"] != "{{ .Folder.Base }}" || len(checked) != 0 {
+			t.Errorf("expected no expansion and no check, got %v, %d checks", metaDataMap, len(checked))
+		}
+	})
+
+	t.Run("the expanded metadata is checked", func(t *testing.T) {
+		checked = nil
+		metaDataMap, err := prepare(&MetadataTemplates{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := map[string]interface{}{"creationTime": "2024-03-01T12:30:00Z", "datasetName": "run_1"}
+		if len(checked) != 1 || !reflect.DeepEqual(checked[0], want) || !reflect.DeepEqual(metaDataMap, want) {
+			t.Errorf("checked %v, metadata %v, want %v", checked, metaDataMap, want)
+		}
+	})
+
+	t.Run("invalid expanded metadata is an error", func(t *testing.T) {
+		checkErr = errors.New("creationTime is invalid")
+		defer func() { checkErr = nil }()
+		if _, err := prepare(&MetadataTemplates{}); err == nil || !errors.Is(err, checkErr) {
+			t.Errorf("expected the check error, got %v", err)
+		}
+	})
+}
+
 // --- PrepareRemoteDataset ---
 
 func TestPrepareRemoteDataset(t *testing.T) {
@@ -127,8 +202,8 @@ func TestPrepareRemoteDataset(t *testing.T) {
 	defer ts.Close()
 
 	client := ts.Client()
-	user := map[string]string{"accessToken": "testToken"}
-	originalMap := map[string]string{}
+	user := datasetUtils.User{AccessToken: "testToken"}
+	originalMap := map[string]interface{}{}
 	metaDataMap := map[string]interface{}{
 		"ownerGroup":   datasetIngestor.DUMMY_OWNER,
 		"owner":        "testOwner",
@@ -136,7 +211,9 @@ func TestPrepareRemoteDataset(t *testing.T) {
 	}
 
 	before := time.Now()
-	PrepareRemoteDataset(client, ts.URL, user, originalMap, metaDataMap, 1)
+	if err := PrepareRemoteDataset(context.Background(), client, ts.URL, user, originalMap, metaDataMap, 1, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
 	after := time.Now()
 
 	if _, ok := metaDataMap["license"]; !ok {
@@ -154,6 +231,210 @@ func TestPrepareRemoteDataset(t *testing.T) {
 	}
 }
 
+// --- CheckFilenames ---
+
+// --- PrepareStreamedDataset / StreamDatasetFiles ---
+
+func TestPrepareStreamedDataset(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[]`))
+	}))
+	defer ts.Close()
+
+	sourceFolder := t.TempDir()
+	metaDataMap := map[string]interface{}{
+		"ownerGroup":   datasetIngestor.DUMMY_OWNER,
+		"creationTime": datasetIngestor.DUMMY_TIME,
+	}
+	if err := PrepareStreamedDataset(context.Background(), ts.Client(), ts.URL, datasetUtils.User{}, map[string]interface{}{}, metaDataMap, 1, sourceFolder); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	fi, err := os.Stat(sourceFolder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, group := datasetIngestor.GetFileOwner(fi); metaDataMap["ownerGroup"] != group {
+		t.Errorf("expected ownerGroup to be the group of the sourceFolder %q, got %v", group, metaDataMap["ownerGroup"])
+	}
+	if creationTime, ok := metaDataMap["creationTime"].(time.Time); !ok || !creationTime.Equal(streamedTimePlaceholder) {
+		t.Errorf("expected creationTime to keep the placeholder until the scan, got %v", metaDataMap["creationTime"])
+	}
+
+	t.Run("fails without the sourceFolder", func(t *testing.T) {
+		err := PrepareStreamedDataset(context.Background(), ts.Client(), ts.URL, datasetUtils.User{}, map[string]interface{}{}, metaDataMap, 1, filepath.Join(sourceFolder, "missing"))
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}
+
+func TestStreamDatasetFiles(t *testing.T) {
+	oldList := getValidatedLocalFileListFunc
+	oldPatchDataset := patchDatasetFunc
+	t.Cleanup(func() {
+		getValidatedLocalFileListFunc = oldList
+		patchDatasetFunc = oldPatchDataset
+	})
+
+	startTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	endTime := time.Date(2020, 6, 7, 8, 9, 10, 0, time.UTC)
+	scan := func(scanErr error) {
+		getValidatedLocalFileListFunc = func(ctx context.Context, sourceFolder string, filelistingPath string,
+			symlinkCallback func(symlinkPath string, sourceFolder string) (bool, error),
+			filenameFilterCallback func(filepath string) bool, options datasetIngestor.ScanOptions,
+		) ([]datasetIngestor.Datafile, time.Time, time.Time, string, int64, int64, error) {
+			if options.OnFile == nil {
+				t.Fatal("expected the files to be streamed")
+			}
+			for _, file := range []datasetIngestor.Datafile{{Path: "a", Size: 1}, {Path: "b", Size: 2, IsSymlink: true}} {
+				if err := options.OnFile(file); err != nil {
+					return nil, time.Time{}, time.Time{}, "", 0, 0, err
+				}
+			}
+			return nil, startTime, endTime, "abc", 2, 3, scanErr
+		}
+	}
+	var posted []datasetIngestor.FileBlock
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var block datasetIngestor.FileBlock
+		if err := json.NewDecoder(r.Body).Decode(&block); err != nil {
+			t.Errorf("can't decode the origdatablock: %v", err)
+		}
+		posted = append(posted, block)
+	}))
+	defer ts.Close()
+	user := datasetUtils.User{AccessToken: "token"}
+
+	t.Run("posts the blocks during the scan and patches the totals and the placeholder times", func(t *testing.T) {
+		posted = nil
+		scan(nil)
+		var patched map[string]interface{}
+		patchDatasetFunc = func(ctx context.Context, client *http.Client, APIServer string, token string, datasetId string, meta map[string]interface{}) error {
+			patched = meta
+			return nil
+		}
+		metaDataMap := map[string]interface{}{"creationTime": streamedTimePlaceholder, "endTime": "2021-01-01T00:00:00Z"}
+		var emptyDatasets, tooLargeDatasets int
+
+		files, err := StreamDatasetFiles(context.Background(), ts.Client(), ts.URL, user, metaDataMap, "testPid", "/folder", "",
+			nil, nil, datasetIngestor.ScanOptions{}, true, &emptyDatasets, &tooLargeDatasets)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(posted) != 1 || len(posted[0].DataFileList) != 2 || posted[0].DatasetId != "testPid" {
+			t.Errorf("expected one block with both files, got %+v", posted)
+		}
+		wantFiles := []datasetIngestor.Datafile{{Path: "a"}, {Path: "b", IsSymlink: true}}
+		if !reflect.DeepEqual(files, wantFiles) {
+			t.Errorf("got the files %+v, want %+v", files, wantFiles)
+		}
+		// the endTime set in the metadata is kept
+		want := map[string]interface{}{"size": int64(3), "numberOfFiles": int64(2), "creationTime": startTime.Format(time.RFC3339)}
+		if !reflect.DeepEqual(patched, want) {
+			t.Errorf("patched %v, want %v", patched, want)
+		}
+		if metaDataMap["creationTime"] != startTime {
+			t.Errorf("expected the creationTime of the metadata to be updated, got %v", metaDataMap["creationTime"])
+		}
+	})
+
+	t.Run("counts an empty dataset and doesn't patch it", func(t *testing.T) {
+		posted = nil
+		scan(&datasetIngestor.EmptyDatasetError{SourceFolder: "/folder"})
+		patchDatasetFunc = func(ctx context.Context, client *http.Client, APIServer string, token string, datasetId string, meta map[string]interface{}) error {
+			t.Error("the dataset shouldn't be patched")
+			return nil
+		}
+		var emptyDatasets, tooLargeDatasets int
+
+		files, err := StreamDatasetFiles(context.Background(), ts.Client(), ts.URL, user, map[string]interface{}{}, "testPid", "/folder", "",
+			nil, nil, datasetIngestor.ScanOptions{}, false, &emptyDatasets, &tooLargeDatasets)
+		var emptyDatasetErr *datasetIngestor.EmptyDatasetError
+		if !errors.As(err, &emptyDatasetErr) {
+			t.Fatalf("expected an EmptyDatasetError, got %v", err)
+		}
+		if emptyDatasets != 1 || tooLargeDatasets != 0 {
+			t.Errorf("got %d empty and %d too large datasets, want 1 and 0", emptyDatasets, tooLargeDatasets)
+		}
+		if files != nil || len(posted) != 0 {
+			t.Errorf("expected no files and no posted blocks, got %+v and %+v", files, posted)
+		}
+	})
+}
+
+// --- UpdateDatasetTotals ---
+
+func TestUpdateDatasetTotals(t *testing.T) {
+	oldPatchDataset := patchDatasetFunc
+	t.Cleanup(func() { patchDatasetFunc = oldPatchDataset })
+
+	var patched map[string]interface{}
+	patchDatasetFunc = func(ctx context.Context, client *http.Client, APIServer string, token string, datasetId string, meta map[string]interface{}) error {
+		if token != "token" || datasetId != "testPid" {
+			t.Errorf("patched dataset %s with token %s, want testPid with token", datasetId, token)
+		}
+		patched = meta
+		return nil
+	}
+
+	err := UpdateDatasetTotals(context.Background(), http.DefaultClient, "", datasetUtils.User{AccessToken: "token"}, "testPid", 3, 1024)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]interface{}{"size": int64(1024), "numberOfFiles": int64(3)}
+	if !reflect.DeepEqual(patched, want) {
+		t.Errorf("patched %v, want %v", patched, want)
+	}
+}
+
+func TestCheckFilenames(t *testing.T) {
+	root := t.TempDir()
+	folders := map[string]string{"good": "run.h5", "star": "run*.h5", "blanks": "run   1.h5"}
+	var sourceFolders []string
+	for folder, file := range folders {
+		sourceFolder := filepath.Join(root, folder)
+		if err := os.Mkdir(sourceFolder, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(sourceFolder, file), []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		sourceFolders = append(sourceFolders, sourceFolder)
+	}
+	failPolicy := datasetIngestor.FilenamePolicy{Mode: datasetIngestor.IllegalFilenamesFail}
+
+	t.Run("the illegal file names of all folders are returned", func(t *testing.T) {
+		err := CheckFilenames(context.Background(), append(sourceFolders, ""), "", "", failPolicy, datasetIngestor.ScanOptions{})
+		var illegalErr *datasetIngestor.IllegalFileNamesError
+		if !errors.As(err, &illegalErr) {
+			t.Fatalf("expected an IllegalFileNamesError, got %v", err)
+		}
+		for _, folder := range []string{"star", "blanks"} {
+			if !strings.Contains(err.Error(), filepath.Join(root, folder)) {
+				t.Errorf("expected the error to name the folder %s, got %v", folder, err)
+			}
+		}
+		if strings.Contains(err.Error(), filepath.Join(root, "good")) {
+			t.Errorf("the folder without illegal names shouldn't be named, got %v", err)
+		}
+	})
+
+	t.Run("excluded files aren't checked", func(t *testing.T) {
+		scanOptions := datasetIngestor.ScanOptions{Exclude: []string{"run*"}}
+		if err := CheckFilenames(context.Background(), sourceFolders, "", "", failPolicy, scanOptions); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("nothing is checked unless the mode is fail", func(t *testing.T) {
+		policy := datasetIngestor.FilenamePolicy{Mode: datasetIngestor.IllegalFilenamesSkip}
+		if err := CheckFilenames(context.Background(), sourceFolders, "", "", policy, datasetIngestor.ScanOptions{}); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
+
 // --- DetermineDatasetLifecycle ---
 
 func TestDetermineDatasetLifecycle(t *testing.T) {
@@ -196,7 +477,7 @@ func withSshMock(t *testing.T, sshErr error, otherErr error) {
 	t.Helper()
 	old := checkDataCentrallyAvailableSsh
 	t.Cleanup(func() { checkDataCentrallyAvailableSsh = old })
-	checkDataCentrallyAvailableSsh = func(username, ARCHIVEServer, sourceFolder string, sshOutput io.Writer) (error, error) {
+	checkDataCentrallyAvailableSsh = func(ctx context.Context, username, ARCHIVEServer, sourceFolder string, sshOutput io.Writer) (error, error) {
 		return sshErr, otherErr
 	}
 }
@@ -204,7 +485,7 @@ func withSshMock(t *testing.T, sshErr error, otherErr error) {
 func TestResolveCentralAvailability_Available(t *testing.T) {
 	withSshMock(t, nil, nil)
 
-	copyFlag, err := ResolveCentralAvailability("user", "server", "/some/folder", false, []string{"group1"}, false, nil)
+	copyFlag, err := ResolveCentralAvailability(context.Background(), "user", "server", "/some/folder", false, []string{"group1"}, false, nil)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -216,7 +497,7 @@ func TestResolveCentralAvailability_Available(t *testing.T) {
 func TestResolveCentralAvailability_Available_PreservesCurrentCopyFlag(t *testing.T) {
 	withSshMock(t, nil, nil)
 
-	copyFlag, err := ResolveCentralAvailability("user", "server", "/some/folder", true, []string{"group1"}, false, nil)
+	copyFlag, err := ResolveCentralAvailability(context.Background(), "user", "server", "/some/folder", true, []string{"group1"}, false, nil)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -228,7 +509,7 @@ func TestResolveCentralAvailability_Available_PreservesCurrentCopyFlag(t *testin
 func TestResolveCentralAvailability_NotAvailable_Noninteractive(t *testing.T) {
 	withSshMock(t, errors.New("not found"), nil)
 
-	copyFlag, err := ResolveCentralAvailability("user", "server", "/some/folder", false, []string{"group1"}, true, nil)
+	copyFlag, err := ResolveCentralAvailability(context.Background(), "user", "server", "/some/folder", false, []string{"group1"}, true, nil)
 	var warning *NotCentrallyAvailableWarning
 	if !errors.As(err, &warning) {
 		t.Fatalf("expected a *NotCentrallyAvailableWarning, got %v", err)
@@ -241,7 +522,7 @@ func TestResolveCentralAvailability_NotAvailable_Noninteractive(t *testing.T) {
 func TestResolveCentralAvailability_NoAccessGroups(t *testing.T) {
 	// ResolveCentralAvailability returns before ever checking central availability when there's
 	// no access group, so no ssh mock is needed here.
-	_, err := ResolveCentralAvailability("user", "server", "/some/folder", false, nil, false, func() bool { return true })
+	_, err := ResolveCentralAvailability(context.Background(), "user", "server", "/some/folder", false, nil, false, func() bool { return true })
 	if !errors.Is(err, ErrCopyRequiresPersonalAccount) {
 		t.Fatalf("expected ErrCopyRequiresPersonalAccount, got %v", err)
 	}
@@ -250,7 +531,7 @@ func TestResolveCentralAvailability_NoAccessGroups(t *testing.T) {
 func TestResolveCentralAvailability_UserAborts(t *testing.T) {
 	withSshMock(t, errors.New("not found"), nil)
 
-	_, err := ResolveCentralAvailability("user", "server", "/some/folder", false, []string{"group1"}, false, func() bool { return false })
+	_, err := ResolveCentralAvailability(context.Background(), "user", "server", "/some/folder", false, []string{"group1"}, false, func() bool { return false })
 	if !errors.Is(err, ErrIngestAborted) {
 		t.Fatalf("expected ErrIngestAborted, got %v", err)
 	}
@@ -259,7 +540,7 @@ func TestResolveCentralAvailability_UserAborts(t *testing.T) {
 func TestResolveCentralAvailability_UserConfirms(t *testing.T) {
 	withSshMock(t, errors.New("not found"), nil)
 
-	copyFlag, err := ResolveCentralAvailability("user", "server", "/some/folder", false, []string{"group1"}, false, func() bool { return true })
+	copyFlag, err := ResolveCentralAvailability(context.Background(), "user", "server", "/some/folder", false, []string{"group1"}, false, func() bool { return true })
 	var warning *NotCentrallyAvailableWarning
 	if !errors.As(err, &warning) {
 		t.Fatalf("expected a *NotCentrallyAvailableWarning, got %v", err)
@@ -272,7 +553,7 @@ func TestResolveCentralAvailability_UserConfirms(t *testing.T) {
 func TestResolveCentralAvailability_OtherError(t *testing.T) {
 	withSshMock(t, nil, errors.New("connection refused"))
 
-	_, err := ResolveCentralAvailability("user", "server", "/some/folder", false, []string{"group1"}, false, nil)
+	_, err := ResolveCentralAvailability(context.Background(), "user", "server", "/some/folder", false, []string{"group1"}, false, nil)
 	var warning *NotCentrallyAvailableWarning
 	if err == nil || errors.As(err, &warning) {
 		t.Fatalf("expected a plain error, got %v", err)
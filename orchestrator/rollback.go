@@ -0,0 +1,70 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/paulscherrerinstitute/scicat-cli/v3/datasetUtils"
+)
+
+var deleteDatasetDocumentsFunc = datasetUtils.DeleteDatasetDocuments
+var getDatasetLifecycleFunc = datasetUtils.GetDatasetLifecycle
+
+// FailedIngestAction selects what happens to a dataset whose ingestion failed after it was created.
+type FailedIngestAction string
+
+const (
+	// FailedIngestDelete deletes the dataset along with the origdatablocks created for it.
+	FailedIngestDelete FailedIngestAction = "delete"
+	// FailedIngestTag keeps the dataset, with FailedIngestStatus as its archiveStatusMessage.
+	FailedIngestTag FailedIngestAction = "tag"
+	// FailedIngestKeep leaves the dataset as it is.
+	FailedIngestKeep FailedIngestAction = "keep"
+)
+
+// FailedIngestStatus is the archiveStatusMessage of the datasets tagged by FailedIngestTag.
+const FailedIngestStatus = "failedIngest"
+
+// ParseFailedIngestAction returns the FailedIngestAction called s.
+func ParseFailedIngestAction(s string) (FailedIngestAction, error) {
+	switch action := FailedIngestAction(s); action {
+	case FailedIngestDelete, FailedIngestTag, FailedIngestKeep:
+		return action, nil
+	}
+	return "", fmt.Errorf("unknown action %q for failed ingests, use one of: delete, tag, keep", s)
+}
+
+// RollbackDataset applies action to the dataset datasetId, whose origdatablocks or files couldn't
+// be added, so the catalog isn't left with a dataset missing some of its files. SciCat usually only
+// lets archive managers delete datasets, so if the deletion is refused, the dataset is tagged
+// instead. It returns the action that was applied.
+func RollbackDataset(ctx context.Context, client *http.Client, APIServer string, user datasetUtils.User, datasetId string, action FailedIngestAction) (FailedIngestAction, error) {
+	switch action {
+	case FailedIngestDelete:
+		log.Printf("Deleting the dataset %s of the failed ingestion...\n", datasetId)
+		err := deleteDatasetDocumentsFunc(ctx, client, APIServer, datasetId, user)
+		var apiErr *datasetUtils.APIError
+		if errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden) {
+			log.Printf("The dataset %s can't be deleted by %s (%v), it's tagged instead\n", datasetId, user.Username, err)
+			return RollbackDataset(ctx, client, APIServer, user, datasetId, FailedIngestTag)
+		}
+		if err != nil {
+			return action, fmt.Errorf("failed to delete dataset %s: %w", datasetId, err)
+		}
+	case FailedIngestTag:
+		log.Printf("Tagging the dataset %s of the failed ingestion with the status %q...\n", datasetId, FailedIngestStatus)
+		// the datasetlifecycle is replaced as a whole by a PATCH, so its other fields are kept
+		lifecycle, err := getDatasetLifecycleFunc(ctx, client, APIServer, user.AccessToken, datasetId)
+		if err != nil {
+			return action, err
+		}
+		lifecycle["archivable"] = false
+		lifecycle["archiveStatusMessage"] = FailedIngestStatus
+		meta := map[string]interface{}{"datasetlifecycle": lifecycle}
+		return action, patchDatasetFunc(ctx, client, APIServer, user.AccessToken, datasetId, meta)
+	}
+	return action, nil
+}
@@ -0,0 +1,102 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/paulscherrerinstitute/scicat-cli/v3/datasetUtils"
+)
+
+func TestParseFailedIngestAction(t *testing.T) {
+	for _, s := range []string{"delete", "tag", "keep"} {
+		if action, err := ParseFailedIngestAction(s); err != nil || string(action) != s {
+			t.Errorf("ParseFailedIngestAction(%q) = %q, %v", s, action, err)
+		}
+	}
+	if _, err := ParseFailedIngestAction("drop"); err == nil {
+		t.Error("expected an error for an unknown action")
+	}
+}
+
+func TestRollbackDataset(t *testing.T) {
+	oldDelete := deleteDatasetDocumentsFunc
+	oldPatch := patchDatasetFunc
+	oldGetLifecycle := getDatasetLifecycleFunc
+	t.Cleanup(func() {
+		deleteDatasetDocumentsFunc = oldDelete
+		patchDatasetFunc = oldPatch
+		getDatasetLifecycleFunc = oldGetLifecycle
+	})
+	var deleted string
+	var patched map[string]interface{}
+	deleteDatasetDocumentsFunc = func(ctx context.Context, client *http.Client, APIServer string, pid string, user datasetUtils.User) error {
+		deleted = pid
+		return nil
+	}
+	patchDatasetFunc = func(ctx context.Context, client *http.Client, APIServer string, token string, datasetId string, meta map[string]interface{}) error {
+		patched = meta
+		return nil
+	}
+	getDatasetLifecycleFunc = func(ctx context.Context, client *http.Client, APIServer string, token string, datasetId string) (map[string]interface{}, error) {
+		return map[string]interface{}{"archivable": true, "retrievable": false, "publishable": true}, nil
+	}
+	user := datasetUtils.User{AccessToken: "testToken"}
+
+	t.Run("delete", func(t *testing.T) {
+		deleted, patched = "", nil
+		if applied, err := RollbackDataset(context.Background(), nil, "", user, "testPid", FailedIngestDelete); err != nil || applied != FailedIngestDelete {
+			t.Fatalf("unexpected result: %q, %v", applied, err)
+		}
+		if deleted != "testPid" || patched != nil {
+			t.Errorf("expected the dataset to be deleted only, deleted %q, patched %v", deleted, patched)
+		}
+	})
+
+	t.Run("tag", func(t *testing.T) {
+		deleted, patched = "", nil
+		if applied, err := RollbackDataset(context.Background(), nil, "", user, "testPid", FailedIngestTag); err != nil || applied != FailedIngestTag {
+			t.Fatalf("unexpected result: %q, %v", applied, err)
+		}
+		lifecycle, _ := patched["datasetlifecycle"].(map[string]interface{})
+		if deleted != "" || lifecycle["archiveStatusMessage"] != FailedIngestStatus || lifecycle["archivable"] != false || lifecycle["publishable"] != true {
+			t.Errorf("expected the dataset to be tagged only, deleted %q, patched %v", deleted, patched)
+		}
+	})
+
+	t.Run("keep", func(t *testing.T) {
+		deleted, patched = "", nil
+		if applied, err := RollbackDataset(context.Background(), nil, "", user, "testPid", FailedIngestKeep); err != nil || applied != FailedIngestKeep {
+			t.Fatalf("unexpected result: %q, %v", applied, err)
+		}
+		if deleted != "" || patched != nil {
+			t.Errorf("expected the dataset to be left alone, deleted %q, patched %v", deleted, patched)
+		}
+	})
+
+	t.Run("delete fails", func(t *testing.T) {
+		deleteDatasetDocumentsFunc = func(ctx context.Context, client *http.Client, APIServer string, pid string, user datasetUtils.User) error {
+			return errors.New("boom")
+		}
+		if _, err := RollbackDataset(context.Background(), nil, "", user, "testPid", FailedIngestDelete); err == nil {
+			t.Error("expected an error")
+		}
+	})
+
+	t.Run("delete isn't allowed", func(t *testing.T) {
+		deleted, patched = "", nil
+		deleteDatasetDocumentsFunc = func(ctx context.Context, client *http.Client, APIServer string, pid string, user datasetUtils.User) error {
+			return fmt.Errorf("delete failed: %w", &datasetUtils.APIError{Method: "DELETE", Path: "/Datasets/" + pid, StatusCode: http.StatusForbidden})
+		}
+		applied, err := RollbackDataset(context.Background(), nil, "", user, "testPid", FailedIngestDelete)
+		if err != nil || applied != FailedIngestTag {
+			t.Fatalf("expected the dataset to be tagged instead, got %q, %v", applied, err)
+		}
+		lifecycle, _ := patched["datasetlifecycle"].(map[string]interface{})
+		if lifecycle["archiveStatusMessage"] != FailedIngestStatus {
+			t.Errorf("expected the dataset to be tagged, patched %v", patched)
+		}
+	})
+}